@@ -7,6 +7,9 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"time"
+
+	"github.com/airforce270/airbot/metrics"
 )
 
 // DefaultBaseURL is the default base URL for the Kick API.
@@ -82,6 +85,7 @@ type Verse struct {
 }
 
 func get(reqURL string) (respBody []byte, err error) {
+	defer metrics.ObserveAPIRequest("bible", time.Now())
 	httpResp, err := http.Get(reqURL)
 	if err != nil {
 		return nil, fmt.Errorf("get request to Bible API (URL:%s) failed: %w", reqURL, err)
@@ -9,6 +9,8 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/airforce270/airbot/metrics"
 )
 
 var (
@@ -63,6 +65,7 @@ func (c *Client) FetchModsAndVIPs(channel string) (*ModsAndVIPsResponse, error)
 
 // FetchFounders fetches the list of founders for a given Twitch channel.
 func (c *Client) FetchFounders(channel string) (*FoundersResponse, error) {
+	defer metrics.ObserveAPIRequest("ivr", time.Now())
 	reqURL := fmt.Sprintf("%s/v2/twitch/founders/%s", c.baseURL, channel)
 	httpResp, err := http.Get(reqURL)
 	if err != nil {
@@ -99,6 +102,7 @@ func (c *Client) FetchFounders(channel string) (*FoundersResponse, error) {
 // If a user or channel was not found,
 // ErrUserNotFound or ErrChannelNotFound will be returned, respectively.
 func (c *Client) FetchSubAge(user, channel string) (*SubAgeResponse, error) {
+	defer metrics.ObserveAPIRequest("ivr", time.Now())
 	reqURL := fmt.Sprintf("%s/v2/twitch/subage/%s/%s", c.baseURL, user, channel)
 	httpResp, err := http.Get(reqURL)
 	if err != nil {
@@ -397,6 +401,7 @@ type SubAgeGiftMetadata struct {
 }
 
 func get(reqURL string) (respBody []byte, err error) {
+	defer metrics.ObserveAPIRequest("ivr", time.Now())
 	httpResp, err := http.Get(reqURL)
 	if err != nil {
 		return nil, fmt.Errorf("get request to IVR failed (URL:%s): %w", reqURL, err)
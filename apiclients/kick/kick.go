@@ -10,6 +10,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/airforce270/airbot/metrics"
+
 	"github.com/Danny-Dasilva/CycleTLS/cycletls"
 )
 
@@ -67,6 +69,7 @@ func (c *Client) FetchChannel(channel string) (*Channel, error) {
 }
 
 func (c *Client) get(reqURL string) (respBody []byte, err error) {
+	defer metrics.ObserveAPIRequest("kick", time.Now())
 	c.Mtx.RLock()
 	opts := cycletls.Options{Ja3: c.JA3, UserAgent: c.UserAgent}
 	c.Mtx.RUnlock()
@@ -5,23 +5,36 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
+	"time"
+
+	"github.com/airforce270/airbot/metrics"
 )
 
+// createPasteURL is the URL of the Pastebin API's paste creation endpoint.
+const createPasteURL = "https://pastebin.com/api/api_post.php"
+
 // NewClient creates a new Pastebin API client.
-// fetchPasteURLOverride is optional and should only be set in test.
-func NewClient(fetchPasteURLOverride string) *Client {
-	return &Client{fetchPasteURLOverride: fetchPasteURLOverride}
+// fetchPasteURLOverride and createPasteURLOverride are optional
+// and should only be set in test.
+func NewClient(fetchPasteURLOverride, createPasteURLOverride string) *Client {
+	return &Client{
+		fetchPasteURLOverride:  fetchPasteURLOverride,
+		createPasteURLOverride: createPasteURLOverride,
+	}
 }
 
 // Client is a client for the Pastebin API.
 type Client struct {
-	fetchPasteURLOverride string
+	fetchPasteURLOverride  string
+	createPasteURLOverride string
 }
 
 // FetchPaste fetches a paste, given a pastebin URL.
 // Example: https://pastebin.com/raw/B7TBjQEy
 func (c *Client) FetchPaste(pasteURL string) (Paste, error) {
+	defer metrics.ObserveAPIRequest("pastebin", time.Now())
 	reqURL := pasteURL
 	if c.fetchPasteURLOverride != "" {
 		reqURL = c.fetchPasteURLOverride
@@ -50,3 +63,40 @@ type Paste []string
 
 // Values returns the paste's values.
 func (p Paste) Values() []string { return []string(p) }
+
+// CreatePaste creates an unlisted paste that expires after a week
+// and returns its URL.
+// devKey is the Pastebin API developer key to create the paste with.
+func (c *Client) CreatePaste(devKey, title, text string) (string, error) {
+	defer metrics.ObserveAPIRequest("pastebin", time.Now())
+	reqURL := createPasteURL
+	if c.createPasteURLOverride != "" {
+		reqURL = c.createPasteURLOverride
+	}
+
+	form := url.Values{
+		"api_dev_key":           {devKey},
+		"api_option":            {"paste"},
+		"api_paste_code":        {text},
+		"api_paste_name":        {title},
+		"api_paste_private":     {"1"}, // unlisted
+		"api_paste_expire_date": {"1W"},
+	}
+	resp, err := http.PostForm(reqURL, form)
+	if err != nil {
+		return "", fmt.Errorf("failed to create paste on pastebin (URL:%s): %w", reqURL, err)
+	}
+	defer func() { _ = resp.Body.Close() }() // ignore error
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response from Pastebin API: %w", err)
+	}
+	// Errors are returned as plain text, i.e. "Bad API request, invalid api_dev_key".
+	pasteURL := strings.TrimSpace(string(body))
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(pasteURL, "http") {
+		return "", fmt.Errorf("bad response from Pastebin API (URL:%s, status:%d): %s", reqURL, resp.StatusCode, pasteURL)
+	}
+
+	return pasteURL, nil
+}
@@ -37,7 +37,7 @@ func TestFetchPaste(t *testing.T) {
 			defer server.Close()
 			server.Resps = []string{tc.useResp}
 
-			client := pastebin.NewClient(server.URL(t).String())
+			client := pastebin.NewClient(server.URL(t).String(), "")
 			got, err := client.FetchPaste("unused")
 			if err != nil {
 				t.Fatalf("FetchPaste() unexpected error: %v", err)
@@ -49,3 +49,52 @@ func TestFetchPaste(t *testing.T) {
 		})
 	}
 }
+
+func TestCreatePaste(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		desc    string
+		useResp string
+		want    string
+		wantErr bool
+	}{
+		{
+			desc:    "success",
+			useResp: pastebintest.CreatePasteResp,
+			want:    "https://pastebin.com/UIFdu235s",
+		},
+		{
+			desc:    "bad key",
+			useResp: pastebintest.CreatePasteBadKeyResp,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			server := fakeserver.New()
+			defer server.Close()
+			server.Resps = []string{tc.useResp}
+
+			client := pastebin.NewClient("", server.URL(t).String())
+			got, err := client.CreatePaste("key", "title", "text")
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("CreatePaste() error = %v, wantErr %t", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("CreatePaste() = %q, want %q", got, tc.want)
+			}
+
+			if len(server.Reqs) != 1 {
+				t.Fatalf("CreatePaste() made %d requests, want 1", len(server.Reqs))
+			}
+			if err := server.Reqs[0].ParseForm(); err != nil {
+				t.Fatalf("Failed to parse request form: %v", err)
+			}
+			if got := server.Reqs[0].PostForm.Get("api_paste_code"); got != "text" {
+				t.Errorf("CreatePaste() sent api_paste_code %q, want %q", got, "text")
+			}
+		})
+	}
+}
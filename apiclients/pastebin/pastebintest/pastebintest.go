@@ -4,4 +4,7 @@ package pastebintest
 const (
 	SingleLineFetchPasteResp = "line1"
 	MultiLineFetchPasteResp  = "line1\nline2\nline3"
+
+	CreatePasteResp       = "https://pastebin.com/UIFdu235s"
+	CreatePasteBadKeyResp = "Bad API request, invalid api_dev_key"
 )
@@ -16,6 +16,7 @@ import (
 	"time"
 
 	"github.com/airforce270/airbot/apiclients/seventv/gqltypes"
+	"github.com/airforce270/airbot/metrics"
 
 	"github.com/hasura/go-graphql-client"
 	"golang.org/x/oauth2"
@@ -70,6 +71,7 @@ type Client struct {
 
 // AddEmote adds a 7TV emote to an emote set.
 func (c *Client) AddEmote(ctx context.Context, emoteSetID, emoteID string) error {
+	defer metrics.ObserveAPIRequest("seventv", time.Now())
 	var addEmote ModifyEmoteSetMutation
 	vars := map[string]any{
 		"action":       gqltypes.ListItemAction("ADD"),
@@ -97,6 +99,7 @@ func (c *Client) AddEmote(ctx context.Context, emoteSetID, emoteID string) error
 // AddEmoteWithAlias adds a 7TV emote to an emote set with a given name (alias).
 // If useName is the blank string, the emote will not be aliased.
 func (c *Client) AddEmoteWithAlias(ctx context.Context, emoteSetID, emoteID, alias string) error {
+	defer metrics.ObserveAPIRequest("seventv", time.Now())
 	var addEmote ModifyEmoteSetWithNameMutation
 	vars := map[string]any{
 		"action":       addAction,
@@ -124,6 +127,7 @@ func (c *Client) AddEmoteWithAlias(ctx context.Context, emoteSetID, emoteID, ali
 // FetchUserConnectionByTwitchUserId fetches a 7tv user+connection
 // given a Twitch userid.
 func (c *Client) FetchUserConnectionByTwitchUserId(uid string) (*PlatformConnection, error) {
+	defer metrics.ObserveAPIRequest("seventv", time.Now())
 	reqURL := c.baseURL.JoinPath("v3", "users", "twitch", uid)
 	rawResp, err := http.Get(reqURL.String())
 	if err != nil {
@@ -149,6 +153,7 @@ func (c *Client) FetchUserConnectionByTwitchUserId(uid string) (*PlatformConnect
 
 // RemoteEmote removes a 7TV emote from an emote set.
 func (c *Client) RemoveEmote(ctx context.Context, emoteSetID, emoteID string) error {
+	defer metrics.ObserveAPIRequest("seventv", time.Now())
 	var addEmote ModifyEmoteSetMutation
 	vars := map[string]any{
 		"action":       removeAction,
@@ -7,10 +7,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"time"
+
+	"github.com/airforce270/airbot/metrics"
 )
 
 // NewClient creates a new Client.
@@ -52,22 +54,22 @@ const pingInterval = 15 * time.Minute
 // StartPinging starts a background task to ping the Supinic API regularly
 // to make sure the API knows the bot is still online.
 // This function blocks and should be run within a goroutine.
-func (c *Client) StartPinging(ctx context.Context) {
+func (c *Client) StartPinging(ctx context.Context, logger *slog.Logger) {
 	pingTimer := time.NewTicker(pingInterval)
 	for {
 		select {
 		case <-ctx.Done():
-			log.Print("Stopping pinging Supinic API, context cancelled")
+			logger.Info("Stopping pinging Supinic API, context cancelled")
 			return
 		case <-pingTimer.C:
-			go c.pingAPI()
+			go c.pingAPI(logger)
 		}
 	}
 }
 
-func (c *Client) pingAPI() {
+func (c *Client) pingAPI(logger *slog.Logger) {
 	if err := c.updateBotActivity(); err != nil {
-		log.Printf("Failed to ping Supinic API: %v", err)
+		logger.Error("Failed to ping Supinic API", "error", err)
 	}
 }
 
@@ -112,6 +114,7 @@ func (c *Client) updateBotActivity() error {
 }
 
 func (c *Client) call(method, path string, body io.Reader) ([]byte, error) {
+	defer metrics.ObserveAPIRequest("supinic", time.Now())
 	reqURL, err := url.JoinPath(c.baseURL, path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create URL (%q, %q): %w", c.baseURL, path, err)
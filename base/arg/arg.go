@@ -3,7 +3,7 @@ package arg
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"regexp"
 	"slices"
 	"strconv"
@@ -58,7 +58,8 @@ type Arg struct {
 
 // Parse parses the param it defines from the given message.
 // It then returns the parsed arg and the remaining message text after parsing.
-func (a Param) Parse(msg string) (Arg, string) {
+// Params of unhandled types are parsed as strings, logging a warning to logger.
+func (a Param) Parse(msg string, logger *slog.Logger) (Arg, string) {
 	var pattern *regexp.Regexp
 	switch a.Type {
 	case Int:
@@ -72,7 +73,7 @@ func (a Param) Parse(msg string) (Arg, string) {
 	case String:
 		pattern = stringPrefixPattern
 	default:
-		log.Printf("Warning: parsing unhandled message type (%q)! Treating as string.", a.Type)
+		logger.Warn("Parsing unhandled param type, treating as string", "param", a.Name, "type", a.Type)
 		pattern = stringPrefixPattern
 	}
 
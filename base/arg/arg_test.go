@@ -3,6 +3,7 @@ package arg
 import (
 	"testing"
 
+	"github.com/airforce270/airbot/logging"
 	"github.com/google/go-cmp/cmp"
 )
 
@@ -283,7 +284,7 @@ func TestParse(t *testing.T) {
 		tc := tc
 		t.Run(tc.desc, func(t *testing.T) {
 			t.Parallel()
-			gotParsed, gotLeft := tc.inputArg.Parse(tc.inputMsg)
+			gotParsed, gotLeft := tc.inputArg.Parse(tc.inputMsg, logging.Discard())
 
 			if diff := cmp.Diff(tc.wantParsed, gotParsed); diff != "" {
 				t.Errorf("Arg.Parse() diff (-want +got):\n%s", diff)
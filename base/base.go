@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"io"
+	"log/slog"
 	"math/rand/v2"
 	"strings"
 	"time"
@@ -21,6 +22,16 @@ import (
 
 var (
 	ErrUserUnknown = errors.New("user has never been seen by the bot")
+	// ErrNotModerator is returned by Moderator methods when the bot isn't allowed to moderate a channel.
+	ErrNotModerator = errors.New("bot isn't a moderator in the channel")
+	// ErrUnauthorized is returned by Moderator methods when the platform rejects the bot's credentials,
+	// i.e. because its access token expired and couldn't be refreshed.
+	ErrUnauthorized = errors.New("bot's credentials were rejected")
+	// ErrEventLimit is returned by HookWatcher methods when the platform
+	// won't publish any more kinds of events to the bot.
+	ErrEventLimit = errors.New("platform won't publish any more events to the bot")
+	// ErrNoSuchUser is returned by Moderator methods when a user doesn't exist on the platform.
+	ErrNoSuchUser = errors.New("user doesn't exist")
 )
 
 // Platform represents a connection to a given platform (i.e. Twitch, Discord)
@@ -34,6 +45,8 @@ type Platform interface {
 	Connect(ctx context.Context) error
 	// Disconnect disconnects from the platform and should be called before exiting.
 	Disconnect() error
+	// Connected returns whether the bot is currently connected to the platform.
+	Connected() bool
 
 	// Listen returns a channel that will provide incoming messages.
 	Listen() <-chan IncomingMessage
@@ -41,6 +54,10 @@ type Platform interface {
 	Send(m Message) error
 	// Reply sends a message in reply to another message.
 	Reply(m Message, replyToID string) error
+	// MaxMessageLength returns the maximum number of characters in a message,
+	// or 0 if messages aren't limited.
+	// Longer messages are split before they're sent.
+	MaxMessageLength() int
 
 	// Join joins a channel.
 	Join(channel, prefix string) error
@@ -59,6 +76,156 @@ type Platform interface {
 	Timeout(username, channel string, duration time.Duration) error
 }
 
+// Whisperer is implemented by platforms that can send private messages to users.
+type Whisperer interface {
+	// Whisper sends a private message to the user with an ID.
+	Whisper(userID, text string) error
+}
+
+// SendChecker is implemented by platforms that can't send some messages,
+// i.e. because of a channel's chat settings.
+type SendChecker interface {
+	// CanSend returns an error describing why a message can't be sent,
+	// or nil if it can be.
+	CanSend(msg Message) error
+}
+
+// Moderator is implemented by platforms that can moderate channels.
+// Methods return ErrNotModerator if the bot isn't allowed to moderate the channel,
+// and ErrNoSuchUser if a user doesn't exist.
+type Moderator interface {
+	// Ban permanently bans a user from a channel.
+	Ban(username, channel, reason string) error
+	// TimeoutWithReason times out a user in a channel, with a reason.
+	TimeoutWithReason(username, channel string, duration time.Duration, reason string) error
+	// Unban removes a ban or timeout from a user in a channel.
+	Unban(username, channel string) error
+	// DeleteMessage deletes a single message from a channel.
+	DeleteMessage(channel, messageID string) error
+	// ClearChat deletes all messages from a channel.
+	ClearChat(channel string) error
+	// UpdateChatSettings changes a channel's chat modes.
+	UpdateChatSettings(channel string, settings ChatSettings) error
+	// Shoutout gives a shoutout to another channel in a channel.
+	Shoutout(channel, target string) error
+	// Announce sends an announcement, a highlighted message, to a channel.
+	Announce(channel, text string) error
+}
+
+// ChatSettings contains changes to a channel's chat modes.
+// Nil fields are left unchanged.
+type ChatSettings struct {
+	// SlowMode is how long users must wait between messages, or 0 to turn slow mode off.
+	SlowMode *time.Duration
+	// EmoteOnly is whether users may only send emotes.
+	EmoteOnly *bool
+	// FollowersOnly is whether only followers may chat.
+	FollowersOnly *bool
+	// FollowersOnlyAge is how long users must have followed to chat, if FollowersOnly is set.
+	FollowersOnlyAge time.Duration
+	// SubscribersOnly is whether only subscribers may chat.
+	SubscribersOnly *bool
+}
+
+// EventSource is implemented by platforms that publish events
+// happening in channels, other than chat messages.
+type EventSource interface {
+	// Events returns a channel that will provide events.
+	Events() <-chan Event
+}
+
+// HookWatcher is implemented by event sources that only watch for
+// the kinds of events channels have hooks for.
+type HookWatcher interface {
+	// HooksChanged starts watching for the kinds of events a channel now has hooks for,
+	// and stops watching for those it no longer does.
+	HooksChanged(channel string) error
+}
+
+// EventKind is a kind of event.
+type EventKind string
+
+const (
+	// EventStreamOnline is sent when a channel goes live.
+	EventStreamOnline EventKind = "stream_online"
+	// EventStreamOffline is sent when a channel stops streaming.
+	EventStreamOffline EventKind = "stream_offline"
+	// EventRaid is sent when a channel is raided.
+	// The raiding channel is the event's user.
+	EventRaid EventKind = "raid"
+	// EventFollow is sent when a user follows a channel.
+	EventFollow EventKind = "follow"
+	// EventSubscribe is sent when a user subscribes to a channel,
+	// including when they receive a gifted subscription.
+	EventSubscribe EventKind = "subscribe"
+	// EventResubscribe is sent when a user shares a resubscription message.
+	EventResubscribe EventKind = "resubscribe"
+	// EventGiftSubscriptions is sent when a user gifts subscriptions.
+	EventGiftSubscriptions EventKind = "gift_subscriptions"
+	// EventRedemption is sent when a user redeems a channel points reward.
+	EventRedemption EventKind = "redemption"
+)
+
+// EventKinds contains all kinds of events.
+var EventKinds = []EventKind{
+	EventStreamOnline,
+	EventStreamOffline,
+	EventRaid,
+	EventFollow,
+	EventSubscribe,
+	EventResubscribe,
+	EventGiftSubscriptions,
+	EventRedemption,
+}
+
+// Event represents something that happened in a channel, other than a chat message.
+// Fields that don't apply to the event's kind are left empty.
+type Event struct {
+	// Kind is the kind of event.
+	Kind EventKind
+	// ID is the unique ID of the event, as provided by the platform.
+	ID string
+	// Channel is the channel the event happened in.
+	Channel string
+	// ChannelID is the unique ID of the channel the event happened in.
+	ChannelID string
+	// UserID is the unique ID of the user that caused the event.
+	UserID string
+	// User is the username of the user that caused the event.
+	// It's empty if the event wasn't caused by a user, or the user is anonymous.
+	User string
+	// Time is when the event happened.
+	Time time.Time
+	// Viewers is the number of viewers brought by a raid.
+	Viewers int
+	// Tier is the tier of a subscription, i.e. "1000".
+	Tier string
+	// Gift is whether a subscription was gifted.
+	Gift bool
+	// Count is the number of subscriptions gifted.
+	Count int
+	// Months is the total number of months a user has been subscribed.
+	Months int
+	// Reward is the title of a redeemed channel points reward.
+	Reward string
+	// Cost is the number of channel points a redeemed reward cost.
+	Cost int
+	// Text is text the user entered, i.e. for a redemption or resubscription.
+	Text string
+	// Title is the title of a stream that went live.
+	Title string
+	// Game is the game or category of a stream that went live.
+	Game string
+}
+
+// IncomingEvent represents an incoming event.
+type IncomingEvent struct {
+	// Event is the event.
+	Event Event
+	// Resources contains resources available to an incoming event.
+	Resources Resources
+}
+
 // Message represents a chat message.
 type Message struct {
 	// Text contains the text of the message.
@@ -69,6 +236,9 @@ type Message struct {
 	// ID is the unique ID of the message, as provided by the platform.
 	// This may not be set - some platforms do not provide an ID.
 	ID string
+	// ParentID is the ID of the message this message is a reply to, if it's a reply.
+	// This may not be set - some platforms do not support replies.
+	ParentID string
 	// UserID is the unique ID of the user that sent the message.
 	UserID string
 	// User is the username of the user that sent the message.
@@ -82,9 +252,13 @@ type IncomingMessage struct {
 	// Message is the message.
 	Message Message
 	// Prefix is the prefix for the channel the message was sent in.
+	// Whispers don't need to start with it.
 	Prefix string
 	// PermissionLevel is the permission level of the user that sent the message.
 	PermissionLevel permission.Level
+	// Whisper is whether the message was whispered to the bot, rather than sent in a channel.
+	// Whispers have no channel.
+	Whisper bool
 	// Resources contains resources available to an incoming message.
 	Resources Resources
 }
@@ -104,6 +278,21 @@ type OutgoingMessage struct {
 	//   2. The platform supports replying to messages
 	//   3. The message is a reply to another message
 	ReplyToID string
+	// WhisperToID is the ID of the user to whisper the message to,
+	// rather than sending it in Channel.
+	// This field is only set if the platform implements Whisperer.
+	WhisperToID string
+}
+
+// OutgoingQueue is a queue of messages waiting to be sent on a platform.
+type OutgoingQueue interface {
+	// Depth returns the number of messages waiting to be sent.
+	Depth() int
+	// ChannelDepth returns the number of messages waiting to be sent to a channel.
+	ChannelDepth(channel string) int
+	// Cancel removes all messages waiting to be sent to a channel,
+	// returning the number of messages removed.
+	Cancel(channel string) int
 }
 
 // Resources contains references to app-level resources.
@@ -114,6 +303,9 @@ type Resources struct {
 	DB *gorm.DB
 	// Cache is a reference to the cache.
 	Cache cache.Cache
+	// MemoryCache is a cache kept in memory, for short-lived data
+	// that doesn't need to survive restarts, i.e. cooldowns.
+	MemoryCache cache.Cache
 	// AllPlatforms contains all platforms currently registered with the bot.
 	AllPlatforms map[string]Platform
 	// NewConfigSource is a function that returns a source of data
@@ -123,6 +315,12 @@ type Resources struct {
 	Rand RandResources
 	// Clients contains API clients.
 	Clients APIClients
+	// Queue is the queue of messages waiting to be sent on the current platform.
+	// It may be nil if messages aren't being sent, i.e. in test.
+	Queue OutgoingQueue
+	// Logger is the logger for the current request,
+	// with the platform, channel, user, and command attached.
+	Logger *slog.Logger
 }
 
 // PlatformByName returns the platform with a given name
@@ -153,6 +351,9 @@ type APIClients struct {
 	// If set, this will override whatever the user enters.
 	// Therefore, it should only be set in test.
 	PastebinFetchPasteURLOverride string
+	// Pastebin CreatePaste URL override.
+	// It should only be set in test.
+	PastebinCreatePasteURLOverride string
 	// 7TV API client.
 	SevenTV *seventv.Client
 }
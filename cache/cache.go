@@ -2,6 +2,7 @@
 package cache
 
 import (
+	"strings"
 	"time"
 )
 
@@ -24,6 +25,10 @@ type Cache interface {
 	// FetchString fetches a string value.
 	// If the key does not exist, an empty string will be returned.
 	FetchString(key string) (string, error)
+
+	// DeleteMatching deletes all values with keys matching a pattern,
+	// where * matches any sequence of characters.
+	DeleteMatching(pattern string) error
 }
 
 const (
@@ -41,3 +46,55 @@ const (
 func GlobalSlowmodeKey(platformName string) string {
 	return "global_slowmode_" + platformName
 }
+
+// ChannelCooldownKey returns the cache key for a command's cooldown in a channel.
+func ChannelCooldownKey(platformName, channel, command string) string {
+	return "cooldown_channel_" + platformName + "_" + strings.ToLower(channel) + "_" + command
+}
+
+// UserCooldownKey returns the cache key for a command's cooldown for a user.
+func UserCooldownKey(platformName, userID, command string) string {
+	return "cooldown_user_" + platformName + "_" + userID + "_" + command
+}
+
+// LiveStatusKey returns the cache key for whether a stream was live when it was last checked,
+// for sending live notifications on a platform.
+func LiveStatusKey(platformName, streamPlatformName, streamer string) string {
+	return "live_status_" + platformName + "_" + streamPlatformName + "_" + strings.ToLower(streamer)
+}
+
+// AutomodStrikesKey returns the cache key for how many times a user has recently been timed out
+// by automod in a channel.
+func AutomodStrikesKey(platformName, channel, userID string) string {
+	return "automod_strikes_" + platformName + "_" + strings.ToLower(channel) + "_" + userID
+}
+
+// AutomodLastMessageKey returns the cache key for the last message a user sent in a channel,
+// and how many times in a row they've sent it, for automod repeat rules.
+func AutomodLastMessageKey(platformName, channel, userID string) string {
+	return "automod_last_message_" + platformName + "_" + strings.ToLower(channel) + "_" + userID
+}
+
+// AutomodRulesVersionKey returns the cache key for the version of a channel's automod rules,
+// which changes whenever they're changed.
+func AutomodRulesVersionKey(platformName, channel string) string {
+	return "automod_rules_version_" + platformName + "_" + strings.ToLower(channel)
+}
+
+// AutomodPermitKey returns the cache key for whether a user may post a link in a channel.
+func AutomodPermitKey(platformName, channel, username string) string {
+	return "automod_permit_" + platformName + "_" + strings.ToLower(channel) + "_" + strings.ToLower(username)
+}
+
+// UserKeyPatterns returns patterns matching all cache keys
+// storing data about a user on a platform, for use with DeleteMatching.
+func UserKeyPatterns(platformName, userID, username string) []string {
+	username = strings.ToLower(username)
+	return []string{
+		UserCooldownKey(platformName, userID, "*"),
+		UserCooldownKey(platformName, username, "*"),
+		AutomodStrikesKey(platformName, "*", userID),
+		AutomodLastMessageKey(platformName, "*", userID),
+		AutomodPermitKey(platformName, "*", username),
+	}
+}
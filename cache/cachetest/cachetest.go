@@ -8,10 +8,10 @@ import (
 	"gorm.io/gorm"
 )
 
-// NewSQLite creates a new cache for test.
-func NewSQLite(t *testing.T, db *gorm.DB) cache.Cache {
+// NewDB creates a new cache for test.
+func NewDB(t *testing.T, db *gorm.DB) cache.Cache {
 	t.Helper()
-	c, err := cache.NewSQLite(db)
+	c, err := cache.NewDB(db)
 	if err != nil {
 		t.Fatalf("Failed to create cache for test: %v", err)
 	}
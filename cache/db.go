@@ -0,0 +1,134 @@
+package cache
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/airforce270/airbot/database/models"
+	"gorm.io/gorm"
+)
+
+// NewDB creates a new Cache backed by the database.
+func NewDB(db *gorm.DB) (DB, error) {
+	return DB{db}, nil
+}
+
+// DB implements Cache for a SQL database.
+type DB struct {
+	db *gorm.DB
+}
+
+func (v *DB) StoreBool(key string, value bool) error {
+	item := models.CacheBoolItem{
+		Key:   key,
+		Value: value,
+	}
+
+	if err := v.db.Save(&item).Error; err != nil {
+		return fmt.Errorf("failed to store %s=%v: %w", key, value, err)
+	}
+
+	return nil
+}
+
+func (v *DB) StoreExpiringBool(key string, value bool, expiration time.Duration) error {
+	item := models.CacheBoolItem{
+		Key:       key,
+		Value:     value,
+		ExpiresAt: time.Now().Add(expiration),
+	}
+
+	if err := v.db.Save(&item).Error; err != nil {
+		return fmt.Errorf("failed to store %q=%t: %w", key, value, err)
+	}
+
+	return nil
+}
+
+func (v *DB) FetchBool(key string) (bool, error) {
+	var item models.CacheBoolItem
+
+	result := v.db.Limit(1).Find(&item, "key = ?", key)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to fetch %q: %w", key, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	if !item.ExpiresAt.IsZero() && item.ExpiresAt.Before(time.Now()) {
+		return false, nil
+	}
+
+	return item.Value, nil
+}
+
+func (v *DB) StoreString(key, value string) error {
+	item := models.CacheStringItem{
+		Key:   key,
+		Value: value,
+	}
+
+	if err := v.db.Save(&item).Error; err != nil {
+		return fmt.Errorf("failed to store %q=%q: %w", key, value, err)
+	}
+
+	return nil
+}
+
+func (v *DB) StoreExpiringString(key, value string, expiration time.Duration) error {
+	item := models.CacheStringItem{
+		Key:       key,
+		Value:     value,
+		ExpiresAt: time.Now().Add(expiration),
+	}
+
+	if err := v.db.Save(&item).Error; err != nil {
+		return fmt.Errorf("failed to store %q=%q: %w", key, value, err)
+	}
+
+	return nil
+}
+
+func (v *DB) FetchString(key string) (string, error) {
+	var item models.CacheStringItem
+
+	result := v.db.Limit(1).Find(&item, "key = ?", key)
+	if result.Error != nil {
+		return "", fmt.Errorf("failed to fetch %q: %w", key, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return "", nil
+	}
+
+	if !item.ExpiresAt.IsZero() && item.ExpiresAt.Before(time.Now()) {
+		return "", nil
+	}
+
+	return item.Value, nil
+}
+
+// likeEscaper escapes LIKE wildcards in a pattern, and replaces * with %.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`, "*", "%")
+
+func (v *DB) DeleteMatching(pattern string) error {
+	like := likeEscaper.Replace(pattern)
+	for _, model := range []any{&models.CacheBoolItem{}, &models.CacheStringItem{}} {
+		if err := v.db.Where(`key LIKE ? ESCAPE '\'`, like).Delete(model).Error; err != nil {
+			return fmt.Errorf("failed to delete %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// PurgeExpired deletes all expired values.
+func (v *DB) PurgeExpired() error {
+	for _, model := range []any{&models.CacheBoolItem{}, &models.CacheStringItem{}} {
+		// Values that never expire have a zero expiry time.
+		if err := v.db.Where("expires_at > ? AND expires_at < ?", time.Time{}, time.Now()).Delete(model).Error; err != nil {
+			return fmt.Errorf("failed to purge expired values: %w", err)
+		}
+	}
+	return nil
+}
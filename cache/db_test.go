@@ -0,0 +1,84 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/airforce270/airbot/cache"
+	"github.com/airforce270/airbot/database/databasetest"
+	"github.com/airforce270/airbot/database/models"
+)
+
+func TestDB_DeleteMatching(t *testing.T) {
+	t.Parallel()
+	c, err := cache.NewDB(databasetest.New(t))
+	if err != nil {
+		t.Fatalf("NewDB() unexpected error: %v", err)
+	}
+
+	forgotten := []string{
+		cache.UserCooldownKey("Twitch", "123", "roulette"),
+		cache.UserCooldownKey("Twitch", "someone", "duel"),
+		cache.AutomodStrikesKey("Twitch", "user1", "123"),
+		cache.AutomodLastMessageKey("Twitch", "user2", "123"),
+		cache.AutomodPermitKey("Twitch", "user1", "Someone"),
+	}
+	kept := []string{
+		cache.UserCooldownKey("Twitch", "1234", "roulette"),
+		cache.UserCooldownKey("Twitch", "456", "roulette"),
+		cache.ChannelCooldownKey("Twitch", "user1", "roulette"),
+		cache.AutomodStrikesKey("Twitch", "user1", "456"),
+		cache.AutomodPermitKey("Twitch", "user1", "someone2"),
+	}
+	for _, key := range append(forgotten, kept...) {
+		if err := c.StoreExpiringString(key, "value", time.Hour); err != nil {
+			t.Fatalf("StoreExpiringString(%q) unexpected error: %v", key, err)
+		}
+	}
+
+	for _, pattern := range cache.UserKeyPatterns("Twitch", "123", "Someone") {
+		if err := c.DeleteMatching(pattern); err != nil {
+			t.Fatalf("DeleteMatching(%q) unexpected error: %v", pattern, err)
+		}
+	}
+
+	for _, key := range forgotten {
+		if got, err := c.FetchString(key); err != nil || got != "" {
+			t.Errorf("FetchString(%q) = %q, %v; want it deleted", key, got, err)
+		}
+	}
+	for _, key := range kept {
+		if got, err := c.FetchString(key); err != nil || got != "value" {
+			t.Errorf("FetchString(%q) = %q, %v; want it kept", key, got, err)
+		}
+	}
+}
+
+func TestDB_PurgeExpired(t *testing.T) {
+	t.Parallel()
+	db := databasetest.New(t)
+	c, err := cache.NewDB(db)
+	if err != nil {
+		t.Fatalf("NewDB() unexpected error: %v", err)
+	}
+	if err := c.StoreString("forever", "value"); err != nil {
+		t.Fatalf("StoreString() unexpected error: %v", err)
+	}
+	if err := c.StoreExpiringBool("expiring", true, time.Hour); err != nil {
+		t.Fatalf("StoreExpiringBool() unexpected error: %v", err)
+	}
+	if err := c.StoreExpiringString("expired", "value", -time.Minute); err != nil {
+		t.Fatalf("StoreExpiringString() unexpected error: %v", err)
+	}
+
+	if err := c.PurgeExpired(); err != nil {
+		t.Fatalf("PurgeExpired() unexpected error: %v", err)
+	}
+
+	var strings, bools int64
+	db.Model(&models.CacheStringItem{}).Count(&strings)
+	db.Model(&models.CacheBoolItem{}).Count(&bools)
+	if strings != 1 || bools != 1 {
+		t.Errorf("PurgeExpired() left %d strings and %d bools, want 1 and 1", strings, bools)
+	}
+}
@@ -0,0 +1,140 @@
+package cache
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryPurgeInterval is how often expired items are purged from a Memory cache.
+const memoryPurgeInterval = 1 * time.Minute
+
+// NewMemory creates a new Cache stored in memory.
+// Values are lost when the bot restarts,
+// so it should only be used for short-lived values, i.e. cooldowns.
+func NewMemory() *Memory {
+	return &Memory{items: map[string]memoryItem{}, now: time.Now}
+}
+
+// Memory implements Cache in memory.
+type Memory struct {
+	// mu protects the fields below.
+	mu sync.Mutex
+	// items contains the cached items, keyed by key.
+	items map[string]memoryItem
+	// lastPurge is when expired items were last purged.
+	lastPurge time.Time
+	// now returns the current time. Overridden in test.
+	now func() time.Time
+}
+
+// memoryItem is an item in a Memory cache.
+type memoryItem struct {
+	// value is the item's value, a bool or string.
+	value any
+	// expiresAt is when the item expires.
+	// If 0, the item never expires.
+	expiresAt time.Time
+}
+
+func (m *Memory) StoreBool(key string, value bool) error {
+	m.store(key, value, 0)
+	return nil
+}
+
+func (m *Memory) StoreExpiringBool(key string, value bool, expiration time.Duration) error {
+	m.store(key, value, expiration)
+	return nil
+}
+
+func (m *Memory) FetchBool(key string) (bool, error) {
+	value, _ := m.fetch(key).(bool)
+	return value, nil
+}
+
+func (m *Memory) StoreString(key, value string) error {
+	m.store(key, value, 0)
+	return nil
+}
+
+func (m *Memory) StoreExpiringString(key, value string, expiration time.Duration) error {
+	m.store(key, value, expiration)
+	return nil
+}
+
+func (m *Memory) FetchString(key string) (string, error) {
+	value, _ := m.fetch(key).(string)
+	return value, nil
+}
+
+func (m *Memory) DeleteMatching(pattern string) error {
+	pieces := strings.Split(pattern, "*")
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key := range m.items {
+		if matchPieces(key, pieces) {
+			delete(m.items, key)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) store(key string, value any, expiration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	item := memoryItem{value: value}
+	if expiration != 0 {
+		item.expiresAt = now.Add(expiration)
+	}
+	m.items[key] = item
+
+	if now.Sub(m.lastPurge) >= memoryPurgeInterval {
+		m.purgeExpired(now)
+	}
+}
+
+func (m *Memory) fetch(key string) any {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.items[key]
+	if !ok {
+		return nil
+	}
+	if !item.expiresAt.IsZero() && !item.expiresAt.After(m.now()) {
+		delete(m.items, key)
+		return nil
+	}
+	return item.value
+}
+
+// purgeExpired deletes all expired items.
+// m.mu must be held.
+func (m *Memory) purgeExpired(now time.Time) {
+	for key, item := range m.items {
+		if !item.expiresAt.IsZero() && !item.expiresAt.After(now) {
+			delete(m.items, key)
+		}
+	}
+	m.lastPurge = now
+}
+
+// matchPieces returns whether s matches a pattern split on *.
+func matchPieces(s string, pieces []string) bool {
+	if len(pieces) == 1 {
+		return s == pieces[0]
+	}
+	rest, ok := strings.CutPrefix(s, pieces[0])
+	if !ok {
+		return false
+	}
+	last := pieces[len(pieces)-1]
+	for _, piece := range pieces[1 : len(pieces)-1] {
+		i := strings.Index(rest, piece)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(piece):]
+	}
+	return len(rest) >= len(last) && strings.HasSuffix(rest, last)
+}
@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemory(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC)
+	m := NewMemory()
+	m.now = func() time.Time { return now }
+
+	if err := m.StoreString("forever", "value"); err != nil {
+		t.Fatalf("StoreString() unexpected error: %v", err)
+	}
+	if err := m.StoreExpiringString("expiring", "value", time.Second); err != nil {
+		t.Fatalf("StoreExpiringString() unexpected error: %v", err)
+	}
+	if err := m.StoreExpiringBool("expiring_bool", true, time.Minute); err != nil {
+		t.Fatalf("StoreExpiringBool() unexpected error: %v", err)
+	}
+	if got, _ := m.FetchString("expiring"); got != "value" {
+		t.Errorf("FetchString(expiring) = %q, want value", got)
+	}
+	if got, _ := m.FetchBool("expiring_bool"); !got {
+		t.Error("FetchBool(expiring_bool) = false, want true")
+	}
+	if got, _ := m.FetchBool("forever"); got {
+		t.Error("FetchBool() of a string = true, want false")
+	}
+
+	now = now.Add(2 * time.Second)
+	if got, _ := m.FetchString("expiring"); got != "" {
+		t.Errorf("FetchString(expiring) after expiry = %q, want empty", got)
+	}
+	if got, _ := m.FetchString("forever"); got != "value" {
+		t.Errorf("FetchString(forever) = %q, want value", got)
+	}
+
+	// Storing purges expired items once the purge interval has passed.
+	now = now.Add(memoryPurgeInterval)
+	if err := m.StoreString("other", "value"); err != nil {
+		t.Fatalf("StoreString() unexpected error: %v", err)
+	}
+	if _, ok := m.items["expiring_bool"]; ok {
+		t.Error("expired item wasn't purged")
+	}
+	if got := len(m.items); got != 2 {
+		t.Errorf("%d items after purge, want 2", got)
+	}
+}
+
+func TestMemory_DeleteMatching(t *testing.T) {
+	t.Parallel()
+	m := NewMemory()
+	for _, key := range []string{"cooldown_user_Twitch_123_roulette", "cooldown_user_Twitch_1234_roulette", "automod_strikes_Twitch_user1_123", "automod_strikes_Twitch_user1_456"} {
+		if err := m.StoreString(key, "value"); err != nil {
+			t.Fatalf("StoreString(%q) unexpected error: %v", key, err)
+		}
+	}
+
+	for _, pattern := range []string{"cooldown_user_Twitch_123_*", "automod_strikes_Twitch_*_123"} {
+		if err := m.DeleteMatching(pattern); err != nil {
+			t.Fatalf("DeleteMatching(%q) unexpected error: %v", pattern, err)
+		}
+	}
+
+	for key, wantKept := range map[string]bool{
+		"cooldown_user_Twitch_123_roulette":  false,
+		"cooldown_user_Twitch_1234_roulette": true,
+		"automod_strikes_Twitch_user1_123":   false,
+		"automod_strikes_Twitch_user1_456":   true,
+	} {
+		if got, _ := m.FetchString(key); (got != "") != wantKept {
+			t.Errorf("FetchString(%q) = %q, want kept = %t", key, got, wantKept)
+		}
+	}
+}
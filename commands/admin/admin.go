@@ -2,9 +2,10 @@
 package admin
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"log"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -17,12 +18,12 @@ import (
 	"github.com/airforce270/airbot/database/models"
 	"github.com/airforce270/airbot/permission"
 	twitchplatform "github.com/airforce270/airbot/platforms/twitch"
-	"github.com/airforce270/airbot/utils"
 	"github.com/airforce270/airbot/utils/restart"
 )
 
 // Commands contains this package's commands.
 var Commands = [...]basecommand.Command{
+	backupCommand,
 	botSlowmodeCommand,
 	echoCommand,
 	joinCommand,
@@ -36,6 +37,14 @@ var Commands = [...]basecommand.Command{
 }
 
 var (
+	backupCommand = basecommand.Command{
+		Name:            "backup",
+		Desc:            "Backs up the database.",
+		Permission:      permission.Owner,
+		ChannelCooldown: 1 * time.Minute,
+		Handler:         backup,
+	}
+
 	botSlowmodeCommand = basecommand.Command{
 		Name: "botslowmode",
 		Desc: "Sets the bot to follow a global (per-platform) 1 second slowmode. If no argument is provided, checks if slowmode is enabled.",
@@ -156,6 +165,57 @@ var (
 	}
 )
 
+func backup(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
+	configSrc, err := msg.Resources.NewConfigSource()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config source: %w", err)
+	}
+	defer func() { _ = configSrc.Close() }() // ignore error
+	cfg, err := config.Read(configSrc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	dir := cfg.Backup.Directory
+	if dir == "" {
+		dir = database.DefaultBackupDir()
+	}
+
+	path, err := database.Backup(context.Background(), msg.Resources.DB, dir)
+	if err != nil {
+		msg.Resources.Logger.Error("Failed to back up database", "error", err)
+		return []*base.Message{
+			{
+				Channel: msg.Message.Channel,
+				Text:    "Failed to back up database.",
+			},
+		}, nil
+	}
+
+	deleted, err := database.RotateBackups(dir, cfg.Backup.Keep)
+	if err != nil {
+		msg.Resources.Logger.Error("Failed to rotate backups", "error", err)
+	}
+
+	text := "Backed up database to " + filepath.Base(path)
+	if len(deleted) > 0 {
+		text += fmt.Sprintf(", deleted %d old %s", len(deleted), pluralBackups(len(deleted)))
+	}
+	return []*base.Message{
+		{
+			Channel: msg.Message.Channel,
+			Text:    text,
+		},
+	}, nil
+}
+
+func pluralBackups(n int) string {
+	if n == 1 {
+		return "backup"
+	}
+	return "backups"
+}
+
 func botSlowmode(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
 	enableArg := args[0]
 	key := cache.GlobalSlowmodeKey(msg.Resources.Platform.Name())
@@ -180,7 +240,7 @@ func botSlowmode(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, er
 	enable := enableArg.BoolValue
 
 	if err := msg.Resources.Cache.StoreBool(key, enable); err != nil {
-		log.Printf("Failed to set bot slowmode to %t on %s: %v", enable, msg.Resources.Platform.Name(), err)
+		msg.Resources.Logger.Error("Failed to set bot slowmode", "enable", enable, "error", err)
 		failureMsgStart := "Failed to enable"
 		if !enable {
 			failureMsgStart = "Failed to disable"
@@ -251,6 +311,17 @@ func joinChannel(msg *base.IncomingMessage, targetChannel, prefix string) ([]*ba
 			},
 		}, nil
 	}
+	if errors.Is(err, twitchplatform.ErrJoinUnconfirmed) {
+		return []*base.Message{
+			{
+				Channel: msg.Message.Channel,
+				Text:    fmt.Sprintf("Twitch hasn't confirmed joining %s yet, will keep trying", targetChannel),
+			},
+		}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to join channel %s: %w", targetChannel, err)
+	}
 
 	msgs := []*base.Message{
 		{
@@ -267,8 +338,6 @@ func joinChannel(msg *base.IncomingMessage, targetChannel, prefix string) ([]*ba
 	return msgs, nil
 }
 
-const maxUsersPerMessage = 15
-
 func joined(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
 	var joinedChannels []*models.JoinedChannel
 	if err := msg.Resources.DB.Find(&joinedChannels).Error; err != nil {
@@ -279,24 +348,12 @@ func joined(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error)
 		channels = append(channels, c.Channel)
 	}
 
-	channelsGroups := utils.Chunk(channels, maxUsersPerMessage)
-
-	var messages []*base.Message
-
-	for i, channelsGroup := range channelsGroups {
-		var text string
-		if i == 0 {
-			text = fmt.Sprintf("Bot is currently in %s", strings.Join(channelsGroup, ", "))
-		} else {
-			text = strings.Join(channels, ", ")
-		}
-		if len(channelsGroups) > 1 && len(channelsGroups)-1 != i {
-			text += ","
-		}
-		messages = append(messages, &base.Message{Channel: msg.Message.Channel, Text: text})
-	}
-
-	return messages, nil
+	return []*base.Message{
+		{
+			Channel: msg.Message.Channel,
+			Text:    fmt.Sprintf("Bot is currently in %s", strings.Join(channels, ", ")),
+		},
+	}, nil
 }
 
 func leaveChannel(msg *base.IncomingMessage, targetChannel string) ([]*base.Message, error) {
@@ -314,7 +371,7 @@ func leaveChannel(msg *base.IncomingMessage, targetChannel string) ([]*base.Mess
 	go func() {
 		time.Sleep(time.Millisecond * 500)
 		if err := msg.Resources.Platform.Leave(targetChannel); err != nil {
-			log.Printf("failed to leave channel %s: %v", targetChannel, err)
+			msg.Resources.Logger.Error("Failed to leave channel", "target_channel", targetChannel, "error", err)
 		}
 	}()
 
@@ -357,7 +414,11 @@ func reloadConfig(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, e
 }
 
 func restartBot(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
-	go restart.WriteRequester(msg.Resources.Cache, msg.Resources.Platform.Name(), msg.Message.Channel, msg.Message.ID)
+	go func() {
+		if err := restart.WriteRequester(msg.Resources.Cache, msg.Resources.Platform.Name(), msg.Message.Channel, msg.Message.ID); err != nil {
+			msg.Resources.Logger.Error("Failed to store where restart was requested from", "error", err)
+		}
+	}()
 
 	const delay = 100 * time.Millisecond
 	time.AfterFunc(delay, func() { restart.C <- true })
@@ -392,7 +453,7 @@ func setPrefix(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, erro
 		}
 
 		if result.RowsAffected == 0 {
-			log.Printf("Failed to update prefix: %v", result.Error)
+			msg.Resources.Logger.Error("Failed to update prefix", "error", result.Error)
 			return []*base.Message{
 				{
 					Channel: msg.Message.Channel,
@@ -403,7 +464,7 @@ func setPrefix(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, erro
 	}
 
 	if err := msg.Resources.Platform.SetPrefix(msg.Message.Channel, newPrefix); err != nil {
-		log.Printf("Failed to update prefix: %v", err)
+		msg.Resources.Logger.Error("Failed to update prefix", "error", err)
 		return []*base.Message{
 			{
 				Channel: msg.Message.Channel,
@@ -3,7 +3,10 @@ package admin_test
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -14,20 +17,39 @@ import (
 	"github.com/airforce270/airbot/apiclients/seventv"
 	"github.com/airforce270/airbot/apiclients/twitchtest"
 	"github.com/airforce270/airbot/base"
+	"github.com/airforce270/airbot/cache"
 	"github.com/airforce270/airbot/cache/cachetest"
 	"github.com/airforce270/airbot/commands"
 	"github.com/airforce270/airbot/commands/commandtest"
 	"github.com/airforce270/airbot/config"
+	"github.com/airforce270/airbot/database"
 	"github.com/airforce270/airbot/database/databasetest"
+	"github.com/airforce270/airbot/logging"
 	"github.com/airforce270/airbot/permission"
 	"github.com/airforce270/airbot/platforms/twitch"
 	"github.com/airforce270/airbot/testing/fakeserver"
+	"github.com/google/go-cmp/cmp"
 	"github.com/pelletier/go-toml/v2"
 )
 
 func TestAdminCommands(t *testing.T) {
 	t.Parallel()
 	tests := []commandtest.Case{
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$backup",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Admin,
+			},
+			Platform: commandtest.TwitchPlatform,
+			Want:     nil,
+		},
 		{
 			Input: base.IncomingMessage{
 				Message: base.Message{
@@ -546,7 +568,7 @@ func TestReloadConfig(t *testing.T) {
 	ctx := context.Background()
 
 	db := databasetest.New(t)
-	cdb := cachetest.NewSQLite(t, db)
+	cdb := cachetest.NewDB(t, db)
 
 	platform := twitch.NewForTesting(t, server.URL(t).String(), db)
 
@@ -603,7 +625,7 @@ func TestReloadConfig(t *testing.T) {
 		Resources:       resources,
 	}
 
-	handler := commands.NewHandlerForTest(db, cdb, resources.AllPlatforms, resources.NewConfigSource, resources.Rand, resources.Clients)
+	handler := commands.NewHandlerForTest(db, cdb, cache.NewMemory(), resources.AllPlatforms, resources.NewConfigSource, resources.Rand, resources.Clients, resources.Queue)
 
 	_, err := handler.Handle(&input)
 	if err != nil {
@@ -615,11 +637,90 @@ func TestReloadConfig(t *testing.T) {
 	}
 }
 
+func TestBackup(t *testing.T) {
+	t.Parallel()
+	server := fakeserver.New()
+	defer server.Close()
+
+	db := databasetest.New(t)
+	cdb := cachetest.NewDB(t, db)
+	platform := twitch.NewForTesting(t, server.URL(t).String(), db)
+
+	dir := t.TempDir()
+	oldBackup := filepath.Join(dir, "airbot-20000101-000000.000.db")
+	if err := os.WriteFile(oldBackup, nil, 0o644); err != nil {
+		t.Fatalf("Failed to create old backup: %v", err)
+	}
+
+	cfg := func() string {
+		var buf strings.Builder
+		cfg := &config.Config{
+			Backup: config.BackupConfig{
+				Directory: dir,
+				Keep:      1,
+			},
+		}
+		if err := toml.NewEncoder(&buf).Encode(cfg); err != nil {
+			t.Fatalf("Failed to encode %+v: %v", cfg, err)
+		}
+		return buf.String()
+	}()
+
+	resources := base.Resources{
+		Platform:     platform,
+		DB:           db,
+		Cache:        cdb,
+		AllPlatforms: map[string]base.Platform{platform.Name(): platform},
+		NewConfigSource: func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(cfg)), nil
+		},
+	}
+
+	input := base.IncomingMessage{
+		Message: base.Message{
+			Text:    "$backup",
+			UserID:  "user1",
+			User:    "user1",
+			Channel: "user2",
+			Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+		},
+		Prefix:          "$",
+		PermissionLevel: permission.Owner,
+		Resources:       resources,
+	}
+
+	handler := commands.NewHandlerForTest(db, cdb, cache.NewMemory(), resources.AllPlatforms, resources.NewConfigSource, resources.Rand, resources.Clients, resources.Queue)
+	got, err := handler.Handle(&input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	backups, err := database.Backups(dir)
+	if err != nil {
+		t.Fatalf("Failed to list backups: %v", err)
+	}
+	if len(backups) != 1 || backups[0] == oldBackup {
+		t.Fatalf("backups after $backup = %v, want a single new backup", backups)
+	}
+
+	want := []*base.OutgoingMessage{
+		{
+			Message: base.Message{
+				Text:    fmt.Sprintf("Backed up database to %s, deleted 1 old backup", filepath.Base(backups[0])),
+				Channel: "user2",
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Handle() diff (-want +got):\n%s", diff)
+	}
+}
+
 var testConfig = config.Config{}
 
 func joinOtherUser1(t testing.TB, r *base.Resources) {
 	t.Helper()
-	handler := commands.NewHandler(context.Background(), r.DB, r.Cache, &testConfig, nil)
+	handler := commands.NewHandler(context.Background(), r.DB, r.Cache, &testConfig, nil, nil, logging.Discard())
 	_, err := handler.Handle(&base.IncomingMessage{
 		Message: base.Message{
 			Text:    "$joinother user1",
@@ -639,7 +740,7 @@ func joinOtherUser1(t testing.TB, r *base.Resources) {
 
 func enableBotSlowmode(t testing.TB, r *base.Resources) {
 	t.Helper()
-	handler := commands.NewHandler(context.Background(), r.DB, r.Cache, &testConfig, nil)
+	handler := commands.NewHandler(context.Background(), r.DB, r.Cache, &testConfig, nil, nil, logging.Discard())
 	_, err := handler.Handle(&base.IncomingMessage{
 		Message: base.Message{
 			Text:    "$botslowmode on",
@@ -0,0 +1,187 @@
+// Package audit implements commands that report on the commands that have been run.
+package audit
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/airforce270/airbot/base"
+	"github.com/airforce270/airbot/base/arg"
+	"github.com/airforce270/airbot/commands/basecommand"
+	"github.com/airforce270/airbot/database/models"
+	"github.com/airforce270/airbot/permission"
+)
+
+// Commands contains this package's commands.
+var Commands = [...]basecommand.Command{
+	commandStatsCommand,
+	auditCommand,
+}
+
+var (
+	commandStatsCommand = basecommand.Command{
+		Name:         "commandstats",
+		Aliases:      []string{"cmdstats"},
+		Desc:         "Replies with how often a command has been run, or the most-run commands if none is given.",
+		Params:       []arg.Param{{Name: "command", Type: arg.String, Required: false}},
+		Permission:   permission.Normal,
+		UserCooldown: 5 * time.Second,
+		Handler:      commandStats,
+	}
+
+	auditCommand = basecommand.Command{
+		Name:       "audit",
+		Desc:       "Replies with the most recent commands run, optionally only those run by a user.",
+		Params:     []arg.Param{{Name: "user", Type: arg.Username, Required: false}},
+		Permission: permission.Owner,
+		Handler:    audit,
+	}
+)
+
+const (
+	// topCommandsCount is the number of commands listed when no command is given to $commandstats.
+	topCommandsCount = 5
+	// auditCount is the number of invocations listed by $audit.
+	auditCount = 5
+	// auditTimeFormat is the format of timestamps listed by $audit.
+	auditTimeFormat = "2006-01-02 15:04:05 MST"
+)
+
+// ranOutcomes contains the outcomes of invocations where the command actually ran.
+var ranOutcomes = []string{models.CommandOutcomeOK, models.CommandOutcomeBadUsage, models.CommandOutcomeError}
+
+func commandStats(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
+	commandArg := args[0]
+	if !commandArg.Present {
+		return topCommands(msg)
+	}
+	command := strings.TrimPrefix(strings.ToLower(commandArg.StringValue), msg.Prefix)
+
+	var stats struct {
+		Runs        int64
+		Users       int64
+		Errors      int64
+		AvgDuration float64
+	}
+	err := msg.Resources.DB.Model(&models.CommandInvocation{}).
+		Select(
+			"COUNT(*) AS runs, COUNT(DISTINCT LOWER(username)) AS users, SUM(CASE WHEN outcome = ? THEN 1 ELSE 0 END) AS errors, COALESCE(AVG(duration), 0) AS avg_duration",
+			models.CommandOutcomeError).
+		Where("command = ? AND outcome IN ?", command, ranOutcomes).
+		Scan(&stats).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch stats for command %s: %w", command, err)
+	}
+
+	if stats.Runs == 0 {
+		return []*base.Message{
+			{
+				Channel: msg.Message.Channel,
+				Text:    fmt.Sprintf("%s%s hasn't been run yet", msg.Prefix, command),
+			},
+		}, nil
+	}
+
+	avg := time.Duration(stats.AvgDuration).Round(time.Millisecond)
+	return []*base.Message{
+		{
+			Channel: msg.Message.Channel,
+			Text: fmt.Sprintf("%s%s has been run %d %s by %d %s (%d failed), taking %s on average",
+				msg.Prefix, command,
+				stats.Runs, pluralize(stats.Runs, "time", "times"),
+				stats.Users, pluralize(stats.Users, "user", "users"),
+				stats.Errors, avg),
+		},
+	}, nil
+}
+
+func topCommands(msg *base.IncomingMessage) ([]*base.Message, error) {
+	var top []struct {
+		Command string
+		Runs    int64
+	}
+	err := msg.Resources.DB.Model(&models.CommandInvocation{}).
+		Select("command, COUNT(*) AS runs").
+		Where("outcome IN ?", ranOutcomes).
+		Group("command").
+		Order("runs DESC, command ASC").
+		Limit(topCommandsCount).
+		Scan(&top).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch most-run commands: %w", err)
+	}
+
+	if len(top) == 0 {
+		return []*base.Message{
+			{
+				Channel: msg.Message.Channel,
+				Text:    "No commands have been run yet",
+			},
+		}, nil
+	}
+
+	entries := make([]string, len(top))
+	for i, t := range top {
+		entries[i] = fmt.Sprintf("%s%s (%d)", msg.Prefix, t.Command, t.Runs)
+	}
+	return []*base.Message{
+		{
+			Channel: msg.Message.Channel,
+			Text:    "Most-run commands: " + strings.Join(entries, ", "),
+		},
+	}, nil
+}
+
+func audit(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
+	query := msg.Resources.DB.Order("time DESC, id DESC").Limit(auditCount)
+	userArg := args[0]
+	if userArg.Present {
+		query = query.Where("LOWER(username) = ?", strings.ToLower(userArg.StringValue))
+	}
+
+	var invocations []models.CommandInvocation
+	if err := query.Find(&invocations).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch command invocations: %w", err)
+	}
+
+	if len(invocations) == 0 {
+		text := "No commands have been run yet"
+		if userArg.Present {
+			text = fmt.Sprintf("%s hasn't run any commands", userArg.StringValue)
+		}
+		return []*base.Message{
+			{
+				Channel: msg.Message.Channel,
+				Text:    text,
+			},
+		}, nil
+	}
+
+	entries := make([]string, len(invocations))
+	for i, inv := range invocations {
+		command := inv.Command
+		if inv.Args != "" {
+			command += " " + inv.Args
+		}
+		outcome := inv.Outcome
+		if inv.ErrorID != "" {
+			outcome += ", ID " + inv.ErrorID
+		}
+		entries[i] = fmt.Sprintf("[%s] %s in #%s: %s%s (%s)",
+			inv.Time.UTC().Format(auditTimeFormat), inv.Username, inv.Channel, msg.Prefix, command, outcome)
+	}
+	return []*base.Message{
+		{
+			Channel: msg.Message.Channel,
+			Text:    strings.Join(entries, " | "),
+		},
+	}, nil
+}
+
+func pluralize(n int64, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
+}
@@ -0,0 +1,216 @@
+package audit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/airforce270/airbot/base"
+	"github.com/airforce270/airbot/commands/commandtest"
+	"github.com/airforce270/airbot/database/models"
+	"github.com/airforce270/airbot/permission"
+)
+
+func TestAuditCommands(t *testing.T) {
+	t.Parallel()
+	tests := []commandtest.Case{
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$commandstats roulette",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedInvocations},
+			Want: []*base.Message{
+				{
+					Text:    "$roulette has been run 3 times by 2 users (1 failed), taking 20ms on average",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$commandstats $ROULETTE",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedInvocations},
+			Want: []*base.Message{
+				{
+					Text:    "$roulette has been run 3 times by 2 users (1 failed), taking 20ms on average",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$commandstats duel",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedInvocations},
+			Want: []*base.Message{
+				{
+					Text:    "$duel hasn't been run yet",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$commandstats",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedInvocations},
+			Want: []*base.Message{
+				{
+					Text:    "Most-run commands: $roulette (3), $leaveother (1)",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$commandstats",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform: commandtest.TwitchPlatform,
+			Want: []*base.Message{
+				{
+					Text:    "No commands have been run yet",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$audit",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Owner,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedInvocations},
+			Want: []*base.Message{
+				{
+					Text:    "[2020-05-15 10:05:00 UTC] user1 in #user2: $leaveother user3 (ok) | [2020-05-15 10:04:00 UTC] user3 in #user2: $roulette 10 (error, ID 0a1b2c3d) | [2020-05-15 10:03:00 UTC] user3 in #user1: $roulette all (cooldown) | [2020-05-15 10:02:00 UTC] user1 in #user1: $roulette 5 (ok) | [2020-05-15 10:01:00 UTC] user1 in #user1: $roulette (bad usage)",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$audit USER3",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Owner,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedInvocations},
+			Want: []*base.Message{
+				{
+					Text:    "[2020-05-15 10:04:00 UTC] user3 in #user2: $roulette 10 (error, ID 0a1b2c3d) | [2020-05-15 10:03:00 UTC] user3 in #user1: $roulette all (cooldown)",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$audit user2",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Owner,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedInvocations},
+			Want: []*base.Message{
+				{
+					Text:    "user2 hasn't run any commands",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$audit",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Admin,
+			},
+			Platform: commandtest.TwitchPlatform,
+			Want:     nil,
+		},
+	}
+
+	commandtest.Run(t, tests)
+}
+
+func seedInvocations(t testing.TB, r *base.Resources) {
+	t.Helper()
+	at := func(minute int) time.Time { return time.Date(2020, 5, 15, 10, minute, 0, 0, time.UTC) }
+	invocations := []models.CommandInvocation{
+		{Platform: "Twitch", Channel: "user1", Username: "user1", Command: "roulette", Outcome: models.CommandOutcomeBadUsage, Duration: 10 * time.Millisecond, Time: at(1)},
+		{Platform: "Twitch", Channel: "user1", Username: "user1", Command: "roulette", Args: "5", Outcome: models.CommandOutcomeOK, Duration: 20 * time.Millisecond, Time: at(2)},
+		{Platform: "Twitch", Channel: "user1", Username: "user3", Command: "roulette", Args: "all", Outcome: models.CommandOutcomeCooldown, Time: at(3)},
+		{Platform: "Twitch", Channel: "user2", Username: "user3", Command: "roulette", Args: "10", Outcome: models.CommandOutcomeError, ErrorID: "0a1b2c3d", Duration: 30 * time.Millisecond, Time: at(4)},
+		{Platform: "Twitch", Channel: "user2", Username: "user1", Command: "leaveother", Args: "user3", Outcome: models.CommandOutcomeOK, Duration: 5 * time.Millisecond, Time: at(5)},
+	}
+	if err := r.DB.Create(&invocations).Error; err != nil {
+		t.Fatalf("Failed to seed command invocations: %v", err)
+	}
+}
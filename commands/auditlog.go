@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/airforce270/airbot/database/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// auditFlushInterval is how often records of commands run are written to the database.
+	auditFlushInterval = 5 * time.Second
+	// auditBatchSize is the most records of commands run written in a single statement.
+	auditBatchSize = 100
+	// maxPendingAudits is the most records of commands run waiting to be written.
+	// Once reached, more are dropped until they're written.
+	maxPendingAudits = 10000
+)
+
+// auditLog records attempts to run commands.
+// Records are written to the database in batches,
+// so handling a command doesn't wait on the database.
+type auditLog struct {
+	// db is a connection to the database.
+	db *gorm.DB
+	// logger is the logger for writing records.
+	logger *slog.Logger
+
+	// mu protects the fields below.
+	mu sync.Mutex
+	// pending contains records waiting to be written, oldest first.
+	pending []models.CommandInvocation
+}
+
+func newAuditLog(db *gorm.DB, logger *slog.Logger) *auditLog {
+	return &auditLog{db: db, logger: logger}
+}
+
+// add queues a record to be written.
+func (a *auditLog) add(invocation models.CommandInvocation) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.pending) >= maxPendingAudits {
+		a.logger.Warn("Dropped command invocation record, too many are waiting to be written", "command", invocation.Command, "outcome", invocation.Outcome)
+		return
+	}
+	a.pending = append(a.pending, invocation)
+}
+
+// run writes pending records every auditFlushInterval until ctx is done.
+// Records still pending then are written by flush.
+func (a *auditLog) run(ctx context.Context) {
+	ticker := time.NewTicker(auditFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			a.flush()
+		}
+	}
+}
+
+// flush writes pending records.
+func (a *auditLog) flush() {
+	a.mu.Lock()
+	pending := a.pending
+	a.pending = nil
+	a.mu.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+	if err := a.db.CreateInBatches(pending, auditBatchSize).Error; err != nil {
+		a.logger.Error("Failed to record command invocations", "count", len(pending), "error", err)
+	}
+}
@@ -0,0 +1,285 @@
+// Package automod implements automated chat moderation,
+// and commands to manage it.
+package automod
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/airforce270/airbot/base"
+	"github.com/airforce270/airbot/base/arg"
+	"github.com/airforce270/airbot/cache"
+	"github.com/airforce270/airbot/commands/basecommand"
+	"github.com/airforce270/airbot/database/models"
+	"github.com/airforce270/airbot/permission"
+)
+
+// Commands contains this package's commands.
+var Commands = [...]basecommand.Command{
+	automodCommand,
+	permitCommand,
+}
+
+var (
+	automodCommand = basecommand.Command{
+		Name: "automod",
+		Desc: "Sets, lists, or removes rules messages in the channel are checked against, i.e. $automod set caps timeout 70. " +
+			"Rules: " + strings.Join(kindNames(), ", ") + ". " +
+			"Actions: " + strings.Join(actions, ", ") + "; timeouts get longer each time a user breaks a rule within an hour. " +
+			"Phrase and regex rules take a phrase or pattern, other rules except links take an optional threshold. " +
+			"$automod exempt <level> sets the lowest permission level that isn't checked (default VIP).",
+		Params: []arg.Param{
+			{Name: "action", Type: arg.String, Required: true, Usage: "set|list|remove|exempt"},
+			{Name: "rule", Type: arg.String, Required: false},
+			{Name: "mode", Type: arg.String, Required: false, Usage: "action"},
+			{Name: "value", Type: arg.Variadic, Required: false},
+		},
+		Permission: permission.Mod,
+		Handler:    automod,
+	}
+	permitCommand = basecommand.Command{
+		Name:       "permit",
+		Desc:       fmt.Sprintf("Lets a user post one link in the next %d seconds, if links aren't allowed by automod.", int(permitDuration.Seconds())),
+		Params:     []arg.Param{{Name: "user", Type: arg.Username, Required: true}},
+		Permission: permission.Mod,
+		Handler:    permit,
+	}
+)
+
+// actions contains the actions automod rules can take.
+var actions = []string{models.AutomodActionDelete, models.AutomodActionTimeout, models.AutomodActionWarn}
+
+// exemptLevels contains the permission levels that can be exempt from automod rules.
+var exemptLevels = []permission.Level{permission.AboveNormal, permission.VIP, permission.Mod, permission.Admin}
+
+func automod(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
+	actionArg, ruleArg, modeArg, valueArg := args[0], args[1], args[2], args[3]
+	if !actionArg.Present {
+		return nil, basecommand.ErrBadUsage
+	}
+
+	switch strings.ToLower(actionArg.StringValue) {
+	case "set":
+		if !ruleArg.Present || !modeArg.Present {
+			return nil, basecommand.ErrBadUsage
+		}
+		return setRule(msg, ruleArg.StringValue, modeArg.StringValue, valueArg.StringValue)
+	case "list":
+		return listRules(msg)
+	case "remove":
+		if !ruleArg.Present {
+			return nil, basecommand.ErrBadUsage
+		}
+		// The value of phrase and regex rules may have spaces, so it can span the mode arg too.
+		value := strings.TrimSpace(modeArg.StringValue + " " + valueArg.StringValue)
+		return removeRule(msg, ruleArg.StringValue, value)
+	case "exempt":
+		if !ruleArg.Present {
+			return nil, basecommand.ErrBadUsage
+		}
+		return setExemptLevel(msg, ruleArg.StringValue)
+	default:
+		return nil, basecommand.ErrBadUsage
+	}
+}
+
+func setRule(msg *base.IncomingMessage, kindName, action, value string) ([]*base.Message, error) {
+	k := kindNamed(strings.ToLower(kindName))
+	if k.Name == "" {
+		return reply(msg, fmt.Sprintf("Unknown rule %q, rules are: %s", kindName, strings.Join(kindNames(), ", "))), nil
+	}
+	action = strings.ToLower(action)
+	if !slices.Contains(actions, action) {
+		return reply(msg, fmt.Sprintf("Unknown action %q, actions are: %s", action, strings.Join(actions, ", "))), nil
+	}
+	value, errMsg := parseValue(k, value)
+	if errMsg != "" {
+		return reply(msg, errMsg), nil
+	}
+
+	key := models.AutomodRule{
+		Platform: msg.Resources.Platform.Name(),
+		Channel:  strings.ToLower(msg.Message.Channel),
+		Kind:     k.Name,
+	}
+	if k.TakesText {
+		// Channels may have many phrases or patterns, but only one of each other rule.
+		key.Value = value
+	}
+	var r models.AutomodRule
+	err := msg.Resources.DB.
+		Where(key).
+		Assign(map[string]any{
+			"value":      value,
+			"action":     action,
+			"created_by": msg.Message.User,
+		}).
+		FirstOrCreate(&r).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to set %s automod rule: %w", k.Name, err)
+	}
+	if err := rulesChanged(msg); err != nil {
+		return nil, err
+	}
+	return reply(msg, fmt.Sprintf("Set the automod rule: %s", describe(r))), nil
+}
+
+func listRules(msg *base.IncomingMessage) ([]*base.Message, error) {
+	var rules []models.AutomodRule
+	err := msg.Resources.DB.
+		Where(models.AutomodRule{Platform: msg.Resources.Platform.Name(), Channel: strings.ToLower(msg.Message.Channel)}).
+		Order("kind ASC, value ASC").
+		Find(&rules).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch automod rules: %w", err)
+	}
+	if len(rules) == 0 {
+		return reply(msg, "No automod rules are set"), nil
+	}
+	exemptLevel, err := fetchExemptLevel(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	descs := make([]string, len(rules))
+	for i, r := range rules {
+		descs[i] = describe(r)
+	}
+	return reply(msg, fmt.Sprintf("Automod rules (%s and up are exempt): %s", exemptLevel.Name(), strings.Join(descs, ", "))), nil
+}
+
+func removeRule(msg *base.IncomingMessage, kindName, value string) ([]*base.Message, error) {
+	k := kindNamed(strings.ToLower(kindName))
+	if k.Name == "" {
+		return reply(msg, fmt.Sprintf("Unknown rule %q, rules are: %s", kindName, strings.Join(kindNames(), ", "))), nil
+	}
+
+	key := models.AutomodRule{
+		Platform: msg.Resources.Platform.Name(),
+		Channel:  strings.ToLower(msg.Message.Channel),
+		Kind:     k.Name,
+	}
+	if k.TakesText {
+		// Without a phrase or pattern, all of them are removed.
+		key.Value = value
+	}
+	result := msg.Resources.DB.Unscoped().Where(key).Delete(&models.AutomodRule{})
+	if err := result.Error; err != nil {
+		return nil, fmt.Errorf("failed to remove %s automod rule: %w", k.Name, err)
+	}
+	if err := rulesChanged(msg); err != nil {
+		return nil, err
+	}
+	switch {
+	case result.RowsAffected == 0:
+		return reply(msg, fmt.Sprintf("No %s automod rule is set", k.Name)), nil
+	case result.RowsAffected == 1:
+		return reply(msg, fmt.Sprintf("Removed the %s automod rule", k.Name)), nil
+	default:
+		return reply(msg, fmt.Sprintf("Removed %d %s automod rules", result.RowsAffected, k.Name)), nil
+	}
+}
+
+func setExemptLevel(msg *base.IncomingMessage, levelName string) ([]*base.Message, error) {
+	var level permission.Level
+	if err := level.UnmarshalText([]byte(levelName)); err != nil || !slices.Contains(exemptLevels, level) {
+		names := make([]string, len(exemptLevels))
+		for i, l := range exemptLevels {
+			names[i] = l.Name()
+		}
+		return reply(msg, "The exempt level must be one of: "+strings.Join(names, ", ")), nil
+	}
+
+	var settings models.AutomodSettings
+	err := msg.Resources.DB.
+		Where(models.AutomodSettings{Platform: msg.Resources.Platform.Name(), Channel: strings.ToLower(msg.Message.Channel)}).
+		Assign(map[string]any{"exempt_level": level}).
+		FirstOrCreate(&settings).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to set automod exempt level: %w", err)
+	}
+	if err := rulesChanged(msg); err != nil {
+		return nil, err
+	}
+	return reply(msg, fmt.Sprintf("%s and up are now exempt from automod", level.Name())), nil
+}
+
+func permit(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
+	userArg := args[0]
+	if !userArg.Present {
+		return nil, basecommand.ErrBadUsage
+	}
+	key := cache.AutomodPermitKey(msg.Resources.Platform.Name(), msg.Message.Channel, userArg.StringValue)
+	if err := msg.Resources.MemoryCache.StoreExpiringBool(key, true, permitDuration); err != nil {
+		return nil, fmt.Errorf("failed to permit %s to post a link: %w", userArg.StringValue, err)
+	}
+	return reply(msg, fmt.Sprintf("%s can post a link in the next %d seconds", userArg.StringValue, int(permitDuration.Seconds()))), nil
+}
+
+// parseValue validates the value of a rule, filling in the default threshold if it's empty.
+// If it's invalid, it returns a message saying why.
+func parseValue(k kind, value string) (parsed, errMsg string) {
+	value = strings.TrimSpace(value)
+	switch {
+	case k.TakesText && value == "":
+		return "", fmt.Sprintf("The %s rule needs a %s", k.Name, textName(k))
+	case k.Name == models.AutomodRuleRegex:
+		if _, err := regexp.Compile(value); err != nil {
+			return "", fmt.Sprintf("Invalid pattern: %v", err)
+		}
+		return value, ""
+	case k.TakesText:
+		return value, ""
+	case k.DefaultThreshold == 0:
+		return "", ""
+	case value == "":
+		return strconv.Itoa(k.DefaultThreshold), ""
+	}
+
+	threshold, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSuffix(value, "%"), "x"))
+	if err != nil || threshold < k.MinThreshold || threshold > k.MaxThreshold {
+		return "", fmt.Sprintf("The %s threshold must be from %d to %d", k.Name, k.MinThreshold, k.MaxThreshold)
+	}
+	return strconv.Itoa(threshold), ""
+}
+
+// describe returns a human-readable description of a rule, i.e. "caps 70% (timeout)".
+func describe(r models.AutomodRule) string {
+	k := kindNamed(r.Kind)
+	switch {
+	case k.TakesText:
+		return fmt.Sprintf("%s %q (%s)", r.Kind, r.Value, r.Action)
+	case r.Value != "":
+		return fmt.Sprintf("%s %s%s (%s)", r.Kind, r.Value, k.Unit, r.Action)
+	default:
+		return fmt.Sprintf("%s (%s)", r.Kind, r.Action)
+	}
+}
+
+// textName returns what the text of a rule that takes text is called.
+func textName(k kind) string {
+	if k.Name == models.AutomodRuleRegex {
+		return "pattern"
+	}
+	return "phrase"
+}
+
+func kindNames() []string {
+	names := make([]string, len(kinds))
+	for i, k := range kinds {
+		names[i] = k.Name
+	}
+	return names
+}
+
+func reply(msg *base.IncomingMessage, text string) []*base.Message {
+	return []*base.Message{
+		{
+			Channel: msg.Message.Channel,
+			Text:    text,
+		},
+	}
+}
@@ -0,0 +1,584 @@
+package automod_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/airforce270/airbot/apiclients/twitchtest"
+	"github.com/airforce270/airbot/base"
+	"github.com/airforce270/airbot/cache"
+	"github.com/airforce270/airbot/commands/commandtest"
+	"github.com/airforce270/airbot/database/models"
+	"github.com/airforce270/airbot/permission"
+)
+
+func TestAutomodCommands(t *testing.T) {
+	t.Parallel()
+	tests := []commandtest.Case{
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$automod set caps timeout 80",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform: commandtest.TwitchPlatform,
+			Want: []*base.Message{
+				{
+					Text:    "Set the automod rule: caps 80% (timeout)",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$automod set caps warn",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedRules},
+			Want: []*base.Message{
+				{
+					Text:    "Set the automod rule: caps 70% (warn)",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$automod set phrase delete bad words",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform: commandtest.TwitchPlatform,
+			Want: []*base.Message{
+				{
+					Text:    `Set the automod rule: phrase "bad words" (delete)`,
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$automod set links delete",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform: commandtest.TwitchPlatform,
+			Want: []*base.Message{
+				{
+					Text:    "Set the automod rule: links (delete)",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$automod set regex warn [a-z",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform: commandtest.TwitchPlatform,
+			Want: []*base.Message{
+				{
+					Text:    "Invalid pattern: error parsing regexp: missing closing ]: `[a-z`",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$automod set phrase warn",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform: commandtest.TwitchPlatform,
+			Want: []*base.Message{
+				{
+					Text:    "The phrase rule needs a phrase",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$automod set caps warn 150",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform: commandtest.TwitchPlatform,
+			Want: []*base.Message{
+				{
+					Text:    "The caps threshold must be from 1 to 100",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$automod set spam warn",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform: commandtest.TwitchPlatform,
+			Want: []*base.Message{
+				{
+					Text:    `Unknown rule "spam", rules are: phrase, regex, caps, symbols, emotes, repeat, links, zalgo`,
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$automod set caps ban",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform: commandtest.TwitchPlatform,
+			Want: []*base.Message{
+				{
+					Text:    `Unknown action "ban", actions are: delete, timeout, warn`,
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$automod set caps",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform: commandtest.TwitchPlatform,
+			Want: []*base.Message{
+				{
+					Text:    "Usage: $automod <set|list|remove|exempt> [rule] [action] [value]",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$automod list",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedRules},
+			Want: []*base.Message{
+				{
+					Text:    `Automod rules (VIP and up are exempt): caps 70% (timeout), links (delete), phrase "bad words" (warn)`,
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$automod list",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform: commandtest.TwitchPlatform,
+			Want: []*base.Message{
+				{
+					Text:    "No automod rules are set",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$automod remove phrase bad words",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedRules},
+			Want: []*base.Message{
+				{
+					Text:    "Removed the phrase automod rule",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$automod remove caps",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedRules},
+			Want: []*base.Message{
+				{
+					Text:    "Removed the caps automod rule",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$automod remove zalgo",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedRules},
+			Want: []*base.Message{
+				{
+					Text:    "No zalgo automod rule is set",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$automod exempt mod",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform: commandtest.TwitchPlatform,
+			Want: []*base.Message{
+				{
+					Text:    "Mod and up are now exempt from automod",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$automod exempt owner",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform: commandtest.TwitchPlatform,
+			Want: []*base.Message{
+				{
+					Text:    "The exempt level must be one of: Above Normal, VIP, Mod, Admin",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$automod list",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedRules},
+			Want:      nil,
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$permit user3",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform:   commandtest.TwitchPlatform,
+			OtherTexts: []string{"$permit @user3"},
+			Want: []*base.Message{
+				{
+					Text:    "user3 can post a link in the next 60 seconds",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$permit user3",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform: commandtest.TwitchPlatform,
+			Want:     nil,
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "these are bad words",
+					UserID:  "user3",
+					User:    "user3",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedRules},
+			Want: []*base.Message{
+				{
+					Text:    "@user3, that phrase isn't allowed",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "these are bad words",
+					UserID:  "user3",
+					User:    "user3",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.VIP,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedRules},
+			Want:      nil,
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "check out example.com",
+					UserID:  "user3",
+					User:    "user3",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedRules},
+			Want: []*base.Message{
+				{
+					Text:    "@user3, links aren't allowed",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "check out example.com",
+					ID:      "abc-123",
+					UserID:  "user3",
+					User:    "user3",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			APIResp:   twitchtest.GetUsersResp,
+			RunBefore: []commandtest.SetupFunc{seedRules},
+			Want:      nil,
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "check out example.com",
+					UserID:  "user3",
+					User:    "user3",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedRules, permitUser3},
+			Want:      nil,
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "WHY IS EVERYONE YELLING",
+					UserID:  "user3",
+					User:    "user3",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			APIResp:   twitchtest.GetUsersResp,
+			RunBefore: []commandtest.SetupFunc{seedRules},
+			Want:      nil,
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$automod list bad words",
+					UserID:  "user3",
+					User:    "user3",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedRules},
+			Want: []*base.Message{
+				{
+					Text:    "@user3, that phrase isn't allowed",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "hello there",
+					UserID:  "user3",
+					User:    "user3",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedRules},
+			Want:      nil,
+		},
+	}
+
+	commandtest.Run(t, tests)
+}
+
+func seedRules(t testing.TB, r *base.Resources) {
+	t.Helper()
+	rules := []models.AutomodRule{
+		{Platform: r.Platform.Name(), Channel: "user2", Kind: models.AutomodRulePhrase, Value: "bad words", Action: models.AutomodActionWarn},
+		{Platform: r.Platform.Name(), Channel: "user2", Kind: models.AutomodRuleLinks, Action: models.AutomodActionDelete},
+		{Platform: r.Platform.Name(), Channel: "user2", Kind: models.AutomodRuleCaps, Value: "70", Action: models.AutomodActionTimeout},
+		{Platform: r.Platform.Name(), Channel: "user1", Kind: models.AutomodRuleZalgo, Value: "6", Action: models.AutomodActionTimeout},
+	}
+	if err := r.DB.Create(&rules).Error; err != nil {
+		t.Fatalf("Failed to create automod rules: %v", err)
+	}
+}
+
+func permitUser3(t testing.TB, r *base.Resources) {
+	t.Helper()
+	if err := r.MemoryCache.StoreExpiringBool(cache.AutomodPermitKey(r.Platform.Name(), "user2", "user3"), true, time.Minute); err != nil {
+		t.Fatalf("Failed to permit user3: %v", err)
+	}
+}
@@ -0,0 +1,453 @@
+package automod
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/airforce270/airbot/base"
+	"github.com/airforce270/airbot/cache"
+	"github.com/airforce270/airbot/database/models"
+	"github.com/airforce270/airbot/metrics"
+	"github.com/airforce270/airbot/permission"
+)
+
+const (
+	// defaultExemptLevel is the lowest permission level that isn't checked against automod rules,
+	// in channels that haven't changed it.
+	defaultExemptLevel = permission.VIP
+	// strikeExpiry is how long a user's strikes last after their latest one.
+	strikeExpiry = 1 * time.Hour
+	// repeatWindow is how long after a message a repeat of it counts towards a repeat rule.
+	repeatWindow = 30 * time.Second
+	// permitDuration is how long a user may post a link after being permitted to.
+	permitDuration = 60 * time.Second
+	// minLengthForRatio is the fewest characters a message needs to be checked against caps and symbols rules,
+	// so short messages like "OK" aren't.
+	minLengthForRatio = 10
+)
+
+// timeoutDurations are how long users are timed out for by automod,
+// by how many strikes they've had recently.
+var timeoutDurations = []time.Duration{
+	1 * time.Minute,
+	10 * time.Minute,
+	1 * time.Hour,
+	24 * time.Hour,
+}
+
+// kind describes a kind of automod rule.
+type kind struct {
+	// Name is the name of the kind, one of the models.AutomodRule constants.
+	Name string
+	// Reason is what users breaking the rule are told they did.
+	Reason string
+	// TakesText is whether the rule is set with a phrase or pattern.
+	TakesText bool
+	// DefaultThreshold is the threshold used if none is given,
+	// or 0 if the rule doesn't have a threshold.
+	DefaultThreshold int
+	// MinThreshold is the lowest threshold allowed.
+	MinThreshold int
+	// MaxThreshold is the highest threshold allowed.
+	MaxThreshold int
+	// Unit is shown after the rule's threshold, i.e. "%".
+	Unit string
+}
+
+// kinds contains the kinds of automod rules.
+var kinds = []kind{
+	{Name: models.AutomodRulePhrase, Reason: "that phrase isn't allowed", TakesText: true},
+	{Name: models.AutomodRuleRegex, Reason: "that phrase isn't allowed", TakesText: true},
+	{Name: models.AutomodRuleCaps, Reason: "too many caps", DefaultThreshold: 70, MinThreshold: 1, MaxThreshold: 100, Unit: "%"},
+	{Name: models.AutomodRuleSymbols, Reason: "too many symbols", DefaultThreshold: 50, MinThreshold: 1, MaxThreshold: 100, Unit: "%"},
+	{Name: models.AutomodRuleEmotes, Reason: "too many repeated emotes", DefaultThreshold: 8, MinThreshold: 2, MaxThreshold: 100, Unit: "x"},
+	{Name: models.AutomodRuleRepeat, Reason: "stop repeating yourself", DefaultThreshold: 3, MinThreshold: 2, MaxThreshold: 20, Unit: "x"},
+	{Name: models.AutomodRuleLinks, Reason: "links aren't allowed"},
+	{Name: models.AutomodRuleZalgo, Reason: "zalgo text isn't allowed", DefaultThreshold: 6, MinThreshold: 1, MaxThreshold: 100},
+}
+
+// linkPattern matches links, with or without a scheme.
+var linkPattern = regexp.MustCompile(`(?i)(?:https?://|www\.)\S+|\b[a-z0-9-]+(?:\.[a-z0-9-]+)*\.(?:com|net|org|tv|gg|io|co|me|ly|xyz|ru|de|uk|info|link|app|dev|gl|be)\b(?:/\S*)?`)
+
+// Checker checks messages against automod rules.
+// Each channel's rules are cached, and reloaded when they're changed.
+type Checker struct {
+	// mu protects channels.
+	mu sync.Mutex
+	// channels contains the cached rules of each channel, keyed by platform and channel.
+	channels map[string]channelRules
+}
+
+// NewChecker creates a new Checker.
+func NewChecker() *Checker {
+	return &Checker{channels: map[string]channelRules{}}
+}
+
+// channelRules are the cached automod rules of a channel.
+type channelRules struct {
+	// version is the version of the rules, from the memory cache.
+	// When the rules are changed, the version is changed, so they're reloaded.
+	version string
+	// rules contains the channel's rules, in the order they're checked.
+	rules []compiledRule
+	// exemptLevel is the lowest permission level that isn't checked against the rules.
+	exemptLevel permission.Level
+}
+
+// compiledRule is an automod rule, with its pattern compiled if it's a regex rule.
+type compiledRule struct {
+	models.AutomodRule
+	// pattern is the compiled pattern of regex rules.
+	pattern *regexp.Regexp
+}
+
+// Check checks a message against the automod rules of the channel it was sent in,
+// and takes action against the sender if it breaks one.
+// It returns messages to send, and whether the message broke a rule.
+// If it did, the message shouldn't be handled any further.
+func (c *Checker) Check(msg *base.IncomingMessage) (respMsgs []*base.Message, broke bool, err error) {
+	if msg.Whisper {
+		return nil, false, nil
+	}
+
+	channelRules, err := c.rules(msg)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(channelRules.rules) == 0 || permission.Authorized(msg.PermissionLevel, channelRules.exemptLevel) {
+		return nil, false, nil
+	}
+
+	for _, r := range channelRules.rules {
+		broken, err := breaks(msg, r)
+		if err != nil {
+			return nil, false, err
+		}
+		if !broken {
+			continue
+		}
+		out, err := enforce(msg, r.AutomodRule)
+		return out, true, err
+	}
+	return nil, false, nil
+}
+
+// rules returns the automod rules of the channel a message was sent in,
+// loading them if they aren't cached or have changed.
+func (c *Checker) rules(msg *base.IncomingMessage) (channelRules, error) {
+	platformName := msg.Resources.Platform.Name()
+	channel := strings.ToLower(msg.Message.Channel)
+	version, err := msg.Resources.MemoryCache.FetchString(cache.AutomodRulesVersionKey(platformName, channel))
+	if err != nil {
+		return channelRules{}, fmt.Errorf("failed to fetch automod rules version for %s: %w", channel, err)
+	}
+
+	key := platformName + ":" + channel
+	c.mu.Lock()
+	cached, ok := c.channels[key]
+	c.mu.Unlock()
+	if ok && cached.version == version {
+		return cached, nil
+	}
+
+	loaded, err := loadRules(msg)
+	if err != nil {
+		return channelRules{}, err
+	}
+	loaded.version = version
+	c.mu.Lock()
+	c.channels[key] = loaded
+	c.mu.Unlock()
+	return loaded, nil
+}
+
+// loadRules loads the automod rules of the channel a message was sent in from the database.
+func loadRules(msg *base.IncomingMessage) (channelRules, error) {
+	channel := strings.ToLower(msg.Message.Channel)
+	var rules []models.AutomodRule
+	err := msg.Resources.DB.
+		Where(models.AutomodRule{Platform: msg.Resources.Platform.Name(), Channel: channel}).
+		Order("id ASC").
+		Find(&rules).Error
+	if err != nil {
+		return channelRules{}, fmt.Errorf("failed to fetch automod rules for %s: %w", channel, err)
+	}
+	if len(rules) == 0 {
+		return channelRules{}, nil
+	}
+
+	exemptLevel, err := fetchExemptLevel(msg)
+	if err != nil {
+		return channelRules{}, err
+	}
+
+	loaded := channelRules{exemptLevel: exemptLevel}
+	for _, r := range rules {
+		compiled, err := compileRule(r)
+		if err != nil {
+			// Patterns are validated when they're set, so this shouldn't happen.
+			msg.Resources.Logger.Warn("Skipping invalid automod pattern", "pattern", r.Value, "error", err)
+			continue
+		}
+		loaded.rules = append(loaded.rules, compiled)
+	}
+	return loaded, nil
+}
+
+// compileRule compiles a rule's pattern, if it's a regex rule.
+func compileRule(r models.AutomodRule) (compiledRule, error) {
+	compiled := compiledRule{AutomodRule: r}
+	if r.Kind != models.AutomodRuleRegex {
+		return compiled, nil
+	}
+	pattern, err := regexp.Compile(r.Value)
+	if err != nil {
+		return compiledRule{}, err
+	}
+	compiled.pattern = pattern
+	return compiled, nil
+}
+
+// rulesChanged marks the automod rules of the channel a message was sent in as changed,
+// so they're reloaded the next time a message is checked.
+func rulesChanged(msg *base.IncomingMessage) error {
+	key := cache.AutomodRulesVersionKey(msg.Resources.Platform.Name(), msg.Message.Channel)
+	if err := msg.Resources.MemoryCache.StoreString(key, strconv.FormatInt(time.Now().UnixNano(), 10)); err != nil {
+		return fmt.Errorf("failed to mark automod rules as changed: %w", err)
+	}
+	return nil
+}
+
+// breaks returns whether a message breaks a rule.
+func breaks(msg *base.IncomingMessage, r compiledRule) (bool, error) {
+	text := normalize(msg.Message.Text)
+	threshold, _ := strconv.Atoi(r.Value)
+
+	switch r.Kind {
+	case models.AutomodRulePhrase:
+		return strings.Contains(strings.ToLower(text), strings.ToLower(r.Value)), nil
+	case models.AutomodRuleRegex:
+		return r.pattern.MatchString(text), nil
+	case models.AutomodRuleCaps:
+		return capsPercent(text) >= threshold, nil
+	case models.AutomodRuleSymbols:
+		return symbolsPercent(text) >= threshold, nil
+	case models.AutomodRuleEmotes:
+		return mostRepeatedWord(text) >= threshold, nil
+	case models.AutomodRuleRepeat:
+		return repeated(msg, text, threshold)
+	case models.AutomodRuleLinks:
+		if !linkPattern.MatchString(text) {
+			return false, nil
+		}
+		permitted, err := usePermit(msg)
+		return !permitted, err
+	case models.AutomodRuleZalgo:
+		return combiningMarks(text) >= threshold, nil
+	default:
+		msg.Resources.Logger.Warn("Skipping unknown automod rule", "kind", r.Kind)
+		return false, nil
+	}
+}
+
+// enforce takes a rule's action against the sender of a message that broke it.
+func enforce(msg *base.IncomingMessage, r models.AutomodRule) ([]*base.Message, error) {
+	logger := msg.Resources.Logger.With("rule", r.Kind, "action", r.Action)
+	metrics.AutomodActions.WithLabelValues(msg.Resources.Platform.Name(), r.Kind, r.Action).Inc()
+	reason := kindNamed(r.Kind).Reason
+	warning := []*base.Message{
+		{
+			Channel: msg.Message.Channel,
+			Text:    fmt.Sprintf("@%s, %s", msg.Message.User, reason),
+		},
+	}
+
+	moderator, ok := msg.Resources.Platform.(base.Moderator)
+	if !ok || r.Action == models.AutomodActionWarn {
+		if !ok && r.Action != models.AutomodActionWarn {
+			logger.Warn("Platform can't moderate, warning instead")
+		}
+		logger.Info("Warned user for breaking automod rule")
+		return warning, nil
+	}
+
+	var err error
+	switch r.Action {
+	case models.AutomodActionDelete:
+		if msg.Message.ID == "" {
+			logger.Warn("Message has no ID, warning instead of deleting it")
+			return warning, nil
+		}
+		err = moderator.DeleteMessage(msg.Message.Channel, msg.Message.ID)
+	case models.AutomodActionTimeout:
+		var duration time.Duration
+		duration, err = strike(msg)
+		if err != nil {
+			return nil, err
+		}
+		logger = logger.With("duration", duration)
+		err = moderator.TimeoutWithReason(msg.Message.User, msg.Message.Channel, duration, "Automod: "+reason)
+	}
+	if errors.Is(err, base.ErrNotModerator) {
+		logger.Warn("Bot isn't a moderator, warning instead")
+		return warning, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s message from %s breaking %s rule: %w", r.Action, msg.Message.User, r.Kind, err)
+	}
+	logger.Info("Took action against message breaking automod rule")
+	return nil, nil
+}
+
+// strike records a strike against the sender of a message,
+// returning how long they should be timed out for.
+func strike(msg *base.IncomingMessage) (time.Duration, error) {
+	key := cache.AutomodStrikesKey(msg.Resources.Platform.Name(), msg.Message.Channel, msg.Message.UserID)
+	strikesStr, err := msg.Resources.MemoryCache.FetchString(key)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch automod strikes: %w", err)
+	}
+	strikes, _ := strconv.Atoi(strikesStr)
+	if err := msg.Resources.MemoryCache.StoreExpiringString(key, strconv.Itoa(strikes+1), strikeExpiry); err != nil {
+		return 0, fmt.Errorf("failed to store automod strikes: %w", err)
+	}
+	return timeoutDurations[min(strikes, len(timeoutDurations)-1)], nil
+}
+
+// repeated records a message and returns whether its sender has sent it at least threshold times in a row.
+func repeated(msg *base.IncomingMessage, text string, threshold int) (bool, error) {
+	key := cache.AutomodLastMessageKey(msg.Resources.Platform.Name(), msg.Message.Channel, msg.Message.UserID)
+	last, err := msg.Resources.MemoryCache.FetchString(key)
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch last message: %w", err)
+	}
+	// Stored as count:text.
+	countStr, lastText, _ := strings.Cut(last, ":")
+	count := 1
+	if text = strings.ToLower(text); lastText == text {
+		n, _ := strconv.Atoi(countStr)
+		count = n + 1
+	}
+	if err := msg.Resources.MemoryCache.StoreExpiringString(key, strconv.Itoa(count)+":"+text, repeatWindow); err != nil {
+		return false, fmt.Errorf("failed to store last message: %w", err)
+	}
+	return count >= threshold, nil
+}
+
+// usePermit returns whether the sender of a message was permitted to post a link,
+// using the permit up if so.
+func usePermit(msg *base.IncomingMessage) (bool, error) {
+	key := cache.AutomodPermitKey(msg.Resources.Platform.Name(), msg.Message.Channel, msg.Message.User)
+	permitted, err := msg.Resources.MemoryCache.FetchBool(key)
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch link permit: %w", err)
+	}
+	if !permitted {
+		return false, nil
+	}
+	if err := msg.Resources.MemoryCache.StoreBool(key, false); err != nil {
+		return false, fmt.Errorf("failed to use up link permit: %w", err)
+	}
+	return true, nil
+}
+
+// fetchExemptLevel returns the lowest permission level that isn't checked against automod rules
+// in the channel a message was sent in.
+func fetchExemptLevel(msg *base.IncomingMessage) (permission.Level, error) {
+	var settings []models.AutomodSettings
+	err := msg.Resources.DB.
+		Where(models.AutomodSettings{Platform: msg.Resources.Platform.Name(), Channel: strings.ToLower(msg.Message.Channel)}).
+		Limit(1).
+		Find(&settings).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch automod settings: %w", err)
+	}
+	if len(settings) == 0 || settings[0].ExemptLevel == 0 {
+		return defaultExemptLevel, nil
+	}
+	return settings[0].ExemptLevel, nil
+}
+
+// normalize removes invisible characters platforms add to bypass duplicate message detection,
+// and collapses whitespace.
+func normalize(text string) string {
+	return strings.Join(strings.Fields(strings.ReplaceAll(text, "\U000E0000", "")), " ")
+}
+
+// capsPercent returns the percentage of letters in a message that are capitals,
+// or 0 if it's too short to tell.
+func capsPercent(text string) int {
+	var letters, caps int
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.IsUpper(r) {
+			caps++
+		}
+	}
+	if letters < minLengthForRatio {
+		return 0
+	}
+	return caps * 100 / letters
+}
+
+// symbolsPercent returns the percentage of characters in a message that are symbols,
+// or 0 if it's too short to tell.
+func symbolsPercent(text string) int {
+	var chars, symbols int
+	for _, r := range text {
+		if unicode.IsSpace(r) || unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		chars++
+		if !unicode.IsLetter(r) && !unicode.IsNumber(r) {
+			symbols++
+		}
+	}
+	if chars < minLengthForRatio {
+		return 0
+	}
+	return symbols * 100 / chars
+}
+
+// mostRepeatedWord returns how many times the most common word in a message appears in it.
+// Emotes are words, so this catches emote spam.
+func mostRepeatedWord(text string) int {
+	counts := map[string]int{}
+	var most int
+	for _, word := range strings.Fields(text) {
+		counts[word]++
+		most = max(most, counts[word])
+	}
+	return most
+}
+
+// combiningMarks returns the number of combining marks in a message,
+// which are stacked to make zalgo text.
+func combiningMarks(text string) int {
+	var marks int
+	for _, r := range text {
+		if unicode.Is(unicode.Mn, r) {
+			marks++
+		}
+	}
+	return marks
+}
+
+// kindNamed returns the kind of rule with a name, or an empty kind if there isn't one.
+func kindNamed(name string) kind {
+	for _, k := range kinds {
+		if k.Name == name {
+			return k
+		}
+	}
+	return kind{}
+}
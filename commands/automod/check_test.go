@@ -0,0 +1,221 @@
+package automod
+
+import (
+	"testing"
+	"time"
+
+	"github.com/airforce270/airbot/base"
+	"github.com/airforce270/airbot/cache"
+	"github.com/airforce270/airbot/cache/cachetest"
+	"github.com/airforce270/airbot/database/databasetest"
+	"github.com/airforce270/airbot/database/models"
+	"github.com/airforce270/airbot/logging"
+	"github.com/airforce270/airbot/permission"
+	"github.com/airforce270/airbot/platforms/twitch"
+)
+
+func TestBreaks(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		desc string
+		text string
+		rule models.AutomodRule
+		want bool
+	}{
+		{
+			desc: "phrase ignores case",
+			text: "some BAD Words here",
+			rule: models.AutomodRule{Kind: models.AutomodRulePhrase, Value: "bad words"},
+			want: true,
+		},
+		{
+			desc: "regex",
+			text: "buy followers at cheap prices",
+			rule: models.AutomodRule{Kind: models.AutomodRuleRegex, Value: `(?i)buy\s+(followers|viewers)`},
+			want: true,
+		},
+		{
+			desc: "caps",
+			text: "WHY IS EVERYONE YELLING lol",
+			rule: models.AutomodRule{Kind: models.AutomodRuleCaps, Value: "70"},
+			want: true,
+		},
+		{
+			desc: "caps ignores short messages",
+			text: "OK LOL",
+			rule: models.AutomodRule{Kind: models.AutomodRuleCaps, Value: "70"},
+			want: false,
+		},
+		{
+			desc: "symbols",
+			text: "!!!!!!!!!!!! ####### ok",
+			rule: models.AutomodRule{Kind: models.AutomodRuleSymbols, Value: "50"},
+			want: true,
+		},
+		{
+			desc: "symbols under threshold",
+			text: "hello there, how are you?",
+			rule: models.AutomodRule{Kind: models.AutomodRuleSymbols, Value: "50"},
+			want: false,
+		},
+		{
+			desc: "emotes",
+			text: "KEKW KEKW KEKW KEKW",
+			rule: models.AutomodRule{Kind: models.AutomodRuleEmotes, Value: "4"},
+			want: true,
+		},
+		{
+			desc: "emotes under threshold",
+			text: "KEKW KEKW LUL KEKW",
+			rule: models.AutomodRule{Kind: models.AutomodRuleEmotes, Value: "4"},
+			want: false,
+		},
+		{
+			desc: "link with scheme",
+			text: "look https://example.org/page",
+			rule: models.AutomodRule{Kind: models.AutomodRuleLinks},
+			want: true,
+		},
+		{
+			desc: "link without scheme",
+			text: "go to clips.twitch.tv/something",
+			rule: models.AutomodRule{Kind: models.AutomodRuleLinks},
+			want: true,
+		},
+		{
+			desc: "no link",
+			text: "i.e. this isn't a link",
+			rule: models.AutomodRule{Kind: models.AutomodRuleLinks},
+			want: false,
+		},
+		{
+			desc: "zalgo",
+			text: "h̶̷e̸̹l̺̻l̼o",
+			rule: models.AutomodRule{Kind: models.AutomodRuleZalgo, Value: "6"},
+			want: true,
+		},
+		{
+			desc: "accents aren't zalgo",
+			text: "café",
+			rule: models.AutomodRule{Kind: models.AutomodRuleZalgo, Value: "6"},
+			want: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			msg := newTestMessage(t, tc.text)
+			rule, err := compileRule(tc.rule)
+			if err != nil {
+				t.Fatalf("compileRule() unexpected error: %v", err)
+			}
+
+			got, err := breaks(msg, rule)
+			if err != nil {
+				t.Fatalf("breaks() unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("breaks(%q, %s) = %t, want %t", tc.text, tc.rule.Kind, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBreaks_Repeat(t *testing.T) {
+	t.Parallel()
+	msg := newTestMessage(t, "")
+	rule := compiledRule{AutomodRule: models.AutomodRule{Kind: models.AutomodRuleRepeat, Value: "3"}}
+
+	texts := []struct {
+		text string
+		want bool
+	}{
+		{text: "hello", want: false},
+		{text: "HELLO", want: false},
+		// Twitch adds an invisible character to bypass duplicate message detection.
+		{text: "hello \U000E0000", want: true},
+		{text: "goodbye", want: false},
+		{text: "hello", want: false},
+	}
+	for _, tc := range texts {
+		msg.Message.Text = tc.text
+		got, err := breaks(msg, rule)
+		if err != nil {
+			t.Fatalf("breaks(%q) unexpected error: %v", tc.text, err)
+		}
+		if got != tc.want {
+			t.Errorf("breaks(%q) = %t, want %t", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestStrike(t *testing.T) {
+	t.Parallel()
+	msg := newTestMessage(t, "")
+
+	want := []time.Duration{1 * time.Minute, 10 * time.Minute, 1 * time.Hour, 24 * time.Hour, 24 * time.Hour}
+	for i, wantDuration := range want {
+		got, err := strike(msg)
+		if err != nil {
+			t.Fatalf("strike() #%d unexpected error: %v", i+1, err)
+		}
+		if got != wantDuration {
+			t.Errorf("strike() #%d = %s, want %s", i+1, got, wantDuration)
+		}
+	}
+}
+
+func newTestMessage(t *testing.T, text string) *base.IncomingMessage {
+	t.Helper()
+	db := databasetest.New(t)
+	return &base.IncomingMessage{
+		Message: base.Message{
+			Text:    text,
+			Channel: "user2",
+			UserID:  "user3",
+			User:    "user3",
+		},
+		PermissionLevel: permission.Normal,
+		Resources: base.Resources{
+			Platform:    twitch.NewForTesting(t, "", db),
+			DB:          db,
+			Cache:       cachetest.NewDB(t, db),
+			MemoryCache: cache.NewMemory(),
+			Logger:      logging.Discard(),
+		},
+	}
+}
+
+func TestChecker_CachesRules(t *testing.T) {
+	t.Parallel()
+	msg := newTestMessage(t, "some bad words")
+	rule := models.AutomodRule{Platform: msg.Resources.Platform.Name(), Channel: "user2", Kind: models.AutomodRulePhrase, Value: "bad", Action: models.AutomodActionWarn}
+	if err := msg.Resources.DB.Create(&rule).Error; err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	c := NewChecker()
+
+	check := func(desc string, want bool) {
+		t.Helper()
+		_, broke, err := c.Check(msg)
+		if err != nil {
+			t.Fatalf("%s: Check() unexpected error: %v", desc, err)
+		}
+		if broke != want {
+			t.Errorf("%s: Check() broke = %t, want %t", desc, broke, want)
+		}
+	}
+
+	check("rule set", true)
+
+	if err := msg.Resources.DB.Unscoped().Delete(&rule).Error; err != nil {
+		t.Fatalf("Failed to delete rule: %v", err)
+	}
+	check("rule deleted without marking rules changed", true)
+
+	if err := rulesChanged(msg); err != nil {
+		t.Fatalf("rulesChanged() unexpected error: %v", err)
+	}
+	check("rules changed", false)
+}
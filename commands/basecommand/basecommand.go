@@ -17,6 +17,39 @@ import (
 // i.e.: not enough args, etc.
 var ErrBadUsage = errors.New("bad usage")
 
+// Error is an error returned by a command handler that should be reported to the user.
+// Handlers may also return plain errors, which are reported with a generic message.
+type Error struct {
+	// UserMessage is the message shown to the user, i.e. "Failed to look up that verse".
+	// If empty, a generic message is shown.
+	UserMessage string
+	// Retryable is whether running the command again may succeed,
+	// i.e. if an external API was temporarily unavailable.
+	Retryable bool
+	// Err is the underlying error.
+	// It's logged, but never shown to the user.
+	Err error
+}
+
+// NewError creates a new Error that isn't retryable.
+func NewError(userMessage string, err error) *Error {
+	return &Error{UserMessage: userMessage, Err: err}
+}
+
+// NewRetryableError creates a new Error for a failure that may succeed if tried again.
+func NewRetryableError(userMessage string, err error) *Error {
+	return &Error{UserMessage: userMessage, Retryable: true, Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.UserMessage
+	}
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
 // Command represents a command the bot handles.
 type Command struct {
 	// Name is the name of the command.
@@ -38,6 +71,12 @@ type Command struct {
 	// DisableReplies will disable the returned messages from being sent as replies.
 	// This only has an effect on platforms that support replies.
 	DisableReplies bool
+	// WhisperSafe is whether the command can be run from a whisper.
+	// Commands that act on the channel they're run in shouldn't be.
+	WhisperSafe bool
+	// WhisperOnly is whether the command can only be run from a whisper,
+	// i.e. because its response is private. It should also be WhisperSafe.
+	WhisperOnly bool
 	// Handler is the function to be run if this command matches.
 	// args contains the arguments to the command as specified by Params.
 	Handler func(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error)
@@ -121,12 +160,17 @@ func FirstArgOrUsername(args []arg.Arg, msg *base.IncomingMessage) string {
 }
 
 // FirstArgOrChannel returns the first provided arg, or the message's channel if not present.
+// Whispers have no channel, so the sender's own channel is used instead.
 func FirstArgOrChannel(args []arg.Arg, msg *base.IncomingMessage) string {
-	if len(args) == 0 {
-		return msg.Message.Channel
+	if len(args) > 0 && args[0].Present {
+		return args[0].StringValue
 	}
-	if firstArg := args[0]; firstArg.Present {
-		return firstArg.StringValue
+	if msg.Whisper {
+		return msg.Message.User
 	}
 	return msg.Message.Channel
 }
+
+// EventHandler handles an event (i.e. a channel going live),
+// possibly returning messages to be sent in response.
+type EventHandler func(event *base.IncomingEvent) ([]*base.Message, error)
@@ -1,6 +1,7 @@
 package basecommand
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
@@ -233,3 +234,52 @@ func TestFirstArgOrChannel(t *testing.T) {
 		})
 	}
 }
+
+func TestError(t *testing.T) {
+	t.Parallel()
+	underlying := errors.New("connection refused")
+	tests := []struct {
+		desc          string
+		err           *Error
+		wantError     string
+		wantRetryable bool
+	}{
+		{
+			desc:      "not retryable",
+			err:       NewError("Failed to do the thing", underlying),
+			wantError: "connection refused",
+		},
+		{
+			desc:          "retryable",
+			err:           NewRetryableError("Failed to do the thing", underlying),
+			wantError:     "connection refused",
+			wantRetryable: true,
+		},
+		{
+			desc:      "no underlying error",
+			err:       NewError("Failed to do the thing", nil),
+			wantError: "Failed to do the thing",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			wrapped := fmt.Errorf("failed to handle: %w", tc.err)
+
+			var got *Error
+			if !errors.As(wrapped, &got) {
+				t.Fatalf("errors.As(%v, *Error) = false, want true", wrapped)
+			}
+			if got.Error() != tc.wantError {
+				t.Errorf("Error() = %q, want %q", got.Error(), tc.wantError)
+			}
+			if got.Retryable != tc.wantRetryable {
+				t.Errorf("Retryable = %t, want %t", got.Retryable, tc.wantRetryable)
+			}
+			if tc.err.Err != nil && !errors.Is(wrapped, underlying) {
+				t.Errorf("errors.Is(%v, %v) = false, want true", wrapped, underlying)
+			}
+		})
+	}
+}
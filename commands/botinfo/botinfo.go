@@ -54,9 +54,10 @@ var Commands = [...]basecommand.Command{
 		},
 	},
 	{
-		Name:       "source",
-		Desc:       "Replies a link to the bot's source code.",
-		Permission: permission.Normal,
+		Name:        "source",
+		Desc:        "Replies a link to the bot's source code.",
+		Permission:  permission.Normal,
+		WhisperSafe: true,
 		Handler: func(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
 			return []*base.Message{
 				{
@@ -164,6 +165,9 @@ func stats(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
 	fmt.Fprintf(&out, ", CPU: %2.1f%%", cpuPercent)
 	fmt.Fprintf(&out, ", RAM: %2.1f%%", memory.UsedPercent)
 	fmt.Fprintf(&out, ", processed %d messages in %d channels in the last %d seconds", recentlyProcessedMessages, joinedChannels, int(recentlyProcessedMessagesInterval.Seconds()))
+	if q := msg.Resources.Queue; q != nil {
+		fmt.Fprintf(&out, ", %d messages queued to send", q.Depth())
+	}
 
 	return []*base.Message{
 		{
@@ -32,7 +32,7 @@ func filesay(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error)
 		return nil, basecommand.ErrBadUsage
 	}
 
-	client := pastebin.NewClient(msg.Resources.Clients.PastebinFetchPasteURLOverride)
+	client := pastebin.NewClient(msg.Resources.Clients.PastebinFetchPasteURLOverride, "")
 	paste, err := client.FetchPaste(pastebinURLArg.StringValue)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch paste %s: %w", pastebinURLArg.StringValue, err)
@@ -0,0 +1,239 @@
+// Package chatlog implements commands that query logged chat messages.
+package chatlog
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/airforce270/airbot/base"
+	"github.com/airforce270/airbot/base/arg"
+	"github.com/airforce270/airbot/commands/basecommand"
+	"github.com/airforce270/airbot/database"
+	"github.com/airforce270/airbot/database/models"
+	"github.com/airforce270/airbot/permission"
+
+	"gorm.io/gorm"
+)
+
+// Commands contains this package's commands.
+var Commands = [...]basecommand.Command{
+	firstMessageCommand,
+	lastSeenCommand,
+	messageCountCommand,
+	randomLineCommand,
+	searchCommand,
+}
+
+var (
+	firstMessageCommand = basecommand.Command{
+		Name:    "firstmessage",
+		Aliases: []string{"fm"},
+		Desc:    "Shows the first logged message from a user, optionally in a specific channel.",
+		Params: []arg.Param{
+			{Name: "user", Type: arg.Username, Required: true},
+			{Name: "channel", Type: arg.Username, Required: false},
+		},
+		Permission:   permission.Normal,
+		UserCooldown: 5 * time.Second,
+		Handler:      firstMessage,
+	}
+
+	lastSeenCommand = basecommand.Command{
+		Name:         "lastseen",
+		Desc:         "Shows when and where a user last sent a message.",
+		Params:       []arg.Param{{Name: "user", Type: arg.Username, Required: true}},
+		Permission:   permission.Normal,
+		UserCooldown: 5 * time.Second,
+		Handler:      lastSeen,
+	}
+
+	messageCountCommand = basecommand.Command{
+		Name:    "messagecount",
+		Aliases: []string{"mc"},
+		Desc:    "Shows how many messages a user has sent, optionally in a specific channel.",
+		Params: []arg.Param{
+			{Name: "user", Type: arg.Username, Required: true},
+			{Name: "channel", Type: arg.Username, Required: false},
+		},
+		Permission:   permission.Normal,
+		UserCooldown: 5 * time.Second,
+		Handler:      messageCount,
+	}
+
+	randomLineCommand = basecommand.Command{
+		Name:    "randomline",
+		Aliases: []string{"rl"},
+		Desc:    "Shows a random logged message from a user, optionally in a specific channel.",
+		Params: []arg.Param{
+			{Name: "user", Type: arg.Username, Required: true},
+			{Name: "channel", Type: arg.Username, Required: false},
+		},
+		Permission:   permission.Normal,
+		UserCooldown: 5 * time.Second,
+		Handler:      randomLine,
+	}
+
+	searchCommand = basecommand.Command{
+		Name:            "search",
+		Desc:            "Searches this channel's logged messages for text.",
+		Params:          []arg.Param{{Name: "text", Type: arg.Variadic, Required: true}},
+		Permission:      permission.Mod,
+		ChannelCooldown: 5 * time.Second,
+		Handler:         search,
+	}
+)
+
+// messageTimeFormat is the format times of messages are shown in.
+const messageTimeFormat = "2006-01-02 15:04:05 MST"
+
+func firstMessage(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
+	return userMessagesCommand(msg, args, func(messages *gorm.DB, target, where string) (string, error) {
+		var first models.Message
+		err := messages.Order("time ASC").Limit(1).Find(&first).Error
+		if err != nil || first.ID == 0 {
+			return "", err
+		}
+		return fmt.Sprintf("%s's first logged message was in #%s at %s: %s", target, first.Channel, formatTime(first.Time), first.Text), nil
+	})
+}
+
+func lastSeen(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
+	return userMessagesCommand(msg, args, func(messages *gorm.DB, target, where string) (string, error) {
+		var last models.Message
+		err := messages.Order("time DESC").Limit(1).Find(&last).Error
+		if err != nil || last.ID == 0 {
+			return "", err
+		}
+		return fmt.Sprintf("%s was last seen in #%s at %s: %s", target, last.Channel, formatTime(last.Time), last.Text), nil
+	})
+}
+
+func messageCount(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
+	return userMessagesCommand(msg, args, func(messages *gorm.DB, target, where string) (string, error) {
+		var count int64
+		if err := messages.Count(&count).Error; err != nil || count == 0 {
+			return "", err
+		}
+		return fmt.Sprintf("%s has sent %d %s%s", target, count, pluralMessages(count), where), nil
+	})
+}
+
+func randomLine(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
+	return userMessagesCommand(msg, args, func(messages *gorm.DB, target, where string) (string, error) {
+		var count int64
+		if err := messages.Count(&count).Error; err != nil || count == 0 {
+			return "", err
+		}
+		offset, err := rand.Int(msg.Resources.Rand.Reader, big.NewInt(count))
+		if err != nil {
+			return "", fmt.Errorf("failed to pick random message: %w", err)
+		}
+
+		var line models.Message
+		if err := messages.Order("id").Offset(int(offset.Int64())).Limit(1).Find(&line).Error; err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("[%s] #%s %s: %s", formatTime(line.Time), line.Channel, target, line.Text), nil
+	})
+}
+
+func search(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
+	textArg := args[0]
+	if !textArg.Present || strings.TrimSpace(textArg.StringValue) == "" {
+		return nil, basecommand.ErrBadUsage
+	}
+	text := strings.TrimSpace(textArg.StringValue)
+
+	matches, total, err := database.SearchMessages(msg.Resources.DB, msg.Message.Channel, text, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	if total == 0 {
+		return []*base.Message{
+			{
+				Channel: msg.Message.Channel,
+				Text:    fmt.Sprintf("No messages in #%s match %q", msg.Message.Channel, text),
+			},
+		}, nil
+	}
+
+	latest := matches[0]
+	return []*base.Message{
+		{
+			Channel: msg.Message.Channel,
+			Text:    fmt.Sprintf("Found %d %s matching %q, most recent: [%s] %s: %s", total, pluralMessages(total), text, formatTime(latest.Time), latest.User.TwitchName, latest.Text),
+		},
+	}, nil
+}
+
+// userMessagesQuery answers a query about a user's messages.
+// messages is a query for the user's messages,
+// target is the user's name,
+// and where describes the channel the messages are limited to, if any, i.e. " in #channel".
+// If the user has no matching messages, it should return an empty string.
+type userMessagesQuery func(messages *gorm.DB, target, where string) (string, error)
+
+// userMessagesCommand runs a query on the messages of the user in the first arg,
+// limited to the channel in the second arg if present.
+func userMessagesCommand(msg *base.IncomingMessage, args []arg.Arg, query userMessagesQuery) ([]*base.Message, error) {
+	targetArg := args[0]
+	if !targetArg.Present {
+		return nil, basecommand.ErrBadUsage
+	}
+	target := targetArg.StringValue
+
+	user, err := msg.Resources.Platform.User(target)
+	if err != nil {
+		if errors.Is(err, base.ErrUserUnknown) {
+			return []*base.Message{
+				{
+					Channel: msg.Message.Channel,
+					Text:    fmt.Sprintf("%s has never been seen by %s", target, msg.Resources.Platform.Username()),
+				},
+			}, nil
+		}
+		return nil, fmt.Errorf("failed to look up user %s: %w", target, err)
+	}
+
+	messages := msg.Resources.DB.Model(&models.Message{}).
+		Where("user_id = ?", user.ID).
+		// Whispers are private, so they're never shown.
+		Where("channel NOT LIKE ?", "whisper-%")
+	var where string
+	if len(args) > 1 && args[1].Present {
+		channel := strings.ToLower(args[1].StringValue)
+		messages = messages.Where("channel = ?", channel)
+		where = " in #" + channel
+	}
+
+	text, err := query(messages.Session(&gorm.Session{}), target, where)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query messages for %s: %w", target, err)
+	}
+	if text == "" {
+		text = fmt.Sprintf("%s has no logged messages%s", target, where)
+	}
+
+	return []*base.Message{
+		{
+			Channel: msg.Message.Channel,
+			Text:    text,
+		},
+	}, nil
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(messageTimeFormat)
+}
+
+func pluralMessages(n int64) string {
+	if n == 1 {
+		return "message"
+	}
+	return "messages"
+}
@@ -0,0 +1,288 @@
+package chatlog_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/airforce270/airbot/base"
+	"github.com/airforce270/airbot/commands/commandtest"
+	"github.com/airforce270/airbot/database/models"
+	"github.com/airforce270/airbot/permission"
+)
+
+func TestChatLogCommands(t *testing.T) {
+	t.Parallel()
+	tests := []commandtest.Case{
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$lastseen user1",
+					UserID:  "user2",
+					User:    "user2",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			OtherTexts: []string{"$lastseen @user1"},
+			Platform:   commandtest.TwitchPlatform,
+			RunBefore:  []commandtest.SetupFunc{seedMessages},
+			Want: []*base.Message{
+				{
+					Text:    "user1 was last seen in #user3 at 2020-05-15 10:03:00 UTC: brown fox again",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$lastseen user3",
+					UserID:  "user2",
+					User:    "user2",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedMessages},
+			Want: []*base.Message{
+				{
+					Text:    "user3 has no logged messages",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$lastseen rando",
+					UserID:  "user2",
+					User:    "user2",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform: commandtest.TwitchPlatform,
+			Want: []*base.Message{
+				{
+					Text:    "rando has never been seen by fake-username",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$firstmessage user1",
+					UserID:  "user2",
+					User:    "user2",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			OtherTexts: []string{"$fm user1"},
+			Platform:   commandtest.TwitchPlatform,
+			RunBefore:  []commandtest.SetupFunc{seedMessages},
+			Want: []*base.Message{
+				{
+					Text:    "user1's first logged message was in #user2 at 2020-05-15 10:00:00 UTC: the quick brown fox",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$firstmessage user1 user3",
+					UserID:  "user2",
+					User:    "user2",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedMessages},
+			Want: []*base.Message{
+				{
+					Text:    "user1's first logged message was in #user3 at 2020-05-15 10:03:00 UTC: brown fox again",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$messagecount user1",
+					UserID:  "user2",
+					User:    "user2",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			OtherTexts: []string{"$mc user1"},
+			Platform:   commandtest.TwitchPlatform,
+			RunBefore:  []commandtest.SetupFunc{seedMessages},
+			Want: []*base.Message{
+				{
+					Text:    "user1 has sent 3 messages",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$messagecount user1 user3",
+					UserID:  "user2",
+					User:    "user2",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedMessages},
+			Want: []*base.Message{
+				{
+					Text:    "user1 has sent 1 message in #user3",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$messagecount user1 user1",
+					UserID:  "user2",
+					User:    "user2",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedMessages},
+			Want: []*base.Message{
+				{
+					Text:    "user1 has no logged messages in #user1",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$randomline user1 user2",
+					UserID:  "user2",
+					User:    "user2",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			OtherTexts: []string{"$rl user1 user2"},
+			Platform:   commandtest.TwitchPlatform,
+			RunBefore:  []commandtest.SetupFunc{seedMessages},
+			Want: []*base.Message{
+				{
+					Text:    "[2020-05-15 10:01:00 UTC] #user2 user1: a slow brown dog",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$search brown",
+					UserID:  "user2",
+					User:    "user2",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedMessages},
+			Want: []*base.Message{
+				{
+					Text:    `Found 2 messages matching "brown", most recent: [2020-05-15 10:01:00 UTC] user1: a slow brown dog`,
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$search secret",
+					UserID:  "user2",
+					User:    "user2",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedMessages},
+			Want: []*base.Message{
+				{
+					Text:    `No messages in #user2 match "secret"`,
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$search brown",
+					UserID:  "user2",
+					User:    "user2",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform: commandtest.TwitchPlatform,
+			Want:     nil,
+		},
+	}
+
+	commandtest.Run(t, tests)
+}
+
+func seedMessages(t testing.TB, r *base.Resources) {
+	t.Helper()
+	var user1 models.User
+	if err := r.DB.First(&user1, "twitch_name = ?", "user1").Error; err != nil {
+		t.Fatalf("Failed to fetch user1: %v", err)
+	}
+	start := time.Date(2020, 5, 15, 10, 0, 0, 0, time.UTC)
+	messages := []*models.Message{
+		{Text: "the quick brown fox", Channel: "user2", UserID: user1.ID, Time: start},
+		{Text: "a slow brown dog", Channel: "user2", UserID: user1.ID, Time: start.Add(1 * time.Minute)},
+		{Text: "brown fox again", Channel: "user3", UserID: user1.ID, Time: start.Add(3 * time.Minute)},
+		{Text: "secret", Channel: "whisper-fake-username", UserID: user1.ID, Time: start.Add(5 * time.Minute)},
+	}
+	for _, m := range messages {
+		if err := r.DB.Create(m).Error; err != nil {
+			t.Fatalf("Failed to seed message: %v", err)
+		}
+	}
+}
@@ -4,12 +4,15 @@ package commands
 import (
 	"context"
 	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/airforce270/airbot/apiclients/bible"
@@ -20,19 +23,30 @@ import (
 	"github.com/airforce270/airbot/base/arg"
 	"github.com/airforce270/airbot/cache"
 	"github.com/airforce270/airbot/commands/admin"
+	"github.com/airforce270/airbot/commands/audit"
+	"github.com/airforce270/airbot/commands/automod"
 	"github.com/airforce270/airbot/commands/basecommand"
 	"github.com/airforce270/airbot/commands/botinfo"
 	"github.com/airforce270/airbot/commands/bulk"
+	"github.com/airforce270/airbot/commands/chatlog"
+	"github.com/airforce270/airbot/commands/cooldown"
 	"github.com/airforce270/airbot/commands/echo"
 	"github.com/airforce270/airbot/commands/fun"
 	"github.com/airforce270/airbot/commands/gamba"
+	"github.com/airforce270/airbot/commands/hooks"
 	"github.com/airforce270/airbot/commands/kick"
 	"github.com/airforce270/airbot/commands/moderation"
+	"github.com/airforce270/airbot/commands/modlog"
+	"github.com/airforce270/airbot/commands/notify"
+	"github.com/airforce270/airbot/commands/privacy"
 	"github.com/airforce270/airbot/commands/seventv"
 	"github.com/airforce270/airbot/commands/twitch"
 	"github.com/airforce270/airbot/config"
 	"github.com/airforce270/airbot/database/models"
+	"github.com/airforce270/airbot/logging"
+	"github.com/airforce270/airbot/metrics"
 	"github.com/airforce270/airbot/permission"
+	"github.com/airforce270/airbot/platforms/ratelimit"
 
 	"gorm.io/gorm"
 )
@@ -41,12 +55,19 @@ import (
 var CommandGroups = map[string][]basecommand.Command{
 	"7TV":        seventv.Commands[:],
 	"Admin":      admin.Commands[:],
+	"Audit":      audit.Commands[:],
+	"Automod":    automod.Commands[:],
 	"Bot info":   append([]basecommand.Command{helpCommand}, botinfo.Commands[:]...),
 	"Bulk":       bulk.Commands[:],
+	"Chat log":   chatlog.Commands[:],
 	"Fun":        fun.Commands[:],
 	"Gamba":      gamba.Commands[:],
+	"Hooks":      hooks.Commands[:],
 	"Kick":       kick.Commands[:],
+	"Mod log":    modlog.Commands[:],
 	"Moderation": moderation.Commands[:],
+	"Notify":     notify.Commands[:],
+	"Privacy":    privacy.Commands[:],
 	"Echo":       echo.Commands[:],
 	"Twitch":     twitch.Commands[:],
 }
@@ -56,6 +77,10 @@ var (
 	allCommands []basecommand.Command
 	// commandPatterns contains a map of patterns to trigger a command to that command.
 	commandPatterns = map[*regexp.Regexp]basecommand.Command{}
+	// eventHandlers contains the handlers run for every event.
+	eventHandlers = []basecommand.EventHandler{
+		hooks.HandleEvent,
+	}
 )
 
 func init() {
@@ -72,10 +97,15 @@ func init() {
 }
 
 // NewHandler creates a new Handler.
-func NewHandler(ctx context.Context, db *gorm.DB, cdb cache.Cache, cfg *config.Config, allPlatforms map[string]base.Platform) Handler {
+// queue is the queue of messages waiting to be sent on the handler's platform.
+func NewHandler(ctx context.Context, db *gorm.DB, cdb cache.Cache, cfg *config.Config, allPlatforms map[string]base.Platform, queue base.OutgoingQueue, logger *slog.Logger) Handler {
+	memoryCache := cache.NewMemory()
+	audits := newAuditLog(db, logger)
+	go audits.run(ctx)
 	return Handler{
 		db:              db,
 		cache:           cdb,
+		memoryCache:     memoryCache,
 		allPlatforms:    allPlatforms,
 		newConfigSource: config.DefaultNewConfigSource,
 		rand: base.RandResources{
@@ -87,18 +117,41 @@ func NewHandler(ctx context.Context, db *gorm.DB, cdb cache.Cache, cfg *config.C
 			Kick:    kickapi.NewClient(kickapi.DefaultBaseURL, cfg.Platforms.Kick.JA3, cfg.Platforms.Kick.UserAgent),
 			SevenTV: seventvapi.NewClient(ctx, seventvapi.DefaultBaseURL, cfg.SevenTV.AccessToken),
 		},
+		queue:        queue,
+		cooldowns:    cooldown.NewStore(memoryCache),
+		cooldownsCfg: cfg.Cooldowns,
+		automod:      automod.NewChecker(),
+		limiter:      ratelimit.New(),
+		audits:       audits,
+		logger:       logger,
 	}
 }
 
 // NewHandlerForTest creates a new Handler for use in testing.
-func NewHandlerForTest(db *gorm.DB, cdb cache.Cache, allPlatforms map[string]base.Platform, newConfigSource func() (io.ReadCloser, error), randOpts base.RandResources, clients base.APIClients) Handler {
+// Cooldown config is read from newConfigSource, if it contains a valid config.
+func NewHandlerForTest(db *gorm.DB, cdb, memoryCache cache.Cache, allPlatforms map[string]base.Platform, newConfigSource func() (io.ReadCloser, error), randOpts base.RandResources, clients base.APIClients, queue base.OutgoingQueue) Handler {
+	var cooldownsCfg config.CooldownsConfig
+	if configSrc, err := newConfigSource(); err == nil {
+		if cfg, err := config.Read(configSrc); err == nil {
+			cooldownsCfg = cfg.Cooldowns
+		}
+		_ = configSrc.Close() // ignore error
+	}
 	return Handler{
 		db:              db,
 		cache:           cdb,
+		memoryCache:     memoryCache,
 		allPlatforms:    allPlatforms,
 		newConfigSource: newConfigSource,
 		rand:            randOpts,
 		Clients:         clients,
+		queue:           queue,
+		cooldowns:       cooldown.NewStore(memoryCache),
+		cooldownsCfg:    cooldownsCfg,
+		automod:         automod.NewChecker(),
+		limiter:         ratelimit.New(),
+		audits:          newAuditLog(db, logging.Discard()),
+		logger:          logging.Discard(),
 	}
 }
 
@@ -108,6 +161,8 @@ type Handler struct {
 	db *gorm.DB
 	// cache is a reference to the cache.
 	cache cache.Cache
+	// memoryCache is a reference to the in-memory cache.
+	memoryCache cache.Cache
 	// allPlatforms contains all registered and configured platforms.
 	allPlatforms map[string]base.Platform
 	// newConfigSource returns a source for the latest config data.
@@ -117,13 +172,53 @@ type Handler struct {
 	// Clients are API Clients to use.
 	// It is only exported for testing and should not be otherwise used.
 	Clients base.APIClients
+	// queue is the queue of messages waiting to be sent.
+	queue base.OutgoingQueue
+	// cooldowns stores command cooldowns.
+	cooldowns *cooldown.Store
+	// cooldownsCfg is the config for cooldowns and rate limits.
+	cooldownsCfg config.CooldownsConfig
+	// automod checks messages against automod rules.
+	automod *automod.Checker
+	// limiter limits how often users may run commands
+	// and how often the bot replies that a command is on cooldown.
+	limiter *ratelimit.Limiter
+	// audits records attempts to run commands.
+	audits *auditLog
+	// logger is the logger for handling commands.
+	logger *slog.Logger
 }
 
 // Handle handles incoming messages, possibly returning messages to be sent in response.
 func (h *Handler) Handle(msg *base.IncomingMessage) ([]*base.OutgoingMessage, error) {
 	h.setResources(msg)
+	requestLogger := msg.Resources.Logger
+	if msg.Whisper && !strings.HasPrefix(strings.TrimSpace(msg.Message.Text), msg.Prefix) {
+		// Whispers can only be commands, so the prefix is optional.
+		msg.Prefix = ""
+	}
+
+	// The sender is looked up once, the first time a command they tried to run is recorded.
+	userID := sync.OnceValue(func() *uint {
+		if user, err := msg.Resources.Platform.User(msg.Message.User); err == nil {
+			return &user.ID
+		}
+		return nil
+	})
 
 	var outMsgs []*base.OutgoingMessage
+	respMsgs, broke, err := h.automod.Check(msg)
+	if err != nil {
+		requestLogger.Error("Automod check failed", "error", err)
+	}
+	for _, respMsg := range respMsgs {
+		outMsgs = append(outMsgs, &base.OutgoingMessage{Message: *respMsg})
+	}
+	if broke {
+		// Commands in messages breaking automod rules aren't run.
+		return outMsgs, nil
+	}
+
 	for pattern, command := range commandPatterns {
 		if !strings.HasPrefix(strings.TrimSpace(msg.Message.Text), msg.Prefix) {
 			continue
@@ -131,110 +226,322 @@ func (h *Handler) Handle(msg *base.IncomingMessage) ([]*base.OutgoingMessage, er
 		if !pattern.MatchString(msg.MessageTextWithoutPrefix()) {
 			continue
 		}
-		if !permission.Authorized(msg.PermissionLevel, command.Permission) {
-			log.Printf("Permission denied: command %s, user %s, channel %s; has permission %s, required: %s", command.Name, msg.Message.User, msg.Message.Channel, msg.PermissionLevel.Name(), command.Permission.Name())
+		logger := requestLogger.With("command", command.Name)
+		msg.Resources.Logger = logger
+		if msg.Whisper && !command.WhisperSafe {
+			logger.Debug("Skipping command, can't be run from a whisper")
 			continue
 		}
-
-		channelCooldown := models.ChannelCommandCooldown{}
-		shouldSetChannelCooldown := true
-		err := h.db.FirstOrCreate(&channelCooldown, models.ChannelCommandCooldown{
-			Channel: msg.Message.Channel,
-			Command: command.Name,
-		}).Error
-		if err != nil {
-			return nil, fmt.Errorf("[%s] failed to get/create channel cooldown for channel %q, command %q: %w", msg.Resources.Platform.Name(), msg.Message.Channel, command.Name, err)
+		if !permission.Authorized(msg.PermissionLevel, command.Permission) {
+			logger.Info("Permission denied", "has_permission", msg.PermissionLevel.Name(), "required_permission", command.Permission.Name())
+			h.audit(msg, command, pattern, models.CommandOutcomeDenied, 0, "", userID())
+			continue
 		}
-		if command.ChannelCooldown > time.Since(channelCooldown.LastRun) {
-			log.Printf("Skipping %s%s: channel cooldown is %s but it has only been %s", msg.Prefix, command.Name, command.ChannelCooldown, time.Since(channelCooldown.LastRun))
+		if !msg.Whisper && command.WhisperOnly {
+			logger.Debug("Skipping command, can only be run from a whisper")
+			outMsgs = append(outMsgs, reply(msg, command, base.Message{
+				Channel: msg.Message.Channel,
+				Text:    fmt.Sprintf("%s%s can only be used in a whisper, so its response isn't posted in chat", msg.Prefix, command.Name),
+			}))
 			continue
 		}
 
-		user, err := msg.Resources.Platform.User(msg.Message.User)
-		if err != nil && !errors.Is(err, base.ErrUserUnknown) {
-			return nil, fmt.Errorf("failed to fetch user %q: %w", msg.Message.User, err)
+		exempt := h.cooldownExempt(msg)
+
+		platformName := msg.Resources.Platform.Name()
+		channelCooldownKey := cache.ChannelCooldownKey(platformName, msg.Message.Channel, command.Name)
+		userCooldownKey := cache.UserCooldownKey(platformName, userKey(msg), command.Name)
+		cooldownKeys := []string{channelCooldownKey, userCooldownKey}
+		// Whispers have no channel, so only the user cooldown applies.
+		shouldSetChannelCooldown := !msg.Whisper
+		if msg.Whisper {
+			cooldownKeys = []string{userCooldownKey}
 		}
-		userCooldown := models.UserCommandCooldown{}
-		shouldSetUserCooldown := false
-		if err == nil || errors.Is(err, base.ErrUserUnknown) {
-			shouldSetUserCooldown = true
-			err := h.db.FirstOrCreate(&userCooldown, models.UserCommandCooldown{
-				UserID:  user.ID,
-				User:    user,
-				Command: command.Name,
-			}).Error
+		shouldSetUserCooldown := true
+		if !exempt {
+			remaining, err := h.cooldownRemaining(cooldownKeys...)
 			if err != nil {
-				return nil, fmt.Errorf("[%s] failed to get/create user cooldown for user %q, command %q, %w", msg.Resources.Platform.Name(), msg.Message.User, command.Name, err)
+				return nil, fmt.Errorf("[%s] failed to check cooldowns for command %q: %w", platformName, command.Name, err)
 			}
-			if command.UserCooldown > time.Since(userCooldown.LastRun) {
-				log.Printf("Skipping %s%s: user cooldown is %s but it has only been %s", msg.Prefix, command.Name, command.UserCooldown, time.Since(userCooldown.LastRun))
+			if remaining > 0 {
+				logger.Debug("Skipping command, cooldown active", "remaining", remaining)
+				h.audit(msg, command, pattern, models.CommandOutcomeCooldown, 0, "", userID())
+				if reply := h.cooldownReply(msg, command, remaining); reply != nil {
+					outMsgs = append(outMsgs, reply)
+				}
+				continue
+			}
+			// Only commands that will run use up the user's rate limit.
+			if delay := h.limiter.Reserve(h.userRateLimitRules(msg)); delay > 0 {
+				logger.Info("Skipping command, user rate limit exceeded", "retry_in", delay)
+				h.audit(msg, command, pattern, models.CommandOutcomeRateLimited, 0, "", userID())
 				continue
 			}
 		}
 
 		args := parseArgs(msg, command, pattern)
 
+		start := time.Now()
 		respMsgs, err := command.Handler(msg, args)
-		if err != nil {
-			if !errors.Is(err, basecommand.ErrBadUsage) {
-				return nil, fmt.Errorf("failed to handle message: %w", err)
+		duration := time.Since(start)
+		metrics.CommandInvocations.WithLabelValues(command.Name).Inc()
+		metrics.CommandLatency.WithLabelValues(command.Name).Observe(duration.Seconds())
+		switch {
+		case err == nil:
+			h.audit(msg, command, pattern, models.CommandOutcomeOK, duration, "", userID())
+			for _, respMsg := range respMsgs {
+				outMsgs = append(outMsgs, reply(msg, command, *respMsg))
 			}
+		case errors.Is(err, basecommand.ErrBadUsage):
+			h.audit(msg, command, pattern, models.CommandOutcomeBadUsage, duration, "", userID())
 			shouldSetChannelCooldown = false
-			outMsg := &base.OutgoingMessage{
-				Message: base.Message{
-					Channel: msg.Message.Channel,
-					Text:    "Usage: " + command.Usage(msg.Prefix),
-				},
-			}
-			if !command.DisableReplies {
-				outMsg.ReplyToID = msg.Message.ID
-			}
-			outMsgs = append(outMsgs, outMsg)
-		} else {
-			for _, respMsg := range respMsgs {
-				outMsg := &base.OutgoingMessage{Message: *respMsg}
-				if !command.DisableReplies && respMsg.Channel == msg.Message.Channel {
-					outMsg.ReplyToID = msg.Message.ID
-				}
-				outMsgs = append(outMsgs, outMsg)
+			outMsgs = append(outMsgs, reply(msg, command, base.Message{
+				Channel: msg.Message.Channel,
+				Text:    "Usage: " + command.Usage(msg.Prefix),
+			}))
+		default:
+			errorID := h.newErrorID()
+			var cmdErr *basecommand.Error
+			retryable := errors.As(err, &cmdErr) && cmdErr.Retryable
+			metrics.CommandErrors.WithLabelValues(command.Name, strconv.FormatBool(retryable)).Inc()
+			logger.Error("Command failed", "error_id", errorID, "retryable", retryable, "text", msg.Message.Text, "error", err)
+			h.audit(msg, command, pattern, models.CommandOutcomeError, duration, errorID, userID())
+			if retryable {
+				// Let the user try again right away.
+				shouldSetChannelCooldown = false
+				shouldSetUserCooldown = false
 			}
+			outMsgs = append(outMsgs, reply(msg, command, base.Message{
+				Channel: msg.Message.Channel,
+				Text:    errorReply(err, errorID),
+			}))
 		}
 
 		if shouldSetChannelCooldown {
-			channelCooldown.LastRun = time.Now()
-			if err := h.db.Save(&channelCooldown).Error; err != nil {
-				log.Printf("failed to save new channel cooldown: %v", err)
+			if err := h.cooldowns.Start(channelCooldownKey, command.ChannelCooldown); err != nil {
+				logger.Error("Failed to start channel cooldown", "error", err)
 			}
 		}
 		if shouldSetUserCooldown {
-			userCooldown.LastRun = time.Now()
-			if err := h.db.Save(&userCooldown).Error; err != nil {
-				log.Printf("failed to save new user cooldown: %v", err)
+			if err := h.cooldowns.Start(userCooldownKey, command.UserCooldown); err != nil {
+				logger.Error("Failed to start user cooldown", "error", err)
 			}
 		}
 	}
 	return outMsgs, nil
 }
 
+// HandleEvent handles incoming events, possibly returning messages to be sent in response.
+func (h *Handler) HandleEvent(event *base.IncomingEvent) ([]*base.OutgoingMessage, error) {
+	// event.Resources.Platform is set before the event reaches here.
+	event.Resources = h.resources(event.Resources.Platform)
+	event.Resources.Logger = h.logger.With(
+		"platform", event.Resources.Platform.Name(),
+		"channel", event.Event.Channel,
+		"event", event.Event.Kind,
+	)
+
+	var outMsgs []*base.OutgoingMessage
+	var errs []error
+	for _, handle := range eventHandlers {
+		respMsgs, err := handle(event)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, respMsg := range respMsgs {
+			outMsgs = append(outMsgs, &base.OutgoingMessage{Message: *respMsg})
+		}
+	}
+	return outMsgs, errors.Join(errs...)
+}
+
 func (h *Handler) setResources(msg *base.IncomingMessage) {
-	msg.Resources.DB = h.db
-	msg.Resources.Cache = h.cache
 	// msg.Resources.Platform is set by the platform,
 	// before the message reaches here
-	msg.Resources.AllPlatforms = h.allPlatforms
-	msg.Resources.NewConfigSource = h.newConfigSource
-	msg.Resources.Rand = h.rand
-	msg.Resources.Clients = h.Clients
+	msg.Resources = h.resources(msg.Resources.Platform)
+	msg.Resources.Logger = h.logger.With(
+		"platform", msg.Resources.Platform.Name(),
+		"channel", msg.Message.Channel,
+		"user", msg.Message.User,
+	)
+}
+
+// resources returns the resources available to messages and events on a platform.
+func (h *Handler) resources(platform base.Platform) base.Resources {
+	return base.Resources{
+		Platform:        platform,
+		DB:              h.db,
+		Cache:           h.cache,
+		MemoryCache:     h.memoryCache,
+		AllPlatforms:    h.allPlatforms,
+		NewConfigSource: h.newConfigSource,
+		Rand:            h.rand,
+		Clients:         h.Clients,
+		Queue:           h.queue,
+	}
+}
+
+// cooldownExempt returns whether the sender of a message bypasses cooldowns and rate limits.
+func (h *Handler) cooldownExempt(msg *base.IncomingMessage) bool {
+	exemptLevel := h.cooldownsCfg.ExemptLevel
+	return exemptLevel != 0 && permission.Authorized(msg.PermissionLevel, exemptLevel)
+}
+
+// userRateLimitRules returns the rules limiting how often the sender of a message may run commands.
+func (h *Handler) userRateLimitRules(msg *base.IncomingMessage) []ratelimit.Rule {
+	rateLimit := h.cooldownsCfg.UserRateLimit
+	if rateLimit.Count <= 0 || rateLimit.Per <= 0 {
+		return nil
+	}
+	return []ratelimit.Rule{
+		{
+			Bucket: "user:" + msg.Resources.Platform.Name() + ":" + userKey(msg),
+			Rate:   ratelimit.Rate{Count: rateLimit.Count, Per: time.Duration(rateLimit.Per)},
+		},
+	}
+}
+
+// cooldownRemaining returns how much longer the longest of the cooldowns with the given keys is active,
+// or 0 if none are.
+func (h *Handler) cooldownRemaining(keys ...string) (time.Duration, error) {
+	var longest time.Duration
+	for _, key := range keys {
+		remaining, err := h.cooldowns.Remaining(key)
+		if err != nil {
+			return 0, err
+		}
+		longest = max(longest, remaining)
+	}
+	return longest, nil
+}
+
+var (
+	// cooldownReplyChannelRate limits how often the bot replies that a command is on cooldown in each channel.
+	cooldownReplyChannelRate = ratelimit.Rate{Count: 1, Per: 5 * time.Second}
+	// cooldownReplyUserRate limits how often the bot replies that a command is on cooldown to each user.
+	cooldownReplyUserRate = ratelimit.Rate{Count: 1, Per: 30 * time.Second}
+)
+
+// cooldownReply returns the reply to send when a command is on cooldown,
+// or nil if no reply should be sent.
+func (h *Handler) cooldownReply(msg *base.IncomingMessage, command basecommand.Command, remaining time.Duration) *base.OutgoingMessage {
+	if !h.cooldownsCfg.Reply {
+		return nil
+	}
+	platformName := msg.Resources.Platform.Name()
+	rules := []ratelimit.Rule{
+		{Bucket: "cooldown_reply_user:" + platformName + ":" + userKey(msg), Rate: cooldownReplyUserRate},
+	}
+	if !msg.Whisper {
+		rules = append(rules, ratelimit.Rule{Bucket: "cooldown_reply_channel:" + platformName + ":" + strings.ToLower(msg.Message.Channel), Rate: cooldownReplyChannelRate})
+	}
+	if h.limiter.Reserve(rules) > 0 {
+		return nil
+	}
+
+	// Round up, so users aren't told they can use a command before they actually can.
+	remaining = (remaining + time.Second - 1).Truncate(time.Second)
+	return reply(msg, command, base.Message{
+		Channel: msg.Message.Channel,
+		Text:    fmt.Sprintf("You can use %s%s again in %s", msg.Prefix, command.Name, remaining),
+	})
+}
+
+// reply returns an outgoing message responding to a command.
+// Responses to whispers that aren't sent to a channel are whispered back.
+func reply(msg *base.IncomingMessage, command basecommand.Command, respMsg base.Message) *base.OutgoingMessage {
+	outMsg := &base.OutgoingMessage{Message: respMsg}
+	switch {
+	case msg.Whisper:
+		if respMsg.Channel == "" {
+			outMsg.WhisperToID = msg.Message.UserID
+		}
+	case !command.DisableReplies && respMsg.Channel == msg.Message.Channel:
+		outMsg.ReplyToID = msg.Message.ID
+	}
+	return outMsg
+}
+
+// userKey returns a key identifying the sender of a message.
+func userKey(msg *base.IncomingMessage) string {
+	if msg.Message.UserID != "" {
+		return msg.Message.UserID
+	}
+	return strings.ToLower(msg.Message.User)
+}
+
+// errorIDBytes is the number of random bytes in an error ID.
+const errorIDBytes = 4
+
+// newErrorID returns a new ID to identify a command failure in the logs.
+func (h *Handler) newErrorID() string {
+	b := make([]byte, errorIDBytes)
+	if h.rand.Reader == nil {
+		_, _ = rand.Read(b)
+	} else if _, err := io.ReadFull(h.rand.Reader, b); err != nil {
+		// Fall back to the system's randomness, an ID is better than none.
+		_, _ = rand.Read(b)
+	}
+	return hex.EncodeToString(b)
+}
+
+// errorReply returns the text to reply with when a command fails.
+func errorReply(err error, errorID string) string {
+	text := "Sorry, something went wrong"
+	var cmdErr *basecommand.Error
+	if errors.As(err, &cmdErr) {
+		if cmdErr.UserMessage != "" {
+			text = cmdErr.UserMessage
+		}
+		if cmdErr.Retryable {
+			text += ", please try again later"
+		}
+	}
+	return fmt.Sprintf("%s (error ID: %s)", text, errorID)
+}
+
+// audit records an attempt to run a command.
+// errorID is the ID of the error, if the command failed.
+// userID is the ID of the user that tried to run it, if they're known.
+func (h *Handler) audit(msg *base.IncomingMessage, command basecommand.Command, pattern *regexp.Regexp, outcome string, duration time.Duration, errorID string, userID *uint) {
+	invocation := models.CommandInvocation{
+		Platform:        msg.Resources.Platform.Name(),
+		Channel:         strings.ToLower(msg.Message.Channel),
+		UserID:          userID,
+		Username:        msg.Message.User,
+		Command:         command.Name,
+		Args:            rawArgs(msg, pattern),
+		PermissionLevel: msg.PermissionLevel.Name(),
+		Outcome:         outcome,
+		Duration:        duration,
+		ErrorID:         errorID,
+		Time:            msg.Message.Time,
+	}
+	if invocation.Time.IsZero() {
+		invocation.Time = time.Now()
+	}
+	h.audits.add(invocation)
+}
+
+// FlushAudits writes records of commands run that haven't been written yet.
+// Records are otherwise written periodically, so this should be called
+// once messages have stopped being handled.
+func (h *Handler) FlushAudits() { h.audits.flush() }
+
+// rawArgs returns the unparsed args from the given message.
+func rawArgs(msg *base.IncomingMessage, pattern *regexp.Regexp) string {
+	return strings.TrimSpace(strings.Join(pattern.FindStringSubmatch(msg.MessageTextWithoutPrefix())[1:], " "))
 }
 
 // parseArgs parses all args from the given message.
 func parseArgs(msg *base.IncomingMessage, cmd basecommand.Command, pattern *regexp.Regexp) []arg.Arg {
 	var parsed []arg.Arg
-	rest := strings.TrimSpace(strings.Join(pattern.FindStringSubmatch(msg.MessageTextWithoutPrefix())[1:], " "))
+	rest := rawArgs(msg, pattern)
 
 	for _, a := range cmd.Params {
 		var value arg.Arg
-		value, rest = a.Parse(rest)
+		value, rest = a.Parse(rest, msg.Resources.Logger)
 		parsed = append(parsed, value)
 	}
 
@@ -243,10 +550,11 @@ func parseArgs(msg *base.IncomingMessage, cmd basecommand.Command, pattern *rege
 
 var (
 	helpCommand = basecommand.Command{
-		Name:    "help",
-		Desc:    "Displays help for a command.",
-		Params:  []arg.Param{{Name: "command", Type: arg.String, Required: false}},
-		Handler: help,
+		Name:        "help",
+		Desc:        "Displays help for a command.",
+		Params:      []arg.Param{{Name: "command", Type: arg.String, Required: false}},
+		WhisperSafe: true,
+		Handler:     help,
 	}
 )
 
@@ -0,0 +1,274 @@
+package commands_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/airforce270/airbot/base"
+	"github.com/airforce270/airbot/cache"
+	"github.com/airforce270/airbot/cache/cachetest"
+	"github.com/airforce270/airbot/commands"
+	"github.com/airforce270/airbot/database/databasetest"
+	"github.com/airforce270/airbot/database/models"
+	"github.com/airforce270/airbot/permission"
+	"github.com/airforce270/airbot/platforms/twitch"
+	"github.com/airforce270/airbot/testing/fakeserver"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestHandle_Cooldowns(t *testing.T) {
+	t.Parallel()
+	type run struct {
+		text       string
+		permission permission.Level
+		want       []string
+	}
+	tests := []struct {
+		desc       string
+		configData string
+		runs       []run
+	}{
+		{
+			desc: "cooldown",
+			runs: []run{
+				{text: "$commandstats", permission: permission.Normal, want: []string{"No commands have been run yet"}},
+				{text: "$commandstats", permission: permission.Normal, want: nil},
+			},
+		},
+		{
+			desc:       "cooldown reply",
+			configData: "[cooldowns]\nreply = true",
+			runs: []run{
+				{text: "$commandstats", permission: permission.Normal, want: []string{"No commands have been run yet"}},
+				{text: "$commandstats", permission: permission.Normal, want: []string{"You can use $commandstats again in 5s"}},
+				// Cooldown replies are rate-limited.
+				{text: "$commandstats", permission: permission.Normal, want: nil},
+			},
+		},
+		{
+			desc:       "exempt from cooldown",
+			configData: "[cooldowns]\nexempt_level = \"Mod\"",
+			runs: []run{
+				{text: "$commandstats", permission: permission.Mod, want: []string{"No commands have been run yet"}},
+				{text: "$commandstats", permission: permission.Mod, want: []string{"Most-run commands: $commandstats (1)"}},
+				{text: "$commandstats", permission: permission.Normal, want: nil},
+			},
+		},
+		{
+			desc:       "user rate limit",
+			configData: "[cooldowns.user_rate_limit]\ncount = 2\nper = \"1m\"",
+			runs: []run{
+				{text: "$prefix", permission: permission.Normal, want: []string{"This channel's prefix is $"}},
+				{text: "$prefix", permission: permission.Normal, want: []string{"This channel's prefix is $"}},
+				{text: "$prefix", permission: permission.Normal, want: nil},
+			},
+		},
+		{
+			desc:       "commands on cooldown don't use up the user rate limit",
+			configData: "[cooldowns.user_rate_limit]\ncount = 2\nper = \"1m\"",
+			runs: []run{
+				{text: "$commandstats", permission: permission.Normal, want: []string{"No commands have been run yet"}},
+				{text: "$commandstats", permission: permission.Normal, want: nil},
+				{text: "$prefix", permission: permission.Normal, want: []string{"This channel's prefix is $"}},
+				{text: "$prefix", permission: permission.Normal, want: nil},
+			},
+		},
+		{
+			desc:       "exempt from user rate limit",
+			configData: "[cooldowns]\nexempt_level = \"VIP\"\n[cooldowns.user_rate_limit]\ncount = 1\nper = \"1m\"",
+			runs: []run{
+				{text: "$prefix", permission: permission.VIP, want: []string{"This channel's prefix is $"}},
+				{text: "$prefix", permission: permission.VIP, want: []string{"This channel's prefix is $"}},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			server := fakeserver.New()
+			defer server.Close()
+			db := databasetest.New(t)
+			cdb := cachetest.NewDB(t, db)
+			platform := twitch.NewForTesting(t, server.URL(t).String(), db)
+
+			resources := base.Resources{
+				Platform:     platform,
+				DB:           db,
+				Cache:        cdb,
+				AllPlatforms: map[string]base.Platform{platform.Name(): platform},
+				NewConfigSource: func() (io.ReadCloser, error) {
+					return io.NopCloser(strings.NewReader(tc.configData)), nil
+				},
+			}
+			handler := commands.NewHandlerForTest(db, cdb, cache.NewMemory(), resources.AllPlatforms, resources.NewConfigSource, resources.Rand, resources.Clients, resources.Queue)
+
+			for i, r := range tc.runs {
+				got, err := handler.Handle(&base.IncomingMessage{
+					Message: base.Message{
+						Text:    r.text,
+						UserID:  "user1",
+						User:    "user1",
+						Channel: "user2",
+						Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+					},
+					Prefix:          "$",
+					PermissionLevel: r.permission,
+					Resources:       resources,
+				})
+				if err != nil {
+					t.Fatalf("[run %d] Handle(%q) unexpected error: %v", i, r.text, err)
+				}
+				var gotTexts []string
+				for _, msg := range got {
+					gotTexts = append(gotTexts, msg.Text)
+				}
+				if diff := cmp.Diff(r.want, gotTexts); diff != "" {
+					t.Errorf("[run %d] Handle(%q) diff (-want +got):\n%s", i, r.text, diff)
+				}
+				handler.FlushAudits()
+			}
+		})
+	}
+}
+
+func TestHandle_Whispers(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		text string
+		want []*base.OutgoingMessage
+	}{
+		{
+			text: "notifylist",
+			want: []*base.OutgoingMessage{
+				{Message: base.Message{Text: "You aren't notified about any streams"}, WhisperToID: "user1"},
+			},
+		},
+		{
+			text: "$notifylist",
+			want: []*base.OutgoingMessage{
+				{Message: base.Message{Text: "You aren't notified about any streams"}, WhisperToID: "user1"},
+			},
+		},
+		{
+			text: "notifyme",
+			want: []*base.OutgoingMessage{
+				{Message: base.Message{Text: "Usage: notifyme <[kick:]channel> [whisper]"}, WhisperToID: "user1"},
+			},
+		},
+		{
+			// Not whisper-safe.
+			text: "prefix",
+			want: nil,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.text, func(t *testing.T) {
+			t.Parallel()
+			server := fakeserver.New()
+			defer server.Close()
+			db := databasetest.New(t)
+			cdb := cachetest.NewDB(t, db)
+			platform := twitch.NewForTesting(t, server.URL(t).String(), db)
+
+			resources := base.Resources{
+				Platform:     platform,
+				DB:           db,
+				Cache:        cdb,
+				AllPlatforms: map[string]base.Platform{platform.Name(): platform},
+				NewConfigSource: func() (io.ReadCloser, error) {
+					return io.NopCloser(strings.NewReader("")), nil
+				},
+			}
+			handler := commands.NewHandlerForTest(db, cdb, cache.NewMemory(), resources.AllPlatforms, resources.NewConfigSource, resources.Rand, resources.Clients, resources.Queue)
+
+			got, err := handler.Handle(&base.IncomingMessage{
+				Message: base.Message{
+					Text:   tc.text,
+					ID:     "whisper-id",
+					UserID: "user1",
+					User:   "user1",
+					Time:   time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+				Whisper:         true,
+				Resources:       resources,
+			})
+			if err != nil {
+				t.Fatalf("Handle(%q) unexpected error: %v", tc.text, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Handle(%q) diff (-want +got):\n%s", tc.text, diff)
+			}
+		})
+	}
+}
+
+func TestHandle_Audit(t *testing.T) {
+	t.Parallel()
+	server := fakeserver.New()
+	defer server.Close()
+	db := databasetest.New(t)
+	cdb := cachetest.NewDB(t, db)
+	platform := twitch.NewForTesting(t, server.URL(t).String(), db)
+
+	resources := base.Resources{
+		Platform:     platform,
+		DB:           db,
+		Cache:        cdb,
+		AllPlatforms: map[string]base.Platform{platform.Name(): platform},
+		NewConfigSource: func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader("")), nil
+		},
+	}
+	handler := commands.NewHandlerForTest(db, cdb, cache.NewMemory(), resources.AllPlatforms, resources.NewConfigSource, resources.Rand, resources.Clients, resources.Queue)
+
+	for _, text := range []string{"$notifylist", "$notifyme"} {
+		_, err := handler.Handle(&base.IncomingMessage{
+			Message: base.Message{
+				Text:    text,
+				Channel: "user1",
+				UserID:  "user1",
+				User:    "user1",
+				Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+			},
+			Prefix:          "$",
+			PermissionLevel: permission.Normal,
+			Resources:       resources,
+		})
+		if err != nil {
+			t.Fatalf("Handle(%q) unexpected error: %v", text, err)
+		}
+	}
+
+	var got []models.CommandInvocation
+	if err := db.Order("id").Find(&got).Error; err != nil {
+		t.Fatalf("Failed to find command invocations: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("command invocations recorded before flushing = %d, want 0", len(got))
+	}
+
+	handler.FlushAudits()
+
+	var user models.User
+	if err := db.Where(models.User{TwitchID: "user1"}).First(&user).Error; err != nil {
+		t.Fatalf("Failed to find user1: %v", err)
+	}
+	if err := db.Order("id").Find(&got).Error; err != nil {
+		t.Fatalf("Failed to find command invocations: %v", err)
+	}
+	want := []models.CommandInvocation{
+		{Platform: "Twitch", Channel: "user1", UserID: &user.ID, Username: "user1", Command: "notifylist", PermissionLevel: "Normal", Outcome: models.CommandOutcomeOK},
+		{Platform: "Twitch", Channel: "user1", UserID: &user.ID, Username: "user1", Command: "notifyme", PermissionLevel: "Normal", Outcome: models.CommandOutcomeBadUsage},
+	}
+	ignore := cmpopts.IgnoreFields(models.CommandInvocation{}, "Model", "Duration", "Time")
+	if diff := cmp.Diff(want, got, ignore); diff != "" {
+		t.Errorf("command invocations diff (-want +got):\n%s", diff)
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/airforce270/airbot/apiclients/kick"
 	"github.com/airforce270/airbot/apiclients/seventv"
 	"github.com/airforce270/airbot/base"
+	"github.com/airforce270/airbot/cache"
 	"github.com/airforce270/airbot/cache/cachetest"
 	"github.com/airforce270/airbot/commands"
 	"github.com/airforce270/airbot/database/databasetest"
@@ -33,6 +34,9 @@ type Case struct {
 	RunBefore  []SetupFunc
 	RunAfter   []TeardownFunc
 	Want       []*base.Message
+	// WantOutgoing overrides Want, for responses that are sent
+	// other than to a channel, i.e. whispers.
+	WantOutgoing []*base.OutgoingMessage
 }
 
 func Run(t *testing.T, tests []Case) {
@@ -49,7 +53,7 @@ func Run(t *testing.T, tests []Case) {
 			server.Resps = tc.apiResps
 
 			db := databasetest.New(t)
-			cdb := cachetest.NewSQLite(t, db)
+			cdb := cachetest.NewDB(t, db)
 
 			var platform base.Platform
 			switch tc.platform {
@@ -60,9 +64,10 @@ func Run(t *testing.T, tests []Case) {
 			}
 
 			resources := base.Resources{
-				Platform: platform,
-				DB:       db,
-				Cache:    cdb,
+				Platform:    platform,
+				DB:          db,
+				Cache:       cdb,
+				MemoryCache: cache.NewMemory(),
 				AllPlatforms: map[string]base.Platform{
 					platform.Name(): platform,
 				},
@@ -74,11 +79,12 @@ func Run(t *testing.T, tests []Case) {
 					Source: fakeExpRandSource{Value: uint64(150)},
 				},
 				Clients: base.APIClients{
-					Bible:                         bible.NewClient(server.URL(t).String()),
-					IVR:                           ivr.NewClient(server.URL(t).String()),
-					Kick:                          kick.NewClient(server.URL(t).String(), "" /* ja3 */, "" /* userAgent */),
-					PastebinFetchPasteURLOverride: server.URL(t).String(),
-					SevenTV:                       seventv.NewClient(ctx, *server.URL(t), "" /* accessToken */),
+					Bible:                          bible.NewClient(server.URL(t).String()),
+					IVR:                            ivr.NewClient(server.URL(t).String()),
+					Kick:                           kick.NewClient(server.URL(t).String(), "" /* ja3 */, "" /* userAgent */),
+					PastebinFetchPasteURLOverride:  server.URL(t).String(),
+					PastebinCreatePasteURLOverride: server.URL(t).String(),
+					SevenTV:                        seventv.NewClient(ctx, *server.URL(t), "" /* accessToken */),
 				},
 			}
 
@@ -88,7 +94,7 @@ func Run(t *testing.T, tests []Case) {
 
 			tc.input.Resources = resources
 
-			handler := commands.NewHandlerForTest(db, cdb, resources.AllPlatforms, resources.NewConfigSource, resources.Rand, resources.Clients)
+			handler := commands.NewHandlerForTest(db, cdb, resources.MemoryCache, resources.AllPlatforms, resources.NewConfigSource, resources.Rand, resources.Clients, resources.Queue)
 			got, err := handler.Handle(&tc.input)
 			if err != nil {
 				t.Fatalf("unexpected error: %v", err)
@@ -152,6 +158,9 @@ func buildTestCases(tcs []Case) []builtCase {
 			for _, want := range tc.Want {
 				builtCase.want = append(builtCase.want, &base.OutgoingMessage{Message: *want})
 			}
+			if tc.WantOutgoing != nil {
+				builtCase.want = tc.WantOutgoing
+			}
 			builtCases = append(builtCases, builtCase)
 		}
 	}
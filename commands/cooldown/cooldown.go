@@ -0,0 +1,52 @@
+// Package cooldown tracks command cooldowns.
+package cooldown
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/airforce270/airbot/cache"
+)
+
+// Store stores cooldowns in a cache.
+// Cooldowns expire from the cache on their own once they end.
+type Store struct {
+	// cache is where cooldowns are stored.
+	cache cache.Cache
+	// now returns the current time. Overridden in test.
+	now func() time.Time
+}
+
+// NewStore creates a new Store.
+func NewStore(c cache.Cache) *Store {
+	return &Store{cache: c, now: time.Now}
+}
+
+// Remaining returns how much longer the cooldown with a key is active,
+// or 0 if it isn't.
+func (s *Store) Remaining(key string) (time.Duration, error) {
+	value, err := s.cache.FetchString(key)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch cooldown %s: %w", key, err)
+	}
+	if value == "" {
+		return 0, nil
+	}
+	end, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse cooldown %s end time %q: %w", key, value, err)
+	}
+	return max(end.Sub(s.now()), 0), nil
+}
+
+// Start starts a cooldown with a key, replacing any active cooldown with the same key.
+func (s *Store) Start(key string, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	end := s.now().Add(d)
+	if err := s.cache.StoreExpiringString(key, end.Format(time.RFC3339Nano), d); err != nil {
+		return fmt.Errorf("failed to store cooldown %s: %w", key, err)
+	}
+	return nil
+}
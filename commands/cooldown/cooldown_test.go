@@ -0,0 +1,45 @@
+package cooldown
+
+import (
+	"testing"
+	"time"
+
+	"github.com/airforce270/airbot/cache/cachetest"
+	"github.com/airforce270/airbot/database/databasetest"
+)
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC)
+	s := NewStore(cachetest.NewDB(t, databasetest.New(t)))
+	s.now = func() time.Time { return now }
+
+	steps := []struct {
+		desc    string
+		start   time.Duration
+		advance time.Duration
+		want    time.Duration
+	}{
+		{desc: "no cooldown", want: 0},
+		{desc: "cooldown started", start: 5 * time.Second, want: 5 * time.Second},
+		{desc: "cooldown partially elapsed", advance: 3 * time.Second, want: 2 * time.Second},
+		{desc: "cooldown elapsed", advance: 3 * time.Second, want: 0},
+		{desc: "cooldown restarted", start: 10 * time.Second, want: 10 * time.Second},
+		{desc: "zero cooldown doesn't replace active cooldown", start: 0, advance: time.Second, want: 9 * time.Second},
+	}
+
+	for _, step := range steps {
+		if err := s.Start("key", step.start); err != nil {
+			t.Fatalf("[%s] Start() unexpected error: %v", step.desc, err)
+		}
+		now = now.Add(step.advance)
+
+		got, err := s.Remaining("key")
+		if err != nil {
+			t.Fatalf("[%s] Remaining() unexpected error: %v", step.desc, err)
+		}
+		if got != step.want {
+			t.Errorf("[%s] Remaining() = %s, want %s", step.desc, got, step.want)
+		}
+	}
+}
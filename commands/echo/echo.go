@@ -15,9 +15,10 @@ import (
 // Commands contains this package's commands.
 var Commands = [...]basecommand.Command{
 	{
-		Name:       "commands",
-		Desc:       "Replies with a link to the commands.",
-		Permission: permission.Normal,
+		Name:        "commands",
+		Desc:        "Replies with a link to the commands.",
+		Permission:  permission.Normal,
+		WhisperSafe: true,
 		Handler: func(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
 			return []*base.Message{
 				{
@@ -5,7 +5,6 @@ import (
 	"crypto/rand"
 	_ "embed"
 	"fmt"
-	"log"
 	"math"
 	"math/big"
 	"strings"
@@ -36,8 +35,9 @@ var (
 			{Name: "book", Type: arg.String, Required: true},
 			{Name: "chapter:verse", Type: arg.String, Required: true},
 		},
-		Permission: permission.Normal,
-		Handler:    bibleVerse,
+		Permission:  permission.Normal,
+		WhisperSafe: true,
+		Handler:     bibleVerse,
 	}
 
 	cockCommand = basecommand.Command{
@@ -56,9 +56,10 @@ var (
 	fortunesLen = big.NewInt(int64(len(fortunes)))
 
 	fortuneCommand = basecommand.Command{
-		Name:       "fortune",
-		Desc:       "Replies with a fortune. Fortunes from https://github.com/bmc/fortunes",
-		Permission: permission.Normal,
+		Name:        "fortune",
+		Desc:        "Replies with a fortune. Fortunes from https://github.com/bmc/fortunes",
+		Permission:  permission.Normal,
+		WhisperSafe: true,
 		Handler: func(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
 			i, err := rand.Int(msg.Resources.Rand.Reader, fortunesLen)
 			if err != nil {
@@ -74,11 +75,12 @@ var (
 	}
 
 	iqCommand = basecommand.Command{
-		Name:       "iq",
-		Desc:       "Tells you someone's IQ",
-		Params:     []arg.Param{{Name: "user", Type: arg.Username, Required: false}},
-		Permission: permission.Normal,
-		Handler:    iq,
+		Name:        "iq",
+		Desc:        "Tells you someone's IQ",
+		Params:      []arg.Param{{Name: "user", Type: arg.Username, Required: false}},
+		Permission:  permission.Normal,
+		WhisperSafe: true,
+		Handler:     iq,
 	}
 
 	shipCommand = basecommand.Command{
@@ -88,8 +90,9 @@ var (
 			{Name: "first-person", Type: arg.Username, Required: true},
 			{Name: "second-person", Type: arg.Username, Required: true},
 		},
-		Permission: permission.Normal,
-		Handler:    ship,
+		Permission:  permission.Normal,
+		WhisperSafe: true,
+		Handler:     ship,
 	}
 )
 
@@ -102,8 +105,7 @@ func bibleVerse(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, err
 
 	verses, err := msg.Resources.Clients.Bible.FetchVerses(book + " " + chapterVerse)
 	if err != nil {
-		log.Printf("Failed to look up Bible verses: %v", err)
-		return nil, nil
+		return nil, basecommand.NewRetryableError("Failed to look up "+book+" "+chapterVerse, fmt.Errorf("failed to look up bible verses: %w", err))
 	}
 
 	return []*base.Message{
@@ -90,6 +90,27 @@ func TestFunCommands(t *testing.T) {
 				},
 			},
 		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$bibleverse Genesis 99:99",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform:   commandtest.TwitchPlatform,
+			OtherTexts: []string{"$bv Genesis 99:99"},
+			Want: []*base.Message{
+				{
+					Text:    "Failed to look up Genesis 99:99, please try again later (error ID: 03030303)",
+					Channel: "user2",
+				},
+			},
+		},
 		{
 			Input: base.IncomingMessage{
 				Message: base.Message{
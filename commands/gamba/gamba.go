@@ -5,7 +5,6 @@ import (
 	"crypto/rand"
 	"errors"
 	"fmt"
-	"log"
 	"math"
 	"math/big"
 	"strconv"
@@ -137,7 +136,7 @@ func accept(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error)
 		pendingDuel.Accepted = true
 		pendingDuel.Pending = false
 		if err := msg.Resources.DB.Save(&pendingDuel).Error; err != nil {
-			log.Printf("failed to persist duel acceptance: %v", err)
+			msg.Resources.Logger.Error("Failed to persist duel acceptance", "error", err)
 		}
 
 		err = msg.Resources.DB.Create(&[]models.GambaTransaction{
@@ -153,7 +152,7 @@ func accept(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error)
 			},
 		}).Error
 		if err != nil {
-			log.Printf("failed to insert gamba transactions: %v", err)
+			msg.Resources.Logger.Error("Failed to insert gamba transactions", "error", err)
 		}
 
 		outMsgs = append(outMsgs, &base.Message{
@@ -188,7 +187,7 @@ func decline(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error)
 		pendingDuel.Accepted = false
 		pendingDuel.Pending = false
 		if err := msg.Resources.DB.Save(&pendingDuel).Error; err != nil {
-			log.Printf("failed to persist duel declining: %v", err)
+			msg.Resources.Logger.Error("Failed to persist duel declining", "error", err)
 		}
 	}
 
@@ -420,7 +419,7 @@ func givePoints(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, err
 		},
 	}).Error
 	if err != nil {
-		log.Printf("failed to insert gamba transactions: %v", err)
+		msg.Resources.Logger.Error("Failed to insert gamba transactions", "error", err)
 	}
 
 	return []*base.Message{
@@ -536,7 +535,7 @@ func roulette(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error
 			Delta: delta,
 		}
 		if err := msg.Resources.DB.Create(&txn).Error; err != nil {
-			log.Printf("failed to insert gamba transaction: %v", err)
+			msg.Resources.Logger.Error("Failed to insert gamba transaction", "error", err)
 		}
 	}()
 
@@ -552,7 +551,7 @@ func roulette(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error
 // FetchUserPoints fetches user points. Only exported for testing, do not use.
 func FetchUserPoints(db *gorm.DB, user models.User) (int64, error) {
 	var points int64
-	if err := db.Model(&models.GambaTransaction{}).Select("COALESCE(SUM(delta), 0)").Where(models.GambaTransaction{UserID: user.ID}).Scan(&points).Error; err != nil {
+	if err := db.Model(&models.GambaTransaction{}).Select("CAST(COALESCE(SUM(delta), 0) AS BIGINT)").Where(models.GambaTransaction{UserID: user.ID}).Scan(&points).Error; err != nil {
 		return 0, fmt.Errorf("failed to fetch points for user %d: %w", user.ID, err)
 	}
 	return points, nil
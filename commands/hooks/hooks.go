@@ -0,0 +1,291 @@
+// Package hooks implements messages sent when events happen in a channel,
+// and commands to manage them.
+package hooks
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/airforce270/airbot/base"
+	"github.com/airforce270/airbot/base/arg"
+	"github.com/airforce270/airbot/commands/basecommand"
+	"github.com/airforce270/airbot/database/models"
+	"github.com/airforce270/airbot/permission"
+)
+
+// Commands contains this package's commands.
+var Commands = [...]basecommand.Command{
+	hookCommand,
+}
+
+var (
+	hookCommand = basecommand.Command{
+		Name: "hook",
+		Desc: "Sets, lists, or removes messages sent when something happens in the channel, i.e. $hook set raid Thanks for the raid @{user} with {viewers} viewers. " +
+			"Events: " + strings.Join(eventNames(), ", ") + ". " +
+			"Placeholders: " + strings.Join(placeholders, ", ") + ". " +
+			"To announce another joined channel's events, use event:channel, i.e. $hook set live:somechannel somechannel is live: {title}",
+		Params: []arg.Param{
+			{Name: "action", Type: arg.String, Required: true, Usage: "set|list|remove"},
+			{Name: "event", Type: arg.String, Required: false, Usage: "event[:channel]"},
+			{Name: "message", Type: arg.Variadic, Required: false},
+		},
+		Permission: permission.Mod,
+		Handler:    hook,
+	}
+)
+
+// events contains the events hooks can be set for, by the name users refer to them by.
+var events = []struct {
+	Name string
+	Kind base.EventKind
+}{
+	{Name: "live", Kind: base.EventStreamOnline},
+	{Name: "offline", Kind: base.EventStreamOffline},
+	{Name: "raid", Kind: base.EventRaid},
+	{Name: "follow", Kind: base.EventFollow},
+	{Name: "sub", Kind: base.EventSubscribe},
+	{Name: "resub", Kind: base.EventResubscribe},
+	{Name: "subgift", Kind: base.EventGiftSubscriptions},
+	{Name: "redemption", Kind: base.EventRedemption},
+}
+
+// placeholders contains the placeholders that are filled in when a hook's message is sent.
+var placeholders = []string{"{user}", "{channel}", "{viewers}", "{tier}", "{months}", "{count}", "{reward}", "{text}", "{title}", "{game}"}
+
+func hook(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
+	actionArg, eventArg, messageArg := args[0], args[1], args[2]
+	if !actionArg.Present {
+		return nil, basecommand.ErrBadUsage
+	}
+
+	switch strings.ToLower(actionArg.StringValue) {
+	case "set":
+		if !eventArg.Present || !messageArg.Present {
+			return nil, basecommand.ErrBadUsage
+		}
+		return setHook(msg, eventArg.StringValue, messageArg.StringValue)
+	case "list":
+		return listHooks(msg)
+	case "remove":
+		if !eventArg.Present {
+			return nil, basecommand.ErrBadUsage
+		}
+		return removeHook(msg, eventArg.StringValue)
+	default:
+		return nil, basecommand.ErrBadUsage
+	}
+}
+
+func setHook(msg *base.IncomingMessage, eventSpec, template string) ([]*base.Message, error) {
+	h, errMsg := parseEventSpec(msg, eventSpec)
+	if errMsg != "" {
+		return reply(msg, errMsg), nil
+	}
+
+	if h.Source != h.Channel {
+		var joined int64
+		err := msg.Resources.DB.Model(&models.JoinedChannel{}).
+			Where("platform = ? AND LOWER(channel) = ?", h.Platform, h.Source).
+			Count(&joined).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to check if %s is joined: %w", h.Source, err)
+		}
+		if joined == 0 {
+			return reply(msg, fmt.Sprintf("The bot isn't in #%s, so it can't see when things happen there", h.Source)), nil
+		}
+	}
+
+	err := msg.Resources.DB.
+		Where(models.EventHook{Platform: h.Platform, Channel: h.Channel, Source: h.Source, Event: h.Event}).
+		Assign(models.EventHook{Template: template, CreatedBy: msg.Message.User}).
+		FirstOrCreate(&h).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to set %s hook: %w", eventSpec, err)
+	}
+	if err := watchHookedEvents(msg, h.Source); errors.Is(err, base.ErrEventLimit) {
+		return reply(msg, fmt.Sprintf("Set the %s hook, but it won't fire: %s won't send the bot events for any more hooks", hookName(h), msg.Resources.Platform.Name())), nil
+	}
+	return reply(msg, fmt.Sprintf("Set the %s hook: %s", hookName(h), template)), nil
+}
+
+func listHooks(msg *base.IncomingMessage) ([]*base.Message, error) {
+	var hooks []models.EventHook
+	err := msg.Resources.DB.
+		Where(models.EventHook{Platform: msg.Resources.Platform.Name(), Channel: strings.ToLower(msg.Message.Channel)}).
+		Order("source ASC, event ASC").
+		Find(&hooks).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch hooks: %w", err)
+	}
+	if len(hooks) == 0 {
+		return reply(msg, "No hooks are set"), nil
+	}
+
+	names := make([]string, len(hooks))
+	for i, h := range hooks {
+		names[i] = hookName(h)
+	}
+	return reply(msg, "Hooks: "+strings.Join(names, ", ")), nil
+}
+
+func removeHook(msg *base.IncomingMessage, eventSpec string) ([]*base.Message, error) {
+	h, errMsg := parseEventSpec(msg, eventSpec)
+	if errMsg != "" {
+		return reply(msg, errMsg), nil
+	}
+
+	result := msg.Resources.DB.Unscoped().
+		Where(models.EventHook{Platform: h.Platform, Channel: h.Channel, Source: h.Source, Event: h.Event}).
+		Delete(&models.EventHook{})
+	if err := result.Error; err != nil {
+		return nil, fmt.Errorf("failed to remove %s hook: %w", eventSpec, err)
+	}
+	if result.RowsAffected == 0 {
+		return reply(msg, fmt.Sprintf("No %s hook is set", hookName(h))), nil
+	}
+	_ = watchHookedEvents(msg, h.Source) // logged
+	return reply(msg, fmt.Sprintf("Removed the %s hook", hookName(h))), nil
+}
+
+// watchHookedEvents tells the platform a channel's hooks changed,
+// so it watches for the events they're for, logging any error.
+func watchHookedEvents(msg *base.IncomingMessage, source string) error {
+	w, ok := msg.Resources.Platform.(base.HookWatcher)
+	if !ok {
+		return nil
+	}
+	err := w.HooksChanged(source)
+	switch {
+	case errors.Is(err, base.ErrEventLimit):
+		msg.Resources.Logger.Error("Can't watch for hooked events, too many are watched for", "source", source, "error", err)
+	case err != nil:
+		msg.Resources.Logger.Warn("Failed to watch for some hooked events", "source", source, "error", err)
+	}
+	return err
+}
+
+// parseEventSpec parses an event name, optionally followed by :channel,
+// into the hook it refers to in the message's channel.
+// If it's invalid, it returns a message saying why.
+func parseEventSpec(msg *base.IncomingMessage, eventSpec string) (h models.EventHook, errMsg string) {
+	channel := strings.ToLower(msg.Message.Channel)
+	name, source, found := strings.Cut(strings.ToLower(eventSpec), ":")
+	source = strings.TrimPrefix(source, "@")
+	if !found || source == "" {
+		source = channel
+	}
+
+	kind, ok := eventKind(name)
+	if !ok {
+		return models.EventHook{}, fmt.Sprintf("Unknown event %q, events are: %s", name, strings.Join(eventNames(), ", "))
+	}
+	return models.EventHook{
+		Platform: msg.Resources.Platform.Name(),
+		Channel:  channel,
+		Source:   source,
+		Event:    string(kind),
+	}, ""
+}
+
+// HandleEvent sends the messages of the hooks triggered by an event.
+func HandleEvent(event *base.IncomingEvent) ([]*base.Message, error) {
+	e := event.Event
+	if e.Kind == base.EventSubscribe && e.Gift {
+		// Gifted subscriptions are announced once, to thank the gifter.
+		return nil, nil
+	}
+
+	var hooks []models.EventHook
+	err := event.Resources.DB.
+		Where(models.EventHook{Platform: event.Resources.Platform.Name(), Source: strings.ToLower(e.Channel), Event: string(e.Kind)}).
+		Find(&hooks).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch hooks for %s in %s: %w", e.Kind, e.Channel, err)
+	}
+
+	msgs := make([]*base.Message, len(hooks))
+	for i, h := range hooks {
+		msgs[i] = &base.Message{
+			Channel: h.Channel,
+			Text:    render(h.Template, e),
+		}
+	}
+	return msgs, nil
+}
+
+// render fills in the placeholders in a template with an event's details.
+func render(template string, e base.Event) string {
+	user := e.User
+	if user == "" {
+		user = "an anonymous user"
+	}
+	return strings.NewReplacer(
+		"{user}", user,
+		"{channel}", e.Channel,
+		"{viewers}", strconv.Itoa(e.Viewers),
+		"{tier}", tierName(e.Tier),
+		"{months}", strconv.Itoa(e.Months),
+		"{count}", strconv.Itoa(e.Count),
+		"{reward}", e.Reward,
+		"{text}", e.Text,
+		"{title}", e.Title,
+		"{game}", e.Game,
+	).Replace(template)
+}
+
+// tierName returns the human-readable name of a subscription tier, i.e. "1" for "1000".
+func tierName(tier string) string {
+	switch tier {
+	case "1000":
+		return "1"
+	case "2000":
+		return "2"
+	case "3000":
+		return "3"
+	default:
+		return tier
+	}
+}
+
+// hookName returns the name users refer to a hook by, i.e. "raid" or "live:somechannel".
+func hookName(h models.EventHook) string {
+	name := h.Event
+	for _, e := range events {
+		if string(e.Kind) == h.Event {
+			name = e.Name
+		}
+	}
+	if h.Source != h.Channel {
+		name += ":" + h.Source
+	}
+	return name
+}
+
+func eventKind(name string) (base.EventKind, bool) {
+	for _, e := range events {
+		if e.Name == name {
+			return e.Kind, true
+		}
+	}
+	return "", false
+}
+
+func eventNames() []string {
+	names := make([]string, len(events))
+	for i, e := range events {
+		names[i] = e.Name
+	}
+	return names
+}
+
+func reply(msg *base.IncomingMessage, text string) []*base.Message {
+	return []*base.Message{
+		{
+			Channel: msg.Message.Channel,
+			Text:    text,
+		},
+	}
+}
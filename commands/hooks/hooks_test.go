@@ -0,0 +1,353 @@
+package hooks_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/airforce270/airbot/base"
+	"github.com/airforce270/airbot/cache"
+	"github.com/airforce270/airbot/cache/cachetest"
+	"github.com/airforce270/airbot/commands"
+	"github.com/airforce270/airbot/commands/commandtest"
+	"github.com/airforce270/airbot/commands/hooks"
+	"github.com/airforce270/airbot/database/databasetest"
+	"github.com/airforce270/airbot/database/models"
+	"github.com/airforce270/airbot/permission"
+	"github.com/airforce270/airbot/platforms/twitch"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestHookCommands(t *testing.T) {
+	t.Parallel()
+	tests := []commandtest.Case{
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$hook set raid Thanks for the raid @{user} with {viewers} viewers",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform: commandtest.TwitchPlatform,
+			Want: []*base.Message{
+				{
+					Text:    "Set the raid hook: Thanks for the raid @{user} with {viewers} viewers",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$hook set raid Thanks for the raid @{user}",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedHooks},
+			Want: []*base.Message{
+				{
+					Text:    "Set the raid hook: Thanks for the raid @{user}",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$hook set live:@user1 user1 is live: {title}",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{joinUser1},
+			Want: []*base.Message{
+				{
+					Text:    "Set the live:user1 hook: user1 is live: {title}",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$hook set live:user3 user3 is live: {title}",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform: commandtest.TwitchPlatform,
+			Want: []*base.Message{
+				{
+					Text:    "The bot isn't in #user3, so it can't see when things happen there",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$hook set host something",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform: commandtest.TwitchPlatform,
+			Want: []*base.Message{
+				{
+					Text:    `Unknown event "host", events are: live, offline, raid, follow, sub, resub, subgift, redemption`,
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$hook set raid",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform: commandtest.TwitchPlatform,
+			Want: []*base.Message{
+				{
+					Text:    "Usage: $hook <set|list|remove> [event[:channel]] [message]",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$hook list",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedHooks},
+			Want: []*base.Message{
+				{
+					Text:    "Hooks: live:user1, raid, resub",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$hook list",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform: commandtest.TwitchPlatform,
+			Want: []*base.Message{
+				{
+					Text:    "No hooks are set",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$hook remove live:user1",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedHooks},
+			Want: []*base.Message{
+				{
+					Text:    "Removed the live:user1 hook",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$hook remove follow",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedHooks},
+			Want: []*base.Message{
+				{
+					Text:    "No follow hook is set",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$hook list",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform: commandtest.TwitchPlatform,
+			Want:     nil,
+		},
+	}
+
+	commandtest.Run(t, tests)
+}
+
+func TestHandleEvent(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		desc  string
+		event base.Event
+		want  []*base.OutgoingMessage
+	}{
+		{
+			desc:  "raid",
+			event: base.Event{Kind: base.EventRaid, Channel: "user2", User: "user3", Viewers: 50},
+			want: []*base.OutgoingMessage{
+				{Message: base.Message{Channel: "user2", Text: "Thanks for the raid @user3 with 50 viewers"}},
+			},
+		},
+		{
+			desc:  "resub",
+			event: base.Event{Kind: base.EventResubscribe, Channel: "user2", User: "user3", Tier: "2000", Months: 12},
+			want: []*base.OutgoingMessage{
+				{Message: base.Message{Channel: "user2", Text: "Thanks for resubscribing at tier 2 for 12 months @user3"}},
+			},
+		},
+		{
+			desc:  "live in other channel",
+			event: base.Event{Kind: base.EventStreamOnline, Channel: "user1", Title: "some title", Game: "Just Chatting"},
+			want: []*base.OutgoingMessage{
+				{Message: base.Message{Channel: "user2", Text: "user1 is live: some title (Just Chatting)"}},
+			},
+		},
+		{
+			desc:  "no hook",
+			event: base.Event{Kind: base.EventFollow, Channel: "user2", User: "user3"},
+			want:  nil,
+		},
+		{
+			desc:  "raid in other channel",
+			event: base.Event{Kind: base.EventRaid, Channel: "user1", User: "user3", Viewers: 50},
+			want:  nil,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			db := databasetest.New(t)
+			cdb := cachetest.NewDB(t, db)
+			platform := twitch.NewForTesting(t, "", db)
+			resources := base.Resources{Platform: platform, DB: db}
+			seedHooks(t, &resources)
+
+			newConfigSource := func() (io.ReadCloser, error) { return io.NopCloser(strings.NewReader("")), nil }
+			handler := commands.NewHandlerForTest(db, cdb, cache.NewMemory(), map[string]base.Platform{platform.Name(): platform}, newConfigSource, base.RandResources{}, base.APIClients{}, nil)
+			got, err := handler.HandleEvent(&base.IncomingEvent{Event: tc.event, Resources: base.Resources{Platform: platform}})
+			if err != nil {
+				t.Fatalf("HandleEvent() unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("HandleEvent() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestHandleEvent_GiftedSub(t *testing.T) {
+	t.Parallel()
+	db := databasetest.New(t)
+	platform := twitch.NewForTesting(t, "", db)
+	resources := base.Resources{Platform: platform, DB: db}
+	seedHooks(t, &resources)
+	if err := db.Create(&models.EventHook{Platform: platform.Name(), Channel: "user2", Source: "user2", Event: string(base.EventSubscribe), Template: "Thanks for subscribing @{user}"}).Error; err != nil {
+		t.Fatalf("Failed to create hook: %v", err)
+	}
+
+	got, err := hooks.HandleEvent(&base.IncomingEvent{
+		Event:     base.Event{Kind: base.EventSubscribe, Channel: "user2", User: "user3", Gift: true},
+		Resources: resources,
+	})
+	if err != nil {
+		t.Fatalf("HandleEvent() unexpected error: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("HandleEvent() = %v, want no messages for a gifted sub", got)
+	}
+}
+
+func joinUser1(t testing.TB, r *base.Resources) {
+	t.Helper()
+	err := r.DB.Create(&models.JoinedChannel{Platform: r.Platform.Name(), Channel: "user1", JoinedAt: time.Date(2020, 5, 15, 10, 0, 0, 0, time.UTC)}).Error
+	if err != nil {
+		t.Fatalf("Failed to join user1: %v", err)
+	}
+}
+
+func seedHooks(t testing.TB, r *base.Resources) {
+	t.Helper()
+	joinUser1(t, r)
+	hooks := []models.EventHook{
+		{Platform: r.Platform.Name(), Channel: "user2", Source: "user2", Event: string(base.EventRaid), Template: "Thanks for the raid @{user} with {viewers} viewers"},
+		{Platform: r.Platform.Name(), Channel: "user2", Source: "user2", Event: string(base.EventResubscribe), Template: "Thanks for resubscribing at tier {tier} for {months} months @{user}"},
+		{Platform: r.Platform.Name(), Channel: "user2", Source: "user1", Event: string(base.EventStreamOnline), Template: "user1 is live: {title} ({game})"},
+	}
+	if err := r.DB.Create(&hooks).Error; err != nil {
+		t.Fatalf("Failed to create hooks: %v", err)
+	}
+}
@@ -21,20 +21,22 @@ var Commands = [...]basecommand.Command{
 
 var (
 	isLiveCommand = basecommand.Command{
-		Name:       "kickislive",
-		Aliases:    []string{"kislive"},
-		Desc:       "Replies with whether the Kick channel is currently live.",
-		Params:     []arg.Param{{Name: "channel", Type: arg.Username, Required: true}},
-		Permission: permission.Normal,
-		Handler:    isLive,
+		Name:        "kickislive",
+		Aliases:     []string{"kislive"},
+		Desc:        "Replies with whether the Kick channel is currently live.",
+		Params:      []arg.Param{{Name: "channel", Type: arg.Username, Required: true}},
+		Permission:  permission.Normal,
+		WhisperSafe: true,
+		Handler:     isLive,
 	}
 	titleCommand = basecommand.Command{
-		Name:       "kicktitle",
-		Aliases:    []string{"ktitle"},
-		Desc:       "Replies with the title of the Kick channel. Currently only works if the channel is live.",
-		Params:     []arg.Param{{Name: "channel", Type: arg.Username, Required: true}},
-		Permission: permission.Normal,
-		Handler:    title,
+		Name:        "kicktitle",
+		Aliases:     []string{"ktitle"},
+		Desc:        "Replies with the title of the Kick channel. Currently only works if the channel is live.",
+		Params:      []arg.Param{{Name: "channel", Type: arg.Username, Required: true}},
+		Permission:  permission.Normal,
+		WhisperSafe: true,
+		Handler:     title,
 	}
 )
 
@@ -2,7 +2,10 @@
 package moderation
 
 import (
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/airforce270/airbot/base"
@@ -13,10 +16,84 @@ import (
 
 // Commands contains this package's commands.
 var Commands = [...]basecommand.Command{
+	announceCommand,
+	banCommand,
+	deleteCommand,
+	shoutoutCommand,
+	slowCommand,
+	stopCommand,
+	untimeoutCommand,
 	vanishCommand,
 }
 
+const (
+	// minSlowMode is the shortest slow mode Twitch allows.
+	minSlowMode = 3 * time.Second
+	// maxSlowMode is the longest slow mode Twitch allows.
+	maxSlowMode = 120 * time.Second
+)
+
 var (
+	announceCommand = basecommand.Command{
+		Name:       "announce",
+		Desc:       "Sends an announcement to the current channel.",
+		Params:     []arg.Param{{Name: "message", Type: arg.Variadic, Required: true}},
+		Permission: permission.Mod,
+		Handler:    announce,
+	}
+
+	banCommand = basecommand.Command{
+		Name: "ban",
+		Desc: "Bans a user from the current channel.",
+		Params: []arg.Param{
+			{Name: "user", Type: arg.Username, Required: true},
+			{Name: "reason", Type: arg.Variadic, Required: false},
+		},
+		Permission: permission.Mod,
+		Handler:    ban,
+	}
+
+	deleteCommand = basecommand.Command{
+		Name:       "delete",
+		Desc:       "Deletes the message replied to from the current channel.",
+		Permission: permission.Mod,
+		Handler:    deleteMessage,
+	}
+
+	shoutoutCommand = basecommand.Command{
+		Name:       "shoutout",
+		Aliases:    []string{"so"},
+		Desc:       "Gives a shoutout to another channel in the current channel.",
+		Params:     []arg.Param{{Name: "channel", Type: arg.Username, Required: true}},
+		Permission: permission.Mod,
+		Handler:    shoutout,
+	}
+
+	slowCommand = basecommand.Command{
+		Name:       "slow",
+		Desc:       fmt.Sprintf("Sets slow mode in the current channel, from %d to %d seconds, or turns it off.", int(minSlowMode.Seconds()), int(maxSlowMode.Seconds())),
+		Params:     []arg.Param{{Name: "seconds", Type: arg.String, Required: true, Usage: "seconds|off"}},
+		Permission: permission.Mod,
+		Handler:    slow,
+	}
+
+	untimeoutCommand = basecommand.Command{
+		Name:       "untimeout",
+		Aliases:    []string{"unban"},
+		Desc:       "Removes a user's ban or timeout in the current channel.",
+		Params:     []arg.Param{{Name: "user", Type: arg.Username, Required: true}},
+		Permission: permission.Mod,
+		Handler:    untimeout,
+	}
+
+	stopCommand = basecommand.Command{
+		Name:       "stop",
+		Aliases:    []string{"cancel"},
+		Desc:       "Cancels all messages waiting to be sent to the current channel.",
+		Permission: permission.Mod,
+		Handler:    stop,
+	}
+
 	vanishCommand = basecommand.Command{
 		Name:       "vanish",
 		Desc:       "Times you out for 1 second.",
@@ -30,3 +107,139 @@ var (
 		},
 	}
 )
+
+func stop(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
+	var cancelled int
+	if msg.Resources.Queue != nil {
+		cancelled = msg.Resources.Queue.Cancel(msg.Message.Channel)
+	}
+
+	text := "No messages are waiting to be sent"
+	if cancelled == 1 {
+		text = "Cancelled 1 message waiting to be sent"
+	} else if cancelled > 1 {
+		text = fmt.Sprintf("Cancelled %d messages waiting to be sent", cancelled)
+	}
+
+	return []*base.Message{
+		{
+			Channel: msg.Message.Channel,
+			Text:    text,
+		},
+	}, nil
+}
+
+func announce(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
+	textArg := args[0]
+	if !textArg.Present {
+		return nil, basecommand.ErrBadUsage
+	}
+	return moderate(msg, "", func(m base.Moderator) error {
+		return m.Announce(msg.Message.Channel, textArg.StringValue)
+	})
+}
+
+func ban(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
+	userArg, reasonArg := args[0], args[1]
+	if !userArg.Present {
+		return nil, basecommand.ErrBadUsage
+	}
+	target := userArg.StringValue
+	return moderate(msg, "Banned "+target, func(m base.Moderator) error {
+		return m.Ban(target, msg.Message.Channel, reasonArg.StringValue)
+	})
+}
+
+func deleteMessage(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
+	if msg.Message.ParentID == "" {
+		return reply(msg, fmt.Sprintf("Reply to the message to delete with %sdelete", msg.Prefix)), nil
+	}
+	return moderate(msg, "Deleted the message", func(m base.Moderator) error {
+		return m.DeleteMessage(msg.Message.Channel, msg.Message.ParentID)
+	})
+}
+
+func shoutout(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
+	channelArg := args[0]
+	if !channelArg.Present {
+		return nil, basecommand.ErrBadUsage
+	}
+	return moderate(msg, "", func(m base.Moderator) error {
+		return m.Shoutout(msg.Message.Channel, channelArg.StringValue)
+	})
+}
+
+func slow(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
+	secondsArg := args[0]
+	if !secondsArg.Present {
+		return nil, basecommand.ErrBadUsage
+	}
+
+	var wait time.Duration
+	if !strings.EqualFold(secondsArg.StringValue, "off") {
+		seconds, err := strconv.Atoi(strings.TrimSuffix(secondsArg.StringValue, "s"))
+		if err != nil {
+			return nil, basecommand.ErrBadUsage
+		}
+		wait = time.Duration(seconds) * time.Second
+		if wait < minSlowMode || wait > maxSlowMode {
+			return reply(msg, fmt.Sprintf("Slow mode must be from %d to %d seconds", int(minSlowMode.Seconds()), int(maxSlowMode.Seconds()))), nil
+		}
+	}
+
+	text := "Slow mode is now off"
+	if wait > 0 {
+		text = fmt.Sprintf("Slow mode is now %s", wait)
+	}
+	return moderate(msg, text, func(m base.Moderator) error {
+		return m.UpdateChatSettings(msg.Message.Channel, base.ChatSettings{SlowMode: &wait})
+	})
+}
+
+func untimeout(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
+	userArg := args[0]
+	if !userArg.Present {
+		return nil, basecommand.ErrBadUsage
+	}
+	target := userArg.StringValue
+	return moderate(msg, target+" can chat again", func(m base.Moderator) error {
+		return m.Unban(target, msg.Message.Channel)
+	})
+}
+
+// moderate runs a moderation action on the message's platform,
+// replying with text if it succeeds, or explaining why it failed.
+// If text is empty, there's no reply when the action succeeds.
+func moderate(msg *base.IncomingMessage, text string, action func(m base.Moderator) error) ([]*base.Message, error) {
+	m, ok := msg.Resources.Platform.(base.Moderator)
+	if !ok {
+		return reply(msg, fmt.Sprintf("Moderation isn't supported on %s", msg.Resources.Platform.Name())), nil
+	}
+
+	err := action(m)
+	switch {
+	case err == nil:
+		if text == "" {
+			return nil, nil
+		}
+		return reply(msg, text), nil
+	case errors.Is(err, base.ErrNotModerator):
+		return reply(msg, fmt.Sprintf("The bot needs to be a moderator in #%s to do that", msg.Message.Channel)), nil
+	case errors.Is(err, base.ErrUnauthorized):
+		msg.Resources.Logger.Error("Moderation failed, credentials were rejected", "error", err)
+		return reply(msg, fmt.Sprintf("The bot's %s credentials were rejected, the bot owner needs to check them", msg.Resources.Platform.Name())), nil
+	case errors.Is(err, base.ErrNoSuchUser):
+		return reply(msg, "That user doesn't exist"), nil
+	default:
+		return nil, fmt.Errorf("failed to moderate %s on %s: %w", msg.Message.Channel, msg.Resources.Platform.Name(), err)
+	}
+}
+
+func reply(msg *base.IncomingMessage, text string) []*base.Message {
+	return []*base.Message{
+		{
+			Channel: msg.Message.Channel,
+			Text:    text,
+		},
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/airforce270/airbot/apiclients/twitchtest"
 	"github.com/airforce270/airbot/base"
 	"github.com/airforce270/airbot/commands/commandtest"
 	"github.com/airforce270/airbot/permission"
@@ -12,6 +13,104 @@ import (
 func TestModerationCommands(t *testing.T) {
 	t.Parallel()
 	tests := []commandtest.Case{
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$stop",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{queueMessages(map[string]int{"user1": 2, "user2": 3})},
+			Want: []*base.Message{
+				{
+					Text:    "Cancelled 3 messages waiting to be sent",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$cancel",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{queueMessages(map[string]int{"user2": 1})},
+			Want: []*base.Message{
+				{
+					Text:    "Cancelled 1 message waiting to be sent",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$stop",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{queueMessages(map[string]int{"user1": 2})},
+			Want: []*base.Message{
+				{
+					Text:    "No messages are waiting to be sent",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$stop",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform: commandtest.TwitchPlatform,
+			Want: []*base.Message{
+				{
+					Text:    "No messages are waiting to be sent",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$stop",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform: commandtest.TwitchPlatform,
+			Want:     nil,
+		},
 		{
 			Input: base.IncomingMessage{
 				Message: base.Message{
@@ -25,16 +124,299 @@ func TestModerationCommands(t *testing.T) {
 				PermissionLevel: permission.Normal,
 			},
 			Platform: commandtest.TwitchPlatform,
-			RunAfter: []commandtest.TeardownFunc{
-				waitForMessagesToSend,
+			APIResp:  twitchtest.GetUsersResp,
+			Want:     nil,
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$ban user2 spamming links",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform: commandtest.TwitchPlatform,
+			APIResp:  twitchtest.GetUsersResp,
+			Want: []*base.Message{
+				{
+					Text:    "Banned user2",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$ban user2",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform: commandtest.TwitchPlatform,
+			APIResp:  twitchtest.GetUsersResp,
+			Want: []*base.Message{
+				{
+					Text:    "Banned user2",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$ban nobody",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform: commandtest.TwitchPlatform,
+			APIResp:  `{"data": []}`,
+			Want: []*base.Message{
+				{
+					Text:    "That user doesn't exist",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$ban user2",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform: commandtest.TwitchPlatform,
+			Want:     nil,
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$untimeout user2",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform:   commandtest.TwitchPlatform,
+			OtherTexts: []string{"$unban user2"},
+			APIResp:    twitchtest.GetUsersResp,
+			Want: []*base.Message{
+				{
+					Text:    "user2 can chat again",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:     "$delete",
+					ParentID: "885196de-cb67-427a-baa8-82f9b0fcd05f",
+					UserID:   "user1",
+					User:     "user1",
+					Channel:  "user2",
+					Time:     time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform: commandtest.TwitchPlatform,
+			APIResp:  twitchtest.GetUsersResp,
+			Want: []*base.Message{
+				{
+					Text:    "Deleted the message",
+					Channel: "user2",
+				},
 			},
-			Want: nil,
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$delete",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform: commandtest.TwitchPlatform,
+			Want: []*base.Message{
+				{
+					Text:    "Reply to the message to delete with $delete",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$slow 30",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform: commandtest.TwitchPlatform,
+			APIResp:  twitchtest.GetUsersResp,
+			Want: []*base.Message{
+				{
+					Text:    "Slow mode is now 30s",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$slow off",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform: commandtest.TwitchPlatform,
+			APIResp:  twitchtest.GetUsersResp,
+			Want: []*base.Message{
+				{
+					Text:    "Slow mode is now off",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$slow 500",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform: commandtest.TwitchPlatform,
+			Want: []*base.Message{
+				{
+					Text:    "Slow mode must be from 3 to 120 seconds",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$slow fast",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform: commandtest.TwitchPlatform,
+			Want: []*base.Message{
+				{
+					Text:    "Usage: $slow <seconds|off>",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$shoutout user1",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform:   commandtest.TwitchPlatform,
+			OtherTexts: []string{"$so user1"},
+			APIResp:    twitchtest.GetUsersResp,
+			Want:       nil,
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$announce Stream starts in 5 minutes",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform: commandtest.TwitchPlatform,
+			APIResp:  twitchtest.GetUsersResp,
+			Want:     nil,
 		},
 	}
 
 	commandtest.Run(t, tests)
 }
 
-func waitForMessagesToSend(t testing.TB) {
-	time.Sleep(20 * time.Millisecond)
+// queueMessages returns a SetupFunc that sets a fake queue
+// with the given number of messages queued per channel.
+func queueMessages(depths map[string]int) commandtest.SetupFunc {
+	return func(t testing.TB, r *base.Resources) {
+		t.Helper()
+		q := fakeQueue{}
+		for channel, depth := range depths {
+			q[channel] = depth
+		}
+		r.Queue = q
+	}
+}
+
+// fakeQueue is a fake base.OutgoingQueue holding the number of messages queued per channel.
+type fakeQueue map[string]int
+
+func (q fakeQueue) Depth() int {
+	var depth int
+	for _, d := range q {
+		depth += d
+	}
+	return depth
+}
+
+func (q fakeQueue) ChannelDepth(channel string) int { return q[channel] }
+
+func (q fakeQueue) Cancel(channel string) int {
+	n := q[channel]
+	delete(q, channel)
+	return n
 }
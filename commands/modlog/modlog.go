@@ -0,0 +1,191 @@
+// Package modlog implements commands that query logged moderation events,
+// i.e. bans and timeouts.
+package modlog
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/airforce270/airbot/base"
+	"github.com/airforce270/airbot/base/arg"
+	"github.com/airforce270/airbot/commands/basecommand"
+	"github.com/airforce270/airbot/database/models"
+	"github.com/airforce270/airbot/permission"
+)
+
+// Commands contains this package's commands.
+var Commands = [...]basecommand.Command{
+	banLeaderboardCommand,
+	lastBanCommand,
+	timeoutsCommand,
+}
+
+var (
+	banLeaderboardCommand = basecommand.Command{
+		Name:         "banleaderboard",
+		Aliases:      []string{"bantop"},
+		Desc:         fmt.Sprintf("Shows the %d users banned or timed out the most in a channel. If no channel is provided, the current channel will be used.", leaderboardSize),
+		Params:       []arg.Param{{Name: "channel", Type: arg.Username, Required: false}},
+		Permission:   permission.Mod,
+		UserCooldown: 5 * time.Second,
+		Handler:      banLeaderboard,
+	}
+
+	lastBanCommand = basecommand.Command{
+		Name:         "lastban",
+		Desc:         "Shows the last user banned from a channel. If no channel is provided, the current channel will be used.",
+		Params:       []arg.Param{{Name: "channel", Type: arg.Username, Required: false}},
+		Permission:   permission.Mod,
+		UserCooldown: 5 * time.Second,
+		Handler:      lastBan,
+	}
+
+	timeoutsCommand = basecommand.Command{
+		Name: "timeouts",
+		Desc: "Shows how many times a user has been timed out, optionally in a specific channel.",
+		Params: []arg.Param{
+			{Name: "user", Type: arg.Username, Required: true},
+			{Name: "channel", Type: arg.Username, Required: false},
+		},
+		Permission:   permission.Mod,
+		UserCooldown: 5 * time.Second,
+		Handler:      timeouts,
+	}
+)
+
+const (
+	// leaderboardSize is the number of users shown by $banleaderboard.
+	leaderboardSize = 5
+	// banTimeFormat is the format times of bans are shown in.
+	banTimeFormat = "2006-01-02 15:04:05 MST"
+)
+
+func banLeaderboard(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
+	channel := strings.ToLower(basecommand.FirstArgOrChannel(args, msg))
+
+	var top []struct {
+		UserID uint
+		Bans   int64
+	}
+	err := msg.Resources.DB.Model(&models.ChatBan{}).
+		Select("user_id, COUNT(*) AS bans").
+		Where(models.ChatBan{Platform: msg.Resources.Platform.Name(), Channel: channel}).
+		Group("user_id").
+		Order("bans DESC, user_id ASC").
+		Limit(leaderboardSize).
+		Scan(&top).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch most banned users in %s: %w", channel, err)
+	}
+
+	if len(top) == 0 {
+		return reply(msg, fmt.Sprintf("No logged bans or timeouts in #%s", channel)), nil
+	}
+
+	ids := make([]uint, len(top))
+	for i, t := range top {
+		ids[i] = t.UserID
+	}
+	var users []models.User
+	if err := msg.Resources.DB.Find(&users, ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch most banned users in %s: %w", channel, err)
+	}
+	names := make(map[uint]string, len(users))
+	for _, u := range users {
+		names[u.ID] = u.TwitchName
+	}
+
+	entries := make([]string, len(top))
+	for i, t := range top {
+		entries[i] = fmt.Sprintf("%d. %s (%d)", i+1, names[t.UserID], t.Bans)
+	}
+	return reply(msg, fmt.Sprintf("Most banned and timed out in #%s: %s", channel, strings.Join(entries, ", "))), nil
+}
+
+func lastBan(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
+	channel := strings.ToLower(basecommand.FirstArgOrChannel(args, msg))
+
+	var last models.ChatBan
+	err := msg.Resources.DB.
+		Where(models.ChatBan{Platform: msg.Resources.Platform.Name(), Channel: channel}).
+		// Duration is zero for bans, so it can't be matched with a struct.
+		Where("duration = 0").
+		Preload("User").
+		Order("time DESC, id DESC").
+		Limit(1).
+		Find(&last).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch last ban in %s: %w", channel, err)
+	}
+
+	if last.ID == 0 {
+		return reply(msg, fmt.Sprintf("No logged bans in #%s", channel)), nil
+	}
+	return reply(msg, fmt.Sprintf("Last ban in #%s: %s at %s", channel, last.User.TwitchName, formatTime(last.Time))), nil
+}
+
+func timeouts(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
+	targetArg, channelArg := args[0], args[1]
+	if !targetArg.Present {
+		return nil, basecommand.ErrBadUsage
+	}
+	target := targetArg.StringValue
+
+	user, err := msg.Resources.Platform.User(target)
+	if err != nil {
+		if errors.Is(err, base.ErrUserUnknown) {
+			return reply(msg, fmt.Sprintf("%s has never been seen by %s", target, msg.Resources.Platform.Username())), nil
+		}
+		return nil, fmt.Errorf("failed to look up user %s: %w", target, err)
+	}
+
+	query := msg.Resources.DB.Model(&models.ChatBan{}).
+		Where(models.ChatBan{Platform: msg.Resources.Platform.Name(), UserID: user.ID}).
+		Where("duration > 0")
+	var where string
+	if channelArg.Present {
+		channel := strings.ToLower(channelArg.StringValue)
+		query = query.Where("channel = ?", channel)
+		where = " in #" + channel
+	}
+
+	var timeouts []models.ChatBan
+	if err := query.Order("time DESC, id DESC").Find(&timeouts).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch timeouts for %s: %w", target, err)
+	}
+
+	if len(timeouts) == 0 {
+		return reply(msg, fmt.Sprintf("%s has no logged timeouts%s", target, where)), nil
+	}
+
+	var total time.Duration
+	for _, t := range timeouts {
+		total += t.Duration
+	}
+	latest := timeouts[0]
+	return reply(msg, fmt.Sprintf("%s has been timed out %d %s%s (%s total), most recently in #%s for %s at %s",
+		target, len(timeouts), pluralize(len(timeouts), "time", "times"), where, total,
+		latest.Channel, latest.Duration, formatTime(latest.Time))), nil
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(banTimeFormat)
+}
+
+func pluralize(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
+}
+
+func reply(msg *base.IncomingMessage, text string) []*base.Message {
+	return []*base.Message{
+		{
+			Channel: msg.Message.Channel,
+			Text:    text,
+		},
+	}
+}
@@ -0,0 +1,250 @@
+package modlog_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/airforce270/airbot/base"
+	"github.com/airforce270/airbot/commands/commandtest"
+	"github.com/airforce270/airbot/database/models"
+	"github.com/airforce270/airbot/permission"
+)
+
+func TestModLogCommands(t *testing.T) {
+	t.Parallel()
+	tests := []commandtest.Case{
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$banleaderboard",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			OtherTexts: []string{"$bantop"},
+			Platform:   commandtest.TwitchPlatform,
+			RunBefore:  []commandtest.SetupFunc{seedBans},
+			Want: []*base.Message{
+				{
+					Text:    "Most banned and timed out in #user2: 1. user3 (2), 2. user1 (1), 3. user2 (1)",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$banleaderboard user1",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedBans},
+			Want: []*base.Message{
+				{
+					Text:    "No logged bans or timeouts in #user1",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$lastban",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedBans},
+			Want: []*base.Message{
+				{
+					Text:    "Last ban in #user2: user3 at 2020-05-15 10:05:00 UTC",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$lastban user3",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedBans},
+			Want: []*base.Message{
+				{
+					Text:    "No logged bans in #user3",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$lastban",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedBans},
+			Want:      nil,
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$timeouts user1",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedBans},
+			Want: []*base.Message{
+				{
+					Text:    "user1 has been timed out 2 times (11m0s total), most recently in #user3 for 1m0s at 2020-05-15 10:02:00 UTC",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$timeouts user1 user2",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedBans},
+			Want: []*base.Message{
+				{
+					Text:    "user1 has been timed out 1 time in #user2 (10m0s total), most recently in #user2 for 10m0s at 2020-05-15 10:00:00 UTC",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$timeouts user2 user3",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedBans},
+			Want: []*base.Message{
+				{
+					Text:    "user2 has no logged timeouts in #user3",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$timeouts nobody",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedBans},
+			Want: []*base.Message{
+				{
+					Text:    "nobody has never been seen by fake-username",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$timeouts",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Mod,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedBans},
+			Want: []*base.Message{
+				{
+					Text:    "Usage: $timeouts <user> [channel]",
+					Channel: "user2",
+				},
+			},
+		},
+	}
+
+	commandtest.Run(t, tests)
+}
+
+func seedBans(t testing.TB, r *base.Resources) {
+	t.Helper()
+	users := map[string]models.User{}
+	for _, name := range []string{"user1", "user2", "user3"} {
+		var user models.User
+		if err := r.DB.First(&user, "twitch_name = ?", name).Error; err != nil {
+			t.Fatalf("Failed to fetch %s: %v", name, err)
+		}
+		users[name] = user
+	}
+	start := time.Date(2020, 5, 15, 10, 0, 0, 0, time.UTC)
+	bans := []*models.ChatBan{
+		{Platform: "Twitch", Channel: "user2", UserID: users["user1"].ID, Duration: 10 * time.Minute, Time: start},
+		{Platform: "Twitch", Channel: "user2", UserID: users["user3"].ID, Duration: 10 * time.Second, Time: start.Add(1 * time.Minute)},
+		{Platform: "Twitch", Channel: "user3", UserID: users["user1"].ID, Duration: 1 * time.Minute, Time: start.Add(2 * time.Minute)},
+		{Platform: "Twitch", Channel: "user2", UserID: users["user2"].ID, Duration: 30 * time.Second, Time: start.Add(3 * time.Minute)},
+		{Platform: "Twitch", Channel: "user2", UserID: users["user3"].ID, Time: start.Add(5 * time.Minute)},
+	}
+	for _, b := range bans {
+		if err := r.DB.Create(b).Error; err != nil {
+			t.Fatalf("Failed to seed ban: %v", err)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+// Package notify implements commands for users to be notified when streams go live.
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	kickclient "github.com/airforce270/airbot/apiclients/kick"
+	"github.com/airforce270/airbot/base"
+	"github.com/airforce270/airbot/base/arg"
+	"github.com/airforce270/airbot/commands/basecommand"
+	"github.com/airforce270/airbot/database/models"
+	"github.com/airforce270/airbot/permission"
+)
+
+// Commands contains this package's commands.
+var Commands = [...]basecommand.Command{
+	notifyMeCommand,
+	unnotifyMeCommand,
+	notifyListCommand,
+}
+
+const (
+	// maxNotificationsPerUser is the most streams a user can be notified about.
+	maxNotificationsPerUser = 20
+	// kickPrefix is the prefix of Kick channels, i.e. kick:channel.
+	kickPrefix = "kick:"
+	// whisperMode is the mode to be whispered instead of pinged.
+	whisperMode = "whisper"
+)
+
+var (
+	notifyMeCommand = basecommand.Command{
+		Name: "notifyme",
+		Desc: "Pings you in this channel when a stream goes live. Add whisper, or whisper the command to the bot, to be whispered instead. Prefix the channel with kick: for Kick streams.",
+		Params: []arg.Param{
+			{Name: "channel", Type: arg.String, Required: true, Usage: "[kick:]channel"},
+			{Name: "mode", Type: arg.String, Required: false, Usage: whisperMode},
+		},
+		Permission:  permission.Normal,
+		WhisperSafe: true,
+		Handler:     notifyMe,
+	}
+	unnotifyMeCommand = basecommand.Command{
+		Name:        "unnotifyme",
+		Desc:        "Stops notifying you when a stream goes live.",
+		Params:      []arg.Param{{Name: "channel", Type: arg.String, Required: true, Usage: "[kick:]channel"}},
+		Permission:  permission.Normal,
+		WhisperSafe: true,
+		Handler:     unnotifyMe,
+	}
+	notifyListCommand = basecommand.Command{
+		Name:        "notifylist",
+		Desc:        "Lists the streams you're notified about when they go live.",
+		Permission:  permission.Normal,
+		WhisperSafe: true,
+		Handler:     notifyList,
+	}
+)
+
+func notifyMe(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
+	channelArg, modeArg := args[0], args[1]
+	if !channelArg.Present {
+		return nil, basecommand.ErrBadUsage
+	}
+	// Whispers have no channel to ping the user in.
+	whisper := msg.Whisper
+	if modeArg.Present {
+		if !strings.EqualFold(modeArg.StringValue, whisperMode) {
+			return nil, basecommand.ErrBadUsage
+		}
+		if _, ok := msg.Resources.Platform.(base.Whisperer); !ok {
+			return reply(msg, fmt.Sprintf("The bot can't whisper users on %s", msg.Resources.Platform.Name())), nil
+		}
+		whisper = true
+	}
+
+	streamPlatform, streamer := parseStream(channelArg.StringValue)
+	if streamer == "" {
+		return nil, basecommand.ErrBadUsage
+	}
+	streamer, found, err := lookUpStreamer(msg, streamPlatform, streamer)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return reply(msg, fmt.Sprintf("Couldn't find %s channel %s", streamPlatform, streamer)), nil
+	}
+
+	key := models.LiveNotification{
+		Platform:       msg.Resources.Platform.Name(),
+		UserID:         msg.Message.UserID,
+		StreamPlatform: streamPlatform,
+		Streamer:       streamer,
+	}
+	var existing, count int64
+	if err := msg.Resources.DB.Model(&models.LiveNotification{}).Where(key).Count(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to check for existing live notification: %w", err)
+	}
+	if existing == 0 {
+		err := msg.Resources.DB.Model(&models.LiveNotification{}).
+			Where(models.LiveNotification{Platform: key.Platform, UserID: key.UserID}).
+			Count(&count).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to count live notifications: %w", err)
+		}
+		if count >= maxNotificationsPerUser {
+			return reply(msg, fmt.Sprintf("You can't be notified about more than %d streams, remove one with %sunnotifyme first", maxNotificationsPerUser, msg.Prefix)), nil
+		}
+	}
+
+	var notification models.LiveNotification
+	err = msg.Resources.DB.
+		Where(key).
+		Assign(map[string]any{
+			"channel":  strings.ToLower(msg.Message.Channel),
+			"username": msg.Message.User,
+			"whisper":  whisper,
+		}).
+		FirstOrCreate(&notification).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to save live notification for %s: %w", streamer, err)
+	}
+
+	if whisper {
+		return reply(msg, fmt.Sprintf("You'll be whispered when %s goes live on %s", streamer, streamPlatform)), nil
+	}
+	return reply(msg, fmt.Sprintf("You'll be pinged here when %s goes live on %s", streamer, streamPlatform)), nil
+}
+
+func unnotifyMe(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
+	channelArg := args[0]
+	if !channelArg.Present {
+		return nil, basecommand.ErrBadUsage
+	}
+	streamPlatform, streamer := parseStream(channelArg.StringValue)
+	if streamer == "" {
+		return nil, basecommand.ErrBadUsage
+	}
+
+	result := msg.Resources.DB.Unscoped().
+		Where(models.LiveNotification{
+			Platform:       msg.Resources.Platform.Name(),
+			UserID:         msg.Message.UserID,
+			StreamPlatform: streamPlatform,
+			Streamer:       streamer,
+		}).
+		Delete(&models.LiveNotification{})
+	if err := result.Error; err != nil {
+		return nil, fmt.Errorf("failed to remove live notification for %s: %w", streamer, err)
+	}
+	if result.RowsAffected == 0 {
+		return reply(msg, fmt.Sprintf("You aren't notified when %s goes live on %s", streamer, streamPlatform)), nil
+	}
+	return reply(msg, fmt.Sprintf("You won't be notified when %s goes live on %s anymore", streamer, streamPlatform)), nil
+}
+
+func notifyList(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
+	var notifications []models.LiveNotification
+	err := msg.Resources.DB.
+		Where(models.LiveNotification{Platform: msg.Resources.Platform.Name(), UserID: msg.Message.UserID}).
+		Order("stream_platform DESC, streamer ASC").
+		Find(&notifications).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch live notifications: %w", err)
+	}
+	if len(notifications) == 0 {
+		return reply(msg, "You aren't notified about any streams"), nil
+	}
+
+	streams := make([]string, len(notifications))
+	for i, n := range notifications {
+		streams[i] = n.Streamer
+		if n.StreamPlatform == models.StreamPlatformKick {
+			streams[i] = kickPrefix + n.Streamer
+		}
+		if n.Whisper {
+			streams[i] += " (whispered)"
+		} else if !strings.EqualFold(n.Channel, msg.Message.Channel) {
+			streams[i] += " (in #" + n.Channel + ")"
+		}
+	}
+	return reply(msg, "You're notified when these go live: "+strings.Join(streams, ", ")), nil
+}
+
+// parseStream parses a [kick:]channel argument into the platform the stream is on
+// and the lowercased channel.
+func parseStream(s string) (streamPlatform, streamer string) {
+	s = strings.ToLower(strings.TrimPrefix(s, "@"))
+	if streamer, ok := strings.CutPrefix(s, kickPrefix); ok {
+		return models.StreamPlatformKick, streamer
+	}
+	return models.StreamPlatformTwitch, s
+}
+
+// lookUpStreamer checks that a channel exists on a stream platform,
+// returning its canonical lowercased name.
+func lookUpStreamer(msg *base.IncomingMessage, streamPlatform, streamer string) (name string, found bool, err error) {
+	switch streamPlatform {
+	case models.StreamPlatformKick:
+		channel, err := msg.Resources.Clients.Kick.FetchChannel(streamer)
+		if errors.Is(err, kickclient.ErrChannelNotFound) {
+			return streamer, false, nil
+		}
+		if err != nil {
+			return "", false, fmt.Errorf("failed to fetch Kick channel %s: %w", streamer, err)
+		}
+		return strings.ToLower(channel.Name), true, nil
+	default:
+		users, err := msg.Resources.Clients.IVR.FetchUsers(streamer)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to fetch IVR user data for %s: %w", streamer, err)
+		}
+		if len(users) == 0 {
+			return streamer, false, nil
+		}
+		return strings.ToLower(users[0].Username), true, nil
+	}
+}
+
+func reply(msg *base.IncomingMessage, text string) []*base.Message {
+	return []*base.Message{
+		{
+			Channel: msg.Message.Channel,
+			Text:    text,
+		},
+	}
+}
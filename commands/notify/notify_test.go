@@ -0,0 +1,283 @@
+package notify_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/airforce270/airbot/apiclients/ivr/ivrtest"
+	"github.com/airforce270/airbot/apiclients/kick/kicktest"
+	"github.com/airforce270/airbot/base"
+	"github.com/airforce270/airbot/commands/commandtest"
+	"github.com/airforce270/airbot/database/models"
+	"github.com/airforce270/airbot/permission"
+)
+
+func TestNotifyCommands(t *testing.T) {
+	t.Parallel()
+	tests := []commandtest.Case{
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$notifyme xqc",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform: commandtest.TwitchPlatform,
+			APIResp:  ivrtest.TwitchUsersNotStreamingResp,
+			Want: []*base.Message{
+				{
+					Text:    "You'll be pinged here when xqc goes live on Twitch",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$notifyme xqc whisper",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			APIResp:   ivrtest.TwitchUsersNotStreamingResp,
+			RunBefore: []commandtest.SetupFunc{seedNotifications},
+			Want: []*base.Message{
+				{
+					Text:    "You'll be whispered when xqc goes live on Twitch",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$notifyme kick:airforce2700",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform: commandtest.TwitchPlatform,
+			APIResp:  kicktest.SmallOfflineGetChannelResp,
+			Want: []*base.Message{
+				{
+					Text:    "You'll be pinged here when airforce2700 goes live on Kick",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$notifyme notarealuser",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform: commandtest.TwitchPlatform,
+			APIResp:  "[]",
+			Want: []*base.Message{
+				{
+					Text:    "Couldn't find Twitch channel notarealuser",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$notifyme xqc",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			APIResp:   ivrtest.TwitchUsersNotStreamingResp,
+			RunBefore: []commandtest.SetupFunc{seedMaxNotifications},
+			Want: []*base.Message{
+				{
+					Text:    "You can't be notified about more than 20 streams, remove one with $unnotifyme first",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$notifyme xqc loudly",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform: commandtest.TwitchPlatform,
+			Want: []*base.Message{
+				{
+					Text:    "Usage: $notifyme <[kick:]channel> [whisper]",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$unnotifyme xqc",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedNotifications},
+			Want: []*base.Message{
+				{
+					Text:    "You won't be notified when xqc goes live on Twitch anymore",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$unnotifyme KICK:AirForce2700",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedNotifications},
+			Want: []*base.Message{
+				{
+					Text:    "You won't be notified when airforce2700 goes live on Kick anymore",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$unnotifyme xqc",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform: commandtest.TwitchPlatform,
+			Want: []*base.Message{
+				{
+					Text:    "You aren't notified when xqc goes live on Twitch",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$notifylist",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedNotifications},
+			Want: []*base.Message{
+				{
+					Text:    "You're notified when these go live: forsen (in #user1), xqc, kick:airforce2700 (whispered)",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$notifylist",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform: commandtest.TwitchPlatform,
+			Want: []*base.Message{
+				{
+					Text:    "You aren't notified about any streams",
+					Channel: "user2",
+				},
+			},
+		},
+	}
+
+	commandtest.Run(t, tests)
+}
+
+func seedNotifications(t testing.TB, r *base.Resources) {
+	t.Helper()
+	notifications := []models.LiveNotification{
+		{Platform: r.Platform.Name(), Channel: "user2", UserID: "user1", Username: "user1", StreamPlatform: models.StreamPlatformTwitch, Streamer: "xqc"},
+		{Platform: r.Platform.Name(), Channel: "user1", UserID: "user1", Username: "user1", StreamPlatform: models.StreamPlatformTwitch, Streamer: "forsen"},
+		{Platform: r.Platform.Name(), Channel: "user2", UserID: "user1", Username: "user1", StreamPlatform: models.StreamPlatformKick, Streamer: "airforce2700", Whisper: true},
+		{Platform: r.Platform.Name(), Channel: "user2", UserID: "user2", Username: "user2", StreamPlatform: models.StreamPlatformTwitch, Streamer: "xqc"},
+	}
+	if err := r.DB.Create(&notifications).Error; err != nil {
+		t.Fatalf("Failed to create live notifications: %v", err)
+	}
+}
+
+func seedMaxNotifications(t testing.TB, r *base.Resources) {
+	t.Helper()
+	var notifications []models.LiveNotification
+	for i := range 20 {
+		notifications = append(notifications, models.LiveNotification{
+			Platform:       r.Platform.Name(),
+			Channel:        "user2",
+			UserID:         "user1",
+			Username:       "user1",
+			StreamPlatform: models.StreamPlatformTwitch,
+			Streamer:       fmt.Sprintf("streamer%d", i),
+		})
+	}
+	if err := r.DB.Create(&notifications).Error; err != nil {
+		t.Fatalf("Failed to create live notifications: %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+// Package privacy implements commands for managing the data stored about users.
+package privacy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/airforce270/airbot/apiclients/pastebin"
+	"github.com/airforce270/airbot/base"
+	"github.com/airforce270/airbot/base/arg"
+	"github.com/airforce270/airbot/cache"
+	"github.com/airforce270/airbot/commands/basecommand"
+	"github.com/airforce270/airbot/config"
+	"github.com/airforce270/airbot/database"
+	"github.com/airforce270/airbot/permission"
+)
+
+// Commands contains this package's commands.
+var Commands = [...]basecommand.Command{
+	forgetCommand,
+	myDataCommand,
+}
+
+var (
+	forgetCommand = basecommand.Command{
+		Name:       "forget",
+		Desc:       "Permanently deletes all data stored about a user. Data shared with other users, such as duels, is anonymized.",
+		Params:     []arg.Param{{Name: "user", Type: arg.Username, Required: true}},
+		Permission: permission.Owner,
+		Handler:    forget,
+	}
+
+	myDataCommand = basecommand.Command{
+		Name:         "mydata",
+		Desc:         "Exports all data stored about you. Must be whispered to the bot, so the export isn't posted in chat.",
+		Permission:   permission.Normal,
+		UserCooldown: 1 * time.Hour,
+		WhisperSafe:  true,
+		WhisperOnly:  true,
+		Handler:      myData,
+	}
+)
+
+// exportTimeFormat is the format of the timestamp in export file names.
+const exportTimeFormat = "20060102-150405"
+
+func forget(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
+	targetArg := args[0]
+	if !targetArg.Present {
+		return nil, basecommand.ErrBadUsage
+	}
+	target := targetArg.StringValue
+
+	user, err := msg.Resources.Platform.User(target)
+	if err != nil {
+		if errors.Is(err, base.ErrUserUnknown) {
+			return []*base.Message{
+				{
+					Channel: msg.Message.Channel,
+					Text:    fmt.Sprintf("%s has never been seen by %s", target, msg.Resources.Platform.Username()),
+				},
+			}, nil
+		}
+		return nil, fmt.Errorf("failed to look up user %s: %w", target, err)
+	}
+
+	deleted, anonymized, err := database.ForgetUser(msg.Resources.DB, &user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to forget user %s: %w", target, err)
+	}
+	for _, c := range []cache.Cache{msg.Resources.Cache, msg.Resources.MemoryCache} {
+		for _, pattern := range cache.UserKeyPatterns(msg.Resources.Platform.Name(), user.TwitchID, user.TwitchName) {
+			if err := c.DeleteMatching(pattern); err != nil {
+				return nil, fmt.Errorf("failed to forget cached data about user %s: %w", target, err)
+			}
+		}
+	}
+
+	text := fmt.Sprintf("Deleted all data stored about %s (%s)", target, formatCounts(deleted))
+	if len(anonymized) > 0 {
+		text += fmt.Sprintf(", anonymized data shared with other users (%s)", formatCounts(anonymized))
+	}
+	return []*base.Message{
+		{
+			Channel: msg.Message.Channel,
+			Text:    text,
+		},
+	}, nil
+}
+
+// formatCounts formats row counts by table, i.e. "gamba transactions: 1, messages: 2".
+func formatCounts(counts map[string]int64) string {
+	parts := make([]string, 0, len(counts))
+	for _, table := range slices.Sorted(maps.Keys(counts)) {
+		parts = append(parts, fmt.Sprintf("%s: %d", strings.ReplaceAll(table, "_", " "), counts[table]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func myData(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
+	user, err := msg.Resources.Platform.User(msg.Message.User)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up user %s: %w", msg.Message.User, err)
+	}
+
+	data, err := database.ExportUserData(msg.Resources.DB, &user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export data for %s: %w", msg.Message.User, err)
+	}
+	export, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode data for %s: %w", msg.Message.User, err)
+	}
+
+	configSrc, err := msg.Resources.NewConfigSource()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config source: %w", err)
+	}
+	defer func() { _ = configSrc.Close() }() // ignore error
+	cfg, err := config.Read(configSrc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	if cfg.Pastebin.IsConfigured() {
+		client := pastebin.NewClient("", msg.Resources.Clients.PastebinCreatePasteURLOverride)
+		title := fmt.Sprintf("%s data for %s", msg.Resources.Platform.Username(), msg.Message.User)
+		pasteURL, err := client.CreatePaste(cfg.Pastebin.APIDevKey, title, string(export))
+		if err != nil {
+			return nil, fmt.Errorf("failed to upload data for %s: %w", msg.Message.User, err)
+		}
+		return []*base.Message{
+			{
+				Channel: msg.Message.Channel,
+				Text:    fmt.Sprintf("Your data (%d records) has been exported to %s - the link expires in a week.", data.Count(), pasteURL),
+			},
+		}, nil
+	}
+
+	path, err := writeExport(msg.Resources.Platform.Name(), msg.Message.User, export)
+	if err != nil {
+		return nil, err
+	}
+	msg.Resources.Logger.Info("Exported user data", "path", path)
+	return []*base.Message{
+		{
+			Channel: msg.Message.Channel,
+			Text:    fmt.Sprintf("Your data (%d records) has been exported to %s, ask the bot owner for a copy.", data.Count(), filepath.Base(path)),
+		},
+	}, nil
+}
+
+// writeExport writes an export to a new file in the exports directory,
+// next to the database, and returns its path.
+func writeExport(platformName, username string, export []byte) (string, error) {
+	dir := filepath.Join(os.Getenv("AIRBOT_SQLITE_DATA_DIR"), "exports")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create exports directory %s: %w", dir, err)
+	}
+	name := fmt.Sprintf("%s-%s-%s.json", strings.ToLower(platformName), strings.ToLower(username), time.Now().UTC().Format(exportTimeFormat))
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, export, 0o600); err != nil {
+		return "", fmt.Errorf("failed to write export %s: %w", path, err)
+	}
+	return path, nil
+}
@@ -0,0 +1,177 @@
+package privacy_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/airforce270/airbot/apiclients/pastebin/pastebintest"
+	"github.com/airforce270/airbot/base"
+	"github.com/airforce270/airbot/commands/commandtest"
+	"github.com/airforce270/airbot/database/models"
+	"github.com/airforce270/airbot/permission"
+)
+
+func TestPrivacyCommands(t *testing.T) {
+	t.Parallel()
+	tests := []commandtest.Case{
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:   "$mydata",
+					UserID: "user1",
+					User:   "user1",
+					Time:   time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+				Whisper:         true,
+			},
+			Platform:   commandtest.TwitchPlatform,
+			ConfigData: "[pastebin]\napi_dev_key = \"key\"",
+			APIResp:    pastebintest.CreatePasteResp,
+			RunBefore:  []commandtest.SetupFunc{seedUser1Data},
+			WantOutgoing: []*base.OutgoingMessage{
+				{
+					Message: base.Message{
+						Text: "Your data (3 records) has been exported to https://pastebin.com/UIFdu235s - the link expires in a week.",
+					},
+					WhisperToID: "user1",
+				},
+			},
+		},
+		{
+			// The export isn't posted in chat, where anyone could open it.
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$mydata",
+					UserID:  "user1",
+					User:    "user1",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Normal,
+			},
+			Platform:   commandtest.TwitchPlatform,
+			ConfigData: "[pastebin]\napi_dev_key = \"key\"",
+			APIResp:    pastebintest.CreatePasteResp,
+			RunBefore:  []commandtest.SetupFunc{seedUser1Data},
+			Want: []*base.Message{
+				{
+					Text:    "$mydata can only be used in a whisper, so its response isn't posted in chat",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$forget user1",
+					UserID:  "user2",
+					User:    "user2",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Owner,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedUser1Data},
+			Want: []*base.Message{
+				{
+					Text:    "Deleted all data stored about user1 (gamba transactions: 1, messages: 1, users: 1)",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$forget user1",
+					UserID:  "user2",
+					User:    "user2",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Owner,
+			},
+			Platform:  commandtest.TwitchPlatform,
+			RunBefore: []commandtest.SetupFunc{seedUser1Data, seedDuel},
+			Want: []*base.Message{
+				{
+					Text:    "Deleted all data stored about user1 (gamba transactions: 1, messages: 1, users: 1), anonymized data shared with other users (duels: 1)",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$forget rando",
+					UserID:  "user2",
+					User:    "user2",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Owner,
+			},
+			Platform: commandtest.TwitchPlatform,
+			Want: []*base.Message{
+				{
+					Text:    "rando has never been seen by fake-username",
+					Channel: "user2",
+				},
+			},
+		},
+		{
+			Input: base.IncomingMessage{
+				Message: base.Message{
+					Text:    "$forget user1",
+					UserID:  "user2",
+					User:    "user2",
+					Channel: "user2",
+					Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				},
+				Prefix:          "$",
+				PermissionLevel: permission.Admin,
+			},
+			Platform: commandtest.TwitchPlatform,
+			Want:     nil,
+		},
+	}
+
+	commandtest.Run(t, tests)
+}
+
+func seedUser1Data(t testing.TB, r *base.Resources) {
+	t.Helper()
+	var user models.User
+	if err := r.DB.First(&user, "twitch_name = ?", "user1").Error; err != nil {
+		t.Fatalf("Failed to fetch user1: %v", err)
+	}
+	seed := []any{
+		&models.Message{Text: "hello", Channel: "user2", UserID: user.ID, Time: time.Now()},
+		&models.GambaTransaction{Game: "roulette", UserID: user.ID, Delta: 10},
+	}
+	for _, row := range seed {
+		if err := r.DB.Create(row).Error; err != nil {
+			t.Fatalf("Failed to seed %T: %v", row, err)
+		}
+	}
+}
+
+func seedDuel(t testing.TB, r *base.Resources) {
+	t.Helper()
+	var user1, user2 models.User
+	if err := r.DB.First(&user1, "twitch_name = ?", "user1").Error; err != nil {
+		t.Fatalf("Failed to fetch user1: %v", err)
+	}
+	if err := r.DB.First(&user2, "twitch_name = ?", "user2").Error; err != nil {
+		t.Fatalf("Failed to fetch user2: %v", err)
+	}
+	if err := r.DB.Create(&models.Duel{UserID: user1.ID, TargetID: user2.ID, Amount: 5}).Error; err != nil {
+		t.Fatalf("Failed to seed duel: %v", err)
+	}
+}
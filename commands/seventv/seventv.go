@@ -5,7 +5,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 
 	seventvapi "github.com/airforce270/airbot/apiclients/seventv"
 	"github.com/airforce270/airbot/base"
@@ -58,7 +57,7 @@ func addEmote(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error
 
 	channel, err := msg.Resources.Platform.User(msg.Message.Channel)
 	if err != nil {
-		log.Printf("Looking up Twitch channel %s failed: %v", msg.Message.Channel, err)
+		msg.Resources.Logger.Error("Looking up Twitch channel failed", "error", err)
 		return []*base.Message{
 			{
 				Channel: msg.Message.Channel,
@@ -68,7 +67,7 @@ func addEmote(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error
 	}
 	userConnection, err := msg.Resources.Clients.SevenTV.FetchUserConnectionByTwitchUserId(channel.TwitchID)
 	if err != nil {
-		log.Printf("Failed to fetch 7TV user connection: %v", err)
+		msg.Resources.Logger.Error("Failed to fetch 7TV user connection", "error", err)
 		return []*base.Message{
 			{
 				Channel: msg.Message.Channel,
@@ -115,7 +114,7 @@ func addEmote(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error
 			}, nil
 		}
 
-		log.Printf("Failed to add 7TV emote: %v", err)
+		msg.Resources.Logger.Error("Failed to add 7TV emote", "error", err)
 		return []*base.Message{
 			{
 				Channel: msg.Message.Channel,
@@ -161,7 +160,7 @@ func emoteCount(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, err
 
 	resp, err := msg.Resources.Clients.SevenTV.FetchUserConnectionByTwitchUserId(user.ID)
 	if err != nil {
-		log.Printf("Failed to fetch 7TV user connection: %v", err)
+		msg.Resources.Logger.Error("Failed to fetch 7TV user connection", "error", err)
 		return []*base.Message{
 			{
 				Channel: msg.Message.Channel,
@@ -187,7 +186,7 @@ func removeEmote(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, er
 
 	channel, err := msg.Resources.Platform.User(msg.Message.Channel)
 	if err != nil {
-		log.Printf("Looking up Twitch channel %s failed: %v", msg.Message.Channel, err)
+		msg.Resources.Logger.Error("Looking up Twitch channel failed", "error", err)
 		return []*base.Message{
 			{
 				Channel: msg.Message.Channel,
@@ -197,7 +196,7 @@ func removeEmote(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, er
 	}
 	userConnection, err := msg.Resources.Clients.SevenTV.FetchUserConnectionByTwitchUserId(channel.TwitchID)
 	if err != nil {
-		log.Printf("Failed to fetch 7TV user connection: %v", err)
+		msg.Resources.Logger.Error("Failed to fetch 7TV user connection", "error", err)
 		return []*base.Message{
 			{
 				Channel: msg.Message.Channel,
@@ -236,7 +235,7 @@ func removeEmote(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, er
 			}, nil
 		}
 
-		log.Printf("Failed to remove 7TV emote: %v", err)
+		msg.Resources.Logger.Error("Failed to remove 7TV emote", "error", err)
 		return []*base.Message{
 			{
 				Channel: msg.Message.Channel,
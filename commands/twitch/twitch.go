@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/airforce270/airbot/apiclients/ivr"
 	"github.com/airforce270/airbot/base"
@@ -12,12 +13,12 @@ import (
 	"github.com/airforce270/airbot/commands/basecommand"
 	"github.com/airforce270/airbot/permission"
 	twitchplatform "github.com/airforce270/airbot/platforms/twitch"
-	"github.com/airforce270/airbot/utils"
 )
 
 // Commands contains this package's commands.
 var Commands = [...]basecommand.Command{
 	banReasonCommand,
+	connStatusCommand,
 	currentGameCommand,
 	foundersCommand,
 	logsCommand,
@@ -30,32 +31,41 @@ var Commands = [...]basecommand.Command{
 	vipsCommand,
 }
 
-const maxUsersPerMessage = 15
-
 var (
 	banReasonCommand = basecommand.Command{
-		Name:       "banreason",
-		Aliases:    []string{"br"},
-		Desc:       "Replies with the reason someone was banned on Twitch.",
-		Params:     []arg.Param{{Name: "user", Type: arg.Username, Required: true}},
-		Permission: permission.Normal,
-		Handler:    banReason,
+		Name:        "banreason",
+		Aliases:     []string{"br"},
+		Desc:        "Replies with the reason someone was banned on Twitch.",
+		Params:      []arg.Param{{Name: "user", Type: arg.Username, Required: true}},
+		Permission:  permission.Normal,
+		WhisperSafe: true,
+		Handler:     banReason,
+	}
+
+	connStatusCommand = basecommand.Command{
+		Name:       "connstatus",
+		Desc:       "Replies with the state of the bot's connection to Twitch chat, and whether it's joined each channel. If a channel is provided, replies with whether it's joined that channel.",
+		Params:     []arg.Param{{Name: "channel", Type: arg.Username, Required: false}},
+		Permission: permission.Mod,
+		Handler:    connStatus,
 	}
 
 	currentGameCommand = basecommand.Command{
-		Name:       "currentgame",
-		Desc:       "Replies with the game that's currently being streamed on a channel.",
-		Params:     []arg.Param{{Name: "channel", Type: arg.Username, Required: true}},
-		Permission: permission.Normal,
-		Handler:    currentGame,
+		Name:        "currentgame",
+		Desc:        "Replies with the game that's currently being streamed on a channel.",
+		Params:      []arg.Param{{Name: "channel", Type: arg.Username, Required: true}},
+		Permission:  permission.Normal,
+		WhisperSafe: true,
+		Handler:     currentGame,
 	}
 
 	foundersCommand = basecommand.Command{
-		Name:       "founders",
-		Desc:       "Replies with a channel's founders. If no channel is provided, the current channel will be used.",
-		Params:     []arg.Param{{Name: "channel", Type: arg.Username, Required: false}},
-		Permission: permission.Normal,
-		Handler:    founders,
+		Name:        "founders",
+		Desc:        "Replies with a channel's founders. If no channel is provided, the current channel will be used.",
+		Params:      []arg.Param{{Name: "channel", Type: arg.Username, Required: false}},
+		Permission:  permission.Normal,
+		WhisperSafe: true,
+		Handler:     founders,
 	}
 
 	logsCommand = basecommand.Command{
@@ -65,24 +75,27 @@ var (
 			{Name: "channel", Type: arg.Username, Required: true},
 			{Name: "user", Type: arg.Username, Required: true},
 		},
-		Permission: permission.Normal,
-		Handler:    logs,
+		Permission:  permission.Normal,
+		WhisperSafe: true,
+		Handler:     logs,
 	}
 
 	modsCommand = basecommand.Command{
-		Name:       "mods",
-		Desc:       "Replies with a channel's mods. If no channel is provided, the current channel will be used.",
-		Params:     []arg.Param{{Name: "channel", Type: arg.Username, Required: false}},
-		Permission: permission.Normal,
-		Handler:    mods,
+		Name:        "mods",
+		Desc:        "Replies with a channel's mods. If no channel is provided, the current channel will be used.",
+		Params:      []arg.Param{{Name: "channel", Type: arg.Username, Required: false}},
+		Permission:  permission.Normal,
+		WhisperSafe: true,
+		Handler:     mods,
 	}
 
 	nameColorCommand = basecommand.Command{
-		Name:       "namecolor",
-		Desc:       "Replies with a user's name color.",
-		Params:     []arg.Param{{Name: "user", Type: arg.Username, Required: false}},
-		Permission: permission.Normal,
-		Handler:    nameColor,
+		Name:        "namecolor",
+		Desc:        "Replies with a user's name color.",
+		Params:      []arg.Param{{Name: "user", Type: arg.Username, Required: false}},
+		Permission:  permission.Normal,
+		WhisperSafe: true,
+		Handler:     nameColor,
 	}
 
 	subAgeCommand = basecommand.Command{
@@ -93,25 +106,28 @@ var (
 			{Name: "user", Type: arg.Username, Required: true},
 			{Name: "channel", Type: arg.Username, Required: true},
 		},
-		Permission: permission.Normal,
-		Handler:    subAge,
+		Permission:  permission.Normal,
+		WhisperSafe: true,
+		Handler:     subAge,
 	}
 
 	titleCommand = basecommand.Command{
-		Name:       "title",
-		Desc:       "Replies with a channel's title. If no channel is provided, the current channel will be used.",
-		Params:     []arg.Param{{Name: "channel", Type: arg.Username, Required: false}},
-		Permission: permission.Normal,
-		Handler:    title,
+		Name:        "title",
+		Desc:        "Replies with a channel's title. If no channel is provided, the current channel will be used.",
+		Params:      []arg.Param{{Name: "channel", Type: arg.Username, Required: false}},
+		Permission:  permission.Normal,
+		WhisperSafe: true,
+		Handler:     title,
 	}
 
 	verifiedBotCommand = basecommand.Command{
-		Name:       "verifiedbot",
-		Aliases:    []string{"vb"},
-		Desc:       "Replies whether a user is a verified bot. Currently offline due to changes on Twitch's end.",
-		Params:     []arg.Param{{Name: "user", Type: arg.Username, Required: false}},
-		Permission: permission.Normal,
-		Handler:    verifiedBot,
+		Name:        "verifiedbot",
+		Aliases:     []string{"vb"},
+		Desc:        "Replies whether a user is a verified bot. Currently offline due to changes on Twitch's end.",
+		Params:      []arg.Param{{Name: "user", Type: arg.Username, Required: false}},
+		Permission:  permission.Normal,
+		WhisperSafe: true,
+		Handler:     verifiedBot,
 	}
 
 	verifiedBotQuietCommand = basecommand.Command{
@@ -124,11 +140,12 @@ var (
 	}
 
 	vipsCommand = basecommand.Command{
-		Name:       "vips",
-		Desc:       "Replies with a channel's VIPs. If no channel is provided, the current channel will be used.",
-		Params:     []arg.Param{{Name: "channel", Type: arg.Username, Required: false}},
-		Permission: permission.Normal,
-		Handler:    vips,
+		Name:        "vips",
+		Desc:        "Replies with a channel's VIPs. If no channel is provided, the current channel will be used.",
+		Params:      []arg.Param{{Name: "channel", Type: arg.Username, Required: false}},
+		Permission:  permission.Normal,
+		WhisperSafe: true,
+		Handler:     vips,
 	}
 )
 
@@ -171,6 +188,83 @@ func banReason(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, erro
 	}, nil
 }
 
+func connStatus(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
+	plat, ok := msg.Resources.PlatformByName(twitchplatform.Name)
+	if !ok {
+		return []*base.Message{
+			{
+				Channel: msg.Message.Channel,
+				Text:    "Twitch connection not configured",
+			},
+		}, nil
+	}
+	tw := plat.(*twitchplatform.Twitch)
+	status := tw.ConnectionStatus()
+
+	if channelArg := args[0]; channelArg.Present {
+		target := strings.ToLower(channelArg.StringValue)
+		for _, c := range status.Channels {
+			if c.Name != target {
+				continue
+			}
+			text := fmt.Sprintf("#%s: %s for %s", c.Name, c.State, time.Since(c.Since).Round(time.Second))
+			if c.State != twitchplatform.JoinStateJoined {
+				text += fmt.Sprintf(" (join attempts: %d)", c.JoinAttempts)
+			}
+			switch {
+			case c.BotIsModerator:
+				text += ", bot is a mod"
+			case c.BotIsVIP:
+				text += ", bot is a VIP"
+			}
+			if len(c.Modes) > 0 {
+				text += ", modes: " + strings.Join(c.Modes, ", ")
+			}
+			return []*base.Message{
+				{
+					Channel: msg.Message.Channel,
+					Text:    text,
+				},
+			}, nil
+		}
+		return []*base.Message{
+			{
+				Channel: msg.Message.Channel,
+				Text:    fmt.Sprintf("Channel %s is not joined", channelArg.StringValue),
+			},
+		}, nil
+	}
+
+	var out strings.Builder
+	if status.Connected {
+		out.WriteString("Connected to Twitch IRC")
+	} else {
+		out.WriteString("Not connected to Twitch IRC")
+	}
+	if !status.Since.IsZero() {
+		fmt.Fprintf(&out, " for %s", time.Since(status.Since).Round(time.Second))
+	}
+	fmt.Fprintf(&out, " (reconnects: %d).", status.Reconnects)
+
+	byState := map[twitchplatform.JoinState][]string{}
+	for _, c := range status.Channels {
+		byState[c.State] = append(byState[c.State], c.Name)
+	}
+	fmt.Fprintf(&out, " Channels: %d joined", len(byState[twitchplatform.JoinStateJoined]))
+	for _, state := range []twitchplatform.JoinState{twitchplatform.JoinStateJoining, twitchplatform.JoinStateFailed} {
+		if names := byState[state]; len(names) > 0 {
+			fmt.Fprintf(&out, ", %d %s (%s)", len(names), state, strings.Join(names, ", "))
+		}
+	}
+
+	return []*base.Message{
+		{
+			Channel: msg.Message.Channel,
+			Text:    out.String(),
+		},
+	}, nil
+}
+
 func currentGame(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
 	targetChannel := basecommand.FirstArgOrChannel(args, msg)
 
@@ -233,24 +327,12 @@ func founders(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error
 		}, nil
 	}
 
-	foundersGroups := utils.Chunk(founders.Founders, maxUsersPerMessage)
-
-	var messages []*base.Message
-
-	for i, foundersGroup := range foundersGroups {
-		var text string
-		if i == 0 {
-			text = fmt.Sprintf("%s's founders are: %s", targetChannel, strings.Join(namesFromFounders(foundersGroup), ", "))
-		} else {
-			text = strings.Join(namesFromFounders(foundersGroup), ", ")
-		}
-		if len(foundersGroups) > 1 && len(foundersGroups)-1 != i {
-			text += ","
-		}
-		messages = append(messages, &base.Message{Channel: msg.Message.Channel, Text: text})
-	}
-
-	return messages, nil
+	return []*base.Message{
+		{
+			Channel: msg.Message.Channel,
+			Text:    fmt.Sprintf("%s's founders are: %s", targetChannel, strings.Join(namesFromFounders(founders.Founders), ", ")),
+		},
+	}, nil
 }
 
 func logs(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
@@ -286,24 +368,12 @@ func mods(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
 		}, nil
 	}
 
-	modGroups := utils.Chunk(modsAndVIPs.Mods, maxUsersPerMessage)
-
-	var messages []*base.Message
-
-	for i, modGroup := range modGroups {
-		var text string
-		if i == 0 {
-			text = fmt.Sprintf("%s's mods are: %s", targetChannel, strings.Join(namesFromModsOrVIPs(modGroup), ", "))
-		} else {
-			text = strings.Join(namesFromModsOrVIPs(modGroup), ", ")
-		}
-		if len(modGroups) > 1 && len(modGroups)-1 != i {
-			text += ","
-		}
-		messages = append(messages, &base.Message{Channel: msg.Message.Channel, Text: text})
-	}
-
-	return messages, nil
+	return []*base.Message{
+		{
+			Channel: msg.Message.Channel,
+			Text:    fmt.Sprintf("%s's mods are: %s", targetChannel, strings.Join(namesFromModsOrVIPs(modsAndVIPs.Mods), ", ")),
+		},
+	}, nil
 }
 
 func nameColor(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
@@ -537,23 +607,12 @@ func vips(msg *base.IncomingMessage, args []arg.Arg) ([]*base.Message, error) {
 		}, nil
 	}
 
-	vipGroups := utils.Chunk(modsAndVIPs.VIPs, maxUsersPerMessage)
-
-	var messages []*base.Message
-	for i, vipGroup := range vipGroups {
-		var text string
-		if i == 0 {
-			text = fmt.Sprintf("%s's VIPs are: %s", targetChannel, strings.Join(namesFromModsOrVIPs(vipGroup), ", "))
-		} else {
-			text = strings.Join(namesFromModsOrVIPs(vipGroup), ", ")
-		}
-		if len(vipGroups) > 1 && len(vipGroups)-1 != i {
-			text += ","
-		}
-		messages = append(messages, &base.Message{Channel: msg.Message.Channel, Text: text})
-	}
-
-	return messages, nil
+	return []*base.Message{
+		{
+			Channel: msg.Message.Channel,
+			Text:    fmt.Sprintf("%s's VIPs are: %s", targetChannel, strings.Join(namesFromModsOrVIPs(modsAndVIPs.VIPs), ", ")),
+		},
+	}, nil
 }
 
 func plural(word string, num int) string {
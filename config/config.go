@@ -5,7 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
+	"time"
+
+	"github.com/airforce270/airbot/permission"
 
 	"github.com/pelletier/go-toml/v2"
 )
@@ -28,14 +32,164 @@ type Config struct {
 	LogIncoming bool `toml:"log_incoming_messages"`
 	// LogOutgoing is whether the bot should log outgoing messages.
 	LogOutgoing bool `toml:"log_outgoing_messages"`
+	// Logging contains config for logging.
+	Logging LoggingConfig
+	// Database contains config for the database connection.
+	Database DatabaseConfig
+	// Backup contains config for database backups.
+	Backup BackupConfig
+	// Audit contains config for the command audit log.
+	Audit AuditConfig
+	// Cooldowns contains config for command cooldowns and rate limits.
+	Cooldowns CooldownsConfig
+	// Messages contains config for outgoing messages.
+	Messages MessagesConfig
+	// Filter contains config for filtering outgoing messages.
+	Filter FilterConfig
+	// Metrics contains config for serving metrics and health checks.
+	Metrics MetricsConfig
+	// Shutdown contains config for shutting down and restarting.
+	Shutdown ShutdownConfig
 	// Platforms contains platform-specific config data.
 	Platforms PlatformConfig
+	// Pastebin contains config for talking to the Pastebin API.
+	Pastebin PastebinConfig
 	// SevenTV contains config for talking to the 7TV API.
 	SevenTV SevenTVConfig
 	// Supinic contains config for talking to the Supinic API.
 	Supinic SupinicConfig
 }
 
+// LoggingConfig contains config for logging.
+type LoggingConfig struct {
+	// Format is the format logs are written in, either "text" or "json".
+	// If empty, text is used.
+	Format string
+	// Level is the minimum level of logs to write.
+	Level slog.Level
+	// Levels contains the minimum level of logs to write for specific subsystems,
+	// keyed by subsystem name, overriding Level.
+	Levels map[string]slog.Level
+	// MessageSampleEvery is how often incoming and outgoing messages are logged,
+	// i.e. 10 logs every 10th message.
+	// Warnings and errors are always logged.
+	// If 0 or 1, every message is logged.
+	MessageSampleEvery int `toml:"message_sample_every"`
+}
+
+// DatabaseConfig contains config for the database connection.
+type DatabaseConfig struct {
+	// Driver is the database driver to use, either "sqlite" or "postgres".
+	// If empty, SQLite is used.
+	Driver string
+	// PostgresDSN is the connection string for the PostgreSQL database.
+	// Only used if Driver is "postgres".
+	PostgresDSN string `toml:"postgres_dsn"`
+}
+
+// BackupConfig contains config for database backups.
+type BackupConfig struct {
+	// Enabled is whether backups should be taken periodically.
+	// Backups can always be taken manually with the backup command.
+	Enabled bool
+	// Interval is how often backups should be taken.
+	// If 0, backups are taken every 24 hours.
+	Interval Duration
+	// Directory is the directory backups should be written to.
+	// If empty, a "backups" directory next to the database will be used.
+	Directory string
+	// Keep is the number of most recent backups to keep.
+	// Older backups are deleted after each backup.
+	// If 0, backups are never deleted.
+	Keep int
+}
+
+// AuditConfig contains config for the command audit log.
+type AuditConfig struct {
+	// Retention is how long records of commands being run are kept.
+	// If 0, records are kept forever.
+	Retention Duration
+}
+
+// CooldownsConfig contains config for command cooldowns and rate limits.
+type CooldownsConfig struct {
+	// Reply is whether the bot should reply when a command is on cooldown,
+	// i.e. "You can use $roulette again in 3s".
+	// Replies are rate-limited, so they can't be used to spam.
+	Reply bool
+	// ExemptLevel is the minimum permission level that bypasses
+	// cooldowns and the user rate limit, i.e. "Mod".
+	// If unset, no one is exempt.
+	ExemptLevel permission.Level `toml:"exempt_level"`
+	// UserRateLimit limits how many commands each user may run,
+	// across all commands and channels.
+	UserRateLimit RateLimitConfig `toml:"user_rate_limit"`
+}
+
+// RateLimitConfig contains config for a rate limit.
+type RateLimitConfig struct {
+	// Count is the number of times something may happen per period.
+	// If 0, there's no limit.
+	Count int
+	// Per is the period.
+	Per Duration
+}
+
+// MessagesConfig contains config for outgoing messages.
+type MessagesConfig struct {
+	// PasteAfter is the number of messages a long message can be split into
+	// before it's uploaded to Pastebin instead, with a link sent in its place.
+	// Only used if Pastebin is configured.
+	// If 0, long messages are always split.
+	PasteAfter int `toml:"paste_after"`
+}
+
+// FilterConfig contains config for filtering outgoing messages.
+// Messages that don't pass the filter aren't sent.
+type FilterConfig struct {
+	// BannedPhrases contains phrases that may not be sent in any channel.
+	// Matching is case-insensitive.
+	BannedPhrases []string `toml:"banned_phrases"`
+	// BannedPatterns contains regular expressions that may not be matched
+	// by messages sent in any channel.
+	BannedPatterns []string `toml:"banned_patterns"`
+	// MaxMentions is the maximum number of users a message may mention (@user).
+	// If 0, mentions aren't limited.
+	MaxMentions int `toml:"max_mentions"`
+	// Channels contains filter config for specific channels, keyed by channel name.
+	Channels map[string]ChannelFilterConfig
+}
+
+// ChannelFilterConfig contains config for filtering messages sent to a specific channel.
+type ChannelFilterConfig struct {
+	// BannedPhrases contains phrases that may not be sent in the channel,
+	// in addition to the global banned phrases.
+	BannedPhrases []string `toml:"banned_phrases"`
+	// BannedPatterns contains regular expressions that may not be matched
+	// by messages sent in the channel, in addition to the global banned patterns.
+	BannedPatterns []string `toml:"banned_patterns"`
+	// MaxMentions is the maximum number of users a message sent in the channel may mention,
+	// overriding the global limit.
+	// If 0, the global limit is used.
+	MaxMentions int `toml:"max_mentions"`
+}
+
+// MetricsConfig contains config for serving metrics and health checks over HTTP.
+type MetricsConfig struct {
+	// Enabled is whether metrics and health checks should be served.
+	Enabled bool
+	// Address is the address to serve on, i.e. ":9090".
+	Address string
+}
+
+// ShutdownConfig contains config for shutting down and restarting.
+type ShutdownConfig struct {
+	// Timeout is how long messages being handled and queued replies
+	// are given to finish when shutting down, before they're abandoned.
+	// If 0, a default is used.
+	Timeout Duration
+}
+
 // PlatformConfig is platform-specific config data.
 type PlatformConfig struct {
 	// Kick contains Kick-specific config data.
@@ -76,8 +230,20 @@ type TwitchConfig struct {
 	RefreshToken string `toml:"refresh_token"`
 	// Owners contains the Twitch usernames of the bot owner(s).
 	Owners []string
+	// EventSub is whether events in joined channels (i.e. going live, raids)
+	// should be subscribed to over EventSub.
+	EventSub bool `toml:"eventsub"`
+}
+
+// PastebinConfig contains config for talking to the Pastebin API.
+type PastebinConfig struct {
+	// APIDevKey is the Pastebin API developer key, used to create pastes.
+	APIDevKey string `toml:"api_dev_key"`
 }
 
+// IsConfigured returns whether pastes can be created.
+func (p PastebinConfig) IsConfigured() bool { return p.APIDevKey != "" }
+
 type SevenTVConfig struct {
 	// AccessToken is the OAuth2 access token to use for 7TV API calls.
 	// It can be obtained by logging in on https://7tv.io/
@@ -114,6 +280,25 @@ func (s SupinicConfig) IsConfigured() bool {
 	return !hasDefaultValue && !isUnset
 }
 
+// Duration is a time.Duration that can be read from a string
+// in the config, i.e. "24h" or "30m".
+type Duration time.Duration
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("failed to parse duration %q: %w", text, err)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
 // Read reads data into a new config.
 func Read(r io.Reader) (*Config, error) {
 	decoder := toml.NewDecoder(r).DisallowUnknownFields()
@@ -2,10 +2,13 @@ package config
 
 import (
 	_ "embed"
+	"log/slog"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/airforce270/airbot/permission"
 	"github.com/google/go-cmp/cmp"
 )
 
@@ -17,6 +20,46 @@ func TestRead(t *testing.T) {
 	want := &Config{
 		LogIncoming: true,
 		LogOutgoing: true,
+		Logging: LoggingConfig{
+			Format:             "text",
+			Level:              slog.LevelInfo,
+			MessageSampleEvery: 1,
+		},
+		Database: DatabaseConfig{
+			Driver:      "sqlite",
+			PostgresDSN: "",
+		},
+		Backup: BackupConfig{
+			Enabled:   false,
+			Interval:  Duration(24 * time.Hour),
+			Directory: "",
+			Keep:      7,
+		},
+		Audit: AuditConfig{
+			Retention: Duration(2160 * time.Hour),
+		},
+		Cooldowns: CooldownsConfig{
+			ExemptLevel: permission.Mod,
+			UserRateLimit: RateLimitConfig{
+				Count: 10,
+				Per:   Duration(30 * time.Second),
+			},
+		},
+		Messages: MessagesConfig{
+			PasteAfter: 3,
+		},
+		Filter: FilterConfig{
+			BannedPhrases:  []string{},
+			BannedPatterns: []string{},
+			MaxMentions:    5,
+		},
+		Metrics: MetricsConfig{
+			Enabled: false,
+			Address: ":9090",
+		},
+		Shutdown: ShutdownConfig{
+			Timeout: Duration(10 * time.Second),
+		},
 		Platforms: PlatformConfig{
 			Kick: KickConfig{
 				JA3:       "",
@@ -30,8 +73,12 @@ func TestRead(t *testing.T) {
 				AccessToken:  "",
 				RefreshToken: "",
 				Owners:       []string{""},
+				EventSub:     true,
 			},
 		},
+		Pastebin: PastebinConfig{
+			APIDevKey: "",
+		},
 		SevenTV: SevenTVConfig{
 			AccessToken: "",
 		},
@@ -94,3 +141,31 @@ func TestSupinicConfigIsConfigured(t *testing.T) {
 		})
 	}
 }
+
+func TestDurationUnmarshalText(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		input   string
+		want    Duration
+		wantErr bool
+	}{
+		{input: "24h", want: Duration(24 * time.Hour)},
+		{input: "1h30m", want: Duration(90 * time.Minute)},
+		{input: "tomorrow", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.input, func(t *testing.T) {
+			t.Parallel()
+			var got Duration
+			err := got.UnmarshalText([]byte(tc.input))
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("UnmarshalText(%q) error = %v, want error: %t", tc.input, err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("UnmarshalText(%q) = %v, want %v", tc.input, got, tc.want)
+			}
+		})
+	}
+}
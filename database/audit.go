@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/airforce270/airbot/database/models"
+
+	"gorm.io/gorm"
+)
+
+// auditPruneInterval is how often old command invocations are pruned.
+const auditPruneInterval = 1 * time.Hour
+
+// PruneCommandInvocations deletes records of commands run before a time,
+// returning the number of records deleted.
+func PruneCommandInvocations(db *gorm.DB, before time.Time) (int64, error) {
+	result := db.Unscoped().Where("time < ?", before).Delete(&models.CommandInvocation{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete command invocations before %s: %w", before, result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// StartPruningCommandInvocations starts a loop to delete records of commands
+// run longer ago than the retention period.
+// This function blocks and should be run within a goroutine.
+func StartPruningCommandInvocations(ctx context.Context, db *gorm.DB, retention time.Duration, logger *slog.Logger) {
+	prune := func() {
+		deleted, err := PruneCommandInvocations(db, time.Now().Add(-retention))
+		if err != nil {
+			logger.Error("Failed to prune command invocations", "error", err)
+			return
+		}
+		if deleted > 0 {
+			logger.Info("Pruned command invocations", "deleted", deleted)
+		}
+	}
+
+	prune()
+	timer := time.NewTicker(auditPruneInterval)
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Stopping pruning command invocations, context cancelled")
+			return
+		case <-timer.C:
+			prune()
+		}
+	}
+}
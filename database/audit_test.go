@@ -0,0 +1,39 @@
+package database_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/airforce270/airbot/database"
+	"github.com/airforce270/airbot/database/databasetest"
+	"github.com/airforce270/airbot/database/models"
+)
+
+func TestPruneCommandInvocations(t *testing.T) {
+	t.Parallel()
+	db := databasetest.New(t)
+	now := time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC)
+
+	for _, age := range []time.Duration{48 * time.Hour, 25 * time.Hour, time.Hour} {
+		invocation := models.CommandInvocation{Command: "echo", Outcome: models.CommandOutcomeOK, Time: now.Add(-age)}
+		if err := db.Create(&invocation).Error; err != nil {
+			t.Fatalf("Failed to create command invocation: %v", err)
+		}
+	}
+
+	deleted, err := database.PruneCommandInvocations(db, now.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("PruneCommandInvocations() unexpected error: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("PruneCommandInvocations() deleted %d, want 2", deleted)
+	}
+
+	var remaining int64
+	if err := db.Unscoped().Model(&models.CommandInvocation{}).Count(&remaining).Error; err != nil {
+		t.Fatalf("Failed to count command invocations: %v", err)
+	}
+	if remaining != 1 {
+		t.Errorf("%d command invocations remaining, want 1", remaining)
+	}
+}
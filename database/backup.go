@@ -0,0 +1,289 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/airforce270/airbot/database/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	// backupFilePrefix is the prefix of all backup file names.
+	backupFilePrefix = "airbot-"
+	// backupFileSuffix is the suffix of all backup file names.
+	backupFileSuffix = ".db"
+	// backupTimeFormat is the format of the timestamp in backup file names.
+	// It sorts lexicographically in chronological order.
+	backupTimeFormat = "20060102-150405.000"
+	// DefaultBackupInterval is how often backups are taken if no interval is configured.
+	DefaultBackupInterval = 24 * time.Hour
+)
+
+// DefaultBackupDir returns the directory backups are written to
+// if none is configured.
+func DefaultBackupDir() string {
+	return filepath.Join(os.Getenv("AIRBOT_SQLITE_DATA_DIR"), "backups")
+}
+
+// Backup writes a consistent snapshot of the database to a new,
+// timestamped file in dir and returns the path to the snapshot.
+// It is safe to call while the database is in use.
+// Only SQLite databases can be backed up.
+func Backup(ctx context.Context, db *gorm.DB, dir string) (string, error) {
+	if !isSQLite(db) {
+		return "", fmt.Errorf("backups are not supported for %s databases", db.Dialector.Name())
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create backup directory %s: %w", dir, err)
+	}
+
+	path := filepath.Join(dir, backupFilePrefix+time.Now().UTC().Format(backupTimeFormat)+backupFileSuffix)
+	if err := db.WithContext(ctx).Exec("VACUUM INTO ?", path).Error; err != nil {
+		return "", fmt.Errorf("failed to write backup to %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// Backups returns the paths of all backups in dir, oldest first.
+func Backups(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read backup directory %s: %w", dir, err)
+	}
+
+	var backups []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, backupFilePrefix) || !strings.HasSuffix(name, backupFileSuffix) {
+			continue
+		}
+		backups = append(backups, filepath.Join(dir, name))
+	}
+	slices.Sort(backups)
+	return backups, nil
+}
+
+// RotateBackups deletes all but the keep most recent backups in dir,
+// returning the paths of the deleted backups.
+// If keep is 0 or less, nothing is deleted.
+func RotateBackups(dir string, keep int) ([]string, error) {
+	if keep <= 0 {
+		return nil, nil
+	}
+	backups, err := Backups(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(backups) <= keep {
+		return nil, nil
+	}
+
+	var deleted []string
+	var errs []error
+	for _, backup := range backups[:len(backups)-keep] {
+		if err := os.Remove(backup); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete old backup %s: %w", backup, err))
+			continue
+		}
+		deleted = append(deleted, backup)
+	}
+	return deleted, errors.Join(errs...)
+}
+
+// StartBackingUp starts a loop to back up the database on an interval,
+// deleting all but the keep most recent backups after each one.
+// If interval isn't positive, DefaultBackupInterval is used.
+// This function blocks and should be run within a goroutine.
+func StartBackingUp(ctx context.Context, db *gorm.DB, dir string, interval time.Duration, keep int, logger *slog.Logger) {
+	if interval <= 0 {
+		logger.Warn("Backup interval isn't set, using the default", "interval", interval, "default", DefaultBackupInterval)
+		interval = DefaultBackupInterval
+	}
+	timer := time.NewTicker(interval)
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Stopping database backups, context cancelled")
+			return
+		case <-timer.C:
+			path, err := Backup(ctx, db, dir)
+			if err != nil {
+				logger.Error("Failed to back up database", "error", err)
+				continue
+			}
+			logger.Info("Backed up database", "path", path)
+			if _, err := RotateBackups(dir, keep); err != nil {
+				logger.Error("Failed to rotate backups", "error", err)
+			}
+		}
+	}
+}
+
+// coreModels are the models whose tables every snapshot must have,
+// as the bot has always had them.
+// Tables added since are created by migrations after a snapshot is restored.
+var coreModels = []any{
+	&models.JoinedChannel{},
+	&models.Message{},
+	&models.User{},
+}
+
+// ValidateSnapshot checks that the SQLite database at path is intact
+// and contains the core tables.
+// Snapshots taken before later schema changes are valid,
+// as migrations bring them up to date when the bot next starts.
+func ValidateSnapshot(ctx context.Context, path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("failed to stat snapshot %s: %w", path, err)
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to find absolute path of snapshot %s: %w", path, err)
+	}
+	uri := url.URL{Scheme: "file", Path: filepath.ToSlash(absPath), RawQuery: "mode=ro"}
+
+	db, err := gorm.Open(sqlite.Open(uri.String()), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot %s: %w", path, err)
+	}
+	db = db.WithContext(ctx)
+	defer func() {
+		if d, err := db.DB(); err == nil {
+			_ = d.Close() // ignore error
+		}
+	}()
+
+	var integrity string
+	if err := db.Raw("PRAGMA integrity_check").Scan(&integrity).Error; err != nil {
+		return fmt.Errorf("failed to check integrity of snapshot %s: %w", path, err)
+	}
+	if integrity != "ok" {
+		return fmt.Errorf("snapshot %s failed integrity check: %s", path, integrity)
+	}
+
+	var missing []string
+	for _, model := range coreModels {
+		table, err := tableName(db, model)
+		if err != nil {
+			return err
+		}
+		if !db.Migrator().HasTable(table) {
+			missing = append(missing, table)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("snapshot %s is missing tables: %s", path, strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// Restore validates the snapshot, then replaces the database at dbFile with it.
+// The database being replaced is kept alongside it and its path is returned.
+// If the restore fails, the database being replaced is left in place.
+// The bot must not be running while a restore is performed.
+func Restore(ctx context.Context, snapshot, dbFile string) (previous string, err error) {
+	if err := ValidateSnapshot(ctx, snapshot); err != nil {
+		return "", fmt.Errorf("snapshot is invalid: %w", err)
+	}
+
+	// The snapshot is copied next to the database first,
+	// so the database is only replaced once the copy is complete.
+	tmp, err := copyToTemp(snapshot, filepath.Dir(dbFile))
+	if err != nil {
+		return "", fmt.Errorf("failed to copy snapshot: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp) // ignore error
+		}
+	}()
+
+	// The write-ahead log and shared memory files belong to the current database,
+	// so they're moved along with it.
+	var moved []string
+	if _, err := os.Stat(dbFile); err == nil {
+		previous = dbFile + ".pre-restore-" + time.Now().UTC().Format(backupTimeFormat)
+		for _, suffix := range []string{"-wal", "-shm", ""} {
+			if err := os.Rename(dbFile+suffix, previous+suffix); err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					continue
+				}
+				return "", errors.Join(
+					fmt.Errorf("failed to move current database %s out of the way: %w", dbFile+suffix, err),
+					moveBack(moved, previous, dbFile))
+			}
+			moved = append(moved, suffix)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("failed to stat current database %s: %w", dbFile, err)
+	}
+
+	if err := os.Rename(tmp, dbFile); err != nil {
+		return "", errors.Join(
+			fmt.Errorf("failed to move snapshot into place: %w", err),
+			moveBack(moved, previous, dbFile))
+	}
+	return previous, nil
+}
+
+// moveBack moves the files of a database moved out of the way by Restore back into place.
+func moveBack(suffixes []string, previous, dbFile string) error {
+	var errs []error
+	for _, suffix := range suffixes {
+		if err := os.Rename(previous+suffix, dbFile+suffix); err != nil {
+			errs = append(errs, fmt.Errorf("failed to move previous database %s back: %w", previous+suffix, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// copyToTemp copies src to a new temporary file in dir and returns its path.
+func copyToTemp(src, dir string) (string, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", src, err)
+	}
+	defer func() { _ = in.Close() }() // ignore error
+
+	out, err := os.CreateTemp(dir, ".restore-*"+backupFileSuffix)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file in %s: %w", dir, err)
+	}
+	dst := out.Name()
+	fail := func(err error) (string, error) {
+		_ = out.Close()    // ignore error
+		_ = os.Remove(dst) // ignore error
+		return "", err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		return fail(fmt.Errorf("failed to copy %s to %s: %w", src, dst, err))
+	}
+	if err := out.Chmod(0o644); err != nil {
+		return fail(fmt.Errorf("failed to set permissions of %s: %w", dst, err))
+	}
+	if err := out.Sync(); err != nil {
+		return fail(fmt.Errorf("failed to sync %s: %w", dst, err))
+	}
+	if err := out.Close(); err != nil {
+		_ = os.Remove(dst) // ignore error
+		return "", fmt.Errorf("failed to close %s: %w", dst, err)
+	}
+	return dst, nil
+}
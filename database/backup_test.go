@@ -0,0 +1,182 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/airforce270/airbot/database"
+	"github.com/airforce270/airbot/database/databasetest"
+	"github.com/airforce270/airbot/database/models"
+)
+
+func TestBackupAndRotate(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	db := databasetest.New(t)
+	dir := t.TempDir()
+
+	var paths []string
+	for range 3 {
+		path, err := database.Backup(ctx, db, dir)
+		if err != nil {
+			t.Fatalf("Backup() unexpected error: %v", err)
+		}
+		paths = append(paths, path)
+	}
+
+	deleted, err := database.RotateBackups(dir, 2)
+	if err != nil {
+		t.Fatalf("RotateBackups() unexpected error: %v", err)
+	}
+	if len(deleted) != 1 || deleted[0] != paths[0] {
+		t.Errorf("RotateBackups() deleted %v, want [%s]", deleted, paths[0])
+	}
+
+	remaining, err := database.Backups(dir)
+	if err != nil {
+		t.Fatalf("Backups() unexpected error: %v", err)
+	}
+	if len(remaining) != 2 || remaining[0] != paths[1] || remaining[1] != paths[2] {
+		t.Errorf("Backups() = %v, want %v", remaining, paths[1:])
+	}
+}
+
+func TestStartBackingUp_ZeroInterval(t *testing.T) {
+	t.Parallel()
+	db := databasetest.New(t)
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		database.StartBackingUp(ctx, db, dir, 0, 0, slog.New(slog.DiscardHandler))
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("StartBackingUp() didn't return after context was cancelled")
+	}
+}
+
+func TestValidateSnapshot(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("valid", func(t *testing.T) {
+		t.Parallel()
+		db := databasetest.New(t)
+		path, err := database.Backup(ctx, db, t.TempDir())
+		if err != nil {
+			t.Fatalf("Backup() unexpected error: %v", err)
+		}
+		if err := database.ValidateSnapshot(ctx, path); err != nil {
+			t.Errorf("ValidateSnapshot() unexpected error: %v", err)
+		}
+	})
+
+	t.Run("special characters in path", func(t *testing.T) {
+		t.Parallel()
+		db := databasetest.New(t)
+		path, err := database.Backup(ctx, db, filepath.Join(t.TempDir(), "what?#100%"))
+		if err != nil {
+			t.Fatalf("Backup() unexpected error: %v", err)
+		}
+		if err := database.ValidateSnapshot(ctx, path); err != nil {
+			t.Errorf("ValidateSnapshot() unexpected error: %v", err)
+		}
+	})
+
+	t.Run("older schema", func(t *testing.T) {
+		t.Parallel()
+		db := databasetest.New(t)
+		// Tables added after the snapshot was taken are created by migrations after it's restored.
+		if err := db.Migrator().DropTable(&models.LiveNotification{}); err != nil {
+			t.Fatalf("Failed to drop table: %v", err)
+		}
+		path, err := database.Backup(ctx, db, t.TempDir())
+		if err != nil {
+			t.Fatalf("Backup() unexpected error: %v", err)
+		}
+		if err := database.ValidateSnapshot(ctx, path); err != nil {
+			t.Errorf("ValidateSnapshot() unexpected error: %v", err)
+		}
+	})
+
+	t.Run("missing core table", func(t *testing.T) {
+		t.Parallel()
+		db := databasetest.New(t)
+		if err := db.Migrator().DropTable(&models.JoinedChannel{}); err != nil {
+			t.Fatalf("Failed to drop table: %v", err)
+		}
+		path, err := database.Backup(ctx, db, t.TempDir())
+		if err != nil {
+			t.Fatalf("Backup() unexpected error: %v", err)
+		}
+		if err := database.ValidateSnapshot(ctx, path); err == nil {
+			t.Error("ValidateSnapshot() expected error, got nil")
+		}
+	})
+
+	t.Run("not a database", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "airbot-garbage.db")
+		if err := os.WriteFile(path, []byte("definitely not sqlite"), 0o644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		if err := database.ValidateSnapshot(ctx, path); err == nil {
+			t.Error("ValidateSnapshot() expected error, got nil")
+		}
+	})
+}
+
+func TestRestore(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	db := databasetest.New(t)
+	snapshot, err := database.Backup(ctx, db, t.TempDir())
+	if err != nil {
+		t.Fatalf("Backup() unexpected error: %v", err)
+	}
+
+	dir := t.TempDir()
+	dbFile := filepath.Join(dir, "sqlite.db")
+	if err := os.WriteFile(dbFile, []byte("old database"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.WriteFile(dbFile+"-wal", []byte("old log"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	previous, err := database.Restore(ctx, snapshot, dbFile)
+	if err != nil {
+		t.Fatalf("Restore() unexpected error: %v", err)
+	}
+
+	if got, err := os.ReadFile(previous); err != nil || string(got) != "old database" {
+		t.Errorf("previous database = %q (err: %v), want %q", got, err, "old database")
+	}
+	if got, err := os.ReadFile(previous + "-wal"); err != nil || string(got) != "old log" {
+		t.Errorf("previous database log = %q (err: %v), want %q", got, err, "old log")
+	}
+	if _, err := os.Stat(dbFile + "-wal"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Stat(%s) error = %v, want the previous database's log moved", dbFile+"-wal", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", dir, err)
+	}
+	if len(entries) != 3 {
+		t.Errorf("%s contains %d files, want 3 (restored and previous database, previous log)", dir, len(entries))
+	}
+	if err := database.ValidateSnapshot(ctx, dbFile); err != nil {
+		t.Errorf("restored database is invalid: %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+
+	"github.com/airforce270/airbot/database/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// copyBatchSize is the number of rows copied at a time.
+const copyBatchSize = 500
+
+// Copy copies all data from src to dst, i.e. to move from SQLite to PostgreSQL.
+// dst is migrated before copying and must not contain any data.
+// Rows keep their IDs, so references between them are preserved.
+// Progress is logged to logger.
+func Copy(ctx context.Context, src, dst *gorm.DB, logger *slog.Logger) error {
+	src, dst = src.WithContext(ctx), dst.WithContext(ctx)
+
+	if err := Migrate(dst); err != nil {
+		return fmt.Errorf("failed to migrate destination database: %w", err)
+	}
+
+	schemas, err := dependencyOrder(dst)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range schemas {
+		var count int64
+		if err := dst.Unscoped().Model(s.model).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to count rows in destination table %s: %w", s.Table, err)
+		}
+		if count > 0 {
+			return fmt.Errorf("destination table %s is not empty (%d rows)", s.Table, count)
+		}
+	}
+
+	for _, s := range schemas {
+		copied, err := copyTable(src, dst, s)
+		if err != nil {
+			return err
+		}
+		logger.Info("Copied rows", "table", s.Table, "rows", copied)
+	}
+
+	if dst.Dialector.Name() == PostgresDriverName {
+		for _, s := range schemas {
+			if err := resetSequence(dst, s); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// modelSchema is a data model along with its parsed schema.
+type modelSchema struct {
+	*schema.Schema
+	model any
+}
+
+// dependencyOrder returns the schemas of all data models,
+// ordered such that every model comes after the models it references.
+func dependencyOrder(db *gorm.DB) ([]modelSchema, error) {
+	remaining := make([]modelSchema, 0, len(models.AllModels))
+	for _, model := range models.AllModels {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, fmt.Errorf("failed to parse model %T: %w", model, err)
+		}
+		remaining = append(remaining, modelSchema{Schema: stmt.Schema, model: model})
+	}
+
+	ordered := make([]modelSchema, 0, len(remaining))
+	done := map[string]bool{}
+	for len(remaining) > 0 {
+		var next []modelSchema
+		for _, s := range remaining {
+			ready := true
+			for _, rel := range s.Relationships.BelongsTo {
+				if rel.FieldSchema.Table != s.Table && !done[rel.FieldSchema.Table] {
+					ready = false
+				}
+			}
+			if !ready {
+				next = append(next, s)
+				continue
+			}
+			ordered = append(ordered, s)
+			done[s.Table] = true
+		}
+		if len(next) == len(remaining) {
+			return nil, fmt.Errorf("models have circular references: %v", next)
+		}
+		remaining = next
+	}
+	return ordered, nil
+}
+
+// copyTable copies all rows of the model's table from src to dst.
+func copyTable(src, dst *gorm.DB, s modelSchema) (int, error) {
+	rows := reflect.New(reflect.SliceOf(reflect.TypeOf(s.model))).Interface()
+	writer := dst.Session(&gorm.Session{SkipHooks: true}).Omit(clause.Associations)
+
+	var copied int
+	err := src.Unscoped().Model(s.model).FindInBatches(rows, copyBatchSize, func(tx *gorm.DB, batch int) error {
+		if tx.RowsAffected == 0 {
+			return nil
+		}
+		if err := writer.Create(rows).Error; err != nil {
+			return fmt.Errorf("failed to write batch %d: %w", batch, err)
+		}
+		copied += int(tx.RowsAffected)
+		return nil
+	}).Error
+	if err != nil {
+		return copied, fmt.Errorf("failed to copy table %s: %w", s.Table, err)
+	}
+	return copied, nil
+}
+
+// resetSequence moves a PostgreSQL table's ID sequence past the copied rows,
+// since inserting rows with explicit IDs doesn't advance it.
+func resetSequence(db *gorm.DB, s modelSchema) error {
+	pk := s.PrioritizedPrimaryField
+	if pk == nil || !pk.AutoIncrement {
+		return nil
+	}
+	err := db.Exec(
+		fmt.Sprintf("SELECT setval(pg_get_serial_sequence(?, ?), MAX(%[1]s)) FROM %[2]s", db.Statement.Quote(pk.DBName), db.Statement.Quote(s.Table)),
+		s.Table, pk.DBName).Error
+	if err != nil {
+		return fmt.Errorf("failed to reset ID sequence for %s: %w", s.Table, err)
+	}
+	return nil
+}
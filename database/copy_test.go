@@ -0,0 +1,90 @@
+package database_test
+
+import (
+	"context"
+	"log"
+	"path/filepath"
+	"testing"
+
+	"github.com/airforce270/airbot/database"
+	"github.com/airforce270/airbot/database/databasetest"
+	"github.com/airforce270/airbot/database/models"
+	"github.com/airforce270/airbot/logging"
+
+	"gorm.io/gorm"
+)
+
+func TestCopy(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	src := databasetest.New(t)
+
+	var user1, user2 models.User
+	if err := src.First(&user1, "twitch_name = ?", "user1").Error; err != nil {
+		t.Fatalf("Failed to fetch user1: %v", err)
+	}
+	if err := src.First(&user2, "twitch_name = ?", "user2").Error; err != nil {
+		t.Fatalf("Failed to fetch user2: %v", err)
+	}
+	seed := []any{
+		&models.Duel{UserID: user1.ID, TargetID: user2.ID, Amount: 50, Pending: true},
+		&models.GambaTransaction{Game: "roulette", UserID: user2.ID, Delta: 100},
+		&models.CacheStringItem{Key: "key1", Value: "value1"},
+		&models.JoinedChannel{Platform: "Twitch", Channel: "user1"},
+	}
+	for _, row := range seed {
+		if err := src.Create(row).Error; err != nil {
+			t.Fatalf("Failed to seed %T: %v", row, err)
+		}
+	}
+	if err := src.Delete(&models.User{}, "twitch_name = ?", "user3").Error; err != nil {
+		t.Fatalf("Failed to soft-delete user3: %v", err)
+	}
+
+	dst := newFileDB(t)
+	if err := database.Copy(ctx, src, dst, logging.Discard()); err != nil {
+		t.Fatalf("Copy() unexpected error: %v", err)
+	}
+
+	var duel models.Duel
+	if err := dst.Preload("User").Preload("Target").First(&duel).Error; err != nil {
+		t.Fatalf("Failed to fetch copied duel: %v", err)
+	}
+	if duel.User.TwitchName != "user1" || duel.Target.TwitchName != "user2" || duel.Amount != 50 {
+		t.Errorf("copied duel = %s vs %s for %d, want user1 vs user2 for 50", duel.User.TwitchName, duel.Target.TwitchName, duel.Amount)
+	}
+
+	var userCount int64
+	if err := dst.Unscoped().Model(&models.User{}).Count(&userCount).Error; err != nil {
+		t.Fatalf("Failed to count copied users: %v", err)
+	}
+	if userCount != 3 {
+		t.Errorf("copied %d users, want 3 (including soft-deleted)", userCount)
+	}
+
+	var item models.CacheStringItem
+	if err := dst.First(&item, "key = ?", "key1").Error; err != nil || item.Value != "value1" {
+		t.Errorf("copied cache item = %q (err: %v), want %q", item.Value, err, "value1")
+	}
+
+	// New rows must not collide with copied IDs.
+	if err := dst.Create(&models.User{TwitchID: "user4", TwitchName: "user4"}).Error; err != nil {
+		t.Errorf("Failed to create user after copy: %v", err)
+	}
+
+	if err := database.Copy(ctx, src, dst, logging.Discard()); err == nil {
+		t.Error("Copy() into non-empty database expected error, got nil")
+	}
+}
+
+// newFileDB creates a new, empty SQLite database in a temporary directory.
+func newFileDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	db, err := database.Connect(ctx, log.Default(), database.SQLite{File: filepath.Join(t.TempDir(), "sqlite.db")})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	return db
+}
@@ -9,70 +9,101 @@ import (
 
 	"github.com/airforce270/airbot/database/models"
 
-	"github.com/glebarez/sqlite"
 	"gorm.io/gorm"
 )
 
-var pragmas = map[string]string{
-	"journal_mode": "WAL",
-	"synchronous":  "NORMAL",
-	"foreign_keys": "ON",
-
-	"user_version": "ON",
-
-	"temp_store": "2",
-	"cache_size": "-32000",
-}
-
-// Connect creates a connection to the database.
-func Connect(ctx context.Context, logger *log.Logger, dbFile string) (*gorm.DB, error) {
-	gormDB, err := gorm.Open(sqlite.Open(dbFile + formatPragmas(pragmas)))
+// Connect creates a connection to the database using the given driver.
+func Connect(ctx context.Context, logger *log.Logger, driver Driver) (*gorm.DB, error) {
+	gormDB, err := gorm.Open(driver.Dialector())
 	if err != nil {
-		return nil, fmt.Errorf("failed to open DB connection: %w", err)
+		return nil, fmt.Errorf("failed to open %s DB connection: %w", driver.Name(), err)
 	}
 	gormDB.WithContext(ctx)
 
-	context.AfterFunc(ctx, func() {
-		if err := close(gormDB); err != nil {
-			logger.Printf("failed to close DB cleanly: %v", err)
-		}
-	})
-
 	db, err := gormDB.DB()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get sql.DB handle: %w", err)
 	}
 	db.SetMaxOpenConns(100)
 
+	context.AfterFunc(ctx, func() {
+		if err := driver.BeforeClose(db); err != nil {
+			logger.Printf("failed to close DB cleanly: %v", err)
+		}
+	})
+
 	return gormDB, nil
 }
 
-func close(db *gorm.DB) error {
-	d, err := db.DB()
+// Close performs any driver-specific maintenance, then closes the connection to the database.
+func Close(db *gorm.DB, driver Driver) error {
+	sqlDB, err := db.DB()
 	if err != nil {
-		return fmt.Errorf("failed to get DB handle: %w", err)
+		return fmt.Errorf("failed to get sql.DB handle: %w", err)
 	}
-
-	if _, err := d.Exec("PRAGMA analysis_limit = 400;"); err != nil {
-		return fmt.Errorf("failed to set analysis_limit: %w", err)
+	if err := driver.BeforeClose(sqlDB); err != nil {
+		return fmt.Errorf("failed to prepare %s DB to close: %w", driver.Name(), err)
 	}
-	if _, err := d.Exec("PRAGMA optimize;"); err != nil {
-		return fmt.Errorf("failed to run optimize: %w", err)
+	if err := sqlDB.Close(); err != nil {
+		return fmt.Errorf("failed to close %s DB connection: %w", driver.Name(), err)
 	}
-
 	return nil
 }
 
-// Migrate performs GORM auto-migrations for all data models.
+// Migrate performs GORM auto-migrations for all data models,
+// along with any data migrations they require.
 func Migrate(db *gorm.DB) error {
+	for _, m := range preAutoMigrations {
+		if err := m.F(db); err != nil {
+			return fmt.Errorf("failed to run migration %q: %w", m.Name, err)
+		}
+	}
 	for _, model := range models.AllModels {
 		if err := db.AutoMigrate(&model); err != nil {
 			return fmt.Errorf("failed to migrate %+v: %w", model, err)
 		}
 	}
+	for _, m := range postAutoMigrations {
+		if err := m.F(db); err != nil {
+			return fmt.Errorf("failed to run migration %q: %w", m.Name, err)
+		}
+	}
 	return nil
 }
 
+// migration is a data migration.
+// Migrations must be idempotent, as they're run on every startup.
+type migration struct {
+	// Name is the migration's human-readable name.
+	Name string
+	// F performs the migration.
+	F func(db *gorm.DB) error
+}
+
+var (
+	// preAutoMigrations are run before GORM auto-migrations.
+	preAutoMigrations = []migration{
+		{Name: "drop command cooldown tables", F: dropCommandCooldowns},
+	}
+	// postAutoMigrations are run after GORM auto-migrations.
+	postAutoMigrations = []migration{
+		{Name: "backfill lowercased twitch names", F: backfillTwitchNameLower},
+		{Name: "create message search index", F: createMessagesFTS},
+	}
+)
+
+// dropCommandCooldowns drops the tables cooldowns were stored in
+// before they were kept in memory.
+func dropCommandCooldowns(db *gorm.DB) error {
+	return db.Migrator().DropTable("channel_command_cooldowns", "user_command_cooldowns")
+}
+
+// backfillTwitchNameLower populates the lowercased name column
+// for users created before it existed.
+func backfillTwitchNameLower(db *gorm.DB) error {
+	return db.Exec("UPDATE users SET twitch_name_lower = LOWER(twitch_name) WHERE (twitch_name_lower IS NULL OR twitch_name_lower = '') AND twitch_name <> ''").Error
+}
+
 func LeaveChannel(db *gorm.DB, platformName, channel string) error {
 	var channels []models.JoinedChannel
 	err := db.Where(models.JoinedChannel{Platform: platformName, Channel: strings.ToLower(channel)}).Find(&channels).Error
@@ -91,20 +122,3 @@ func LeaveChannel(db *gorm.DB, platformName, channel string) error {
 	}
 	return nil
 }
-
-func formatPragmas(ps map[string]string) string {
-	var out strings.Builder
-
-	var i int
-	for p, v := range ps {
-		if i == 0 {
-			out.WriteString("?")
-		} else {
-			out.WriteString("&")
-		}
-		fmt.Fprintf(&out, "_pragma=%s(%s)", p, v)
-		i++
-	}
-
-	return out.String()
-}
@@ -0,0 +1,172 @@
+package database_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/airforce270/airbot/database"
+	"github.com/airforce270/airbot/database/databasetest"
+	"github.com/airforce270/airbot/database/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	benchUsers     = 20_000
+	benchMessages  = 200_000
+	benchChannels  = 500
+	benchLookupKey = benchUsers / 2
+)
+
+func TestMigrate_BackfillsTwitchNameLower(t *testing.T) {
+	t.Parallel()
+	db := databasetest.New(t)
+
+	if err := db.Exec("INSERT INTO users (twitch_id, twitch_name, twitch_name_lower) VALUES ('legacy', 'LegacyUser', '')").Error; err != nil {
+		t.Fatalf("Failed to insert legacy user: %v", err)
+	}
+
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("Migrate() unexpected error: %v", err)
+	}
+
+	var user models.User
+	if err := db.First(&user, "twitch_id = ?", "legacy").Error; err != nil {
+		t.Fatalf("Failed to fetch legacy user: %v", err)
+	}
+	if got, want := user.TwitchNameLower, "legacyuser"; got != want {
+		t.Errorf("TwitchNameLower = %q, want %q", got, want)
+	}
+}
+
+func TestClose(t *testing.T) {
+	t.Parallel()
+	db := newFileDB(t)
+
+	if err := database.Close(db, database.SQLite{}); err != nil {
+		t.Fatalf("Close() unexpected error: %v", err)
+	}
+	if err := db.Exec("SELECT 1").Error; err == nil {
+		t.Error("query after Close() expected error, got nil")
+	}
+}
+
+func TestMigrate_DropsCommandCooldowns(t *testing.T) {
+	t.Parallel()
+	db := databasetest.New(t)
+
+	tables := []string{"channel_command_cooldowns", "user_command_cooldowns"}
+	for _, table := range tables {
+		if err := db.Exec("CREATE TABLE " + table + " (id integer PRIMARY KEY, command text)").Error; err != nil {
+			t.Fatalf("Failed to create %s: %v", table, err)
+		}
+	}
+
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("Migrate() unexpected error: %v", err)
+	}
+
+	for _, table := range tables {
+		if db.Migrator().HasTable(table) {
+			t.Errorf("%s exists after Migrate(), want it dropped", table)
+		}
+	}
+}
+
+func BenchmarkUserLookupByName(b *testing.B) {
+	for _, indexed := range []bool{true, false} {
+		b.Run(indexLabel(indexed), func(b *testing.B) {
+			db := newBenchDB(b, indexed)
+			name := fmt.Sprintf("USER%d", benchLookupKey)
+			lowerName := strings.ToLower(name)
+			b.ResetTimer()
+			for b.Loop() {
+				var user models.User
+				var err error
+				if indexed {
+					err = db.Where("twitch_name_lower = ?", lowerName).Limit(1).Find(&user).Error
+				} else {
+					err = db.Where("LOWER(twitch_name) = LOWER(?)", name).Limit(1).Find(&user).Error
+				}
+				if err != nil || user.ID == 0 {
+					b.Fatalf("lookup failed: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkRecentActiveUserIDs(b *testing.B) {
+	for _, indexed := range []bool{true, false} {
+		b.Run(indexLabel(indexed), func(b *testing.B) {
+			db := newBenchDB(b, indexed)
+			since := benchStart.Add(time.Duration(benchMessages-1_000) * time.Second)
+			b.ResetTimer()
+			for b.Loop() {
+				var messages []models.Message
+				err := db.Select("user_id").Distinct("user_id").Where("time > ?", since).Find(&messages).Error
+				if err != nil || len(messages) == 0 {
+					b.Fatalf("query failed (%d results): %v", len(messages), err)
+				}
+			}
+		})
+	}
+}
+
+// benchStart is the time of the first seeded message.
+var benchStart = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// newBenchDB creates a large synthetic database for benchmarking.
+// If indexed is false, the indexes on hot-path columns are dropped.
+func newBenchDB(b *testing.B, indexed bool) *gorm.DB {
+	b.Helper()
+	db := databasetest.New(b)
+
+	seed := []string{
+		fmt.Sprintf(`INSERT INTO users (created_at, updated_at, twitch_id, twitch_name, twitch_name_lower)
+			WITH RECURSIVE seq(n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM seq WHERE n < %d)
+			SELECT CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, 'id' || n, 'User' || n, 'user' || n FROM seq`, benchUsers),
+		fmt.Sprintf(`INSERT INTO messages (created_at, updated_at, text, channel, user_id, time)
+			WITH RECURSIVE seq(n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM seq WHERE n < %d)
+			SELECT CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, 'message ' || n, 'channel' || (n %% %d), 1 + (n %% %d), datetime('%s', '+' || n || ' seconds') FROM seq`,
+			benchMessages, benchChannels, benchUsers, benchStart.Format("2006-01-02 15:04:05")),
+	}
+	// Seeding is expected to be slow, don't log it.
+	quiet := db.Session(&gorm.Session{Logger: logger.Discard})
+	for _, stmt := range seed {
+		if err := quiet.Exec(stmt).Error; err != nil {
+			b.Fatalf("Failed to seed benchmark DB: %v", err)
+		}
+	}
+
+	if !indexed {
+		indexes := []struct {
+			model any
+			name  string
+		}{
+			{&models.User{}, "idx_users_twitch_name_lower"},
+			{&models.Message{}, "idx_messages_time_user_id"},
+		}
+		for _, idx := range indexes {
+			if err := db.Migrator().DropIndex(idx.model, idx.name); err != nil {
+				b.Fatalf("Failed to drop index %s: %v", idx.name, err)
+			}
+		}
+	}
+
+	if err := quiet.Exec("ANALYZE").Error; err != nil {
+		b.Fatalf("Failed to analyze benchmark DB: %v", err)
+	}
+
+	return db
+}
+
+func indexLabel(indexed bool) string {
+	if indexed {
+		return "indexed"
+	}
+	return "unindexed"
+}
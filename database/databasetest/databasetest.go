@@ -13,11 +13,11 @@ import (
 )
 
 // New creates a new in-memory database for testing.
-func New(t *testing.T) *gorm.DB {
+func New(t testing.TB) *gorm.DB {
 	t.Helper()
 	ctx := context.TODO()
 
-	db, err := database.Connect(ctx, log.Default(), ":memory:")
+	db, err := database.Connect(ctx, log.Default(), database.SQLite{File: ":memory:"})
 	if err != nil {
 		t.Fatalf("Failed to create new in-memory DB: %v", err)
 	}
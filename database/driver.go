@@ -0,0 +1,115 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/airforce270/airbot/config"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+const (
+	// SQLiteDriverName is the name of the SQLite driver, as used in the config.
+	SQLiteDriverName = "sqlite"
+	// PostgresDriverName is the name of the PostgreSQL driver, as used in the config.
+	PostgresDriverName = "postgres"
+)
+
+// Driver is a database driver.
+type Driver interface {
+	// Name returns the driver's name, as used in the config.
+	Name() string
+	// Dialector returns the GORM dialector used to open a connection.
+	Dialector() gorm.Dialector
+	// BeforeClose performs any driver-specific maintenance
+	// that should be done before the connection is closed.
+	BeforeClose(db *sql.DB) error
+}
+
+// NewDriver returns the driver selected by the config.
+// sqliteFile is the path to the SQLite database file, if SQLite is used.
+func NewDriver(cfg config.DatabaseConfig, sqliteFile string) (Driver, error) {
+	switch strings.ToLower(cfg.Driver) {
+	case "", SQLiteDriverName:
+		return SQLite{File: sqliteFile}, nil
+	case PostgresDriverName:
+		if cfg.PostgresDSN == "" {
+			return nil, fmt.Errorf("database driver %s requires postgres_dsn to be set", PostgresDriverName)
+		}
+		return Postgres{DSN: cfg.PostgresDSN}, nil
+	default:
+		return nil, fmt.Errorf("unknown database driver %q (must be %s or %s)", cfg.Driver, SQLiteDriverName, PostgresDriverName)
+	}
+}
+
+// SQLite is a driver for a SQLite database.
+type SQLite struct {
+	// File is the path to the database file, or ":memory:".
+	File string
+}
+
+var sqlitePragmas = map[string]string{
+	"journal_mode": "WAL",
+	"synchronous":  "NORMAL",
+	"foreign_keys": "ON",
+
+	"user_version": "ON",
+
+	"temp_store": "2",
+	"cache_size": "-32000",
+}
+
+func (d SQLite) Name() string { return SQLiteDriverName }
+
+func (d SQLite) Dialector() gorm.Dialector {
+	return sqlite.Open(d.File + formatPragmas(sqlitePragmas))
+}
+
+func (d SQLite) BeforeClose(db *sql.DB) error {
+	if _, err := db.Exec("PRAGMA analysis_limit = 400;"); err != nil {
+		return fmt.Errorf("failed to set analysis_limit: %w", err)
+	}
+	if _, err := db.Exec("PRAGMA optimize;"); err != nil {
+		return fmt.Errorf("failed to run optimize: %w", err)
+	}
+	return nil
+}
+
+// Postgres is a driver for a PostgreSQL database.
+type Postgres struct {
+	// DSN is the connection string for the database,
+	// i.e. "host=localhost user=airbot password=airbot dbname=airbot port=5432 sslmode=disable"
+	DSN string
+}
+
+func (d Postgres) Name() string { return PostgresDriverName }
+
+func (d Postgres) Dialector() gorm.Dialector { return postgres.Open(d.DSN) }
+
+func (d Postgres) BeforeClose(db *sql.DB) error { return nil }
+
+// isSQLite returns whether the connection is to a SQLite database.
+func isSQLite(db *gorm.DB) bool {
+	return db.Dialector.Name() == SQLiteDriverName
+}
+
+func formatPragmas(ps map[string]string) string {
+	var out strings.Builder
+
+	var i int
+	for p, v := range ps {
+		if i == 0 {
+			out.WriteString("?")
+		} else {
+			out.WriteString("&")
+		}
+		fmt.Fprintf(&out, "_pragma=%s(%s)", p, v)
+		i++
+	}
+
+	return out.String()
+}
@@ -0,0 +1,57 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/airforce270/airbot/config"
+	"github.com/airforce270/airbot/database"
+)
+
+func TestNewDriver(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		desc    string
+		cfg     config.DatabaseConfig
+		want    database.Driver
+		wantErr bool
+	}{
+		{
+			desc: "default",
+			cfg:  config.DatabaseConfig{},
+			want: database.SQLite{File: "sqlite.db"},
+		},
+		{
+			desc: "sqlite",
+			cfg:  config.DatabaseConfig{Driver: "sqlite"},
+			want: database.SQLite{File: "sqlite.db"},
+		},
+		{
+			desc: "postgres",
+			cfg:  config.DatabaseConfig{Driver: "Postgres", PostgresDSN: "host=localhost"},
+			want: database.Postgres{DSN: "host=localhost"},
+		},
+		{
+			desc:    "postgres without dsn",
+			cfg:     config.DatabaseConfig{Driver: "postgres"},
+			wantErr: true,
+		},
+		{
+			desc:    "unknown",
+			cfg:     config.DatabaseConfig{Driver: "mysql"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			got, err := database.NewDriver(tc.cfg, "sqlite.db")
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("NewDriver() error = %v, wantErr %t", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("NewDriver() = %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+}
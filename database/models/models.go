@@ -2,23 +2,93 @@
 package models
 
 import (
+	"strings"
 	"time"
 
+	"github.com/airforce270/airbot/permission"
 	"gorm.io/gorm"
 )
 
 // AllModels contains one of each defined data model, for auto-migrations.
 var AllModels = []any{
+	AutomodRule{},
+	AutomodSettings{},
 	BotBan{},
 	CacheBoolItem{},
 	CacheStringItem{},
-	ChannelCommandCooldown{},
+	ChatBan{},
+	CommandInvocation{},
+	DeletedMessage{},
 	Duel{},
+	EventHook{},
 	GambaTransaction{},
 	JoinedChannel{},
+	LiveNotification{},
 	Message{},
+	OAuthToken{},
 	User{},
-	UserCommandCooldown{},
+}
+
+// Kinds of automod rules.
+const (
+	// AutomodRulePhrase matches messages containing a phrase, ignoring case.
+	AutomodRulePhrase = "phrase"
+	// AutomodRuleRegex matches messages matching a regular expression.
+	AutomodRuleRegex = "regex"
+	// AutomodRuleCaps matches messages with at least a percentage of capital letters.
+	AutomodRuleCaps = "caps"
+	// AutomodRuleSymbols matches messages with at least a percentage of symbols.
+	AutomodRuleSymbols = "symbols"
+	// AutomodRuleEmotes matches messages repeating the same word or emote at least a number of times.
+	AutomodRuleEmotes = "emotes"
+	// AutomodRuleRepeat matches a user sending the same message at least a number of times in a row.
+	AutomodRuleRepeat = "repeat"
+	// AutomodRuleLinks matches messages containing links, unless the user was permitted to post one.
+	AutomodRuleLinks = "links"
+	// AutomodRuleZalgo matches messages with at least a number of combining marks (zalgo text).
+	AutomodRuleZalgo = "zalgo"
+)
+
+// Actions taken when a message breaks an automod rule.
+const (
+	// AutomodActionDelete deletes the message.
+	AutomodActionDelete = "delete"
+	// AutomodActionTimeout times the user out, for longer each time they break a rule.
+	AutomodActionTimeout = "timeout"
+	// AutomodActionWarn warns the user in chat.
+	AutomodActionWarn = "warn"
+)
+
+// AutomodRule is a rule messages in a channel are checked against.
+type AutomodRule struct {
+	gorm.Model
+
+	// Platform is the platform the rule is on.
+	Platform string `gorm:"uniqueIndex:idx_automod_rules_platform_channel_kind_value"`
+	// Channel is the channel the rule applies to.
+	Channel string `gorm:"uniqueIndex:idx_automod_rules_platform_channel_kind_value"`
+	// Kind is the kind of rule, one of the AutomodRule constants.
+	Kind string `gorm:"uniqueIndex:idx_automod_rules_platform_channel_kind_value"`
+	// Value is the phrase or pattern of phrase and regex rules,
+	// or the threshold of other rules.
+	// It's empty for link rules.
+	Value string `gorm:"uniqueIndex:idx_automod_rules_platform_channel_kind_value"`
+	// Action is what's done when a message breaks the rule, one of the AutomodAction constants.
+	Action string
+	// CreatedBy is the name of the user that set the rule.
+	CreatedBy string
+}
+
+// AutomodSettings contains a channel's automod settings.
+type AutomodSettings struct {
+	gorm.Model
+
+	// Platform is the platform the channel is on.
+	Platform string `gorm:"uniqueIndex:idx_automod_settings_platform_channel"`
+	// Channel is the channel the settings apply to.
+	Channel string `gorm:"uniqueIndex:idx_automod_settings_platform_channel"`
+	// ExemptLevel is the lowest permission level that isn't checked against automod rules.
+	ExemptLevel permission.Level
 }
 
 // BotBan represents a bot being banned from a channel.
@@ -61,16 +131,88 @@ type CacheStringItem struct {
 	ExpiresAt time.Time
 }
 
-// ChannelCommandCooldown contains a record of a command cooldown in a channel.
-type ChannelCommandCooldown struct {
+// ChatBan is a user being banned or timed out from a channel.
+type ChatBan struct {
 	gorm.Model
 
-	// Channel is the channel the command has a cooldown in.
+	// Platform is the platform the channel is on.
+	Platform string `gorm:"index:idx_chat_bans_platform_channel_time,priority:1"`
+	// Channel is the channel the user was banned from.
+	Channel string `gorm:"index:idx_chat_bans_platform_channel_time,priority:2"`
+	// UserID is the ID of the user that was banned.
+	UserID uint
+	// User is the user that was banned.
+	User User
+	// Duration is how long the user was timed out for, or 0 if they were banned.
+	Duration time.Duration
+	// Time is when the user was banned.
+	Time time.Time `gorm:"index:idx_chat_bans_platform_channel_time,priority:3"`
+}
+
+// Outcomes of command invocations.
+const (
+	// CommandOutcomeOK means the command ran successfully.
+	CommandOutcomeOK = "ok"
+	// CommandOutcomeBadUsage means the command was run with invalid arguments.
+	CommandOutcomeBadUsage = "bad usage"
+	// CommandOutcomeDenied means the user didn't have permission to run the command.
+	CommandOutcomeDenied = "denied"
+	// CommandOutcomeCooldown means the command wasn't run because it was on cooldown.
+	CommandOutcomeCooldown = "cooldown"
+	// CommandOutcomeRateLimited means the command wasn't run because the user had run too many commands recently.
+	CommandOutcomeRateLimited = "rate limited"
+	// CommandOutcomeError means the command failed.
+	CommandOutcomeError = "error"
+)
+
+// CommandInvocation contains a record of a user trying to run a command.
+type CommandInvocation struct {
+	gorm.Model
+
+	// Platform is the platform the command was run on.
+	Platform string
+	// Channel is the channel the command was run in.
+	Channel string
+	// UserID is the ID of the user that ran the command, if they're known.
+	UserID *uint `gorm:"index"`
+	// User is the user that ran the command, if they're known.
+	User *User
+	// Username is the name of the user that ran the command.
+	Username string
+	// Command is the name of the command.
+	Command string `gorm:"index"`
+	// Args contains the arguments the command was run with.
+	Args string
+	// PermissionLevel is the name of the user's permission level.
+	PermissionLevel string
+	// Outcome is the outcome of the invocation, one of the CommandOutcome constants.
+	Outcome string
+	// Duration is how long the command took to run.
+	Duration time.Duration
+	// ErrorID is the ID of the error logged if the command failed.
+	ErrorID string
+	// Time is when the command was run.
+	Time time.Time `gorm:"index"`
+}
+
+// DeletedMessage is a chat message deleted by a moderator.
+type DeletedMessage struct {
+	gorm.Model
+
+	// Platform is the platform the channel is on.
+	Platform string
+	// Channel is the channel the message was sent in.
 	Channel string
-	// Command is the name of the command with a cooldown.
-	Command string
-	// LastRun is when the command was last run in the channel.
-	LastRun time.Time
+	// UserID is the ID of the user that sent the message.
+	UserID uint
+	// User is the user that sent the message.
+	User User
+	// MessageID is the platform's ID of the message.
+	MessageID string
+	// Text is the text of the message.
+	Text string
+	// Time is when the message was deleted.
+	Time time.Time
 }
 
 // Duel represents a gamba duel.
@@ -95,6 +237,26 @@ type Duel struct {
 	Won bool
 }
 
+// EventHook is a message sent when an event (i.e. a raid) happens in a channel.
+type EventHook struct {
+	gorm.Model
+
+	// Platform is the platform the hook is on.
+	Platform string `gorm:"uniqueIndex:idx_event_hooks_platform_channel_source_event"`
+	// Channel is the channel the message is sent to.
+	Channel string `gorm:"uniqueIndex:idx_event_hooks_platform_channel_source_event"`
+	// Source is the channel whose events trigger the hook.
+	// It's usually the same as Channel, but may be another channel,
+	// i.e. to announce when it goes live.
+	Source string `gorm:"uniqueIndex:idx_event_hooks_platform_channel_source_event;index"`
+	// Event is the kind of event that triggers the hook.
+	Event string `gorm:"uniqueIndex:idx_event_hooks_platform_channel_source_event"`
+	// Template is the message sent, with placeholders (i.e. {user}) filled in.
+	Template string
+	// CreatedBy is the name of the user that set the hook.
+	CreatedBy string
+}
+
 // GambaTransaction represents a single gamba transaction.
 type GambaTransaction struct {
 	gorm.Model
@@ -125,6 +287,34 @@ type JoinedChannel struct {
 	JoinedAt time.Time
 }
 
+// Platforms streams can be on, for live notifications.
+const (
+	// StreamPlatformTwitch is a stream on Twitch.
+	StreamPlatformTwitch = "Twitch"
+	// StreamPlatformKick is a stream on Kick.
+	StreamPlatformKick = "Kick"
+)
+
+// LiveNotification is a user's request to be notified when a stream goes live.
+type LiveNotification struct {
+	gorm.Model
+
+	// Platform is the platform the user is notified on.
+	Platform string `gorm:"uniqueIndex:idx_live_notifications_platform_user_stream"`
+	// Channel is the channel the user is pinged in, if they aren't whispered.
+	Channel string
+	// UserID is the ID of the user on the platform.
+	UserID string `gorm:"uniqueIndex:idx_live_notifications_platform_user_stream"`
+	// Username is the name of the user.
+	Username string
+	// StreamPlatform is the platform the stream is on, one of the StreamPlatform constants.
+	StreamPlatform string `gorm:"uniqueIndex:idx_live_notifications_platform_user_stream"`
+	// Streamer is the lowercased name of the channel the stream is on.
+	Streamer string `gorm:"uniqueIndex:idx_live_notifications_platform_user_stream"`
+	// Whisper is whether the user is whispered rather than pinged in Channel.
+	Whisper bool
+}
+
 // Message represents a chat message.
 type Message struct {
 	gorm.Model
@@ -135,13 +325,36 @@ type Message struct {
 	// (or should be sent in).
 	Channel string
 	// UserID is the ID of the user that sent the message.
-	UserID uint
+	UserID uint `gorm:"index:idx_messages_time_user_id,priority:2"`
 	// User is the username of the user that sent the message.
 	User User
 	// Time is when the message was sent.
-	Time time.Time
+	Time time.Time `gorm:"index:idx_messages_time_user_id,priority:1"`
 }
 
+// OAuthToken is an OAuth token refreshed by the bot, encrypted at rest.
+type OAuthToken struct {
+	gorm.Model
+
+	// Platform is the platform the token is for.
+	Platform string `gorm:"uniqueIndex:idx_oauth_tokens_platform_username"`
+	// Username is the name of the bot account the token is for.
+	Username string `gorm:"uniqueIndex:idx_oauth_tokens_platform_username"`
+	// AccessToken is the encrypted access token.
+	AccessToken string
+	// RefreshToken is the encrypted refresh token.
+	RefreshToken string
+	// ConfigTokenHash is a hash of the access token in the config
+	// when the token was first refreshed from it.
+	// If the config's token changes, it's used instead of this one.
+	ConfigTokenHash string
+}
+
+// DeletedUserName is the name of the placeholder user that rows shared with
+// forgotten users, such as duels, reference instead of them.
+// It isn't a valid username on any platform.
+const DeletedUserName = "[deleted user]"
+
 // User represents a user.
 type User struct {
 	gorm.Model
@@ -150,18 +363,14 @@ type User struct {
 	TwitchID string
 	// TwitchName is the user's username on Twitch, if known
 	TwitchName string
+	// TwitchNameLower is TwitchName, lowercased.
+	// It is set automatically when the user is saved and is used for
+	// case-insensitive lookups.
+	TwitchNameLower string `gorm:"index"`
 }
 
-// UserCommandCooldown contains a record of a command cooldown for a user.
-type UserCommandCooldown struct {
-	gorm.Model
-
-	// UserID is the ID of the user with the cooldown.
-	UserID uint
-	// User is the user with the cooldown.
-	User User
-	// Command is the name of the command with a cooldown.
-	Command string
-	// LastRun is when the command was last run in the channel.
-	LastRun time.Time
+// BeforeSave keeps TwitchNameLower in sync with TwitchName.
+func (u *User) BeforeSave(tx *gorm.DB) error {
+	u.TwitchNameLower = strings.ToLower(u.TwitchName)
+	return nil
 }
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/airforce270/airbot/database/models"
+
+	"gorm.io/gorm"
+)
+
+// messagesFTSTable is the name of the SQLite FTS5 index over message text.
+const messagesFTSTable = "messages_fts"
+
+// createMessagesFTS creates the full-text search index over message text
+// and the triggers that keep it in sync with the messages table.
+// It is only created for SQLite databases.
+func createMessagesFTS(db *gorm.DB) error {
+	if !isSQLite(db) {
+		return nil
+	}
+
+	exists := db.Migrator().HasTable(messagesFTSTable)
+	stmts := []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS messages_fts USING fts5(text, content='messages', content_rowid='id')`,
+		`CREATE TRIGGER IF NOT EXISTS messages_fts_insert AFTER INSERT ON messages BEGIN
+			INSERT INTO messages_fts(rowid, text) VALUES (new.id, new.text);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS messages_fts_delete AFTER DELETE ON messages BEGIN
+			INSERT INTO messages_fts(messages_fts, rowid, text) VALUES ('delete', old.id, old.text);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS messages_fts_update AFTER UPDATE OF text ON messages BEGIN
+			INSERT INTO messages_fts(messages_fts, rowid, text) VALUES ('delete', old.id, old.text);
+			INSERT INTO messages_fts(rowid, text) VALUES (new.id, new.text);
+		END`,
+	}
+	for _, stmt := range stmts {
+		if err := db.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+
+	if !exists {
+		// Index messages stored before the index existed.
+		if err := db.Exec("INSERT INTO messages_fts(messages_fts) VALUES ('rebuild')").Error; err != nil {
+			return fmt.Errorf("failed to build index: %w", err)
+		}
+	}
+	return nil
+}
+
+// SearchMessages searches for messages in a channel containing text,
+// returning up to limit of the most recent matches along with the total number of matches.
+// SQLite databases use the full-text search index, which matches whole words;
+// other databases match any substring.
+func SearchMessages(db *gorm.DB, channel, text string, limit int) (matches []models.Message, total int64, err error) {
+	query := db.Model(&models.Message{}).Where("messages.channel = ?", strings.ToLower(channel))
+	if isSQLite(db) {
+		// Quote the text so it's searched for as a phrase rather than parsed as a query.
+		phrase := `"` + strings.ReplaceAll(text, `"`, `""`) + `"`
+		query = query.Joins("JOIN messages_fts ON messages_fts.rowid = messages.id").Where("messages_fts MATCH ?", phrase)
+	} else {
+		escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(text)
+		query = query.Where("messages.text ILIKE ?", "%"+escaped+"%")
+	}
+
+	query = query.Session(&gorm.Session{})
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count messages matching %q in %s: %w", text, channel, err)
+	}
+	if err := query.Preload("User").Order("messages.time DESC").Limit(limit).Find(&matches).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to search messages matching %q in %s: %w", text, channel, err)
+	}
+	return matches, total, nil
+}
@@ -0,0 +1,135 @@
+package database_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/airforce270/airbot/database"
+	"github.com/airforce270/airbot/database/databasetest"
+	"github.com/airforce270/airbot/database/models"
+)
+
+func TestSearchMessages(t *testing.T) {
+	t.Parallel()
+	db := databasetest.New(t)
+
+	var user models.User
+	if err := db.First(&user, "twitch_name = ?", "user1").Error; err != nil {
+		t.Fatalf("Failed to fetch user1: %v", err)
+	}
+	start := time.Date(2020, 5, 15, 10, 0, 0, 0, time.UTC)
+	messages := []*models.Message{
+		{Text: "the quick brown fox", Channel: "user2", UserID: user.ID, Time: start},
+		{Text: "a slow brown dog", Channel: "user2", UserID: user.ID, Time: start.Add(time.Minute)},
+		{Text: "brown fox again", Channel: "user3", UserID: user.ID, Time: start.Add(2 * time.Minute)},
+		{Text: "quick \"brown\" fox", Channel: "user2", UserID: user.ID, Time: start.Add(3 * time.Minute)},
+	}
+	for _, m := range messages {
+		if err := db.Create(m).Error; err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+	}
+	// Edits and deletions must be reflected in the index.
+	if err := db.Model(messages[0]).Update("text", "the quick red fox").Error; err != nil {
+		t.Fatalf("Failed to update message: %v", err)
+	}
+	if err := db.Unscoped().Delete(messages[3]).Error; err != nil {
+		t.Fatalf("Failed to delete message: %v", err)
+	}
+
+	tests := []struct {
+		desc      string
+		channel   string
+		text      string
+		wantTexts []string
+		wantTotal int64
+	}{
+		{
+			desc:      "single word",
+			channel:   "user2",
+			text:      "brown",
+			wantTexts: []string{"a slow brown dog"},
+			wantTotal: 1,
+		},
+		{
+			desc:      "phrase",
+			channel:   "user2",
+			text:      "quick red",
+			wantTexts: []string{"the quick red fox"},
+			wantTotal: 1,
+		},
+		{
+			desc:      "other channel",
+			channel:   "USER3",
+			text:      "fox",
+			wantTexts: []string{"brown fox again"},
+			wantTotal: 1,
+		},
+		{
+			desc:      "query syntax is not interpreted",
+			channel:   "user2",
+			text:      `brown" OR "fox`,
+			wantTotal: 0,
+		},
+		{
+			desc:      "no matches",
+			channel:   "user2",
+			text:      "cat",
+			wantTotal: 0,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			got, total, err := database.SearchMessages(db, tc.channel, tc.text, 5)
+			if err != nil {
+				t.Fatalf("SearchMessages() unexpected error: %v", err)
+			}
+			if total != tc.wantTotal {
+				t.Errorf("SearchMessages() total = %d, want %d", total, tc.wantTotal)
+			}
+			var gotTexts []string
+			for _, m := range got {
+				gotTexts = append(gotTexts, m.Text)
+				if m.User.TwitchName != "user1" {
+					t.Errorf("SearchMessages() match user = %q, want %q", m.User.TwitchName, "user1")
+				}
+			}
+			if len(gotTexts) != len(tc.wantTexts) || (len(gotTexts) > 0 && gotTexts[0] != tc.wantTexts[0]) {
+				t.Errorf("SearchMessages() = %q, want %q", gotTexts, tc.wantTexts)
+			}
+		})
+	}
+}
+
+func TestMigrate_IndexesExistingMessages(t *testing.T) {
+	t.Parallel()
+	db := databasetest.New(t)
+
+	for _, stmt := range []string{
+		"DROP TRIGGER messages_fts_insert",
+		"DROP TRIGGER messages_fts_delete",
+		"DROP TRIGGER messages_fts_update",
+		"DROP TABLE messages_fts",
+	} {
+		if err := db.Exec(stmt).Error; err != nil {
+			t.Fatalf("Failed to drop search index (%s): %v", stmt, err)
+		}
+	}
+	if err := db.Create(&models.Message{Text: "legacy message", Channel: "user2", UserID: 1, Time: time.Now()}).Error; err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("Migrate() unexpected error: %v", err)
+	}
+
+	_, total, err := database.SearchMessages(db, "user2", "legacy", 1)
+	if err != nil {
+		t.Fatalf("SearchMessages() unexpected error: %v", err)
+	}
+	if total != 1 {
+		t.Errorf("SearchMessages() total = %d, want 1", total)
+	}
+}
@@ -0,0 +1,270 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/airforce270/airbot/database/models"
+
+	"gorm.io/gorm"
+)
+
+// UserData contains all data stored about a user, keyed by table name.
+// Rows are stored as column name to value maps.
+type UserData map[string][]map[string]any
+
+// Count returns the total number of rows in the data.
+func (d UserData) Count() int {
+	var n int
+	for _, rows := range d {
+		n += len(rows)
+	}
+	return n
+}
+
+// Tables returns the names of the tables in the data, sorted.
+func (d UserData) Tables() []string {
+	tables := make([]string, 0, len(d))
+	for table := range d {
+		tables = append(tables, table)
+	}
+	slices.Sort(tables)
+	return tables
+}
+
+// userReference is a table that references users.
+type userReference struct {
+	modelSchema
+	// columns are the columns that reference a user's ID.
+	columns []string
+}
+
+// where returns a query for the rows in the table referencing the user.
+func (r userReference) where(db *gorm.DB, user *models.User) *gorm.DB {
+	conds := make([]string, len(r.columns))
+	args := make([]any, len(r.columns))
+	for i, col := range r.columns {
+		conds[i] = db.Statement.Quote(col) + " = ?"
+		args[i] = user.ID
+	}
+	return db.Unscoped().Table(r.Table).Where(strings.Join(conds, " OR "), args...)
+}
+
+// userReferences returns all tables that reference users,
+// ordered such that tables come before the tables they reference.
+func userReferences(db *gorm.DB) ([]userReference, error) {
+	schemas, err := dependencyOrder(db)
+	if err != nil {
+		return nil, err
+	}
+	usersTable, err := tableName(db, &models.User{})
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []userReference
+	for _, s := range slices.Backward(schemas) {
+		var columns []string
+		for _, rel := range s.Relationships.BelongsTo {
+			if rel.FieldSchema.Table != usersTable {
+				continue
+			}
+			for _, ref := range rel.References {
+				if ref.OwnPrimaryKey {
+					continue
+				}
+				columns = append(columns, ref.ForeignKey.DBName)
+			}
+		}
+		if len(columns) > 0 {
+			refs = append(refs, userReference{modelSchema: s, columns: columns})
+		}
+	}
+	return refs, nil
+}
+
+// userPlatform is the platform users' IDs (models.User.TwitchID) are from.
+const userPlatform = "Twitch"
+
+// platformUserModels are models that reference users by their platform and ID on it,
+// in Platform and UserID fields, rather than by a foreign key to their user record.
+var platformUserModels = []any{
+	&models.LiveNotification{},
+}
+
+// wherePlatformUser returns a query for the rows of a model in platformUserModels referencing the user.
+func wherePlatformUser(db *gorm.DB, model any, user *models.User) *gorm.DB {
+	return db.Unscoped().Model(model).Where("platform = ? AND user_id = ?", userPlatform, user.TwitchID)
+}
+
+func tableName(db *gorm.DB, model any) (string, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return "", fmt.Errorf("failed to parse model %T: %w", model, err)
+	}
+	return stmt.Schema.Table, nil
+}
+
+// ExportUserData returns all data stored about a user,
+// including their user record and every row that references them.
+func ExportUserData(db *gorm.DB, user *models.User) (UserData, error) {
+	refs, err := userReferences(db)
+	if err != nil {
+		return nil, err
+	}
+	usersTable, err := tableName(db, &models.User{})
+	if err != nil {
+		return nil, err
+	}
+
+	data := UserData{}
+	var userRows []map[string]any
+	if err := db.Unscoped().Table(usersTable).Where("id = ?", user.ID).Find(&userRows).Error; err != nil {
+		return nil, fmt.Errorf("failed to export user %d: %w", user.ID, err)
+	}
+	data[usersTable] = userRows
+
+	for _, ref := range refs {
+		var rows []map[string]any
+		if err := ref.where(db, user).Order("id").Find(&rows).Error; err != nil {
+			return nil, fmt.Errorf("failed to export %s for user %d: %w", ref.Table, user.ID, err)
+		}
+		if len(rows) > 0 {
+			data[ref.Table] = rows
+		}
+	}
+
+	if user.TwitchID == "" {
+		return data, nil
+	}
+	for _, model := range platformUserModels {
+		table, err := tableName(db, model)
+		if err != nil {
+			return nil, err
+		}
+		var rows []map[string]any
+		if err := wherePlatformUser(db, model, user).Order("id").Find(&rows).Error; err != nil {
+			return nil, fmt.Errorf("failed to export %s for user %d: %w", table, user.ID, err)
+		}
+		if len(rows) > 0 {
+			data[table] = rows
+		}
+	}
+	return data, nil
+}
+
+// ForgetUser permanently deletes a user along with every row that only references them.
+// Rows shared with other users, such as duels, are kept for the other users,
+// but anonymized by referencing the deleted user placeholder instead.
+// It returns the number of rows deleted and anonymized in each table.
+func ForgetUser(db *gorm.DB, user *models.User) (deleted, anonymized map[string]int64, err error) {
+	refs, err := userReferences(db)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	deleted, anonymized = map[string]int64{}, map[string]int64{}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		placeholder, err := deletedUser(tx)
+		if err != nil {
+			return err
+		}
+		if placeholder.ID == user.ID {
+			return errors.New("the deleted user placeholder can't be forgotten")
+		}
+
+		for _, ref := range refs {
+			n, err := ref.anonymize(tx, user, &placeholder)
+			if err != nil {
+				return fmt.Errorf("failed to anonymize %s for user %d: %w", ref.Table, user.ID, err)
+			}
+			if n > 0 {
+				anonymized[ref.Table] = n
+			}
+
+			result := ref.where(tx, user).Delete(reflect.New(reflect.TypeOf(ref.model)).Interface())
+			if err := result.Error; err != nil {
+				return fmt.Errorf("failed to delete %s for user %d: %w", ref.Table, user.ID, err)
+			}
+			if result.RowsAffected > 0 {
+				deleted[ref.Table] = result.RowsAffected
+			}
+		}
+
+		if user.TwitchID != "" {
+			for _, model := range platformUserModels {
+				result := wherePlatformUser(tx, model, user).Delete(model)
+				if err := result.Error; err != nil {
+					return fmt.Errorf("failed to delete %s for user %d: %w", result.Statement.Table, user.ID, err)
+				}
+				if result.RowsAffected > 0 {
+					deleted[result.Statement.Table] = result.RowsAffected
+				}
+			}
+		}
+
+		// Foreign keys are enforced, so this fails if any references were missed.
+		result := tx.Unscoped().Delete(&models.User{}, user.ID)
+		if err := result.Error; err != nil {
+			return fmt.Errorf("failed to delete user %d: %w", user.ID, err)
+		}
+		deleted[result.Statement.Table] = result.RowsAffected
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return deleted, anonymized, nil
+}
+
+// anonymize replaces references to the user with the placeholder
+// in rows that also reference another user, returning the number of rows changed.
+func (r userReference) anonymize(db *gorm.DB, user, placeholder *models.User) (int64, error) {
+	if len(r.columns) < 2 {
+		return 0, nil
+	}
+	changed := map[any]bool{}
+	for _, col := range r.columns {
+		var others []string
+		var args []any
+		for _, other := range r.columns {
+			if other == col {
+				continue
+			}
+			others = append(others, db.Statement.Quote(other)+" <> ?")
+			args = append(args, user.ID)
+		}
+		query := db.Unscoped().Table(r.Table).
+			Where(db.Statement.Quote(col)+" = ?", user.ID).
+			Where(strings.Join(others, " OR "), args...)
+
+		var ids []any
+		if err := query.Pluck("id", &ids).Error; err != nil {
+			return 0, err
+		}
+		if len(ids) == 0 {
+			continue
+		}
+		if err := db.Unscoped().Table(r.Table).Where("id IN ?", ids).Update(col, placeholder.ID).Error; err != nil {
+			return 0, err
+		}
+		for _, id := range ids {
+			changed[id] = true
+		}
+	}
+	return int64(len(changed)), nil
+}
+
+// deletedUser returns the placeholder user that rows shared with forgotten users reference,
+// creating it if needed.
+func deletedUser(db *gorm.DB) (models.User, error) {
+	var user models.User
+	err := db.Where(models.User{TwitchName: models.DeletedUserName}).FirstOrCreate(&user).Error
+	if err != nil {
+		return models.User{}, fmt.Errorf("failed to find/create deleted user placeholder: %w", err)
+	}
+	return user, nil
+}
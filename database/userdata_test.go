@@ -0,0 +1,135 @@
+package database_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/airforce270/airbot/database"
+	"github.com/airforce270/airbot/database/databasetest"
+	"github.com/airforce270/airbot/database/models"
+
+	"github.com/google/go-cmp/cmp"
+	"gorm.io/gorm"
+)
+
+func TestExportUserData(t *testing.T) {
+	t.Parallel()
+	db := databasetest.New(t)
+	user1, user2 := seedUserData(t, db)
+
+	got, err := database.ExportUserData(db, &user1)
+	if err != nil {
+		t.Fatalf("ExportUserData() unexpected error: %v", err)
+	}
+
+	wantCounts := map[string]int{
+		"users":              1,
+		"messages":           2,
+		"gamba_transactions": 1,
+		"duels":              3,
+		"live_notifications": 1,
+	}
+	gotCounts := map[string]int{}
+	for table, rows := range got {
+		gotCounts[table] = len(rows)
+	}
+	if diff := cmp.Diff(wantCounts, gotCounts); diff != "" {
+		t.Errorf("ExportUserData() row counts diff (-want +got):\n%s", diff)
+	}
+	if got, want := got["messages"][0]["text"], "hello"; got != want {
+		t.Errorf("ExportUserData() first message = %v, want %q", got, want)
+	}
+
+	other, err := database.ExportUserData(db, &user2)
+	if err != nil {
+		t.Fatalf("ExportUserData() unexpected error: %v", err)
+	}
+	if got, want := other.Count(), 5; got != want {
+		t.Errorf("ExportUserData() for user2 returned %d rows, want %d", got, want)
+	}
+}
+
+func TestForgetUser(t *testing.T) {
+	t.Parallel()
+	db := databasetest.New(t)
+	user1, user2 := seedUserData(t, db)
+
+	gotDeleted, gotAnonymized, err := database.ForgetUser(db, &user1)
+	if err != nil {
+		t.Fatalf("ForgetUser() unexpected error: %v", err)
+	}
+
+	wantDeleted := map[string]int64{
+		"users":              1,
+		"messages":           2,
+		"gamba_transactions": 1,
+		"duels":              1,
+		"live_notifications": 1,
+	}
+	if diff := cmp.Diff(wantDeleted, gotDeleted); diff != "" {
+		t.Errorf("ForgetUser() deleted diff (-want +got):\n%s", diff)
+	}
+	wantAnonymized := map[string]int64{"duels": 2}
+	if diff := cmp.Diff(wantAnonymized, gotAnonymized); diff != "" {
+		t.Errorf("ForgetUser() anonymized diff (-want +got):\n%s", diff)
+	}
+
+	remaining, err := database.ExportUserData(db, &user1)
+	if err != nil {
+		t.Fatalf("ExportUserData() unexpected error: %v", err)
+	}
+	if remaining.Count() != 0 {
+		t.Errorf("ExportUserData() after ForgetUser() = %v, want no data", remaining)
+	}
+
+	// Data that doesn't involve the forgotten user is kept,
+	// and duels with them are kept, but anonymized.
+	other, err := database.ExportUserData(db, &user2)
+	if err != nil {
+		t.Fatalf("ExportUserData() unexpected error: %v", err)
+	}
+	if got, want := other.Count(), 5; got != want {
+		t.Errorf("ExportUserData() for user2 returned %d rows, want %d", got, want)
+	}
+	var placeholder models.User
+	if err := db.First(&placeholder, "twitch_name = ?", models.DeletedUserName).Error; err != nil {
+		t.Fatalf("Failed to fetch deleted user placeholder: %v", err)
+	}
+	var duels []models.Duel
+	if err := db.Order("id").Find(&duels).Error; err != nil {
+		t.Fatalf("Failed to fetch duels: %v", err)
+	}
+	if len(duels) != 2 || duels[0].UserID != placeholder.ID || duels[0].TargetID != user2.ID || duels[1].UserID != user2.ID || duels[1].TargetID != placeholder.ID {
+		t.Errorf("duels after ForgetUser() = %+v, want user1 replaced with the placeholder (ID %d)", duels, placeholder.ID)
+	}
+}
+
+// seedUserData seeds data for user1, along with a message and a live notification from user2,
+// duels between them, and a duel only involving user1.
+func seedUserData(t *testing.T, db *gorm.DB) (user1, user2 models.User) {
+	t.Helper()
+	if err := db.First(&user1, "twitch_name = ?", "user1").Error; err != nil {
+		t.Fatalf("Failed to fetch user1: %v", err)
+	}
+	if err := db.First(&user2, "twitch_name = ?", "user2").Error; err != nil {
+		t.Fatalf("Failed to fetch user2: %v", err)
+	}
+	now := time.Now()
+	seed := []any{
+		&models.Message{Text: "hello", Channel: "user2", UserID: user1.ID, Time: now},
+		&models.Message{Text: "goodbye", Channel: "user2", UserID: user1.ID, Time: now},
+		&models.Message{Text: "hi", Channel: "user2", UserID: user2.ID, Time: now},
+		&models.GambaTransaction{Game: "roulette", UserID: user1.ID, Delta: 10},
+		&models.Duel{UserID: user1.ID, TargetID: user2.ID, Amount: 5},
+		&models.Duel{UserID: user2.ID, TargetID: user1.ID, Amount: 5},
+		&models.Duel{UserID: user1.ID, TargetID: user1.ID, Amount: 5},
+		&models.LiveNotification{Platform: "Twitch", UserID: user1.TwitchID, Username: user1.TwitchName, StreamPlatform: models.StreamPlatformTwitch, Streamer: "user3", Whisper: true},
+		&models.LiveNotification{Platform: "Twitch", UserID: user2.TwitchID, Username: user2.TwitchName, StreamPlatform: models.StreamPlatformTwitch, Streamer: "user3", Whisper: true},
+	}
+	for _, row := range seed {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatalf("Failed to seed %T: %v", row, err)
+		}
+	}
+	return user1, user2
+}
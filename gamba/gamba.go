@@ -5,7 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"slices"
 	"time"
 
@@ -24,19 +24,28 @@ var (
 
 // StartGrantingPoints starts a loop to grant points to all chatters on an interval.
 // This function blocks and should be run within a goroutine.
-func StartGrantingPoints(ctx context.Context, ps map[string]base.Platform, db *gorm.DB) {
+func StartGrantingPoints(ctx context.Context, ps map[string]base.Platform, db *gorm.DB, logger *slog.Logger) {
 	timer := time.NewTicker(grantInterval)
 	for {
 		select {
 		case <-ctx.Done():
-			log.Print("Stopping point granting, context cancelled")
+			logger.Info("Stopping point granting, context cancelled")
 			return
 		case <-timer.C:
-			go grantPoints(ps, db)
+			go grantPoints(ps, db, logger)
 		}
 	}
 }
 
+// PointsInCirculation returns the total number of points held by all users.
+func PointsInCirculation(db *gorm.DB) (int64, error) {
+	var points int64
+	if err := db.Model(&models.GambaTransaction{}).Select("CAST(COALESCE(SUM(delta), 0) AS BIGINT)").Scan(&points).Error; err != nil {
+		return 0, fmt.Errorf("failed to sum gamba transactions: %w", err)
+	}
+	return points, nil
+}
+
 // grant represents a points grant that may be given to a user.
 type grant struct {
 	// User is the user the grant is for.
@@ -83,10 +92,10 @@ func InboundPendingDuels(user *models.User, expire time.Duration, db *gorm.DB) (
 }
 
 // grantPoints performs a single point grant to all active and inactive users.
-func grantPoints(ps map[string]base.Platform, db *gorm.DB) {
+func grantPoints(ps map[string]base.Platform, db *gorm.DB, logger *slog.Logger) {
 	var grants []grant
 
-	for _, inactiveUser := range getInactiveUsers(ps, db) {
+	for _, inactiveUser := range getInactiveUsers(ps, db, logger) {
 		grants = append(grants, grant{
 			User:     inactiveUser,
 			IsActive: false,
@@ -95,7 +104,7 @@ func grantPoints(ps map[string]base.Platform, db *gorm.DB) {
 
 	activeUsers, err := getActiveUsers(db)
 	if err != nil {
-		log.Printf("Failed to fetch users: %v", err)
+		logger.Error("Failed to fetch active users", "error", err)
 	}
 	for _, activeUser := range activeUsers {
 		grants = append(grants, grant{
@@ -109,7 +118,7 @@ func grantPoints(ps map[string]base.Platform, db *gorm.DB) {
 	for _, g := range grants {
 		err := g.Persist(db)
 		if err != nil {
-			log.Printf("Failed to grant points - failed to persist grant: %v", err)
+			logger.Error("Failed to grant points - failed to persist grant", "error", err)
 			return
 		}
 	}
@@ -117,12 +126,12 @@ func grantPoints(ps map[string]base.Platform, db *gorm.DB) {
 
 // getInactiveUsers gets all inactive users to grant points to.
 // The users returned are not guaranteed to be inactive, the results returned are overinclusive.
-func getInactiveUsers(ps map[string]base.Platform, db *gorm.DB) []models.User {
+func getInactiveUsers(ps map[string]base.Platform, db *gorm.DB, logger *slog.Logger) []models.User {
 	var users []models.User
 	for _, p := range ps {
 		allUsers, err := p.CurrentUsers()
 		if err != nil {
-			log.Printf("Failed to retrieve users from %s: %v", p.Name(), err)
+			logger.Error("Failed to retrieve users", "platform", p.Name(), "error", err)
 			continue
 		}
 
@@ -133,7 +142,7 @@ func getInactiveUsers(ps map[string]base.Platform, db *gorm.DB) []models.User {
 					// user needs to type something somewhere before they can get points automatically
 					continue
 				}
-				log.Printf("Failed to retrieve user from %s: %v", p.Name(), err)
+				logger.Error("Failed to retrieve user", "platform", p.Name(), "user", u, "error", err)
 				continue
 			}
 			users = append(users, user)
@@ -13,6 +13,7 @@ import (
 	"github.com/airforce270/airbot/base"
 	"github.com/airforce270/airbot/database/databasetest"
 	"github.com/airforce270/airbot/database/models"
+	"github.com/airforce270/airbot/logging"
 	"github.com/airforce270/airbot/platforms/twitch"
 
 	"github.com/google/go-cmp/cmp"
@@ -173,7 +174,7 @@ func TestGrantPoints(t *testing.T) {
 		"FakeTwitch": twitch.NewForTesting(t, server.URL, db),
 	}
 
-	grantPoints(ps, db)
+	grantPoints(ps, db, logging.Discard())
 
 	var transactions []models.GambaTransaction
 	if err := db.Find(&transactions).Error; err != nil {
@@ -197,6 +198,22 @@ func TestGrantPoints(t *testing.T) {
 	}
 }
 
+func TestPointsInCirculation(t *testing.T) {
+	t.Parallel()
+	db := databasetest.New(t)
+
+	if got, err := PointsInCirculation(db); err != nil || got != 0 {
+		t.Errorf("PointsInCirculation() with no transactions = %d, %v; want 0, nil", got, err)
+	}
+
+	add50PointsToUser1(t, db)
+	add50PointsToUser2(t, db)
+
+	if got, err := PointsInCirculation(db); err != nil || got != 100 {
+		t.Errorf("PointsInCirculation() = %d, %v; want 100, nil", got, err)
+	}
+}
+
 func TestGetInactiveUsers(t *testing.T) {
 	t.Parallel()
 	db := databasetest.New(t)
@@ -219,7 +236,7 @@ func TestGetInactiveUsers(t *testing.T) {
 		"FakeTwitch": twitch.NewForTesting(t, server.URL, db),
 	}
 
-	got := getInactiveUsers(ps, db)
+	got := getInactiveUsers(ps, db, logging.Discard())
 	want := []models.User{user1, user2}
 
 	if len(got) != len(want) {
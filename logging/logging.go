@@ -0,0 +1,154 @@
+// Package logging provides structured, leveled logging for each of the bot's subsystems.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+
+	"github.com/airforce270/airbot/config"
+)
+
+// Subsystem names, used to set per-subsystem log levels.
+const (
+	Main      = "main"
+	Platforms = "platforms"
+	Incoming  = "incoming"
+	Outgoing  = "outgoing"
+	Commands  = "commands"
+	Twitch    = "twitch"
+	Database  = "database"
+	Gamba     = "gamba"
+	Supinic   = "supinic"
+	Metrics   = "metrics"
+)
+
+// Log formats.
+const (
+	TextFormat = "text"
+	JSONFormat = "json"
+)
+
+// Loggers creates loggers for subsystems.
+type Loggers struct {
+	// handler is the handler all logs are written through.
+	handler slog.Handler
+	// level is the minimum level for subsystems without a specific level.
+	level slog.Level
+	// levels contains the minimum levels for specific subsystems.
+	levels map[string]slog.Level
+	// sampleEvery is how often records are written by sampled loggers.
+	sampleEvery uint64
+}
+
+// New creates loggers that write to w, as configured.
+func New(cfg config.LoggingConfig, w io.Writer) (*Loggers, error) {
+	// Levels are checked per subsystem, so the handler writes everything it gets.
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug}
+
+	var handler slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case "", TextFormat:
+		handler = slog.NewTextHandler(w, opts)
+	case JSONFormat:
+		handler = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q, must be %q or %q", cfg.Format, TextFormat, JSONFormat)
+	}
+
+	sampleEvery := uint64(1)
+	if cfg.MessageSampleEvery > 1 {
+		sampleEvery = uint64(cfg.MessageSampleEvery)
+	}
+
+	return &Loggers{
+		handler:     handler,
+		level:       cfg.Level,
+		levels:      cfg.Levels,
+		sampleEvery: sampleEvery,
+	}, nil
+}
+
+// For returns the logger for a subsystem.
+func (l *Loggers) For(subsystem string) *slog.Logger {
+	return slog.New(l.subsystemHandler(subsystem))
+}
+
+// Sampled returns a logger for a subsystem that only writes every Nth record,
+// as configured, for high-volume paths like logging every message.
+// Warnings and errors are always written.
+func (l *Loggers) Sampled(subsystem string) *slog.Logger {
+	return slog.New(&samplingHandler{
+		handler: l.subsystemHandler(subsystem),
+		every:   l.sampleEvery,
+		count:   &atomic.Uint64{},
+	})
+}
+
+func (l *Loggers) subsystemHandler(subsystem string) slog.Handler {
+	level, ok := l.levels[subsystem]
+	if !ok {
+		level = l.level
+	}
+	return &levelHandler{
+		handler: l.handler.WithAttrs([]slog.Attr{slog.String("subsystem", subsystem)}),
+		level:   level,
+	}
+}
+
+// Discard returns a logger that discards all logs.
+func Discard() *slog.Logger {
+	return slog.New(slog.DiscardHandler)
+}
+
+// levelHandler is a handler that only writes records at or above a level.
+type levelHandler struct {
+	handler slog.Handler
+	level   slog.Level
+}
+
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level && h.handler.Enabled(ctx, level)
+}
+
+func (h *levelHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.handler.Handle(ctx, r)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{handler: h.handler.WithAttrs(attrs), level: h.level}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{handler: h.handler.WithGroup(name), level: h.level}
+}
+
+// samplingHandler is a handler that only writes every Nth record below warning level.
+type samplingHandler struct {
+	handler slog.Handler
+	every   uint64
+	// count is the number of records seen, shared with derived handlers.
+	count *atomic.Uint64
+}
+
+func (h *samplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+func (h *samplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < slog.LevelWarn && h.every > 1 && (h.count.Add(1)-1)%h.every != 0 {
+		return nil
+	}
+	return h.handler.Handle(ctx, r)
+}
+
+func (h *samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &samplingHandler{handler: h.handler.WithAttrs(attrs), every: h.every, count: h.count}
+}
+
+func (h *samplingHandler) WithGroup(name string) slog.Handler {
+	return &samplingHandler{handler: h.handler.WithGroup(name), every: h.every, count: h.count}
+}
@@ -0,0 +1,104 @@
+package logging_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/airforce270/airbot/config"
+	"github.com/airforce270/airbot/logging"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLoggers_Levels(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	loggers, err := logging.New(config.LoggingConfig{
+		Format: logging.JSONFormat,
+		Level:  slog.LevelWarn,
+		Levels: map[string]slog.Level{logging.Twitch: slog.LevelDebug},
+	}, &buf)
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	loggers.For(logging.Main).Info("main info")
+	loggers.For(logging.Main).Warn("main warn")
+	loggers.For(logging.Twitch).Debug("twitch debug", "channel", "user1")
+
+	want := []map[string]any{
+		{"level": "WARN", "msg": "main warn", "subsystem": "main"},
+		{"level": "DEBUG", "msg": "twitch debug", "subsystem": "twitch", "channel": "user1"},
+	}
+	if diff := cmp.Diff(want, readJSONLogs(t, &buf)); diff != "" {
+		t.Errorf("Logs written unexpected (-want +got):\n%s", diff)
+	}
+}
+
+func TestLoggers_Sampled(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	loggers, err := logging.New(config.LoggingConfig{
+		Format:             logging.JSONFormat,
+		MessageSampleEvery: 3,
+	}, &buf)
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	logger := loggers.Sampled(logging.Incoming)
+	for i := range 6 {
+		// Loggers derived from a sampled logger share its count.
+		logger.With("i", i).Info("message")
+	}
+	logger.Warn("warning")
+
+	want := []map[string]any{
+		{"level": "INFO", "msg": "message", "subsystem": "incoming", "i": float64(0)},
+		{"level": "INFO", "msg": "message", "subsystem": "incoming", "i": float64(3)},
+		{"level": "WARN", "msg": "warning", "subsystem": "incoming"},
+	}
+	if diff := cmp.Diff(want, readJSONLogs(t, &buf)); diff != "" {
+		t.Errorf("Logs written unexpected (-want +got):\n%s", diff)
+	}
+}
+
+func TestNew_Format(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		format  string
+		wantErr bool
+	}{
+		{format: "", wantErr: false},
+		{format: "text", wantErr: false},
+		{format: "JSON", wantErr: false},
+		{format: "xml", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.format, func(t *testing.T) {
+			t.Parallel()
+			_, err := logging.New(config.LoggingConfig{Format: tc.format}, &bytes.Buffer{})
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Errorf("New(%q) error = %v, want error: %t", tc.format, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+// readJSONLogs parses the JSON logs written to buf, without their times.
+func readJSONLogs(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var logs []map[string]any
+	for line := range strings.SplitSeq(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Failed to parse log line %q: %v", line, err)
+		}
+		delete(record, "time")
+		logs = append(logs, record)
+	}
+	return logs
+}
@@ -7,9 +7,11 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"log/slog"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"syscall"
 	"time"
 
 	"github.com/airforce270/airbot/apiclients/supinic"
@@ -18,16 +20,13 @@ import (
 	"github.com/airforce270/airbot/config"
 	"github.com/airforce270/airbot/database"
 	"github.com/airforce270/airbot/gamba"
+	"github.com/airforce270/airbot/logging"
+	"github.com/airforce270/airbot/metrics"
 	"github.com/airforce270/airbot/platforms"
 	"github.com/airforce270/airbot/utils/cleanup"
 	"github.com/airforce270/airbot/utils/restart"
 )
 
-const (
-	waitForCancelFuncs         = 100 * time.Millisecond
-	waitForContextCancellation = 100 * time.Millisecond
-)
-
 type postStartupResources struct {
 	platforms map[string]base.Platform
 	cache     cache.Cache
@@ -44,7 +43,7 @@ func reStart(ctx context.Context) (cleanup.Cleaner, error) {
 		return nil, err
 	}
 	if err := restart.Notify(resources.cache, resources.platforms); err != nil {
-		log.Printf("Failed to notify restart: %v", err)
+		slog.Error("Failed to notify restart", "error", err)
 	}
 	return cleaner, err
 }
@@ -52,7 +51,7 @@ func reStart(ctx context.Context) (cleanup.Cleaner, error) {
 func start(ctx context.Context) (cleanup.Cleaner, postStartupResources, error) {
 	cleaner := cleanup.NewCleaner()
 
-	log.Print("Reading config...")
+	slog.Info("Reading config...")
 	configSrc, err := config.DefaultNewConfigSource()
 	if err != nil {
 		return nil, postStartupResources{}, fmt.Errorf("failed to open config source: %w", err)
@@ -67,94 +66,168 @@ func start(ctx context.Context) (cleanup.Cleaner, postStartupResources, error) {
 		return nil, postStartupResources{}, fmt.Errorf("failed to close config after reading: %w", err)
 	}
 
-	log.Printf("Connecting to database...")
-	db, err := database.Connect(ctx, log.Default(), filepath.Join(os.Getenv("AIRBOT_SQLITE_DATA_DIR"), "sqlite.db"))
+	loggers, err := logging.New(cfg.Logging, os.Stderr)
+	if err != nil {
+		return nil, postStartupResources{}, fmt.Errorf("failed to set up logging: %w", err)
+	}
+	logger := loggers.For(logging.Main)
+	// Route anything still logged through the log package to the main logger.
+	slog.SetDefault(logger)
+
+	driver, err := database.NewDriver(cfg.Database, dbFilePath())
+	if err != nil {
+		return nil, postStartupResources{}, fmt.Errorf("failed to select database driver: %w", err)
+	}
+
+	logger.Info("Connecting to database...", "driver", driver.Name())
+	// The database is closed by the cleaner once everything using it has stopped,
+	// not as soon as ctx is cancelled.
+	db, err := database.Connect(context.WithoutCancel(ctx), slog.NewLogLogger(loggers.For(logging.Database).Handler(), slog.LevelError), driver)
 	if err != nil {
 		return nil, postStartupResources{}, fmt.Errorf("failed to connect to database: %w", err)
 	}
+	cleaner.Register(cleanup.Func{Name: "database", F: func() error { return database.Close(db, driver) }})
 
-	log.Printf("Connecting to cache...")
-	cdb, err := cache.NewSQLite(db)
+	logger.Info("Connecting to cache...")
+	cdb, err := cache.NewDB(db)
 	if err != nil {
 		return nil, postStartupResources{}, fmt.Errorf("failed to connect to cache: %w", err)
 	}
 
-	log.Printf("Performing database migrations...")
+	logger.Info("Performing database migrations...")
 	if err = database.Migrate(db); err != nil {
 		return nil, postStartupResources{}, fmt.Errorf("failed to perform database migrations: %w", err)
 	}
+	if err := cdb.PurgeExpired(); err != nil {
+		logger.Warn("Failed to purge expired cache values", "error", err)
+	}
 
-	log.Printf("Preparing chat connections...")
-	ps, err := platforms.Build(cfg, db, &cdb)
+	logger.Info("Preparing chat connections...")
+	ps, err := platforms.Build(cfg, db, &cdb, loggers)
 	if err != nil {
 		return nil, postStartupResources{}, fmt.Errorf("failed to build platforms: %w", err)
 	}
 
 	for _, p := range ps {
-		log.Printf("Connecting to %s...", p.Name())
+		logger.Info("Connecting to platform...", "platform", p.Name())
 		if err := p.Connect(ctx); err != nil {
 			return cleaner, postStartupResources{}, fmt.Errorf("failed to connect to %s: %w", p.Name(), err)
 		}
 
-		log.Printf("Starting to handle messages on %s...", p.Name())
-		go platforms.StartHandling(ctx, p, db, &cdb, cfg, ps, cfg.LogIncoming, cfg.LogOutgoing)
+		logger.Info("Starting to handle messages...", "platform", p.Name())
+		handlingErr := make(chan error, 1)
+		go func() { handlingErr <- platforms.StartHandling(ctx, p, db, &cdb, cfg, ps, loggers) }()
+		// Cleanup runs in reverse, so handling finishes before disconnecting.
 		cleaner.Register(cleanup.Func{Name: p.Name(), F: p.Disconnect})
+		cleaner.Register(cleanup.Func{Name: p.Name() + " message handling", F: func() error { return <-handlingErr }})
 	}
 
-	go gamba.StartGrantingPoints(ctx, ps, db)
+	go gamba.StartGrantingPoints(ctx, ps, db, loggers.For(logging.Gamba))
+
+	if cfg.Backup.Enabled && driver.Name() != database.SQLiteDriverName {
+		logger.Warn("Periodic database backups are only supported for "+database.SQLiteDriverName+", not starting them", "driver", driver.Name())
+	} else if cfg.Backup.Enabled {
+		logger.Info("Starting periodic database backups...")
+		backupDir := cfg.Backup.Directory
+		if backupDir == "" {
+			backupDir = database.DefaultBackupDir()
+		}
+		go database.StartBackingUp(ctx, db, backupDir, time.Duration(cfg.Backup.Interval), cfg.Backup.Keep, loggers.For(logging.Database))
+	}
+
+	if retention := time.Duration(cfg.Audit.Retention); retention > 0 {
+		logger.Info("Starting pruning the command audit log...", "retention", retention)
+		go database.StartPruningCommandInvocations(ctx, db, retention, loggers.For(logging.Database))
+	}
+
+	if cfg.Metrics.Enabled {
+		logger.Info("Starting to serve metrics...", "address", cfg.Metrics.Address)
+		checks := map[string]metrics.HealthCheck{}
+		for _, p := range ps {
+			checks[p.Name()] = p.Connected
+		}
+		gambaPoints := func() (int64, error) { return gamba.PointsInCirculation(db) }
+		metricsLogger := loggers.For(logging.Metrics)
+		server := metrics.NewServer(cfg.Metrics.Address, checks, gambaPoints, metricsLogger)
+		go func() {
+			if err := server.Serve(ctx); err != nil {
+				metricsLogger.Error("Metrics server failed", "error", err)
+			}
+		}()
+	}
 
 	if cfg.Supinic.IsConfigured() && cfg.Supinic.ShouldPingAPI {
-		log.Println("Starting to ping the Supinic API...")
+		logger.Info("Starting to ping the Supinic API...")
 		supinicClient := supinic.NewClient(cfg.Supinic.UserID, cfg.Supinic.APIKey)
-		go supinicClient.StartPinging(ctx)
+		go supinicClient.StartPinging(ctx, loggers.For(logging.Supinic))
 	}
 
 	return cleaner, postStartupResources{cache: &cdb, platforms: ps}, nil
 }
 
+// dbFilePath returns the path to the SQLite database file.
+func dbFilePath() string {
+	return filepath.Join(os.Getenv("AIRBOT_SQLITE_DATA_DIR"), "sqlite.db")
+}
+
 func main() {
-	ctx, cancel := context.WithCancel(context.Background())
 	log.SetFlags(log.Ldate | log.Ltime | log.Lmicroseconds)
 
-	c := make(chan os.Signal, 2)
-	signal.Notify(c, os.Interrupt)
-	go func() {
-		<-c
-		cancel()
-		time.Sleep(waitForContextCancellation)
-		os.Exit(1)
-	}()
+	if len(os.Args) > 1 {
+		if err := runSubcommand(os.Args[1], os.Args[2:]); err != nil {
+			slog.Error("Subcommand failed", "subcommand", os.Args[1], "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
 
 	cleaner, err := initialStart(ctx)
 	if err != nil {
-		log.Fatalf("Failed to start: %v", err)
+		slog.Error("Failed to start", "error", err)
+		os.Exit(1)
 	}
-	log.Printf("Airbot is now running.")
+	slog.Info("Airbot is now running.")
 
 	for {
 		select {
 		case <-restart.C:
-			log.Printf("Restarting...")
-
-			if err := cleaner.Cleanup(); err != nil {
-				log.Printf("Cleanup failed: %v", err)
-			}
-			time.Sleep(waitForCancelFuncs)
-			cancel()
-			time.Sleep(waitForContextCancellation)
+			slog.Info("Restarting...")
+			shutdown(cancel, cleaner)
 
 			ctx, cancel = context.WithCancel(context.Background())
 
 			cleaner, err = reStart(ctx)
 			if err != nil {
-				log.Fatalf("Failed to start: %v", err)
+				slog.Error("Failed to start", "error", err)
+				os.Exit(1)
 			}
-			log.Printf("Airbot is now running (restarted).")
-		case <-ctx.Done():
-			log.Printf("Context cancelled, Airbot shutting down.")
+			slog.Info("Airbot is now running (restarted).")
+		case sig := <-signals:
+			slog.Info("Airbot shutting down...", "signal", sig)
+			go func() {
+				sig := <-signals
+				slog.Warn("Received signal again, exiting immediately.", "signal", sig)
+				os.Exit(1)
+			}()
+			shutdown(cancel, cleaner)
+			slog.Info("Airbot has shut down.")
 			return
 		}
 	}
 }
 
+// shutdown stops everything started by start.
+// Messages being handled and queued replies are given time to finish first.
+func shutdown(cancel context.CancelFunc, cleaner cleanup.Cleaner) {
+	cancel()
+	if err := cleaner.Cleanup(); err != nil {
+		slog.Error("Cleanup failed", "error", err)
+	}
+}
+
 // send message that says "Restarted" once bot is restarted
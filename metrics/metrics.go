@@ -0,0 +1,113 @@
+// Package metrics provides Prometheus metrics and an HTTP server to expose them.
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "airbot"
+
+// Registry contains all of the bot's metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// MessagesReceived counts messages received, by platform and channel.
+	MessagesReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_received_total",
+		Help:      "Number of chat messages received.",
+	}, []string{"platform", "channel"})
+
+	// MessagesSent counts messages sent, by platform and channel.
+	MessagesSent = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_sent_total",
+		Help:      "Number of chat messages sent.",
+	}, []string{"platform", "channel"})
+
+	// WhispersSent counts whispers sent, by platform.
+	WhispersSent = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "whispers_sent_total",
+		Help:      "Number of whispers sent.",
+	}, []string{"platform"})
+
+	// EventsReceived counts events received (i.e. a channel going live), by platform and kind.
+	EventsReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_received_total",
+		Help:      "Number of channel events received.",
+	}, []string{"platform", "kind"})
+
+	// CommandInvocations counts commands run, by command.
+	CommandInvocations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "command_invocations_total",
+		Help:      "Number of commands run.",
+	}, []string{"command"})
+
+	// CommandErrors counts commands that failed, by command and whether the failure was retryable.
+	CommandErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "command_errors_total",
+		Help:      "Number of commands that failed.",
+	}, []string{"command", "retryable"})
+
+	// CommandLatency measures how long commands take to run, by command.
+	CommandLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "command_duration_seconds",
+		Help:      "How long commands take to run.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"command"})
+
+	// AutomodActions counts actions taken against messages breaking automod rules, by platform, rule kind, and action.
+	AutomodActions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "automod_actions_total",
+		Help:      "Number of actions taken against messages breaking automod rules.",
+	}, []string{"platform", "rule", "action"})
+
+	// SendQueueDepth measures the number of messages waiting to be sent, by platform.
+	SendQueueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "send_queue_depth",
+		Help:      "Number of messages waiting to be sent.",
+	}, []string{"platform"})
+
+	// APIRequestLatency measures how long API requests take, by API client.
+	APIRequestLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "api_request_duration_seconds",
+		Help:      "How long requests to external APIs take.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"client"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		MessagesReceived,
+		MessagesSent,
+		WhispersSent,
+		EventsReceived,
+		CommandInvocations,
+		CommandErrors,
+		CommandLatency,
+		AutomodActions,
+		SendQueueDepth,
+		APIRequestLatency,
+	)
+}
+
+// ObserveAPIRequest records how long an API request that started at start took.
+// It's intended to be deferred at the start of a request:
+//
+//	defer metrics.ObserveAPIRequest("ivr", time.Now())
+func ObserveAPIRequest(client string, start time.Time) {
+	APIRequestLatency.WithLabelValues(client).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,122 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// shutdownTimeout is how long the server waits for requests to finish when shutting down.
+const shutdownTimeout = 5 * time.Second
+
+// HealthCheck reports whether a component, i.e. a platform's connection, is healthy.
+type HealthCheck func() bool
+
+// Server serves metrics at /metrics and health checks at /healthz.
+type Server struct {
+	// addr is the address to listen on, i.e. ":9090".
+	addr string
+	// checks contains the health checks to run, keyed by component name.
+	checks map[string]HealthCheck
+	// gatherer gathers the metrics to serve.
+	gatherer prometheus.Gatherer
+	// logger is the logger for the server.
+	logger *slog.Logger
+}
+
+// NewServer creates a new Server.
+// gambaPoints returns the number of gamba points in circulation.
+func NewServer(addr string, checks map[string]HealthCheck, gambaPoints func() (int64, error), logger *slog.Logger) *Server {
+	// Metrics that depend on this server's resources are registered separately,
+	// so they can be replaced when the bot restarts.
+	local := prometheus.NewRegistry()
+	local.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "gamba_points_in_circulation",
+		Help:      "Total gamba points held by all users.",
+	}, func() float64 {
+		points, err := gambaPoints()
+		if err != nil {
+			logger.Error("Failed to fetch gamba points in circulation", "error", err)
+			return 0
+		}
+		return float64(points)
+	}))
+
+	return &Server{
+		addr:     addr,
+		checks:   checks,
+		gatherer: prometheus.Gatherers{Registry, local},
+		logger:   logger,
+	}
+}
+
+// Handler returns the server's HTTP handler.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", promhttp.HandlerFor(s.gatherer, promhttp.HandlerOpts{}))
+	mux.HandleFunc("GET /healthz", s.healthz)
+	return mux
+}
+
+// Serve serves HTTP requests until the context is cancelled.
+// This function blocks and should be run within a goroutine.
+func (s *Server) Serve(ctx context.Context) error {
+	srv := &http.Server{
+		Addr:              s.addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errC := make(chan error, 1)
+	go func() { errC <- srv.ListenAndServe() }()
+
+	select {
+	case err := <-errC:
+		return fmt.Errorf("failed to serve metrics on %s: %w", s.addr, err)
+	case <-ctx.Done():
+	}
+
+	s.logger.Info("Stopping metrics server, context cancelled")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down metrics server: %w", err)
+	}
+	if err := <-errC; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to serve metrics on %s: %w", s.addr, err)
+	}
+	return nil
+}
+
+// healthResponse is the response to a health check.
+type healthResponse struct {
+	// Healthy is whether all components are healthy.
+	Healthy bool `json:"healthy"`
+	// Components contains whether each component is healthy, keyed by component name.
+	Components map[string]bool `json:"components"`
+}
+
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	resp := healthResponse{Healthy: true, Components: map[string]bool{}}
+	for name, check := range s.checks {
+		healthy := check()
+		resp.Components[name] = healthy
+		resp.Healthy = resp.Healthy && healthy
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !resp.Healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		s.logger.Error("Failed to write health check response", "error", err)
+	}
+}
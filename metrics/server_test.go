@@ -0,0 +1,108 @@
+package metrics_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/airforce270/airbot/logging"
+	"github.com/airforce270/airbot/metrics"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestServer_Healthz(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		desc       string
+		checks     map[string]metrics.HealthCheck
+		wantStatus int
+		wantBody   map[string]any
+	}{
+		{
+			desc: "healthy",
+			checks: map[string]metrics.HealthCheck{
+				"Twitch": func() bool { return true },
+			},
+			wantStatus: http.StatusOK,
+			wantBody: map[string]any{
+				"healthy":    true,
+				"components": map[string]any{"Twitch": true},
+			},
+		},
+		{
+			desc: "unhealthy",
+			checks: map[string]metrics.HealthCheck{
+				"Twitch": func() bool { return false },
+				"Other":  func() bool { return true },
+			},
+			wantStatus: http.StatusServiceUnavailable,
+			wantBody: map[string]any{
+				"healthy":    false,
+				"components": map[string]any{"Twitch": false, "Other": true},
+			},
+		},
+		{
+			desc:       "no components",
+			checks:     nil,
+			wantStatus: http.StatusOK,
+			wantBody: map[string]any{
+				"healthy":    true,
+				"components": map[string]any{},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			server := metrics.NewServer("", tc.checks, noGambaPoints, logging.Discard())
+
+			rec := httptest.NewRecorder()
+			server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+			if rec.Code != tc.wantStatus {
+				t.Errorf("GET /healthz status = %d, want %d", rec.Code, tc.wantStatus)
+			}
+			var got map[string]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to parse response %q: %v", rec.Body.String(), err)
+			}
+			if diff := cmp.Diff(tc.wantBody, got); diff != "" {
+				t.Errorf("GET /healthz body unexpected (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestServer_Metrics(t *testing.T) {
+	t.Parallel()
+	metrics.CommandInvocations.WithLabelValues("testcommand").Inc()
+	gambaPoints := func() (int64, error) { return 1234, nil }
+	server := metrics.NewServer("", nil, gambaPoints, logging.Discard())
+
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /metrics status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	body, err := io.ReadAll(rec.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	for _, want := range []string{
+		`airbot_command_invocations_total{command="testcommand"}`,
+		"airbot_gamba_points_in_circulation 1234",
+		"go_goroutines",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("GET /metrics response doesn't contain %q", want)
+		}
+	}
+}
+
+func noGambaPoints() (int64, error) { return 0, errors.New("no database") }
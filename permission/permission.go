@@ -2,8 +2,10 @@
 package permission
 
 import (
+	"fmt"
 	"math"
 	"strconv"
+	"strings"
 )
 
 // Level represents a permission level.
@@ -18,6 +20,30 @@ func (l Level) Name() string {
 	return name
 }
 
+// UnmarshalText implements encoding.TextUnmarshaler,
+// so levels can be read by name from the config, i.e. "Mod".
+// Names are case-insensitive and may omit spaces.
+// Levels without a name are read from their number, as returned by Name.
+func (l *Level) UnmarshalText(text []byte) error {
+	want := strings.ReplaceAll(string(text), " ", "")
+	for level, name := range names {
+		if strings.EqualFold(strings.ReplaceAll(name, " ", ""), want) {
+			*l = level
+			return nil
+		}
+	}
+	if n, err := strconv.ParseUint(want, 10, 8); err == nil {
+		*l = Level(n)
+		return nil
+	}
+	return fmt.Errorf("unknown permission level %q", text)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (l Level) MarshalText() ([]byte, error) {
+	return []byte(l.Name()), nil
+}
+
 // IsElevated indicates whether this Level is an elevated one.
 func (l Level) IsElevated() bool {
 	return l > Normal
@@ -0,0 +1,41 @@
+package permission_test
+
+import (
+	"testing"
+
+	"github.com/airforce270/airbot/permission"
+)
+
+func TestLevel_UnmarshalText(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		input   string
+		want    permission.Level
+		wantErr bool
+	}{
+		{input: "Mod", want: permission.Mod},
+		{input: "vip", want: permission.VIP},
+		{input: "Above Normal", want: permission.AboveNormal},
+		{input: "abovenormal", want: permission.AboveNormal},
+		{input: "OWNER", want: permission.Owner},
+		{input: "0", want: 0},
+		{input: "60", want: permission.Mod},
+		{input: "moderator", wantErr: true},
+		{input: "", wantErr: true},
+		{input: "256", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			t.Parallel()
+			var got permission.Level
+			err := got.UnmarshalText([]byte(tc.input))
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("UnmarshalText(%q) error = %v, wantErr %t", tc.input, err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("UnmarshalText(%q) = %s, want %s", tc.input, got.Name(), tc.want.Name())
+			}
+		})
+	}
+}
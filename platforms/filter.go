@@ -0,0 +1,139 @@
+package platforms
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/airforce270/airbot/base"
+	"github.com/airforce270/airbot/config"
+)
+
+var (
+	// errChatCommand is returned for messages that would be run as chat commands.
+	errChatCommand = errors.New("message would be run as a chat command")
+	// errBannedPhrase is returned for messages containing a banned phrase.
+	errBannedPhrase = errors.New("message contains a banned phrase")
+	// errBannedPattern is returned for messages matching a banned pattern.
+	errBannedPattern = errors.New("message matches a banned pattern")
+	// errMassPing is returned for messages mentioning too many users.
+	errMassPing = errors.New("message mentions too many users")
+)
+
+// filter checks outgoing messages against the configured rules.
+type filter struct {
+	// global contains the rules for all channels.
+	global filterRules
+	// channels contains the additional rules for specific channels, keyed by lowercased channel.
+	channels map[string]filterRules
+}
+
+// filterRules are the rules a message must pass to be sent.
+type filterRules struct {
+	// phrases contains lowercased banned phrases.
+	phrases []string
+	// patterns contains banned patterns.
+	patterns []*regexp.Regexp
+	// maxMentions is the maximum number of users a message may mention, or 0 if unlimited.
+	maxMentions int
+}
+
+// newFilter creates a filter from config.
+// Invalid patterns are logged and ignored.
+func newFilter(cfg config.FilterConfig, logger *slog.Logger) *filter {
+	f := &filter{
+		global:   newFilterRules(cfg.BannedPhrases, cfg.BannedPatterns, cfg.MaxMentions, logger),
+		channels: map[string]filterRules{},
+	}
+	for channel, channelCfg := range cfg.Channels {
+		f.channels[strings.ToLower(channel)] = newFilterRules(channelCfg.BannedPhrases, channelCfg.BannedPatterns, channelCfg.MaxMentions, logger)
+	}
+	return f
+}
+
+func newFilterRules(phrases, patterns []string, maxMentions int, logger *slog.Logger) filterRules {
+	rules := filterRules{maxMentions: maxMentions}
+	for _, phrase := range phrases {
+		if phrase = strings.TrimSpace(phrase); phrase != "" {
+			rules.phrases = append(rules.phrases, strings.ToLower(phrase))
+		}
+	}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			logger.Warn("Ignoring invalid banned pattern", "pattern", pattern, "error", err)
+			continue
+		}
+		rules.patterns = append(rules.patterns, re)
+	}
+	return rules
+}
+
+// Check returns an error describing why a message may not be sent,
+// or nil if it may be sent.
+// It should be run on whole messages, before they're split or pasted,
+// so mentions and phrases spread across parts are caught.
+func (f *filter) Check(msg base.Message) error {
+	if err := f.CheckPrefix(msg); err != nil {
+		return err
+	}
+	text := strings.TrimSpace(msg.Text)
+
+	channelRules := f.channels[strings.ToLower(msg.Channel)]
+	if maxMentions := f.maxMentions(msg.Channel); maxMentions > 0 {
+		if n := countMentions(text); n > maxMentions {
+			return fmt.Errorf("%w (%d, max %d)", errMassPing, n, maxMentions)
+		}
+	}
+
+	lowerText := strings.ToLower(text)
+	for _, rules := range []filterRules{f.global, channelRules} {
+		for _, phrase := range rules.phrases {
+			if strings.Contains(lowerText, phrase) {
+				return fmt.Errorf("%w (%q)", errBannedPhrase, phrase)
+			}
+		}
+		for _, re := range rules.patterns {
+			if re.MatchString(text) {
+				return fmt.Errorf("%w (%q)", errBannedPattern, re)
+			}
+		}
+	}
+	return nil
+}
+
+// CheckPrefix returns errChatCommand if a message would be run as a chat command,
+// or nil if it may be sent.
+// Unlike the other rules, this applies to each part a message is split into.
+func (f *filter) CheckPrefix(msg base.Message) error {
+	text := strings.TrimSpace(msg.Text)
+	if strings.HasPrefix(text, "/") || strings.HasPrefix(text, ".") {
+		return errChatCommand
+	}
+	return nil
+}
+
+// maxMentions returns the maximum number of users a message to a channel may mention,
+// or 0 if unlimited.
+func (f *filter) maxMentions(channel string) int {
+	if n := f.channels[strings.ToLower(channel)].maxMentions; n > 0 {
+		return n
+	}
+	return f.global.maxMentions
+}
+
+// countMentions returns the number of distinct users mentioned (@user) in text.
+func countMentions(text string) int {
+	mentioned := map[string]bool{}
+	for _, word := range strings.Fields(text) {
+		name, ok := strings.CutPrefix(word, "@")
+		name = strings.TrimRight(name, ",.!?:;")
+		if !ok || name == "" {
+			continue
+		}
+		mentioned[strings.ToLower(name)] = true
+	}
+	return len(mentioned)
+}
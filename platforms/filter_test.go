@@ -0,0 +1,122 @@
+package platforms
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/airforce270/airbot/base"
+	"github.com/airforce270/airbot/config"
+	"github.com/airforce270/airbot/logging"
+)
+
+func TestFilter_Check(t *testing.T) {
+	t.Parallel()
+	f := newFilter(config.FilterConfig{
+		BannedPhrases:  []string{"Bad Word"},
+		BannedPatterns: []string{`\bn[o0]pe\b`, `(invalid`},
+		MaxMentions:    3,
+		Channels: map[string]config.ChannelFilterConfig{
+			"User2": {
+				BannedPhrases:  []string{"spoiler"},
+				BannedPatterns: []string{`(?i)^gg$`},
+				MaxMentions:    1,
+			},
+		},
+	}, logging.Discard())
+
+	tests := []struct {
+		desc    string
+		channel string
+		text    string
+		wantErr error
+	}{
+		{
+			desc:    "allowed",
+			channel: "user1",
+			text:    "hello world",
+			wantErr: nil,
+		},
+		{
+			desc:    "slash command",
+			channel: "user1",
+			text:    "/ban user3",
+			wantErr: errChatCommand,
+		},
+		{
+			desc:    "dot command with leading space",
+			channel: "user1",
+			text:    " .ban user3",
+			wantErr: errChatCommand,
+		},
+		{
+			desc:    "slash later in message",
+			channel: "user1",
+			text:    "and/or",
+			wantErr: nil,
+		},
+		{
+			desc:    "global banned phrase, case-insensitive",
+			channel: "user1",
+			text:    "this is a BAD WORD here",
+			wantErr: errBannedPhrase,
+		},
+		{
+			desc:    "global banned pattern",
+			channel: "user1",
+			text:    "n0pe",
+			wantErr: errBannedPattern,
+		},
+		{
+			desc:    "channel banned phrase in other channel",
+			channel: "user1",
+			text:    "spoiler: it was fine",
+			wantErr: nil,
+		},
+		{
+			desc:    "channel banned phrase",
+			channel: "user2",
+			text:    "spoiler: it was fine",
+			wantErr: errBannedPhrase,
+		},
+		{
+			desc:    "channel banned pattern",
+			channel: "user2",
+			text:    "GG",
+			wantErr: errBannedPattern,
+		},
+		{
+			desc:    "global banned phrase in channel with own rules",
+			channel: "user2",
+			text:    "bad word",
+			wantErr: errBannedPhrase,
+		},
+		{
+			desc:    "mentions within global limit",
+			channel: "user1",
+			text:    "@a @b @c @a",
+			wantErr: nil,
+		},
+		{
+			desc:    "mass ping",
+			channel: "user1",
+			text:    "@a, @b, @c, @d",
+			wantErr: errMassPing,
+		},
+		{
+			desc:    "mass ping with channel limit",
+			channel: "user2",
+			text:    "@a @b",
+			wantErr: errMassPing,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			err := f.Check(base.Message{Channel: tc.channel, Text: tc.text})
+			if !errors.Is(err, tc.wantErr) || (err == nil) != (tc.wantErr == nil) {
+				t.Errorf("Check(%s, %q) error = %v, want %v", tc.channel, tc.text, err, tc.wantErr)
+			}
+		})
+	}
+}
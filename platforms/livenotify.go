@@ -0,0 +1,302 @@
+package platforms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	kickapi "github.com/airforce270/airbot/apiclients/kick"
+	"github.com/airforce270/airbot/base"
+	"github.com/airforce270/airbot/cache"
+	"github.com/airforce270/airbot/database/models"
+	"github.com/airforce270/airbot/platforms/twitch"
+
+	"golang.org/x/sync/errgroup"
+	"gorm.io/gorm"
+)
+
+const (
+	// livePollInterval is how often streams are checked for going live.
+	livePollInterval = 1 * time.Minute
+	// mentionsPerLiveMessage is the most users pinged in a single live notification,
+	// if the channel doesn't allow fewer.
+	mentionsPerLiveMessage = 10
+	// maxConcurrentKickFetches is the most Kick channels looked up at once.
+	maxConcurrentKickFetches = 5
+)
+
+// Live statuses stored in the cache.
+const (
+	liveStatusLive    = "live"
+	liveStatusOffline = "offline"
+)
+
+// streamStatus is the status of a stream.
+type streamStatus struct {
+	// Live is whether the stream is live.
+	Live bool
+	// Title is the title of the stream, if it's live.
+	Title string
+	// Game is the game or category being streamed, if it's live.
+	Game string
+}
+
+// streamFetcher returns the status of each of a list of lowercased streamers'
+// streams on a stream platform, by streamer.
+// Streamers whose streams couldn't be checked are left out.
+type streamFetcher func(streamPlatform string, streamers []string) (map[string]streamStatus, error)
+
+// liveNotifier notifies users on a platform when streams they've subscribed to go live.
+type liveNotifier struct {
+	// p is the platform users are notified on.
+	p base.Platform
+	// db is the database subscriptions are stored in.
+	db *gorm.DB
+	// cdb is the cache the last seen status of each stream is stored in.
+	cdb cache.Cache
+	// fetch looks up which streams are live.
+	fetch streamFetcher
+	// filter checks notifications before they're queued.
+	filter *filter
+	// split splits notifications that are too long.
+	split *splitter
+	// queue is where notifications are queued to be sent.
+	queue *outgoingQueue
+	// logger is the logger to log to.
+	logger *slog.Logger
+}
+
+// newStreamFetcher creates a streamFetcher that looks up Twitch streams with tw,
+// and Kick streams with kick.
+// If tw is nil, Twitch streams can't be looked up.
+func newStreamFetcher(tw *twitch.Twitch, kick *kickapi.Client, logger *slog.Logger) streamFetcher {
+	return func(streamPlatform string, streamers []string) (map[string]streamStatus, error) {
+		switch streamPlatform {
+		case models.StreamPlatformTwitch:
+			if tw == nil {
+				return nil, errors.New("twitch isn't enabled")
+			}
+			streams, err := tw.LiveStreams(streamers)
+			if err != nil {
+				return nil, err
+			}
+			statuses := make(map[string]streamStatus, len(streamers))
+			for _, streamer := range streamers {
+				statuses[streamer] = streamStatus{}
+			}
+			for streamer, stream := range streams {
+				statuses[streamer] = streamStatus{Live: true, Title: stream.Title, Game: stream.GameName}
+			}
+			return statuses, nil
+		case models.StreamPlatformKick:
+			return fetchKickStreams(kick.FetchChannel, streamers, logger), nil
+		default:
+			return nil, fmt.Errorf("unknown stream platform %q", streamPlatform)
+		}
+	}
+}
+
+// fetchKickStreams looks up Kick streamers' streams with fetchChannel,
+// up to maxConcurrentKickFetches at a time.
+// Streamers that fail to be looked up are logged and left out.
+func fetchKickStreams(fetchChannel func(channel string) (*kickapi.Channel, error), streamers []string, logger *slog.Logger) map[string]streamStatus {
+	var mu sync.Mutex
+	statuses := make(map[string]streamStatus, len(streamers))
+	var g errgroup.Group
+	g.SetLimit(maxConcurrentKickFetches)
+	for _, streamer := range streamers {
+		g.Go(func() error {
+			var status streamStatus
+			channel, err := fetchChannel(streamer)
+			switch {
+			case errors.Is(err, kickapi.ErrChannelNotFound):
+				// Channels that don't exist aren't live.
+			case err != nil:
+				logger.Warn("Failed to fetch Kick channel", "streamer", streamer, "error", err)
+				return nil
+			case channel.Livestream != nil && channel.Livestream.IsLive:
+				status = streamStatus{Live: true, Title: channel.Livestream.Title}
+				if len(channel.Livestream.Categories) > 0 {
+					status.Game = channel.Livestream.Categories[0].DisplayName
+				}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			statuses[streamer] = status
+			return nil
+		})
+	}
+	_ = g.Wait() // never returns an error
+	return statuses
+}
+
+// start checks for streams going live until the context is cancelled.
+// This function blocks and should be run within a goroutine.
+func (n *liveNotifier) start(ctx context.Context) {
+	ticker := time.NewTicker(livePollInterval)
+	defer ticker.Stop()
+	for {
+		n.pollRecovered()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pollRecovered calls poll, logging any error or panic.
+func (n *liveNotifier) pollRecovered() {
+	defer func() {
+		if r := recover(); r != nil {
+			n.logger.Error("Live notification poll panicked, recovered", "panic", r, "stack", string(debug.Stack()))
+		}
+	}()
+	if err := n.poll(); err != nil {
+		n.logger.Error("Failed to check for live streams", "error", err)
+	}
+}
+
+// streamKey identifies a stream.
+type streamKey struct {
+	// platform is the platform the stream is on.
+	platform string
+	// streamer is the lowercased name of the channel the stream is on.
+	streamer string
+}
+
+// poll checks whether subscribed streams have gone live since they were last checked,
+// and notifies their subscribers if so.
+// Streams that haven't been checked before are only recorded, so restarts don't re-notify.
+func (n *liveNotifier) poll() error {
+	var subs []models.LiveNotification
+	if err := n.db.Where(models.LiveNotification{Platform: n.p.Name()}).Order("id").Find(&subs).Error; err != nil {
+		return fmt.Errorf("failed to fetch live notification subscriptions: %w", err)
+	}
+
+	subsByStream := map[streamKey][]models.LiveNotification{}
+	streamersByPlatform := map[string][]string{}
+	for _, sub := range subs {
+		key := streamKey{platform: sub.StreamPlatform, streamer: strings.ToLower(sub.Streamer)}
+		if _, ok := subsByStream[key]; !ok {
+			streamersByPlatform[key.platform] = append(streamersByPlatform[key.platform], key.streamer)
+		}
+		subsByStream[key] = append(subsByStream[key], sub)
+	}
+
+	var errs []error
+	for streamPlatform, streamers := range streamersByPlatform {
+		statuses, err := n.fetch(streamPlatform, streamers)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to check %s streams: %w", streamPlatform, err))
+			continue
+		}
+		for _, streamer := range streamers {
+			stream, ok := statuses[streamer]
+			if !ok {
+				// The stream couldn't be checked, so its last status is kept.
+				continue
+			}
+			wentLive, err := n.updateStatus(streamPlatform, streamer, stream.Live)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if wentLive {
+				n.notify(streamPlatform, streamer, stream, subsByStream[streamKey{platform: streamPlatform, streamer: streamer}])
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// updateStatus records whether a stream is live, returning whether it went live since it was last checked.
+func (n *liveNotifier) updateStatus(streamPlatform, streamer string, isLive bool) (wentLive bool, err error) {
+	key := cache.LiveStatusKey(n.p.Name(), streamPlatform, streamer)
+	prev, err := n.cdb.FetchString(key)
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch live status of %s on %s: %w", streamer, streamPlatform, err)
+	}
+	status := liveStatusOffline
+	if isLive {
+		status = liveStatusLive
+	}
+	if status == prev {
+		return false, nil
+	}
+	if err := n.cdb.StoreString(key, status); err != nil {
+		return false, fmt.Errorf("failed to store live status of %s on %s: %w", streamer, streamPlatform, err)
+	}
+	return prev == liveStatusOffline && isLive, nil
+}
+
+// notify notifies subscribers that a stream went live.
+func (n *liveNotifier) notify(streamPlatform, streamer string, stream streamStatus, subs []models.LiveNotification) {
+	text := liveText(streamPlatform, streamer, stream)
+	n.logger.Info("Stream went live, notifying subscribers", "stream_platform", streamPlatform, "streamer", streamer, "subscribers", len(subs))
+
+	var pinged []models.LiveNotification
+	var whispers []*base.OutgoingMessage
+	for _, sub := range subs {
+		if !sub.Whisper {
+			pinged = append(pinged, sub)
+			continue
+		}
+		whispers = append(whispers, &base.OutgoingMessage{
+			Message:     base.Message{Text: text},
+			WhisperToID: sub.UserID,
+		})
+	}
+	queueAll(n.split, n.filter, n.queue, append(liveMessages(text, pinged, n.filter.maxMentions), whispers...), n.logger)
+}
+
+// liveText returns the text announcing that a stream went live.
+func liveText(streamPlatform, streamer string, stream streamStatus) string {
+	var text strings.Builder
+	fmt.Fprintf(&text, "%s is now live on %s", streamer, streamPlatform)
+	if stream.Game != "" {
+		fmt.Fprintf(&text, " playing %s", stream.Game)
+	}
+	if stream.Title != "" {
+		fmt.Fprintf(&text, ": %s", stream.Title)
+	}
+	return text.String()
+}
+
+// liveMessages returns the messages pinging subscribers about a stream going live,
+// with subscribers grouped by the channel they're pinged in.
+// Each message mentions no more users than the channel allows.
+func liveMessages(text string, subs []models.LiveNotification, maxMentions func(channel string) int) []*base.OutgoingMessage {
+	var channels []string
+	usersByChannel := map[string][]string{}
+	for _, sub := range subs {
+		channel := strings.ToLower(sub.Channel)
+		if _, ok := usersByChannel[channel]; !ok {
+			channels = append(channels, channel)
+		}
+		usersByChannel[channel] = append(usersByChannel[channel], "@"+sub.Username)
+	}
+
+	var msgs []*base.OutgoingMessage
+	for _, channel := range channels {
+		perMessage := mentionsPerLiveMessage
+		if limit := maxMentions(channel); limit > 0 && limit < perMessage {
+			perMessage = limit
+		}
+		for users := range slices.Chunk(usersByChannel[channel], perMessage) {
+			msgs = append(msgs, &base.OutgoingMessage{
+				Message: base.Message{
+					Channel: channel,
+					Text:    text + " | " + strings.Join(users, " "),
+				},
+			})
+		}
+	}
+	return msgs
+}
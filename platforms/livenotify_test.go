@@ -0,0 +1,217 @@
+package platforms
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	kickapi "github.com/airforce270/airbot/apiclients/kick"
+	"github.com/airforce270/airbot/base"
+	"github.com/airforce270/airbot/cache/cachetest"
+	"github.com/airforce270/airbot/config"
+	"github.com/airforce270/airbot/database/databasetest"
+	"github.com/airforce270/airbot/database/models"
+	"github.com/airforce270/airbot/logging"
+	"github.com/airforce270/airbot/platforms/twitch"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLiveNotifier_Poll(t *testing.T) {
+	t.Parallel()
+	db := databasetest.New(t)
+	tw := twitch.NewForTesting(t, "", db)
+	subs := []models.LiveNotification{
+		{Platform: tw.Name(), Channel: "user2", UserID: "1", Username: "user1", StreamPlatform: models.StreamPlatformTwitch, Streamer: "xqc"},
+		{Platform: tw.Name(), Channel: "user2", UserID: "2", Username: "user2", StreamPlatform: models.StreamPlatformTwitch, Streamer: "xqc", Whisper: true},
+		{Platform: tw.Name(), Channel: "user1", UserID: "3", Username: "user3", StreamPlatform: models.StreamPlatformKick, Streamer: "forsen"},
+	}
+	if err := db.Create(&subs).Error; err != nil {
+		t.Fatalf("Failed to create live notifications: %v", err)
+	}
+
+	// live contains the streams that are live, by stream platform.
+	// Streams in unchecked are left out of fetches, as if they couldn't be checked.
+	live := map[string]map[string]streamStatus{}
+	var unchecked []string
+	n := &liveNotifier{
+		p:   tw,
+		db:  db,
+		cdb: cachetest.NewDB(t, db),
+		fetch: func(streamPlatform string, streamers []string) (map[string]streamStatus, error) {
+			statuses := map[string]streamStatus{}
+			for _, streamer := range streamers {
+				if slices.Contains(unchecked, streamer) {
+					continue
+				}
+				stream, ok := live[streamPlatform][streamer]
+				stream.Live = ok
+				statuses[streamer] = stream
+			}
+			return statuses, nil
+		},
+		filter: newFilter(config.FilterConfig{}, logging.Discard()),
+		split:  newSplitter(tw, &config.Config{}, logging.Discard()),
+		queue:  newOutgoingQueue(maxQueuedMessagesPerChannel),
+		logger: logging.Discard(),
+	}
+
+	polls := []struct {
+		desc      string
+		live      map[string]map[string]streamStatus
+		unchecked []string
+		want      []base.OutgoingMessage
+	}{
+		{
+			desc: "first check only records status",
+			live: map[string]map[string]streamStatus{
+				models.StreamPlatformKick: {"forsen": {Title: "forsen title"}},
+			},
+		},
+		{
+			desc: "xqc goes live",
+			live: map[string]map[string]streamStatus{
+				models.StreamPlatformTwitch: {"xqc": {Title: "xqc title", Game: "Just Chatting"}},
+				models.StreamPlatformKick:   {"forsen": {Title: "forsen title"}},
+			},
+			want: []base.OutgoingMessage{
+				{Message: base.Message{Channel: "user2", Text: "xqc is now live on Twitch playing Just Chatting: xqc title | @user1"}},
+				{Message: base.Message{Text: "xqc is now live on Twitch playing Just Chatting: xqc title"}, WhisperToID: "2"},
+			},
+		},
+		{
+			desc: "still live",
+			live: map[string]map[string]streamStatus{
+				models.StreamPlatformTwitch: {"xqc": {Title: "xqc title", Game: "Just Chatting"}},
+			},
+		},
+		{
+			desc:      "xqc can't be checked",
+			unchecked: []string{"xqc"},
+		},
+		{
+			desc: "xqc is still live after a failed check",
+			live: map[string]map[string]streamStatus{
+				models.StreamPlatformTwitch: {"xqc": {Title: "xqc title", Game: "Just Chatting"}},
+			},
+		},
+		{
+			desc: "forsen goes live again",
+			live: map[string]map[string]streamStatus{
+				models.StreamPlatformKick: {"forsen": {Title: "forsen title 2"}},
+			},
+			want: []base.OutgoingMessage{
+				{Message: base.Message{Channel: "user1", Text: "forsen is now live on Kick: forsen title 2 | @user3"}},
+			},
+		},
+	}
+
+	for _, poll := range polls {
+		live, unchecked = poll.live, poll.unchecked
+
+		if err := n.poll(); err != nil {
+			t.Fatalf("[%s] poll() unexpected error: %v", poll.desc, err)
+		}
+
+		var got []base.OutgoingMessage
+		for _, channel := range n.queue.Channels() {
+			for {
+				msg, ok := n.queue.Pop(channel)
+				if !ok {
+					break
+				}
+				got = append(got, msg)
+			}
+		}
+		if diff := cmp.Diff(poll.want, got); diff != "" {
+			t.Errorf("[%s] poll() queued diff (-want +got):\n%s", poll.desc, diff)
+		}
+	}
+}
+
+func TestFetchKickStreams(t *testing.T) {
+	t.Parallel()
+	channels := map[string]*kickapi.Channel{
+		"offline": {},
+		"live": {Livestream: &kickapi.Livestream{
+			IsLive:     true,
+			Title:      "live title",
+			Categories: []kickapi.ExtendedCategory{{DisplayName: "Just Chatting"}},
+		}},
+	}
+	fetchChannel := func(channel string) (*kickapi.Channel, error) {
+		if channel == "broken" {
+			return nil, errors.New("kick is down")
+		}
+		if c, ok := channels[channel]; ok {
+			return c, nil
+		}
+		return nil, kickapi.ErrChannelNotFound
+	}
+
+	got := fetchKickStreams(fetchChannel, []string{"offline", "live", "broken", "missing"}, logging.Discard())
+
+	want := map[string]streamStatus{
+		"offline": {},
+		"live":    {Live: true, Title: "live title", Game: "Just Chatting"},
+		"missing": {},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("fetchKickStreams() diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestLiveMessages(t *testing.T) {
+	t.Parallel()
+	subs := func(channel string, users ...string) []models.LiveNotification {
+		var subs []models.LiveNotification
+		for _, user := range users {
+			subs = append(subs, models.LiveNotification{Channel: channel, Username: user})
+		}
+		return subs
+	}
+	tests := []struct {
+		desc        string
+		subs        []models.LiveNotification
+		maxMentions int
+		want        []*base.OutgoingMessage
+	}{
+		{
+			desc: "none",
+		},
+		{
+			desc: "grouped by channel",
+			subs: append(subs("user1", "a", "b"), subs("User2", "c")...),
+			want: []*base.OutgoingMessage{
+				{Message: base.Message{Channel: "user1", Text: "xqc is live | @a @b"}},
+				{Message: base.Message{Channel: "user2", Text: "xqc is live | @c"}},
+			},
+		},
+		{
+			desc:        "split by channel max mentions",
+			subs:        subs("user1", "a", "b", "c"),
+			maxMentions: 2,
+			want: []*base.OutgoingMessage{
+				{Message: base.Message{Channel: "user1", Text: "xqc is live | @a @b"}},
+				{Message: base.Message{Channel: "user1", Text: "xqc is live | @c"}},
+			},
+		},
+		{
+			desc: "split by default max mentions",
+			subs: subs("user1", "a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k"),
+			want: []*base.OutgoingMessage{
+				{Message: base.Message{Channel: "user1", Text: "xqc is live | @a @b @c @d @e @f @g @h @i @j"}},
+				{Message: base.Message{Channel: "user1", Text: "xqc is live | @k"}},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			got := liveMessages("xqc is live", tc.subs, func(string) int { return tc.maxMentions })
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("liveMessages() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
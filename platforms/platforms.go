@@ -3,15 +3,23 @@ package platforms
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
 	"runtime/debug"
+	"strings"
 	"time"
 
+	kickapi "github.com/airforce270/airbot/apiclients/kick"
 	"github.com/airforce270/airbot/base"
 	"github.com/airforce270/airbot/cache"
 	"github.com/airforce270/airbot/commands"
 	"github.com/airforce270/airbot/config"
+	"github.com/airforce270/airbot/logging"
+	"github.com/airforce270/airbot/metrics"
+	"github.com/airforce270/airbot/platforms/ratelimit"
 	"github.com/airforce270/airbot/platforms/twitch"
+	"github.com/airforce270/airbot/utils/secrets"
 
 	"gorm.io/gorm"
 )
@@ -19,110 +27,319 @@ import (
 const ctxCheckInterval = 50 * time.Millisecond
 
 // Build builds connections to enabled platforms based on the config.
-func Build(cfg *config.Config, db *gorm.DB, cdb cache.Cache) (map[string]base.Platform, error) {
+func Build(cfg *config.Config, db *gorm.DB, cdb cache.Cache, loggers *logging.Loggers) (map[string]base.Platform, error) {
 	p := map[string]base.Platform{}
 	if twc := cfg.Platforms.Twitch; twc.Enabled {
-		log.Printf("Building Twitch platform...")
-		tw := twitch.New(twc.Username, twc.Owners, twc.ClientID, twc.ClientSecret, twc.AccessToken, twc.RefreshToken, db, cdb)
+		loggers.For(logging.Platforms).Info("Building Twitch platform...")
+		tokenCipher, err := secrets.NewFromEnv()
+		if err != nil && !errors.Is(err, secrets.ErrNoKey) {
+			return nil, fmt.Errorf("failed to create token cipher: %w", err)
+		}
+		tw := twitch.New(twc.Username, twc.Owners, twc.ClientID, twc.ClientSecret, twc.AccessToken, twc.RefreshToken, tokenCipher, twc.EventSub, db, cdb, loggers.For(logging.Twitch))
 		p[twitch.Name] = tw
 	}
 	return p, nil
 }
 
 // StartHandling starts handling commands coming from the given platform.
+// When the context is cancelled, it stops accepting messages,
+// then waits for messages being handled and queued replies to be sent,
+// up to the configured shutdown timeout.
+// It returns an error describing anything abandoned.
 // This function blocks and should be run within a goroutine.
-func StartHandling(ctx context.Context, p base.Platform, db *gorm.DB, cdb cache.Cache, cfg *config.Config, allPlatforms map[string]base.Platform, logIncoming, logOutgoing bool) {
-	handler := commands.NewHandler(ctx, db, cdb, cfg, allPlatforms)
-	inC := p.Listen()
+func StartHandling(ctx context.Context, p base.Platform, db *gorm.DB, cdb cache.Cache, cfg *config.Config, allPlatforms map[string]base.Platform, loggers *logging.Loggers) error {
+	logger := loggers.For(logging.Platforms).With("platform", p.Name())
+	incoming, outgoing := logging.Discard(), logging.Discard()
+	if cfg.LogIncoming {
+		incoming = loggers.Sampled(logging.Incoming).With("platform", p.Name())
+	}
+	if cfg.LogOutgoing {
+		outgoing = loggers.Sampled(logging.Outgoing).With("platform", p.Name())
+	}
 
-	outC := make(chan base.OutgoingMessage, 100)
-	go startSending(ctx, p, outC, cdb, logOutgoing)
+	// Sending outlives ctx, so queued messages can be sent while shutting down.
+	sendCtx, stopSending := context.WithCancel(context.WithoutCancel(ctx))
+	defer stopSending()
+	s := newSender(p, newOutgoingQueue(maxQueuedMessagesPerChannel), newFilter(cfg.Filter, logger), cdb, logger, outgoing)
+	sending := make(chan struct{})
+	go func() {
+		defer close(sending)
+		s.start(sendCtx)
+	}()
 
-	timer := time.NewTicker(ctxCheckInterval)
-	for {
-		select {
-		case <-ctx.Done():
-			log.Print("Stopping message handling, context cancelled")
-			return
-		default:
-		}
+	split := newSplitter(p, cfg, logger)
 
+	tw, _ := allPlatforms[twitch.Name].(*twitch.Twitch)
+	notifier := &liveNotifier{
+		p:      p,
+		db:     db,
+		cdb:    cdb,
+		fetch:  newStreamFetcher(tw, kickapi.NewClient(kickapi.DefaultBaseURL, cfg.Platforms.Kick.JA3, cfg.Platforms.Kick.UserAgent), logger),
+		filter: s.filter,
+		split:  split,
+		queue:  s.queue,
+		logger: logger,
+	}
+	go notifier.start(ctx)
+
+	handler := commands.NewHandler(ctx, db, cdb, cfg, allPlatforms, s.queue, loggers.For(logging.Commands))
+	inC := p.Listen()
+	// Platforms that don't publish events leave this nil, so it's never received from.
+	var eventC <-chan base.Event
+	if source, ok := p.(base.EventSource); ok {
+		eventC = source.Events()
+	}
+
+	var handling inFlight
+	for accepting := true; accepting; {
 		select {
-		case <-timer.C:
+		case <-ctx.Done():
+			accepting = false
 		case msg := <-inC:
-			go processMessage(&handler, db, p, outC, msg, logIncoming)
+			handling.Go(func() { processMessage(&handler, split, s.filter, s.queue, msg, logger, incoming) })
+		case event := <-eventC:
+			handling.Go(func() { processEvent(&handler, split, s.filter, s.queue, p, event, logger, incoming) })
 		}
+	}
+
+	timeout := time.Duration(cfg.Shutdown.Timeout)
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	logger.Info("Stopped accepting messages, waiting for handling to finish", "in_flight", handling.Count(), "queued", s.queue.Depth(), "timeout", timeout)
+	deadline := time.Now().Add(timeout)
+	if handling.Wait(deadline) {
+		waitForEmpty(s.queue, deadline)
+	}
+	handler.FlushAudits()
+	stopSending()
+	<-sending
 
-		timer.Reset(ctxCheckInterval)
+	report := ShutdownReport{InFlight: handling.Count(), Unsent: s.queue.Drain()}
+	for _, msg := range report.Unsent {
+		logger.Warn("Abandoned unsent message", "channel", msg.Channel, "text", msg.Text)
 	}
+	if err := report.Err(); err != nil {
+		logger.Warn("Stopped message handling before it finished", "in_flight", report.InFlight, "unsent", len(report.Unsent))
+		return err
+	}
+	logger.Info("Stopped message handling")
+	return nil
 }
 
 // processMessage processes a single message and may queue messages to be sent in response.
-func processMessage(handler *commands.Handler, db *gorm.DB, p base.Platform, outC chan<- base.OutgoingMessage, msg base.IncomingMessage, logIncoming bool) {
+// Incoming messages are logged to incoming.
+func processMessage(handler *commands.Handler, split *splitter, filter *filter, queue *outgoingQueue, msg base.IncomingMessage, logger, incoming *slog.Logger) {
 	defer func() {
 		if r := recover(); r != nil {
-			log.Printf("processMessage panicked, recovered: %v; %s", r, debug.Stack())
+			logger.Error("processMessage panicked, recovered", "panic", r, "stack", string(debug.Stack()))
 		}
 	}()
 
-	if logIncoming {
-		log.Printf("[%s<- %s/%s]: %s", p.Name(), msg.Message.Channel, msg.Message.User, msg.Message.Text)
-	}
+	incoming.Info("Received message", "channel", msg.Message.Channel, "user", msg.Message.User, "text", msg.Message.Text)
+	metrics.MessagesReceived.WithLabelValues(msg.Resources.Platform.Name(), strings.ToLower(msg.Message.Channel)).Inc()
 
 	outMsgs, err := handler.Handle(&msg)
 	if err != nil {
-		log.Printf("Failed to handle message %v: %v", msg, err)
-		return
-	}
-	if len(outMsgs) == 0 {
+		logger.Error("Failed to handle message", "channel", msg.Message.Channel, "user", msg.Message.User, "text", msg.Message.Text, "error", err)
 		return
 	}
+	queueAll(split, filter, queue, outMsgs, logger)
+}
 
+// queueAll splits messages to fit the platform and queues them to be sent.
+// Messages blocked by the filter are dropped before they're split or pasted.
+func queueAll(split *splitter, filter *filter, queue *outgoingQueue, outMsgs []*base.OutgoingMessage, logger *slog.Logger) {
 	for _, outMsg := range outMsgs {
-		outC <- *outMsg
+		if err := filter.Check(outMsg.Message); err != nil {
+			logger.Warn("Blocked message", "channel", outMsg.Channel, "text", outMsg.Text, "error", err)
+			continue
+		}
+		for _, part := range split.Split(*outMsg) {
+			if err := queue.Push(part); err != nil {
+				logger.Warn("Dropping message", "channel", part.Channel, "text", part.Text, "error", err)
+			}
+		}
 	}
 }
 
+// processEvent processes a single event and may queue messages to be sent in response.
+// Incoming events are logged to incoming.
+func processEvent(handler *commands.Handler, split *splitter, filter *filter, queue *outgoingQueue, p base.Platform, event base.Event, logger, incoming *slog.Logger) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("processEvent panicked, recovered", "panic", r, "stack", string(debug.Stack()))
+		}
+	}()
+
+	incoming.Info("Received event", "kind", event.Kind, "channel", event.Channel, "user", event.User)
+	metrics.EventsReceived.WithLabelValues(p.Name(), string(event.Kind)).Inc()
+
+	outMsgs, err := handler.HandleEvent(&base.IncomingEvent{Event: event, Resources: base.Resources{Platform: p}})
+	if err != nil {
+		logger.Error("Failed to handle event", "kind", event.Kind, "channel", event.Channel, "user", event.User, "error", err)
+	}
+	queueAll(split, filter, queue, outMsgs, logger)
+}
+
 const slowmodeSleepDuration = 1 * time.Second
 
-// startSending sends messages from the queue.
-func startSending(ctx context.Context, p base.Platform, outC <-chan base.OutgoingMessage, cdb cache.Cache, logOutgoing bool) {
-	timer := time.NewTicker(ctxCheckInterval)
+// sender sends queued messages to a platform, respecting its rate limits.
+type sender struct {
+	p       base.Platform
+	queue   *outgoingQueue
+	filter  *filter
+	cdb     cache.Cache
+	limiter *ratelimit.Limiter
+	logger  *slog.Logger
+	// outgoing is the logger sent messages are logged to.
+	outgoing *slog.Logger
+}
+
+func newSender(p base.Platform, queue *outgoingQueue, filter *filter, cdb cache.Cache, logger, outgoing *slog.Logger) *sender {
+	return &sender{
+		p:        p,
+		queue:    queue,
+		filter:   filter,
+		cdb:      cdb,
+		limiter:  ratelimit.New(),
+		logger:   logger,
+		outgoing: outgoing,
+	}
+}
+
+// start sends messages from the queue.
+// This function blocks and should be run within a goroutine.
+func (s *sender) start(ctx context.Context) {
 	for {
 		select {
 		case <-ctx.Done():
-			log.Print("Stopping message sending, context cancelled")
+			s.logger.Info("Stopping message sending, context cancelled")
 			return
 		default:
 		}
 
+		out, wait, ok := s.next()
+		metrics.SendQueueDepth.WithLabelValues(s.p.Name()).Set(float64(s.queue.Depth()))
+		if ok {
+			s.send(out)
+			continue
+		}
+
+		if wait <= 0 {
+			wait = ctxCheckInterval
+		}
+		timer := time.NewTimer(wait)
 		select {
+		case <-ctx.Done():
+		case <-s.queue.Pushed():
 		case <-timer.C:
-		case out := <-outC:
-			if logOutgoing {
-				log.Printf("[%s-> %s/%s]: %s", p.Name(), out.Channel, p.Username(), out.Text)
-			}
+		}
+		timer.Stop()
+	}
+}
 
-			if out.ReplyToID != "" {
-				if err := p.Reply(out.Message, out.ReplyToID); err != nil {
-					log.Printf("Failed to send message (reply) %v: %v", out, err)
-				}
-			} else {
-				if err := p.Send(out.Message); err != nil {
-					log.Printf("Failed to send message %v: %v", out, err)
+// next returns the next message that can be sent,
+// trying each channel with queued messages in turn.
+// Messages that would be run as chat commands are dropped before reserving a rate limit,
+// so they don't use up the channel's budget.
+// If none can be sent yet, it returns how long until one can
+// (or 0 if there are no messages).
+func (s *sender) next() (out base.OutgoingMessage, wait time.Duration, ok bool) {
+	limited, isLimited := s.p.(ratelimit.Platform)
+	whisperLimited, isWhisperLimited := s.p.(ratelimit.WhisperPlatform)
+	for _, channel := range s.queue.Channels() {
+		next, ok := s.peekAllowed(channel)
+		if !ok {
+			continue
+		}
+		if isLimited || isWhisperLimited {
+			var rules []ratelimit.Rule
+			switch {
+			case next.WhisperToID != "" && isWhisperLimited:
+				rules = whisperLimited.WhisperRateLimits()
+			case next.WhisperToID == "" && isLimited:
+				rules = limited.RateLimits(channel)
+			}
+			if delay := s.limiter.Reserve(rules); delay > 0 {
+				if wait == 0 || delay < wait {
+					wait = delay
 				}
+				continue
 			}
+		}
+		if out, ok := s.queue.Pop(channel); ok {
+			return out, 0, true
+		}
+	}
+	return base.OutgoingMessage{}, wait, false
+}
 
-			slowmode, err := cdb.FetchBool(cache.GlobalSlowmodeKey(p.Name()))
-			if err != nil {
-				log.Printf("Failed to fetch slowmode status for %s: %v", p.Name(), err)
-			}
+// peekAllowed returns the next message queued for a channel that wouldn't be run as a chat command
+// and that the platform can send, dropping any before it that can't be sent.
+// The rest of the filter is checked before messages are split and queued.
+func (s *sender) peekAllowed(channel string) (base.OutgoingMessage, bool) {
+	checker, canCheck := s.p.(base.SendChecker)
+	for {
+		next, ok := s.queue.Peek(channel)
+		if !ok {
+			return base.OutgoingMessage{}, false
+		}
+		err := s.filter.CheckPrefix(next.Message)
+		if err == nil && canCheck && next.WhisperToID == "" {
+			err = checker.CanSend(next.Message)
+		}
+		if err == nil {
+			return next, true
+		}
+		s.logger.Warn("Blocked message", "channel", next.Channel, "text", next.Text, "error", err)
+		s.queue.Pop(channel)
+	}
+}
 
-			if slowmode {
-				time.Sleep(slowmodeSleepDuration)
-			}
+// send sends a single message.
+func (s *sender) send(out base.OutgoingMessage) {
+	s.outgoing.Info("Sending message", "channel", out.Channel, "whisper_to", out.WhisperToID, "user", s.p.Username(), "text", out.Text)
+
+	if out.WhisperToID != "" {
+		s.whisper(out)
+		return
+	}
+
+	var err error
+	if out.ReplyToID != "" {
+		if err = s.p.Reply(out.Message, out.ReplyToID); err != nil {
+			s.logger.Error("Failed to send message (reply)", "channel", out.Channel, "text", out.Text, "reply_to", out.ReplyToID, "error", err)
 		}
+	} else {
+		if err = s.p.Send(out.Message); err != nil {
+			s.logger.Error("Failed to send message", "channel", out.Channel, "text", out.Text, "error", err)
+		}
+	}
+	if err == nil {
+		metrics.MessagesSent.WithLabelValues(s.p.Name(), strings.ToLower(out.Channel)).Inc()
+	}
+
+	slowmode, err := s.cdb.FetchBool(cache.GlobalSlowmodeKey(s.p.Name()))
+	if err != nil {
+		s.logger.Error("Failed to fetch slowmode status", "error", err)
+	}
+
+	if slowmode {
+		time.Sleep(slowmodeSleepDuration)
+	}
+}
 
-		timer.Reset(ctxCheckInterval)
+// whisper sends a single whisper.
+func (s *sender) whisper(out base.OutgoingMessage) {
+	whisperer, ok := s.p.(base.Whisperer)
+	if !ok {
+		s.logger.Error("Failed to send whisper, platform doesn't support whispers", "whisper_to", out.WhisperToID, "text", out.Text)
+		return
+	}
+	if err := whisperer.Whisper(out.WhisperToID, out.Text); err != nil {
+		s.logger.Error("Failed to send whisper", "whisper_to", out.WhisperToID, "text", out.Text, "error", err)
+		return
 	}
+	metrics.WhispersSent.WithLabelValues(s.p.Name()).Inc()
 }
@@ -0,0 +1,103 @@
+package platforms
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/airforce270/airbot/base"
+	"github.com/airforce270/airbot/cache/cachetest"
+	"github.com/airforce270/airbot/config"
+	"github.com/airforce270/airbot/database/databasetest"
+	"github.com/airforce270/airbot/logging"
+	"github.com/airforce270/airbot/platforms/twitch"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSender_Next_DropsBlockedMessagesBeforeRateLimiting(t *testing.T) {
+	t.Parallel()
+	db := databasetest.New(t)
+	s := newSender(twitch.NewForTesting(t, "", db), newOutgoingQueue(10), newFilter(config.FilterConfig{}, logging.Discard()), cachetest.NewDB(t, db), logging.Discard(), logging.Discard())
+	push(t, s.queue, "user1", "/ban someone", "hello", "again")
+
+	out, _, ok := s.next()
+	if !ok || out.Text != "hello" {
+		t.Fatalf("next() = %q, %t; want hello, true", out.Text, ok)
+	}
+	// One message a second may be sent to a channel, so this is held back
+	// by the message just sent, not the blocked one.
+	if out, wait, ok := s.next(); ok || wait <= 0 {
+		t.Errorf("next() = %q, %s, %t; want it rate limited", out.Text, wait, ok)
+	}
+	if got := s.queue.Depth(); got != 1 {
+		t.Errorf("Depth() = %d, want 1", got)
+	}
+}
+
+// refusingPlatform is a platform that can't send messages containing "refused".
+type refusingPlatform struct {
+	*twitch.Twitch
+}
+
+func (p refusingPlatform) CanSend(msg base.Message) error {
+	if strings.Contains(msg.Text, "refused") {
+		return errors.New("refused")
+	}
+	return nil
+}
+
+func TestSender_Next_DropsUnsendableMessagesBeforeRateLimiting(t *testing.T) {
+	t.Parallel()
+	db := databasetest.New(t)
+	p := refusingPlatform{twitch.NewForTesting(t, "", db)}
+	s := newSender(p, newOutgoingQueue(10), newFilter(config.FilterConfig{}, logging.Discard()), cachetest.NewDB(t, db), logging.Discard(), logging.Discard())
+	push(t, s.queue, "user1", "refused", "hello")
+
+	out, _, ok := s.next()
+	if !ok || out.Text != "hello" {
+		t.Fatalf("next() = %q, %t; want hello, true", out.Text, ok)
+	}
+	if got := s.queue.Depth(); got != 0 {
+		t.Errorf("Depth() = %d, want 0", got)
+	}
+}
+
+func TestQueueAll_FiltersWholeMessages(t *testing.T) {
+	t.Parallel()
+	var pasted []string
+	split := &splitter{
+		maxLength:  10,
+		pasteAfter: 3,
+		paste: func(text string) (string, error) {
+			pasted = append(pasted, text)
+			return "https://pastebin.com/abc", nil
+		},
+	}
+	f := newFilter(config.FilterConfig{BannedPhrases: []string{"bad word"}, MaxMentions: 2}, logging.Discard())
+	q := newOutgoingQueue(10)
+
+	queueAll(split, f, q, []*base.OutgoingMessage{
+		// Each part mentions at most 2 users.
+		{Message: base.Message{Channel: "user1", Text: "@aaa @bbb @ccc @ddd"}},
+		// The banned phrase is split across parts.
+		{Message: base.Message{Channel: "user1", Text: "a very bad word"}},
+		// Long enough to be pasted.
+		{Message: base.Message{Channel: "user1", Text: strings.Repeat("word ", 10) + "bad word"}},
+		{Message: base.Message{Channel: "user1", Text: "hello world"}},
+	}, logging.Discard())
+
+	var got []string
+	for {
+		out, ok := q.Pop("user1")
+		if !ok {
+			break
+		}
+		got = append(got, out.Text)
+	}
+	if diff := cmp.Diff([]string{"hello", "world"}, got); diff != "" {
+		t.Errorf("queued messages diff (-want +got):\n%s", diff)
+	}
+	if len(pasted) > 0 {
+		t.Errorf("pasted %q, want blocked messages not to be pasted", pasted)
+	}
+}
@@ -0,0 +1,204 @@
+package platforms
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/airforce270/airbot/base"
+)
+
+// maxQueuedMessagesPerChannel is the maximum number of messages
+// that can be waiting to be sent to a single channel.
+const maxQueuedMessagesPerChannel = 100
+
+// whisperQueuePrefix is the prefix of the keys of whisper queues.
+// It can't appear in channel names.
+const whisperQueuePrefix = "whisper:"
+
+// errQueueFull is returned when a message is pushed to a channel whose queue is full.
+var errQueueFull = errors.New("channel's outgoing queue is full")
+
+// outgoingQueue holds messages waiting to be sent, in a separate queue per channel,
+// so a busy channel can't delay messages to other channels.
+// Whispers are queued per recipient, as if each were a channel.
+// Channels are served round-robin.
+// When a channel's queue is full, new messages to it are dropped.
+type outgoingQueue struct {
+	// maxPerChannel is the maximum number of messages queued per channel.
+	maxPerChannel int
+	// pushed receives a value when a message is pushed.
+	pushed chan struct{}
+
+	mu sync.Mutex
+	// queues contains the queued messages, keyed by lowercased channel.
+	queues map[string][]base.OutgoingMessage
+	// order contains the channels with queued messages, in the order they're served.
+	order []string
+	// next is the index in order of the next channel to be served.
+	next int
+}
+
+func newOutgoingQueue(maxPerChannel int) *outgoingQueue {
+	return &outgoingQueue{
+		maxPerChannel: maxPerChannel,
+		pushed:        make(chan struct{}, 1),
+		queues:        map[string][]base.OutgoingMessage{},
+	}
+}
+
+// Push adds a message to the end of its channel's queue.
+// It returns errQueueFull if the channel's queue is full, in which case the message is dropped.
+func (q *outgoingQueue) Push(msg base.OutgoingMessage) error {
+	channel := queueKey(msg)
+
+	q.mu.Lock()
+	queue, ok := q.queues[channel]
+	if len(queue) >= q.maxPerChannel {
+		q.mu.Unlock()
+		return errQueueFull
+	}
+	if !ok {
+		q.order = append(q.order, channel)
+	}
+	q.queues[channel] = append(queue, msg)
+	q.mu.Unlock()
+
+	select {
+	case q.pushed <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Depth returns the number of messages waiting to be sent.
+func (q *outgoingQueue) Depth() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var depth int
+	for _, queue := range q.queues {
+		depth += len(queue)
+	}
+	return depth
+}
+
+// ChannelDepth returns the number of messages waiting to be sent to a channel.
+func (q *outgoingQueue) ChannelDepth(channel string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.queues[strings.ToLower(channel)])
+}
+
+// Cancel removes all messages waiting to be sent to a channel,
+// returning the number of messages removed.
+func (q *outgoingQueue) Cancel(channel string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	channel = strings.ToLower(channel)
+	n := len(q.queues[channel])
+	q.remove(channel)
+	return n
+}
+
+// Drain removes and returns all messages waiting to be sent.
+func (q *outgoingQueue) Drain() []base.OutgoingMessage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var msgs []base.OutgoingMessage
+	for _, channel := range q.order {
+		msgs = append(msgs, q.queues[channel]...)
+	}
+	clear(q.queues)
+	q.order = nil
+	q.next = 0
+	return msgs
+}
+
+// Channels returns the channels with queued messages,
+// starting with the next channel to be served.
+func (q *outgoingQueue) Channels() []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	channels := make([]string, 0, len(q.order))
+	channels = append(channels, q.order[q.next:]...)
+	return append(channels, q.order[:q.next]...)
+}
+
+// Peek returns the next message to be sent to a channel without removing it.
+func (q *outgoingQueue) Peek(channel string) (base.OutgoingMessage, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	queue := q.queues[strings.ToLower(channel)]
+	if len(queue) == 0 {
+		return base.OutgoingMessage{}, false
+	}
+	return queue[0], true
+}
+
+// Pop removes and returns the next message to be sent to a channel.
+// The channel after it becomes the next to be served.
+func (q *outgoingQueue) Pop(channel string) (base.OutgoingMessage, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	channel = strings.ToLower(channel)
+	queue := q.queues[channel]
+	if len(queue) == 0 {
+		return base.OutgoingMessage{}, false
+	}
+
+	msg := queue[0]
+	i := q.indexOf(channel)
+	if len(queue) == 1 {
+		q.remove(channel)
+		// Removing the channel shifts the channel after it into its index.
+		q.next = i
+	} else {
+		q.queues[channel] = queue[1:]
+		q.next = i + 1
+	}
+	if q.next >= len(q.order) {
+		q.next = 0
+	}
+	return msg, true
+}
+
+// Pushed returns a channel that receives a value when a message is pushed.
+func (q *outgoingQueue) Pushed() <-chan struct{} {
+	return q.pushed
+}
+
+// remove removes a channel and its messages.
+// Must be called with q.mu held.
+func (q *outgoingQueue) remove(channel string) {
+	delete(q.queues, channel)
+	i := q.indexOf(channel)
+	if i == -1 {
+		return
+	}
+	q.order = append(q.order[:i], q.order[i+1:]...)
+	if i < q.next {
+		q.next--
+	}
+	if q.next >= len(q.order) {
+		q.next = 0
+	}
+}
+
+// indexOf returns the index of a channel in the serving order, or -1.
+// Must be called with q.mu held.
+func (q *outgoingQueue) indexOf(channel string) int {
+	for i, c := range q.order {
+		if c == channel {
+			return i
+		}
+	}
+	return -1
+}
+
+// queueKey returns the key of the queue a message waits in.
+func queueKey(msg base.OutgoingMessage) string {
+	if msg.WhisperToID != "" {
+		return whisperQueuePrefix + msg.WhisperToID
+	}
+	return strings.ToLower(msg.Channel)
+}
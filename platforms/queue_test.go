@@ -0,0 +1,142 @@
+package platforms
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/airforce270/airbot/base"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestOutgoingQueue_RoundRobin(t *testing.T) {
+	t.Parallel()
+	q := newOutgoingQueue(10)
+	push(t, q, "user1", "a1", "a2", "a3")
+	push(t, q, "user2", "b1")
+	push(t, q, "user3", "c1", "c2")
+
+	var got []string
+	for {
+		channels := q.Channels()
+		if len(channels) == 0 {
+			break
+		}
+		msg, ok := q.Pop(channels[0])
+		if !ok {
+			t.Fatalf("Pop(%q) found no message", channels[0])
+		}
+		got = append(got, msg.Text)
+	}
+
+	want := []string{"a1", "b1", "c1", "a2", "c2", "a3"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Messages sent in wrong order (-want +got):\n%s", diff)
+	}
+}
+
+func TestOutgoingQueue_SkipsLimitedChannel(t *testing.T) {
+	t.Parallel()
+	q := newOutgoingQueue(10)
+	push(t, q, "user1", "a1", "a2")
+	push(t, q, "user2", "b1", "b2")
+
+	// user1 is rate limited, so user2 is served out of turn.
+	if msg, _ := q.Pop("user2"); msg.Text != "b1" {
+		t.Fatalf("Pop(user2) = %q, want b1", msg.Text)
+	}
+	want := []string{"user1", "user2"}
+	if diff := cmp.Diff(want, q.Channels()); diff != "" {
+		t.Errorf("Channels() unexpected result (-want +got):\n%s", diff)
+	}
+}
+
+func TestOutgoingQueue_Overflow(t *testing.T) {
+	t.Parallel()
+	q := newOutgoingQueue(2)
+	push(t, q, "user1", "a1", "a2")
+
+	if err := q.Push(base.OutgoingMessage{Message: base.Message{Channel: "user1", Text: "a3"}}); !errors.Is(err, errQueueFull) {
+		t.Errorf("Push() to full queue error = %v, want %v", err, errQueueFull)
+	}
+	if err := q.Push(base.OutgoingMessage{Message: base.Message{Channel: "user2", Text: "b1"}}); err != nil {
+		t.Errorf("Push() to other channel unexpected error = %v", err)
+	}
+	if got := q.ChannelDepth("USER1"); got != 2 {
+		t.Errorf("ChannelDepth(USER1) = %d, want 2", got)
+	}
+	if got := q.Depth(); got != 3 {
+		t.Errorf("Depth() = %d, want 3", got)
+	}
+}
+
+func TestOutgoingQueue_Cancel(t *testing.T) {
+	t.Parallel()
+	q := newOutgoingQueue(10)
+	push(t, q, "user1", "a1", "a2")
+	push(t, q, "user2", "b1")
+
+	if got := q.Cancel("user1"); got != 2 {
+		t.Errorf("Cancel(user1) = %d, want 2", got)
+	}
+	if got := q.Cancel("user1"); got != 0 {
+		t.Errorf("second Cancel(user1) = %d, want 0", got)
+	}
+	want := []string{"user2"}
+	if diff := cmp.Diff(want, q.Channels()); diff != "" {
+		t.Errorf("Channels() unexpected result (-want +got):\n%s", diff)
+	}
+	if got := q.Depth(); got != 1 {
+		t.Errorf("Depth() = %d, want 1", got)
+	}
+}
+
+func TestOutgoingQueue_Drain(t *testing.T) {
+	t.Parallel()
+	q := newOutgoingQueue(10)
+	push(t, q, "user1", "a1", "a2")
+	push(t, q, "user2", "b1")
+
+	var got []string
+	for _, msg := range q.Drain() {
+		got = append(got, msg.Text)
+	}
+	want := []string{"a1", "a2", "b1"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Drain() unexpected result (-want +got):\n%s", diff)
+	}
+	if got := q.Depth(); got != 0 {
+		t.Errorf("Depth() after Drain() = %d, want 0", got)
+	}
+	if got := q.Channels(); len(got) != 0 {
+		t.Errorf("Channels() after Drain() = %v, want none", got)
+	}
+}
+
+func TestOutgoingQueue_Whispers(t *testing.T) {
+	t.Parallel()
+	q := newOutgoingQueue(1)
+	push(t, q, "user1", "a1")
+	for _, userID := range []string{"1", "2"} {
+		if err := q.Push(base.OutgoingMessage{Message: base.Message{Text: "w" + userID}, WhisperToID: userID}); err != nil {
+			t.Fatalf("Push(whisper to %s) unexpected error: %v", userID, err)
+		}
+	}
+
+	// Whispers are queued per recipient, separately from channels.
+	want := []string{"user1", "whisper:1", "whisper:2"}
+	if diff := cmp.Diff(want, q.Channels()); diff != "" {
+		t.Errorf("Channels() unexpected result (-want +got):\n%s", diff)
+	}
+	if msg, _ := q.Pop("whisper:2"); msg.Text != "w2" || msg.WhisperToID != "2" {
+		t.Errorf("Pop(whisper:2) = %+v, want whisper w2 to 2", msg)
+	}
+}
+
+func push(t *testing.T, q *outgoingQueue, channel string, texts ...string) {
+	t.Helper()
+	for _, text := range texts {
+		if err := q.Push(base.OutgoingMessage{Message: base.Message{Channel: channel, Text: text}}); err != nil {
+			t.Fatalf("Push(%s, %s) unexpected error: %v", channel, text, err)
+		}
+	}
+}
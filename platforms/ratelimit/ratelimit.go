@@ -0,0 +1,138 @@
+// Package ratelimit provides token-bucket rate limiting for outgoing messages.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Rate is a number of messages allowed per period.
+type Rate struct {
+	// Count is the number of messages allowed per period.
+	Count int
+	// Per is the period.
+	Per time.Duration
+}
+
+// Rule is a rate limit on a bucket.
+// Buckets are shared by every message subject to a rule with the same bucket name,
+// so a bucket can limit a single channel or all channels on a platform.
+type Rule struct {
+	// Bucket is the name of the bucket the rule applies to, i.e. "channel:user1".
+	Bucket string
+	// Rate is the bucket's rate limit.
+	Rate Rate
+}
+
+// Platform is implemented by platforms that limit how quickly messages may be sent.
+type Platform interface {
+	// RateLimits returns the rules that apply to sending a message to a channel.
+	// A message is only sent once it fits within every rule.
+	RateLimits(channel string) []Rule
+}
+
+// WhisperPlatform is implemented by platforms that limit how quickly whispers may be sent.
+type WhisperPlatform interface {
+	// WhisperRateLimits returns the rules that apply to sending a whisper.
+	// A whisper is only sent once it fits within every rule.
+	WhisperRateLimits() []Rule
+}
+
+// Limiter is a rate limiter made up of token buckets.
+// Each bucket holds up to its rate's count of tokens, starts full,
+// and refills at its rate; sending a message takes a token from every bucket it's subject to.
+type Limiter struct {
+	// now returns the current time. Overridden in test.
+	now func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// New creates a new Limiter.
+func New() *Limiter {
+	return &Limiter{
+		now:     time.Now,
+		buckets: map[string]*bucket{},
+	}
+}
+
+// Reserve takes a token from each of the rules' buckets if all of them have one,
+// returning 0, in which case a message subject to the rules may be sent.
+// Otherwise, it takes nothing and returns how long to wait before trying again.
+func (l *Limiter) Reserve(rules []Rule) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	var delay time.Duration
+	for _, rule := range rules {
+		b := l.bucket(rule, now)
+		if d := b.timeUntilToken(); d > delay {
+			delay = d
+		}
+	}
+	if delay > 0 {
+		return delay
+	}
+
+	for _, rule := range rules {
+		if b := l.buckets[rule.Bucket]; b.limited() {
+			b.tokens--
+		}
+	}
+	return 0
+}
+
+// bucket returns the bucket for a rule, creating or updating it as needed.
+// Must be called with l.mu held.
+func (l *Limiter) bucket(rule Rule, now time.Time) *bucket {
+	b, ok := l.buckets[rule.Bucket]
+	if !ok {
+		b = &bucket{tokens: float64(rule.Rate.Count), last: now}
+		l.buckets[rule.Bucket] = b
+	}
+	b.refill(rule.Rate, now)
+	return b
+}
+
+// bucket is a token bucket.
+type bucket struct {
+	// rate is the bucket's current rate.
+	rate Rate
+	// tokens is the number of tokens in the bucket.
+	tokens float64
+	// last is when the bucket was last refilled.
+	last time.Time
+}
+
+// refill adds the tokens earned since the last refill.
+// The rate may change between calls, i.e. when the bot is modded.
+func (b *bucket) refill(rate Rate, now time.Time) {
+	b.rate = rate
+	if !b.limited() {
+		return
+	}
+	elapsed := now.Sub(b.last)
+	b.last = now
+	if elapsed > 0 {
+		b.tokens += elapsed.Seconds() * float64(rate.Count) / rate.Per.Seconds()
+	}
+	if capacity := float64(rate.Count); b.tokens > capacity {
+		b.tokens = capacity
+	}
+}
+
+// timeUntilToken returns how long until the bucket has a whole token.
+func (b *bucket) timeUntilToken() time.Duration {
+	if !b.limited() || b.tokens >= 1 {
+		return 0
+	}
+	perToken := b.rate.Per.Seconds() / float64(b.rate.Count)
+	return time.Duration((1 - b.tokens) * perToken * float64(time.Second))
+}
+
+// limited returns whether the bucket's rate limits anything.
+func (b *bucket) limited() bool {
+	return b.rate.Count > 0 && b.rate.Per > 0
+}
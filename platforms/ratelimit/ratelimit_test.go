@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter_Reserve(t *testing.T) {
+	t.Parallel()
+	perChannel := Rule{Bucket: "channel:user1", Rate: Rate{Count: 1, Per: time.Second}}
+	global := Rule{Bucket: "global", Rate: Rate{Count: 3, Per: 30 * time.Second}}
+
+	type step struct {
+		advance time.Duration
+		rules   []Rule
+		want    time.Duration
+	}
+	tests := []struct {
+		desc  string
+		steps []step
+	}{
+		{
+			desc: "bucket starts full",
+			steps: []step{
+				{rules: []Rule{global}, want: 0},
+				{rules: []Rule{global}, want: 0},
+				{rules: []Rule{global}, want: 0},
+				{rules: []Rule{global}, want: 10 * time.Second},
+			},
+		},
+		{
+			desc: "bucket refills",
+			steps: []step{
+				{rules: []Rule{perChannel}, want: 0},
+				{rules: []Rule{perChannel}, want: time.Second},
+				{advance: 400 * time.Millisecond, rules: []Rule{perChannel}, want: 600 * time.Millisecond},
+				{advance: 600 * time.Millisecond, rules: []Rule{perChannel}, want: 0},
+			},
+		},
+		{
+			desc: "message must fit every rule",
+			steps: []step{
+				{rules: []Rule{perChannel, global}, want: 0},
+				{advance: time.Second, rules: []Rule{perChannel, global}, want: 0},
+				{advance: time.Second, rules: []Rule{perChannel, global}, want: 0},
+				// The channel bucket has a token, but the global bucket doesn't.
+				{advance: time.Second, rules: []Rule{perChannel, global}, want: 7 * time.Second},
+				// Nothing was taken from the channel bucket while waiting.
+				{advance: 7 * time.Second, rules: []Rule{perChannel, global}, want: 0},
+			},
+		},
+		{
+			desc: "buckets are independent",
+			steps: []step{
+				{rules: []Rule{perChannel}, want: 0},
+				{rules: []Rule{{Bucket: "channel:user2", Rate: perChannel.Rate}}, want: 0},
+				{rules: []Rule{perChannel}, want: time.Second},
+			},
+		},
+		{
+			desc: "rate can change",
+			steps: []step{
+				{rules: []Rule{perChannel}, want: 0},
+				{rules: []Rule{{Bucket: perChannel.Bucket, Rate: Rate{Count: 100, Per: 30 * time.Second}}}, want: 300 * time.Millisecond},
+			},
+		},
+		{
+			desc: "zero rate is unlimited",
+			steps: []step{
+				{rules: []Rule{{Bucket: "unlimited"}}, want: 0},
+				{rules: []Rule{{Bucket: "unlimited"}}, want: 0},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			now := time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC)
+			l := New()
+			l.now = func() time.Time { return now }
+
+			for i, s := range tc.steps {
+				now = now.Add(s.advance)
+				got := l.Reserve(s.rules)
+				if diff := got - s.want; diff < -time.Millisecond || diff > time.Millisecond {
+					t.Errorf("step %d: Reserve() = %s, want %s", i, got, s.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package platforms
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/airforce270/airbot/base"
+)
+
+// defaultShutdownTimeout is how long message handling is given to finish
+// when shutting down, if no timeout is configured.
+const defaultShutdownTimeout = 10 * time.Second
+
+// inFlight tracks incoming messages that are being handled.
+type inFlight struct {
+	wg    sync.WaitGroup
+	count atomic.Int64
+}
+
+// Go handles a message in a new goroutine.
+func (f *inFlight) Go(handle func()) {
+	f.count.Add(1)
+	f.wg.Go(func() {
+		defer f.count.Add(-1)
+		handle()
+	})
+}
+
+// Count returns the number of messages being handled.
+func (f *inFlight) Count() int64 { return f.count.Load() }
+
+// Wait waits for all messages to finish being handled,
+// returning false if they haven't by the deadline.
+func (f *inFlight) Wait(deadline time.Time) bool {
+	done := make(chan struct{})
+	go func() {
+		f.wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// waitForEmpty waits for all queued messages to be sent,
+// returning false if they haven't been by the deadline.
+func waitForEmpty(q *outgoingQueue, deadline time.Time) bool {
+	ticker := time.NewTicker(ctxCheckInterval)
+	defer ticker.Stop()
+	for q.Depth() > 0 {
+		if !time.Now().Before(deadline) {
+			return false
+		}
+		<-ticker.C
+	}
+	return true
+}
+
+// ShutdownReport describes what was abandoned when message handling stopped.
+type ShutdownReport struct {
+	// InFlight is the number of incoming messages that were still being handled.
+	InFlight int64
+	// Unsent contains the messages that were never sent.
+	Unsent []base.OutgoingMessage
+}
+
+// Err returns an error describing what was abandoned, or nil if nothing was.
+func (r ShutdownReport) Err() error {
+	if r.InFlight == 0 && len(r.Unsent) == 0 {
+		return nil
+	}
+	return fmt.Errorf("abandoned %d incoming messages still being handled and %d unsent messages", r.InFlight, len(r.Unsent))
+}
@@ -0,0 +1,88 @@
+package platforms
+
+import (
+	"testing"
+	"time"
+
+	"github.com/airforce270/airbot/base"
+)
+
+func TestInFlight_Wait(t *testing.T) {
+	t.Parallel()
+	var f inFlight
+	release := make(chan struct{})
+	f.Go(func() {})
+	f.Go(func() { <-release })
+
+	if f.Wait(time.Now().Add(50 * time.Millisecond)) {
+		t.Fatal("Wait() = true while a message is still being handled, want false")
+	}
+	if got := f.Count(); got != 1 {
+		t.Errorf("Count() = %d, want 1", got)
+	}
+
+	close(release)
+	if !f.Wait(time.Now().Add(time.Second)) {
+		t.Fatal("Wait() = false after all messages were handled, want true")
+	}
+	if got := f.Count(); got != 0 {
+		t.Errorf("Count() = %d, want 0", got)
+	}
+}
+
+func TestWaitForEmpty(t *testing.T) {
+	t.Parallel()
+	q := newOutgoingQueue(10)
+	push(t, q, "user1", "a1", "a2")
+
+	if waitForEmpty(q, time.Now().Add(2*ctxCheckInterval)) {
+		t.Fatal("waitForEmpty() = true with messages queued, want false")
+	}
+
+	go func() {
+		for range 2 {
+			q.Pop("user1")
+		}
+	}()
+	if !waitForEmpty(q, time.Now().Add(time.Second)) {
+		t.Fatal("waitForEmpty() = false after messages were sent, want true")
+	}
+}
+
+func TestShutdownReport_Err(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		desc    string
+		report  ShutdownReport
+		wantErr string
+	}{
+		{
+			desc:   "nothing abandoned",
+			report: ShutdownReport{},
+		},
+		{
+			desc: "abandoned",
+			report: ShutdownReport{
+				InFlight: 1,
+				Unsent:   []base.OutgoingMessage{{Message: base.Message{Channel: "user1", Text: "a1"}}},
+			},
+			wantErr: "abandoned 1 incoming messages still being handled and 1 unsent messages",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			err := tc.report.Err()
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("Err() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.wantErr {
+				t.Errorf("Err() = %v, want %s", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package platforms
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode"
+
+	"github.com/airforce270/airbot/apiclients/pastebin"
+	"github.com/airforce270/airbot/base"
+	"github.com/airforce270/airbot/config"
+)
+
+// pasteFunc uploads text to a paste service, returning the paste's URL.
+type pasteFunc func(text string) (string, error)
+
+// splitter splits messages that are too long for a platform into multiple messages.
+type splitter struct {
+	// maxLength is the maximum number of characters in a message.
+	// If 0, messages are never split.
+	maxLength int
+	// pasteAfter is the number of messages a message can be split into
+	// before it's pasted instead.
+	// If 0, or paste is nil, messages are never pasted.
+	pasteAfter int
+	// paste uploads long messages.
+	paste pasteFunc
+	// logger is the logger to log failed pastes to.
+	logger *slog.Logger
+}
+
+// newSplitter creates a splitter for messages sent to a platform.
+// Long messages are pasted to Pastebin if it's configured.
+func newSplitter(p base.Platform, cfg *config.Config, logger *slog.Logger) *splitter {
+	s := &splitter{
+		maxLength:  p.MaxMessageLength(),
+		pasteAfter: cfg.Messages.PasteAfter,
+		logger:     logger,
+	}
+	if cfg.Pastebin.IsConfigured() {
+		client := pastebin.NewClient("", "")
+		s.paste = func(text string) (string, error) {
+			return client.CreatePaste(cfg.Pastebin.APIDevKey, fmt.Sprintf("%s message", p.Username()), text)
+		}
+	}
+	return s
+}
+
+// Split splits a message into messages no longer than the max length,
+// breaking on word boundaries where possible.
+// If the message would be split into too many messages, it's pasted instead,
+// and a single message with a link to the paste is returned.
+// Only the first message is sent as a reply.
+func (s *splitter) Split(msg base.OutgoingMessage) []base.OutgoingMessage {
+	if s.maxLength <= 0 {
+		return []base.OutgoingMessage{msg}
+	}
+
+	parts := splitText(msg.Text, s.maxLength)
+	if len(parts) <= 1 {
+		return []base.OutgoingMessage{msg}
+	}
+
+	if s.paste != nil && s.pasteAfter > 0 && len(parts) > s.pasteAfter {
+		pasteURL, err := s.paste(msg.Text)
+		if err == nil {
+			out := msg
+			out.Text = fmt.Sprintf("Output too long, see %s", pasteURL)
+			return []base.OutgoingMessage{out}
+		}
+		s.logger.Warn("Failed to paste long message, splitting it instead", "channel", msg.Channel, "error", err)
+	}
+
+	outs := make([]base.OutgoingMessage, len(parts))
+	for i, part := range parts {
+		outs[i] = msg
+		outs[i].Text = part
+		if i > 0 {
+			outs[i].ReplyToID = ""
+		}
+	}
+	return outs
+}
+
+// splitText splits text into parts of at most maxLength characters,
+// breaking on whitespace where possible.
+// Words longer than maxLength are broken up.
+func splitText(text string, maxLength int) []string {
+	remaining := []rune(strings.TrimSpace(text))
+	if len(remaining) <= maxLength {
+		return []string{string(remaining)}
+	}
+
+	var parts []string
+	for len(remaining) > maxLength {
+		end := maxLength
+		// Break at the last whitespace that keeps the part within the limit.
+		for i := maxLength; i > 0; i-- {
+			if unicode.IsSpace(remaining[i]) {
+				end = i
+				break
+			}
+		}
+
+		parts = append(parts, strings.TrimRightFunc(string(remaining[:end]), unicode.IsSpace))
+		remaining = []rune(strings.TrimLeftFunc(string(remaining[end:]), unicode.IsSpace))
+	}
+	if len(remaining) > 0 {
+		parts = append(parts, string(remaining))
+	}
+	return parts
+}
@@ -0,0 +1,147 @@
+package platforms
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/airforce270/airbot/base"
+	"github.com/airforce270/airbot/logging"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSplitText(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		desc      string
+		text      string
+		maxLength int
+		want      []string
+	}{
+		{
+			desc:      "short",
+			text:      "hello world",
+			maxLength: 20,
+			want:      []string{"hello world"},
+		},
+		{
+			desc:      "exactly max length",
+			text:      "hello world",
+			maxLength: 11,
+			want:      []string{"hello world"},
+		},
+		{
+			desc:      "splits on spaces",
+			text:      "the quick brown fox jumps over the lazy dog",
+			maxLength: 15,
+			want:      []string{"the quick brown", "fox jumps over", "the lazy dog"},
+		},
+		{
+			desc:      "collapses whitespace at breaks",
+			text:      "aaaa    bbbb    cccc",
+			maxLength: 6,
+			want:      []string{"aaaa", "bbbb", "cccc"},
+		},
+		{
+			desc:      "breaks long words",
+			text:      "abcdefghij kl",
+			maxLength: 4,
+			want:      []string{"abcd", "efgh", "ij", "kl"},
+		},
+		{
+			desc:      "counts characters, not bytes",
+			text:      "ääää öööö",
+			maxLength: 5,
+			want:      []string{"ääää", "öööö"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			got := splitText(tc.text, tc.maxLength)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("splitText(%q, %d) unexpected result (-want +got):\n%s", tc.text, tc.maxLength, diff)
+			}
+		})
+	}
+}
+
+func TestSplitter_Split(t *testing.T) {
+	t.Parallel()
+	longText := strings.Repeat("word ", 10) // 10 parts at max length 4
+	pasteOK := func(text string) (string, error) { return "https://pastebin.com/abc", nil }
+	pasteErr := func(text string) (string, error) { return "", errors.New("paste failed") }
+
+	tests := []struct {
+		desc     string
+		splitter splitter
+		msg      base.OutgoingMessage
+		want     []base.OutgoingMessage
+	}{
+		{
+			desc:     "unlimited",
+			splitter: splitter{maxLength: 0},
+			msg:      outgoing(longText, "id1"),
+			want:     []base.OutgoingMessage{outgoing(longText, "id1")},
+		},
+		{
+			desc:     "short",
+			splitter: splitter{maxLength: 100},
+			msg:      outgoing("hello", "id1"),
+			want:     []base.OutgoingMessage{outgoing("hello", "id1")},
+		},
+		{
+			desc:     "split, only first is reply",
+			splitter: splitter{maxLength: 5},
+			msg:      outgoing("hello there world", "id1"),
+			want: []base.OutgoingMessage{
+				outgoing("hello", "id1"),
+				outgoing("there", ""),
+				outgoing("world", ""),
+			},
+		},
+		{
+			desc:     "pasted",
+			splitter: splitter{maxLength: 4, pasteAfter: 3, paste: pasteOK},
+			msg:      outgoing(longText, "id1"),
+			want:     []base.OutgoingMessage{outgoing("Output too long, see https://pastebin.com/abc", "id1")},
+		},
+		{
+			desc:     "not enough parts to paste",
+			splitter: splitter{maxLength: 5, pasteAfter: 3, paste: pasteOK},
+			msg:      outgoing("hello there world", ""),
+			want: []base.OutgoingMessage{
+				outgoing("hello", ""),
+				outgoing("there", ""),
+				outgoing("world", ""),
+			},
+		},
+		{
+			desc:     "paste fails",
+			splitter: splitter{maxLength: 5, pasteAfter: 1, paste: pasteErr, logger: logging.Discard()},
+			msg:      outgoing("hello world", ""),
+			want: []base.OutgoingMessage{
+				outgoing("hello", ""),
+				outgoing("world", ""),
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			got := tc.splitter.Split(tc.msg)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Split() unexpected result (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func outgoing(text, replyToID string) base.OutgoingMessage {
+	return base.OutgoingMessage{
+		Message:   base.Message{Channel: "user1", Text: text},
+		ReplyToID: replyToID,
+	}
+}
@@ -0,0 +1,242 @@
+package twitch
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JoinState is whether the bot has joined a channel's chat.
+type JoinState string
+
+const (
+	// JoinStateJoining means the bot asked to join the channel, but Twitch hasn't confirmed it yet.
+	JoinStateJoining JoinState = "joining"
+	// JoinStateJoined means Twitch confirmed the bot joined the channel.
+	JoinStateJoined JoinState = "joined"
+	// JoinStateFailed means Twitch didn't confirm the bot joined the channel after retrying.
+	// It's retried again later.
+	JoinStateFailed JoinState = "failed"
+)
+
+type twitchChannel struct {
+	ID             string
+	Name           string
+	Prefix         string
+	BotIsModerator bool
+	BotIsVIP       bool
+	// Room contains the channel's chat settings.
+	Room roomState
+	// State is whether the bot has joined the channel's chat.
+	State JoinState
+	// StateSince is when State last changed.
+	StateSince time.Time
+	// JoinAttempts is how many times the bot has asked to join the channel since it was last joined.
+	JoinAttempts int
+}
+
+// channelRegistry contains the channels the bot has joined.
+// It's safe for concurrent use.
+// Channels are copied in and out, so callers can't modify them without holding the lock.
+type channelRegistry struct {
+	mu sync.Mutex
+	// channels contains the joined channels.
+	channels []*twitchChannel
+	// joinWaiters contains channels waiting for a join to be confirmed, by lowercased channel name.
+	// They receive nil when it's confirmed, or an error if it fails.
+	joinWaiters map[string][]chan error
+}
+
+func newChannelRegistry(channels ...*twitchChannel) *channelRegistry {
+	r := &channelRegistry{joinWaiters: map[string][]chan error{}}
+	r.Replace(channels)
+	return r
+}
+
+// All returns copies of all channels.
+func (r *channelRegistry) All() []*twitchChannel {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	channels := make([]*twitchChannel, len(r.channels))
+	for i, c := range r.channels {
+		copied := *c
+		channels[i] = &copied
+	}
+	return channels
+}
+
+// Names returns the names of all channels.
+func (r *channelRegistry) Names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make([]string, len(r.channels))
+	for i, c := range r.channels {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// Get returns a copy of a channel, and whether it was found.
+func (r *channelRegistry) Get(name string) (twitchChannel, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c := r.find(name); c != nil {
+		return *c, true
+	}
+	return twitchChannel{}, false
+}
+
+// Add adds a channel, replacing any channel with the same name.
+func (r *channelRegistry) Add(c twitchChannel) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c.StateSince.IsZero() {
+		c.StateSince = time.Now()
+	}
+	r.channels = slices.DeleteFunc(r.channels, func(existing *twitchChannel) bool {
+		return strings.EqualFold(existing.Name, c.Name)
+	})
+	r.channels = append(r.channels, &c)
+}
+
+// Remove removes a channel, returning it and whether it was found.
+func (r *channelRegistry) Remove(name string) (twitchChannel, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := r.find(name)
+	if c == nil {
+		return twitchChannel{}, false
+	}
+	r.channels = slices.DeleteFunc(r.channels, func(existing *twitchChannel) bool { return existing == c })
+	return *c, true
+}
+
+// Replace replaces all channels.
+func (r *channelRegistry) Replace(channels []*twitchChannel) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.channels = make([]*twitchChannel, len(channels))
+	for i, c := range channels {
+		copied := *c
+		if copied.StateSince.IsZero() {
+			copied.StateSince = time.Now()
+		}
+		r.channels[i] = &copied
+	}
+}
+
+// Update calls update with the channel with a name, so it can be modified.
+// It returns whether the channel was found.
+func (r *channelRegistry) Update(name string, update func(c *twitchChannel)) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := r.find(name)
+	if c == nil {
+		return false
+	}
+	update(c)
+	return true
+}
+
+// Rename renames the channel with an ID.
+func (r *channelRegistry) Rename(id, name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range r.channels {
+		if c.ID == id {
+			c.Name = name
+		}
+	}
+}
+
+// SetState sets the join state of a channel.
+func (r *channelRegistry) SetState(name string, state JoinState) {
+	r.Update(name, func(c *twitchChannel) { setState(c, state) })
+}
+
+// RejoinAll marks every channel as joining once,
+// i.e. after connecting, when the IRC client rejoins them all.
+func (r *channelRegistry) RejoinAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for _, c := range r.channels {
+		c.State = JoinStateJoining
+		c.StateSince = now
+		c.JoinAttempts = 1
+	}
+}
+
+// ExpectJoin returns a channel that receives nil when joining a channel is confirmed,
+// or an error if it fails.
+// If the join times out, the caller should call StopExpecting.
+func (r *channelRegistry) ExpectJoin(name string) <-chan error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	name = strings.ToLower(name)
+	c := make(chan error, 1)
+	r.joinWaiters[name] = append(r.joinWaiters[name], c)
+	return c
+}
+
+// StopExpecting stops waiting for a join returned by ExpectJoin.
+func (r *channelRegistry) StopExpecting(name string, waiter <-chan error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	name = strings.ToLower(name)
+	r.joinWaiters[name] = slices.DeleteFunc(r.joinWaiters[name], func(c chan error) bool { return c == waiter })
+	if len(r.joinWaiters[name]) == 0 {
+		delete(r.joinWaiters, name)
+	}
+}
+
+// ConfirmJoin marks a channel as joined, and notifies anything waiting for it to be.
+func (r *channelRegistry) ConfirmJoin(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c := r.find(name); c != nil {
+		setState(c, JoinStateJoined)
+	}
+	r.notify(name, nil)
+}
+
+// FailJoin notifies anything waiting for a channel to be joined that it failed.
+func (r *channelRegistry) FailJoin(name string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.notify(name, err)
+}
+
+// notify sends a join result to everything waiting for a channel to be joined.
+// r.mu must be held.
+func (r *channelRegistry) notify(name string, err error) {
+	name = strings.ToLower(name)
+	for _, c := range r.joinWaiters[name] {
+		c <- err
+	}
+	delete(r.joinWaiters, name)
+}
+
+// find returns the channel with a name, or nil if there isn't one.
+// r.mu must be held.
+func (r *channelRegistry) find(name string) *twitchChannel {
+	for _, c := range r.channels {
+		if strings.EqualFold(c.Name, name) {
+			return c
+		}
+	}
+	return nil
+}
+
+// setState sets the join state of a channel.
+func setState(c *twitchChannel, state JoinState) {
+	if c.State == state {
+		return
+	}
+	c.State = state
+	c.StateSince = time.Now()
+	if state == JoinStateJoined {
+		c.JoinAttempts = 0
+	}
+}
@@ -0,0 +1,114 @@
+package twitch
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/airforce270/airbot/database/databasetest"
+)
+
+func TestChannelRegistry_ConfirmJoin(t *testing.T) {
+	t.Parallel()
+	r := newChannelRegistry(&twitchChannel{Name: "User1", State: JoinStateJoining, JoinAttempts: 2})
+
+	confirmed := r.ExpectJoin("user1")
+	r.ConfirmJoin("user1")
+
+	select {
+	case err := <-confirmed:
+		if err != nil {
+			t.Errorf("ExpectJoin() got error %v, want nil", err)
+		}
+	default:
+		t.Fatal("ExpectJoin() didn't receive confirmation")
+	}
+	got, ok := r.Get("user1")
+	if !ok {
+		t.Fatal("Get() didn't find channel")
+	}
+	if got.State != JoinStateJoined {
+		t.Errorf("State = %s, want %s", got.State, JoinStateJoined)
+	}
+	if got.JoinAttempts != 0 {
+		t.Errorf("JoinAttempts = %d, want 0", got.JoinAttempts)
+	}
+}
+
+func TestChannelRegistry_FailJoin(t *testing.T) {
+	t.Parallel()
+	r := newChannelRegistry()
+
+	confirmed := r.ExpectJoin("user1")
+	stopped := r.ExpectJoin("user1")
+	r.StopExpecting("user1", stopped)
+	r.FailJoin("user1", ErrBotIsBanned)
+
+	select {
+	case err := <-confirmed:
+		if !errors.Is(err, ErrBotIsBanned) {
+			t.Errorf("ExpectJoin() got error %v, want %v", err, ErrBotIsBanned)
+		}
+	default:
+		t.Fatal("ExpectJoin() didn't receive failure")
+	}
+	select {
+	case err := <-stopped:
+		t.Errorf("ExpectJoin() after StopExpecting() got %v, want nothing", err)
+	default:
+	}
+}
+
+func TestChannelRegistry_CopiesChannels(t *testing.T) {
+	t.Parallel()
+	original := &twitchChannel{Name: "user1", Prefix: "$"}
+	r := newChannelRegistry(original)
+
+	original.Prefix = "!"
+	for _, c := range r.All() {
+		c.Prefix = "?"
+	}
+
+	got, _ := r.Get("user1")
+	if got.Prefix != "$" {
+		t.Errorf("Prefix = %q, want %q", got.Prefix, "$")
+	}
+}
+
+func TestTwitch_RetryJoins(t *testing.T) {
+	t.Parallel()
+	tw := NewForTesting(t, "", databasetest.New(t))
+	now := time.Now()
+	tw.channels.Replace([]*twitchChannel{
+		{Name: "joined", State: JoinStateJoined, StateSince: now.Add(-time.Hour)},
+		{Name: "recentlyjoining", State: JoinStateJoining, StateSince: now, JoinAttempts: 1},
+		{Name: "stuckjoining", State: JoinStateJoining, StateSince: now.Add(-2 * joinRetryAfter), JoinAttempts: 1},
+		{Name: "outofattempts", State: JoinStateJoining, StateSince: now.Add(-2 * joinRetryAfter), JoinAttempts: maxJoinAttempts},
+		{Name: "recentlyfailed", State: JoinStateFailed, StateSince: now},
+		{Name: "failed", State: JoinStateFailed, StateSince: now.Add(-2 * failedJoinRetryAfter), JoinAttempts: maxJoinAttempts},
+	})
+
+	tw.retryJoins(now)
+
+	tests := []struct {
+		channel          string
+		wantState        JoinState
+		wantJoinAttempts int
+	}{
+		{channel: "joined", wantState: JoinStateJoined, wantJoinAttempts: 0},
+		{channel: "recentlyjoining", wantState: JoinStateJoining, wantJoinAttempts: 1},
+		{channel: "stuckjoining", wantState: JoinStateJoining, wantJoinAttempts: 2},
+		{channel: "outofattempts", wantState: JoinStateFailed, wantJoinAttempts: maxJoinAttempts},
+		{channel: "recentlyfailed", wantState: JoinStateFailed, wantJoinAttempts: 0},
+		{channel: "failed", wantState: JoinStateJoining, wantJoinAttempts: 1},
+	}
+	for _, tc := range tests {
+		got, ok := tw.channels.Get(tc.channel)
+		if !ok {
+			t.Fatalf("Get(%q) didn't find channel", tc.channel)
+		}
+		if got.State != tc.wantState || got.JoinAttempts != tc.wantJoinAttempts {
+			t.Errorf("%s: got state %s with %d join attempts, want %s with %d", tc.channel, got.State, got.JoinAttempts, tc.wantState, tc.wantJoinAttempts)
+		}
+	}
+}
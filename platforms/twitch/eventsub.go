@@ -0,0 +1,627 @@
+package twitch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/airforce270/airbot/base"
+
+	"github.com/coder/websocket"
+	"github.com/nicklaw5/helix/v2"
+)
+
+// EventSub websocket message types.
+// See https://dev.twitch.tv/docs/eventsub/websocket-reference/
+const (
+	eventSubMessageWelcome      = "session_welcome"
+	eventSubMessageKeepalive    = "session_keepalive"
+	eventSubMessageNotification = "notification"
+	eventSubMessageReconnect    = "session_reconnect"
+	eventSubMessageRevocation   = "revocation"
+)
+
+const (
+	// defaultEventSubURL is the URL of Twitch's EventSub websocket server.
+	defaultEventSubURL = "wss://eventsub.wss.twitch.tv/ws"
+	// eventSubWelcomeTimeout is how long to wait for the welcome message after connecting.
+	eventSubWelcomeTimeout = 10 * time.Second
+	// eventSubKeepaliveGrace is how long past the keepalive timeout to wait for a message
+	// before assuming the connection is dead.
+	eventSubKeepaliveGrace = 5 * time.Second
+	// eventSubMinBackoff is the initial delay before reconnecting after the connection is lost.
+	eventSubMinBackoff = time.Second
+	// eventSubMaxBackoff is the longest delay before reconnecting after the connection is lost.
+	eventSubMaxBackoff = 2 * time.Minute
+	// eventSubSeenMessages is the number of message IDs remembered,
+	// to ignore notifications Twitch sends more than once.
+	eventSubSeenMessages = 100
+)
+
+// eventSubscription is an EventSub subscription created for joined channels
+// with hooks for its kind of event.
+type eventSubscription struct {
+	// Kind is the kind of event the subscription publishes.
+	Kind base.EventKind
+	// Type is the subscription type.
+	Type string
+	// Version is the version of the subscription type.
+	Version string
+	// Condition returns the subscription's condition for a channel.
+	Condition func(channelID, botID string) helix.EventSubCondition
+}
+
+// eventSubscriptions contains the subscriptions that can be created for each joined channel.
+// Only subscriptions to kinds of events a channel has hooks for are created,
+// as each session can only afford a few.
+// Subscriptions to subscribers and redemptions require the broadcaster to have
+// authorized the bot, and follows require the bot to be a moderator,
+// so they will fail in most channels.
+var eventSubscriptions = []eventSubscription{
+	{Kind: base.EventStreamOnline, Type: helix.EventSubTypeStreamOnline, Version: "1", Condition: broadcasterCondition},
+	{Kind: base.EventStreamOffline, Type: helix.EventSubTypeStreamOffline, Version: "1", Condition: broadcasterCondition},
+	{
+		Kind:    base.EventRaid,
+		Type:    helix.EventSubTypeChannelRaid,
+		Version: "1",
+		Condition: func(channelID, botID string) helix.EventSubCondition {
+			return helix.EventSubCondition{ToBroadcasterUserID: channelID}
+		},
+	},
+	{
+		Kind:    base.EventFollow,
+		Type:    helix.EventSubTypeChannelFollow,
+		Version: "2",
+		Condition: func(channelID, botID string) helix.EventSubCondition {
+			return helix.EventSubCondition{BroadcasterUserID: channelID, ModeratorUserID: botID}
+		},
+	},
+	{Kind: base.EventSubscribe, Type: helix.EventSubTypeChannelSubscription, Version: "1", Condition: broadcasterCondition},
+	{Kind: base.EventResubscribe, Type: helix.EventSubTypeChannelSubscriptionMessage, Version: "1", Condition: broadcasterCondition},
+	{Kind: base.EventGiftSubscriptions, Type: helix.EventSubTypeChannelSubscriptionGift, Version: "1", Condition: broadcasterCondition},
+	{Kind: base.EventRedemption, Type: helix.EventSubTypeChannelPointsCustomRewardRedemptionAdd, Version: "1", Condition: broadcasterCondition},
+}
+
+func broadcasterCondition(channelID, botID string) helix.EventSubCondition {
+	return helix.EventSubCondition{BroadcasterUserID: channelID}
+}
+
+// activeSubscription is a subscription created for a channel.
+type activeSubscription struct {
+	// ID is the subscription's ID.
+	ID string
+	// Kind is the kind of event the subscription publishes.
+	Kind base.EventKind
+	// Type is the subscription type.
+	Type string
+	// Cost is how much the subscription counts against the session's max total cost.
+	Cost int
+}
+
+// eventSubMessage is a message sent by the EventSub websocket server.
+type eventSubMessage struct {
+	Metadata struct {
+		MessageID        string    `json:"message_id"`
+		MessageType      string    `json:"message_type"`
+		MessageTimestamp time.Time `json:"message_timestamp"`
+		SubscriptionType string    `json:"subscription_type"`
+	} `json:"metadata"`
+	Payload struct {
+		Session      eventSubSession            `json:"session"`
+		Subscription helix.EventSubSubscription `json:"subscription"`
+		Event        json.RawMessage            `json:"event"`
+	} `json:"payload"`
+}
+
+// eventSubSession is an EventSub websocket session.
+type eventSubSession struct {
+	ID                      string `json:"id"`
+	KeepaliveTimeoutSeconds int    `json:"keepalive_timeout_seconds"`
+	ReconnectURL            string `json:"reconnect_url"`
+}
+
+// keepaliveTimeout returns how long to wait for a message before assuming the connection is dead.
+func (s eventSubSession) keepaliveTimeout() time.Duration {
+	return time.Duration(s.KeepaliveTimeoutSeconds)*time.Second + eventSubKeepaliveGrace
+}
+
+// eventSub is a client for Twitch EventSub, using the websocket transport.
+// It subscribes to events in each joined channel and publishes them as base.Events.
+// See https://dev.twitch.tv/docs/eventsub/handling-websocket-events/
+type eventSub struct {
+	// url is the URL of the EventSub websocket server.
+	url string
+	// helix is the Twitch API client subscriptions are created with.
+	helix *helix.Client
+	// botID is the Twitch ID of the account the bot is running as.
+	botID string
+	// channels returns the currently joined channels.
+	channels func() []*twitchChannel
+	// hookedEvents returns the kinds of events a channel has hooks for.
+	hookedEvents func(channel string) (map[base.EventKind]bool, error)
+	// events receives published events.
+	events chan base.Event
+	// logger is the logger for EventSub.
+	logger *slog.Logger
+
+	// subscribeMu is held while subscriptions are created or removed,
+	// so the same subscription isn't created twice.
+	subscribeMu sync.Mutex
+	// mu protects the fields below.
+	mu sync.Mutex
+	// sessionID is the ID of the current session, or empty if not connected.
+	sessionID string
+	// subscriptions contains the subscriptions created for each channel, by channel ID.
+	subscriptions map[string][]activeSubscription
+	// totalCost is the total cost of the session's subscriptions, as last reported by Twitch.
+	totalCost int
+	// maxTotalCost is the most the session's subscriptions may cost, as last reported by Twitch,
+	// or 0 if it hasn't been reported.
+	maxTotalCost int
+	// seen contains the IDs of recently received notifications, oldest first.
+	seen []string
+}
+
+func newEventSub(url string, helixClient *helix.Client, botID string, channels func() []*twitchChannel, hookedEvents func(channel string) (map[base.EventKind]bool, error), events chan base.Event, logger *slog.Logger) *eventSub {
+	return &eventSub{
+		url:           url,
+		helix:         helixClient,
+		botID:         botID,
+		channels:      channels,
+		hookedEvents:  hookedEvents,
+		events:        events,
+		logger:        logger,
+		subscriptions: map[string][]activeSubscription{},
+	}
+}
+
+// run connects to the EventSub server and publishes events until ctx is cancelled,
+// reconnecting with backoff if the connection is lost.
+// This function blocks and should be run within a goroutine.
+func (e *eventSub) run(ctx context.Context) {
+	backoff := eventSubMinBackoff
+	for {
+		welcomed, err := e.connect(ctx, e.url)
+		if ctx.Err() != nil {
+			e.logger.Info("Stopping EventSub, context cancelled")
+			return
+		}
+		if welcomed {
+			backoff = eventSubMinBackoff
+		}
+		e.logger.Warn("EventSub connection lost, reconnecting", "retry_in", backoff, "error", err)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			e.logger.Info("Stopping EventSub, context cancelled")
+			return
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, eventSubMaxBackoff)
+	}
+}
+
+// connect starts a new session, subscribes to events in each joined channel,
+// and publishes events until the connection is lost or ctx is cancelled.
+// It returns whether the session was welcomed, and why it ended.
+func (e *eventSub) connect(ctx context.Context, url string) (welcomed bool, err error) {
+	conn, session, err := e.dial(ctx, url)
+	if err != nil {
+		return false, err
+	}
+	defer func() { conn.CloseNow() }()
+	defer e.setSession("")
+
+	e.setSession(session.ID)
+	e.logger.Info("Connected to EventSub", "session_id", session.ID)
+	e.subscribeAll()
+
+	for {
+		msg, err := e.read(ctx, conn, session.keepaliveTimeout())
+		if err != nil {
+			if ctx.Err() != nil {
+				conn.Close(websocket.StatusNormalClosure, "")
+			}
+			return true, err
+		}
+
+		switch msg.Metadata.MessageType {
+		case eventSubMessageKeepalive:
+		case eventSubMessageNotification:
+			e.handleNotification(ctx, msg)
+		case eventSubMessageReconnect:
+			// Subscriptions carry over to the new connection,
+			// which must be welcomed before the old one is closed.
+			e.logger.Info("EventSub reconnect requested, reconnecting...")
+			newConn, newSession, err := e.dial(ctx, msg.Payload.Session.ReconnectURL)
+			if err != nil {
+				return true, fmt.Errorf("failed to reconnect: %w", err)
+			}
+			conn.Close(websocket.StatusNormalClosure, "")
+			conn, session = newConn, newSession
+			e.setSession(session.ID)
+		case eventSubMessageRevocation:
+			sub := msg.Payload.Subscription
+			e.logger.Warn("EventSub subscription revoked", "type", sub.Type, "status", sub.Status, "channel_id", sub.Condition.BroadcasterUserID+sub.Condition.ToBroadcasterUserID)
+		default:
+			e.logger.Debug("Unknown EventSub message type", "type", msg.Metadata.MessageType)
+		}
+	}
+}
+
+// dial connects to the EventSub server and waits for the session to be welcomed.
+func (e *eventSub) dial(ctx context.Context, url string) (*websocket.Conn, eventSubSession, error) {
+	conn, _, err := websocket.Dial(ctx, url, nil)
+	if err != nil {
+		return nil, eventSubSession{}, fmt.Errorf("failed to connect to %s: %w", url, err)
+	}
+	msg, err := e.read(ctx, conn, eventSubWelcomeTimeout)
+	if err != nil {
+		conn.CloseNow()
+		return nil, eventSubSession{}, fmt.Errorf("failed to read welcome message: %w", err)
+	}
+	if msg.Metadata.MessageType != eventSubMessageWelcome {
+		conn.CloseNow()
+		return nil, eventSubSession{}, fmt.Errorf("expected %s message, got %s", eventSubMessageWelcome, msg.Metadata.MessageType)
+	}
+	return conn, msg.Payload.Session, nil
+}
+
+// read reads the next message, waiting up to timeout.
+func (e *eventSub) read(ctx context.Context, conn *websocket.Conn, timeout time.Duration) (*eventSubMessage, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	_, data, err := conn.Read(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message: %w", err)
+	}
+	var msg eventSubMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal message %q: %w", data, err)
+	}
+	return &msg, nil
+}
+
+// handleNotification publishes the event in a notification.
+func (e *eventSub) handleNotification(ctx context.Context, msg *eventSubMessage) {
+	if e.seenBefore(msg.Metadata.MessageID) {
+		e.logger.Debug("Ignoring duplicate EventSub notification", "message_id", msg.Metadata.MessageID)
+		return
+	}
+	event, err := parseEvent(msg)
+	if err != nil {
+		e.logger.Error("Failed to parse EventSub notification", "type", msg.Metadata.SubscriptionType, "error", err)
+		return
+	}
+	if event.Kind == base.EventStreamOnline {
+		if err := e.addStreamInfo(&event); err != nil {
+			e.logger.Warn("Failed to look up stream info", "channel", event.Channel, "error", err)
+		}
+	}
+	select {
+	case e.events <- event:
+	case <-ctx.Done():
+	}
+}
+
+// addStreamInfo adds the title and game of the stream that went live to an event.
+func (e *eventSub) addStreamInfo(event *base.Event) error {
+	resp, err := e.helix.GetChannelInformation(&helix.GetChannelInformationParams{
+		BroadcasterIDs: []string{event.ChannelID},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get info for channel %s from Helix: %w", event.Channel, err)
+	}
+	if len(resp.Data.Channels) == 0 {
+		return fmt.Errorf("channel %s not found: %w", event.Channel, ErrChannelNotFound)
+	}
+	event.Title = resp.Data.Channels[0].Title
+	event.Game = resp.Data.Channels[0].GameName
+	return nil
+}
+
+// seenBefore returns whether a notification with a message ID has been received recently,
+// and remembers it if not.
+func (e *eventSub) seenBefore(messageID string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, id := range e.seen {
+		if id == messageID {
+			return true
+		}
+	}
+	if len(e.seen) >= eventSubSeenMessages {
+		e.seen = e.seen[1:]
+	}
+	e.seen = append(e.seen, messageID)
+	return false
+}
+
+func (e *eventSub) setSession(sessionID string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.sessionID = sessionID
+	if sessionID == "" {
+		// Subscriptions are deleted along with their session.
+		clear(e.subscriptions)
+		e.totalCost = 0
+	}
+}
+
+// subscribeAll subscribes to events in each joined channel.
+func (e *eventSub) subscribeAll() {
+	for _, channel := range e.channels() {
+		if err := e.subscribe(channel); err != nil {
+			e.logSubscribeError(channel.Name, err)
+		}
+	}
+}
+
+// logSubscribeError logs an error subscribing to events in a channel.
+func (e *eventSub) logSubscribeError(channel string, err error) {
+	if errors.Is(err, base.ErrEventLimit) {
+		e.logger.Error("EventSub subscription cost limit reached, hooks for some events won't fire", "channel", channel, "error", err)
+		return
+	}
+	e.logger.Warn("Failed to subscribe to some events", "channel", channel, "error", err)
+}
+
+// subscribe subscribes to the kinds of events in a channel that it has hooks for,
+// and unsubscribes from those it no longer has hooks for.
+// If not connected, it does nothing; the channel is subscribed to when connected.
+// It returns an error wrapping base.ErrEventLimit if the session can't afford more subscriptions.
+func (e *eventSub) subscribe(channel *twitchChannel) error {
+	e.subscribeMu.Lock()
+	defer e.subscribeMu.Unlock()
+
+	e.mu.Lock()
+	sessionID := e.sessionID
+	active := slices.Clone(e.subscriptions[channel.ID])
+	e.mu.Unlock()
+	if sessionID == "" {
+		return nil
+	}
+	if channel.ID == "" {
+		return fmt.Errorf("channel %s has no ID", channel.Name)
+	}
+	hooked, err := e.hookedEvents(channel.Name)
+	if err != nil {
+		return fmt.Errorf("failed to look up hooked events in %s: %w", channel.Name, err)
+	}
+
+	var subs []activeSubscription
+	var errs []error
+	for _, sub := range active {
+		if hooked[sub.Kind] {
+			subs = append(subs, sub)
+			continue
+		}
+		if err := e.remove(sub); err != nil {
+			errs = append(errs, err)
+			subs = append(subs, sub)
+		}
+	}
+	for _, s := range eventSubscriptions {
+		if !hooked[s.Kind] || slices.ContainsFunc(subs, func(sub activeSubscription) bool { return sub.Type == s.Type }) {
+			continue
+		}
+		created, err := e.create(s, channel.ID, sessionID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to subscribe to %s: %w", s.Type, err))
+			continue
+		}
+		subs = append(subs, created...)
+	}
+
+	e.mu.Lock()
+	if e.sessionID == sessionID {
+		e.subscriptions[channel.ID] = subs
+	}
+	e.mu.Unlock()
+	return errors.Join(errs...)
+}
+
+// create creates a subscription for a channel, recording its cost.
+// It returns an error wrapping base.ErrEventLimit if the session can't afford it.
+func (e *eventSub) create(s eventSubscription, channelID, sessionID string) ([]activeSubscription, error) {
+	e.mu.Lock()
+	totalCost, maxTotalCost := e.totalCost, e.maxTotalCost
+	e.mu.Unlock()
+	if maxTotalCost > 0 && totalCost >= maxTotalCost {
+		return nil, fmt.Errorf("subscriptions already cost %d of %d: %w", totalCost, maxTotalCost, base.ErrEventLimit)
+	}
+
+	resp, err := e.helix.CreateEventSubSubscription(&helix.EventSubSubscription{
+		Type:      s.Type,
+		Version:   s.Version,
+		Condition: s.Condition(channelID, e.botID),
+		Transport: helix.EventSubTransport{Method: "websocket", SessionID: sessionID},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, fmt.Errorf("%d %s: %w", resp.StatusCode, resp.ErrorMessage, base.ErrEventLimit)
+	}
+	if resp.StatusCode != http.StatusAccepted {
+		return nil, fmt.Errorf("%d %s", resp.StatusCode, resp.ErrorMessage)
+	}
+
+	e.mu.Lock()
+	if e.sessionID == sessionID {
+		e.totalCost, e.maxTotalCost = resp.Data.TotalCost, resp.Data.MaxTotalCost
+	}
+	e.mu.Unlock()
+	var subs []activeSubscription
+	for _, sub := range resp.Data.EventSubSubscriptions {
+		subs = append(subs, activeSubscription{ID: sub.ID, Kind: s.Kind, Type: sub.Type, Cost: sub.Cost})
+	}
+	return subs, nil
+}
+
+// remove removes a subscription.
+func (e *eventSub) remove(sub activeSubscription) error {
+	resp, err := e.helix.RemoveEventSubSubscription(sub.ID)
+	if err != nil {
+		return fmt.Errorf("failed to remove subscription %s: %w", sub.ID, err)
+	}
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("failed to remove subscription %s: %d %s", sub.ID, resp.StatusCode, resp.ErrorMessage)
+	}
+	e.mu.Lock()
+	e.totalCost = max(e.totalCost-sub.Cost, 0)
+	e.mu.Unlock()
+	return nil
+}
+
+// unsubscribe deletes the subscriptions to events in a channel.
+func (e *eventSub) unsubscribe(channelID string) error {
+	e.subscribeMu.Lock()
+	defer e.subscribeMu.Unlock()
+
+	e.mu.Lock()
+	subs := e.subscriptions[channelID]
+	delete(e.subscriptions, channelID)
+	e.mu.Unlock()
+
+	var errs []error
+	for _, sub := range subs {
+		if err := e.remove(sub); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// subscribed returns whether a channel is currently subscribed to a subscription type.
+func (e *eventSub) subscribed(channelID, subscriptionType string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, sub := range e.subscriptions[channelID] {
+		if sub.Type == subscriptionType {
+			return true
+		}
+	}
+	return false
+}
+
+// parseEvent converts a notification to a base.Event.
+func parseEvent(msg *eventSubMessage) (base.Event, error) {
+	event := base.Event{
+		ID:   msg.Metadata.MessageID,
+		Time: msg.Metadata.MessageTimestamp,
+	}
+	data := msg.Payload.Event
+	switch msg.Metadata.SubscriptionType {
+	case helix.EventSubTypeStreamOnline:
+		var e helix.EventSubStreamOnlineEvent
+		if err := json.Unmarshal(data, &e); err != nil {
+			return base.Event{}, fmt.Errorf("failed to unmarshal %s event: %w", msg.Metadata.SubscriptionType, err)
+		}
+		event.Kind = base.EventStreamOnline
+		event.Channel = e.BroadcasterUserLogin
+		event.ChannelID = e.BroadcasterUserID
+		if !e.StartedAt.IsZero() {
+			event.Time = e.StartedAt.Time
+		}
+	case helix.EventSubTypeStreamOffline:
+		var e helix.EventSubStreamOfflineEvent
+		if err := json.Unmarshal(data, &e); err != nil {
+			return base.Event{}, fmt.Errorf("failed to unmarshal %s event: %w", msg.Metadata.SubscriptionType, err)
+		}
+		event.Kind = base.EventStreamOffline
+		event.Channel = e.BroadcasterUserLogin
+		event.ChannelID = e.BroadcasterUserID
+	case helix.EventSubTypeChannelRaid:
+		var e helix.EventSubChannelRaidEvent
+		if err := json.Unmarshal(data, &e); err != nil {
+			return base.Event{}, fmt.Errorf("failed to unmarshal %s event: %w", msg.Metadata.SubscriptionType, err)
+		}
+		event.Kind = base.EventRaid
+		event.Channel = e.ToBroadcasterUserLogin
+		event.ChannelID = e.ToBroadcasterUserID
+		event.UserID = e.FromBroadcasterUserID
+		event.User = e.FromBroadcasterUserLogin
+		event.Viewers = e.Viewers
+	case helix.EventSubTypeChannelFollow:
+		var e helix.EventSubChannelFollowEvent
+		if err := json.Unmarshal(data, &e); err != nil {
+			return base.Event{}, fmt.Errorf("failed to unmarshal %s event: %w", msg.Metadata.SubscriptionType, err)
+		}
+		event.Kind = base.EventFollow
+		event.Channel = e.BroadcasterUserLogin
+		event.ChannelID = e.BroadcasterUserID
+		event.UserID = e.UserID
+		event.User = e.UserLogin
+		if !e.FollowedAt.IsZero() {
+			event.Time = e.FollowedAt.Time
+		}
+	case helix.EventSubTypeChannelSubscription:
+		var e helix.EventSubChannelSubscribeEvent
+		if err := json.Unmarshal(data, &e); err != nil {
+			return base.Event{}, fmt.Errorf("failed to unmarshal %s event: %w", msg.Metadata.SubscriptionType, err)
+		}
+		event.Kind = base.EventSubscribe
+		event.Channel = e.BroadcasterUserLogin
+		event.ChannelID = e.BroadcasterUserID
+		event.UserID = e.UserID
+		event.User = e.UserLogin
+		event.Tier = e.Tier
+		event.Gift = e.IsGift
+	case helix.EventSubTypeChannelSubscriptionMessage:
+		var e helix.EventSubChannelSubscriptionMessageEvent
+		if err := json.Unmarshal(data, &e); err != nil {
+			return base.Event{}, fmt.Errorf("failed to unmarshal %s event: %w", msg.Metadata.SubscriptionType, err)
+		}
+		event.Kind = base.EventResubscribe
+		event.Channel = e.BroadcasterUserLogin
+		event.ChannelID = e.BroadcasterUserID
+		event.UserID = e.UserID
+		event.User = e.UserLogin
+		event.Tier = e.Tier
+		event.Months = e.CumulativeMonths
+		event.Text = e.Message.Text
+	case helix.EventSubTypeChannelSubscriptionGift:
+		var e helix.EventSubChannelSubscriptionGiftEvent
+		if err := json.Unmarshal(data, &e); err != nil {
+			return base.Event{}, fmt.Errorf("failed to unmarshal %s event: %w", msg.Metadata.SubscriptionType, err)
+		}
+		event.Kind = base.EventGiftSubscriptions
+		event.Channel = e.BroadcasterUserLogin
+		event.ChannelID = e.BroadcasterUserID
+		if !e.IsAnonymous {
+			event.UserID = e.UserID
+			event.User = e.UserLogin
+		}
+		event.Tier = e.Tier
+		event.Gift = true
+		event.Count = e.Total
+	case helix.EventSubTypeChannelPointsCustomRewardRedemptionAdd:
+		var e helix.EventSubChannelPointsCustomRewardRedemptionEvent
+		if err := json.Unmarshal(data, &e); err != nil {
+			return base.Event{}, fmt.Errorf("failed to unmarshal %s event: %w", msg.Metadata.SubscriptionType, err)
+		}
+		event.Kind = base.EventRedemption
+		event.Channel = e.BroadcasterUserLogin
+		event.ChannelID = e.BroadcasterUserID
+		event.UserID = e.UserID
+		event.User = e.UserLogin
+		event.Reward = e.Reward.Title
+		event.Cost = e.Reward.Cost
+		event.Text = e.UserInput
+		if !e.RedeemedAt.IsZero() {
+			event.Time = e.RedeemedAt.Time
+		}
+	default:
+		return base.Event{}, fmt.Errorf("unknown subscription type %q", msg.Metadata.SubscriptionType)
+	}
+	return event, nil
+}
@@ -0,0 +1,450 @@
+package twitch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/airforce270/airbot/base"
+	"github.com/airforce270/airbot/logging"
+	"github.com/coder/websocket"
+	"github.com/google/go-cmp/cmp"
+	"github.com/nicklaw5/helix/v2"
+)
+
+func TestEventSub(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	helixServer := newFakeEventSubHelixServer(t, 0)
+	reconnectURL := newFakeEventSubServer(t, func(ctx context.Context, conn *websocket.Conn) {
+		writeEventSubMessage(t, ctx, conn, welcomeMessage("session-1"))
+		writeEventSubMessage(t, ctx, conn, notificationMessage("message-3", helix.EventSubTypeStreamOffline, `{"broadcaster_user_login":"user1"}`))
+		<-ctx.Done()
+	})
+	server := newFakeEventSubServer(t, func(ctx context.Context, conn *websocket.Conn) {
+		writeEventSubMessage(t, ctx, conn, welcomeMessage("session-1"))
+		writeEventSubMessage(t, ctx, conn, `{"metadata":{"message_id":"keepalive-1","message_type":"session_keepalive"},"payload":{}}`)
+		writeEventSubMessage(t, ctx, conn, notificationMessage("message-1", helix.EventSubTypeStreamOnline, `{"broadcaster_user_id":"1","broadcaster_user_login":"user1","started_at":"2020-05-15T10:07:00Z"}`))
+		// Twitch may send a notification more than once.
+		writeEventSubMessage(t, ctx, conn, notificationMessage("message-1", helix.EventSubTypeStreamOnline, `{"broadcaster_user_id":"1","broadcaster_user_login":"user1","started_at":"2020-05-15T10:07:00Z"}`))
+		writeEventSubMessage(t, ctx, conn, notificationMessage("message-2", helix.EventSubTypeChannelRaid, `{"from_broadcaster_user_id":"3","from_broadcaster_user_login":"user3","to_broadcaster_user_login":"user2","viewers":50}`))
+		writeEventSubMessage(t, ctx, conn, fmt.Sprintf(`{"metadata":{"message_id":"reconnect-1","message_type":"session_reconnect"},"payload":{"session":{"id":"session-1","reconnect_url":%q}}}`, reconnectURL))
+		<-ctx.Done()
+	})
+
+	helixClient := newFakeEventSubHelixClient(t, helixServer)
+	channels := []*twitchChannel{{ID: "1", Name: "user1"}, {ID: "2", Name: "user2"}}
+	hooked := hookedEvents(base.EventStreamOnline, base.EventStreamOffline, base.EventRaid)
+	events := make(chan base.Event)
+	e := newEventSub(server, helixClient, "99", func() []*twitchChannel { return channels }, hooked, events, logging.Discard())
+	go e.run(ctx)
+
+	want := []base.Event{
+		{
+			Kind:      base.EventStreamOnline,
+			ID:        "message-1",
+			Channel:   "user1",
+			ChannelID: "1",
+			Time:      time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+			Title:     "some title",
+			Game:      "Just Chatting",
+		},
+		{
+			Kind:    base.EventRaid,
+			ID:      "message-2",
+			Channel: "user2",
+			UserID:  "3",
+			User:    "user3",
+			Time:    time.Date(2020, 5, 15, 10, 6, 0, 0, time.UTC),
+			Viewers: 50,
+		},
+		{
+			Kind:    base.EventStreamOffline,
+			ID:      "message-3",
+			Channel: "user1",
+			Time:    time.Date(2020, 5, 15, 10, 6, 0, 0, time.UTC),
+		},
+	}
+	var got []base.Event
+	for range want {
+		select {
+		case event := <-events:
+			got = append(got, event)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for events, got %v", got)
+		}
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("events diff (-want +got):\n%s", diff)
+	}
+
+	// Subscriptions carry over when reconnecting, so they should only be created once.
+	wantSubscriptions := 2 * 3
+	if got := helixServer.Subscriptions(); len(got) != wantSubscriptions {
+		t.Errorf("subscriptions created = %d, want %d", len(got), wantSubscriptions)
+	}
+	for _, sub := range helixServer.Subscriptions() {
+		if sub.Transport.Method != "websocket" || sub.Transport.SessionID != "session-1" {
+			t.Errorf("subscription %s transport = %+v, want websocket session-1", sub.Type, sub.Transport)
+		}
+	}
+}
+
+func TestEventSub_Unsubscribe(t *testing.T) {
+	t.Parallel()
+	helixServer := newFakeEventSubHelixServer(t, 0)
+	helixClient := newFakeEventSubHelixClient(t, helixServer)
+	hooked := hookedEvents(base.EventRaid, base.EventFollow)
+	e := newEventSub("", helixClient, "99", func() []*twitchChannel { return nil }, hooked, make(chan base.Event), logging.Discard())
+	e.setSession("session-1")
+
+	if err := e.subscribe(&twitchChannel{ID: "1", Name: "user1"}); err != nil {
+		t.Fatalf("subscribe() unexpected error: %v", err)
+	}
+	if err := e.unsubscribe("1"); err != nil {
+		t.Fatalf("unsubscribe() unexpected error: %v", err)
+	}
+
+	if got, want := helixServer.Removed(), 2; got != want {
+		t.Errorf("subscriptions removed = %d, want %d", got, want)
+	}
+}
+
+func TestEventSub_Subscribe_OnlyHookedEvents(t *testing.T) {
+	t.Parallel()
+	helixServer := newFakeEventSubHelixServer(t, 0)
+	helixClient := newFakeEventSubHelixClient(t, helixServer)
+	kinds := []base.EventKind{base.EventRaid, base.EventFollow}
+	hooked := func(channel string) (map[base.EventKind]bool, error) {
+		return hookedEvents(kinds...)(channel)
+	}
+	e := newEventSub("", helixClient, "99", func() []*twitchChannel { return nil }, hooked, make(chan base.Event), logging.Discard())
+	e.setSession("session-1")
+	channel := &twitchChannel{ID: "1", Name: "user1"}
+
+	if err := e.subscribe(channel); err != nil {
+		t.Fatalf("subscribe() unexpected error: %v", err)
+	}
+	var gotTypes []string
+	for _, sub := range helixServer.Subscriptions() {
+		gotTypes = append(gotTypes, sub.Type)
+	}
+	if diff := cmp.Diff([]string{helix.EventSubTypeChannelRaid, helix.EventSubTypeChannelFollow}, gotTypes); diff != "" {
+		t.Errorf("subscription types diff (-want +got):\n%s", diff)
+	}
+
+	// The follow hook is removed.
+	kinds = []base.EventKind{base.EventRaid}
+	if err := e.subscribe(channel); err != nil {
+		t.Fatalf("subscribe() unexpected error: %v", err)
+	}
+	if got, want := len(helixServer.Subscriptions()), 2; got != want {
+		t.Errorf("subscriptions created = %d, want %d", got, want)
+	}
+	if got, want := helixServer.Removed(), 1; got != want {
+		t.Errorf("subscriptions removed = %d, want %d", got, want)
+	}
+	if e.subscribed("1", helix.EventSubTypeChannelFollow) || !e.subscribed("1", helix.EventSubTypeChannelRaid) {
+		t.Errorf("subscriptions = %+v, want only raids", e.subscriptions["1"])
+	}
+}
+
+func TestEventSub_Subscribe_CostLimit(t *testing.T) {
+	t.Parallel()
+	helixServer := newFakeEventSubHelixServer(t, 3)
+	helixClient := newFakeEventSubHelixClient(t, helixServer)
+	hooked := hookedEvents(base.EventStreamOnline, base.EventStreamOffline)
+	e := newEventSub("", helixClient, "99", func() []*twitchChannel { return nil }, hooked, make(chan base.Event), logging.Discard())
+	e.setSession("session-1")
+
+	if err := e.subscribe(&twitchChannel{ID: "1", Name: "user1"}); err != nil {
+		t.Fatalf("subscribe(user1) unexpected error: %v", err)
+	}
+	if err := e.subscribe(&twitchChannel{ID: "2", Name: "user2"}); !errors.Is(err, base.ErrEventLimit) {
+		t.Errorf("subscribe(user2) error = %v, want %v", err, base.ErrEventLimit)
+	}
+	// The limit is known, so no more subscriptions are attempted.
+	if err := e.subscribe(&twitchChannel{ID: "3", Name: "user3"}); !errors.Is(err, base.ErrEventLimit) {
+		t.Errorf("subscribe(user3) error = %v, want %v", err, base.ErrEventLimit)
+	}
+	if got, want := len(helixServer.Subscriptions()), 3; got != want {
+		t.Errorf("subscriptions created = %d, want %d", got, want)
+	}
+}
+
+// hookedEvents returns a function that reports every channel has hooks for kinds of events.
+func hookedEvents(kinds ...base.EventKind) func(channel string) (map[base.EventKind]bool, error) {
+	return func(channel string) (map[base.EventKind]bool, error) {
+		hooked := map[base.EventKind]bool{}
+		for _, kind := range kinds {
+			hooked[kind] = true
+		}
+		return hooked, nil
+	}
+}
+
+// newFakeEventSubHelixClient creates a Helix client that calls a fake server.
+func newFakeEventSubHelixClient(t *testing.T, server *fakeEventSubHelixServer) *helix.Client {
+	t.Helper()
+	helixClient, err := helix.NewClient(&helix.Options{
+		ClientID:        "fake-client-id",
+		UserAccessToken: "fake-access-token",
+		APIBaseURL:      server.URL,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create Helix client: %v", err)
+	}
+	return helixClient
+}
+
+func TestParseEvent(t *testing.T) {
+	t.Parallel()
+	timestamp := time.Date(2020, 5, 15, 10, 6, 0, 0, time.UTC)
+	tests := []struct {
+		desc             string
+		subscriptionType string
+		event            string
+		want             base.Event
+		wantErr          bool
+	}{
+		{
+			desc:             "follow",
+			subscriptionType: helix.EventSubTypeChannelFollow,
+			event:            `{"user_id":"3","user_login":"user3","broadcaster_user_login":"user1","followed_at":"2020-05-15T10:07:00Z"}`,
+			want: base.Event{
+				Kind:    base.EventFollow,
+				ID:      "message-1",
+				Channel: "user1",
+				UserID:  "3",
+				User:    "user3",
+				Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+			},
+		},
+		{
+			desc:             "subscribe",
+			subscriptionType: helix.EventSubTypeChannelSubscription,
+			event:            `{"user_id":"3","user_login":"user3","broadcaster_user_login":"user1","tier":"1000","is_gift":true}`,
+			want: base.Event{
+				Kind:    base.EventSubscribe,
+				ID:      "message-1",
+				Channel: "user1",
+				UserID:  "3",
+				User:    "user3",
+				Time:    timestamp,
+				Tier:    "1000",
+				Gift:    true,
+			},
+		},
+		{
+			desc:             "resubscribe",
+			subscriptionType: helix.EventSubTypeChannelSubscriptionMessage,
+			event:            `{"user_id":"3","user_login":"user3","broadcaster_user_login":"user1","tier":"2000","message":{"text":"hello"},"cumulative_months":12}`,
+			want: base.Event{
+				Kind:    base.EventResubscribe,
+				ID:      "message-1",
+				Channel: "user1",
+				UserID:  "3",
+				User:    "user3",
+				Time:    timestamp,
+				Tier:    "2000",
+				Months:  12,
+				Text:    "hello",
+			},
+		},
+		{
+			desc:             "gift subscriptions",
+			subscriptionType: helix.EventSubTypeChannelSubscriptionGift,
+			event:            `{"user_id":"3","user_login":"user3","broadcaster_user_login":"user1","tier":"1000","total":5}`,
+			want: base.Event{
+				Kind:    base.EventGiftSubscriptions,
+				ID:      "message-1",
+				Channel: "user1",
+				UserID:  "3",
+				User:    "user3",
+				Time:    timestamp,
+				Tier:    "1000",
+				Gift:    true,
+				Count:   5,
+			},
+		},
+		{
+			desc:             "anonymous gift subscriptions",
+			subscriptionType: helix.EventSubTypeChannelSubscriptionGift,
+			event:            `{"user_id":"3","user_login":"user3","broadcaster_user_login":"user1","tier":"1000","total":5,"is_anonymous":true}`,
+			want: base.Event{
+				Kind:    base.EventGiftSubscriptions,
+				ID:      "message-1",
+				Channel: "user1",
+				Time:    timestamp,
+				Tier:    "1000",
+				Gift:    true,
+				Count:   5,
+			},
+		},
+		{
+			desc:             "redemption",
+			subscriptionType: helix.EventSubTypeChannelPointsCustomRewardRedemptionAdd,
+			event:            `{"user_id":"3","user_login":"user3","broadcaster_user_login":"user1","user_input":"some text","reward":{"title":"Hydrate","cost":500},"redeemed_at":"2020-05-15T10:07:00Z"}`,
+			want: base.Event{
+				Kind:    base.EventRedemption,
+				ID:      "message-1",
+				Channel: "user1",
+				UserID:  "3",
+				User:    "user3",
+				Time:    time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC),
+				Reward:  "Hydrate",
+				Cost:    500,
+				Text:    "some text",
+			},
+		},
+		{
+			desc:             "unknown type",
+			subscriptionType: "channel.something",
+			event:            `{}`,
+			wantErr:          true,
+		},
+		{
+			desc:             "invalid event",
+			subscriptionType: helix.EventSubTypeChannelFollow,
+			event:            `[]`,
+			wantErr:          true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			var msg eventSubMessage
+			if err := json.Unmarshal([]byte(notificationMessage("message-1", tc.subscriptionType, tc.event)), &msg); err != nil {
+				t.Fatalf("Failed to unmarshal message: %v", err)
+			}
+
+			got, err := parseEvent(&msg)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("parseEvent() error = %v, wantErr %t", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("parseEvent() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// newFakeEventSubServer starts a fake EventSub websocket server,
+// which runs handle for each connection, and returns its URL.
+func newFakeEventSubServer(t *testing.T, handle func(ctx context.Context, conn *websocket.Conn)) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			t.Errorf("Failed to accept websocket connection: %v", err)
+			return
+		}
+		defer conn.CloseNow()
+		// Reading is required to notice the client closing the connection.
+		ctx := conn.CloseRead(r.Context())
+		handle(ctx, conn)
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func writeEventSubMessage(t *testing.T, ctx context.Context, conn *websocket.Conn, msg string) {
+	t.Helper()
+	if err := conn.Write(ctx, websocket.MessageText, []byte(msg)); err != nil && ctx.Err() == nil {
+		t.Errorf("Failed to write message %s: %v", msg, err)
+	}
+}
+
+func welcomeMessage(sessionID string) string {
+	return fmt.Sprintf(`{"metadata":{"message_id":"welcome-%s","message_type":"session_welcome"},"payload":{"session":{"id":%q,"keepalive_timeout_seconds":10}}}`, sessionID, sessionID)
+}
+
+func notificationMessage(messageID, subscriptionType, event string) string {
+	return fmt.Sprintf(`{"metadata":{"message_id":%q,"message_type":"notification","message_timestamp":"2020-05-15T10:06:00Z","subscription_type":%q},"payload":{"subscription":{"type":%q},"event":%s}}`, messageID, subscriptionType, subscriptionType, event)
+}
+
+// fakeEventSubHelixServer is a fake Helix server that accepts EventSub subscriptions
+// and returns the same info for every channel.
+type fakeEventSubHelixServer struct {
+	*httptest.Server
+	// maxTotalCost is the most the subscriptions may cost, each costing 1.
+	// If 0, there's no limit.
+	maxTotalCost int
+
+	mu            sync.Mutex
+	subscriptions []helix.EventSubSubscription
+	removed       int
+}
+
+func newFakeEventSubHelixServer(t *testing.T, maxTotalCost int) *fakeEventSubHelixServer {
+	t.Helper()
+	s := &fakeEventSubHelixServer{maxTotalCost: maxTotalCost}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/channels" {
+			fmt.Fprintf(w, `{"data":[{"broadcaster_id":%q,"title":"some title","game_name":"Just Chatting"}]}`, r.URL.Query().Get("broadcaster_id"))
+			return
+		}
+		if r.URL.Path != "/eventsub/subscriptions" {
+			http.NotFound(w, r)
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		switch r.Method {
+		case http.MethodPost:
+			var sub helix.EventSubSubscription
+			if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			totalCost := len(s.subscriptions) - s.removed
+			if s.maxTotalCost > 0 && totalCost >= s.maxTotalCost {
+				http.Error(w, `{"error":"Too Many Requests","status":429,"message":"subscription limit exceeded"}`, http.StatusTooManyRequests)
+				return
+			}
+			sub.ID = fmt.Sprintf("subscription-%d", len(s.subscriptions))
+			sub.Cost = 1
+			s.subscriptions = append(s.subscriptions, sub)
+			w.WriteHeader(http.StatusAccepted)
+			resp := map[string]any{
+				"data":           []helix.EventSubSubscription{sub},
+				"total_cost":     totalCost + 1,
+				"max_total_cost": s.maxTotalCost,
+			}
+			if err := json.NewEncoder(w).Encode(resp); err != nil {
+				t.Errorf("Failed to write response: %v", err)
+			}
+		case http.MethodDelete:
+			s.removed++
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Subscriptions returns the subscriptions created.
+func (s *fakeEventSubHelixServer) Subscriptions() []helix.EventSubSubscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]helix.EventSubSubscription(nil), s.subscriptions...)
+}
+
+// Removed returns the number of subscriptions removed.
+func (s *fakeEventSubHelixServer) Removed() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.removed
+}
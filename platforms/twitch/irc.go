@@ -0,0 +1,259 @@
+package twitch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	twitchirc "github.com/gempir/go-twitch-irc/v4"
+)
+
+const (
+	// ircMinBackoff is the initial delay before reconnecting after the IRC connection fails.
+	ircMinBackoff = time.Second
+	// ircMaxBackoff is the longest delay before reconnecting after the IRC connection fails.
+	ircMaxBackoff = 2 * time.Minute
+	// initialConnectTimeout is how long Connect waits for the first IRC connection
+	// before carrying on and letting it connect in the background.
+	initialConnectTimeout = 30 * time.Second
+	// joinTimeout is how long to wait for Twitch to confirm the bot joined a channel
+	// before trying again.
+	joinTimeout = 5 * time.Second
+	// maxJoinAttempts is how many times to try joining a channel
+	// before marking it as failed.
+	maxJoinAttempts = 3
+	// joinCheckInterval is how often channels Twitch hasn't confirmed the bot joined are checked.
+	joinCheckInterval = 30 * time.Second
+	// joinRetryAfter is how long to wait for Twitch to confirm the bot joined a channel
+	// after connecting before trying again.
+	// It's longer than joinTimeout, since joins are rate limited when joining every channel at once.
+	joinRetryAfter = 1 * time.Minute
+	// failedJoinRetryAfter is how long to wait before trying to join a failed channel again.
+	failedJoinRetryAfter = 10 * time.Minute
+)
+
+// ErrJoinUnconfirmed is returned by Join when Twitch doesn't confirm the bot joined a channel.
+// Joining it will be retried in the background.
+var ErrJoinUnconfirmed = errors.New("twitch didn't confirm joining the channel")
+
+// ConnectionStatus describes the bot's connection to Twitch chat.
+type ConnectionStatus struct {
+	// Connected is whether the bot is connected to Twitch IRC.
+	Connected bool
+	// Since is when the bot last connected to Twitch IRC, or lost its connection.
+	Since time.Time
+	// Reconnects is how many times the bot has reconnected to Twitch IRC since it started.
+	Reconnects int
+	// Channels contains whether the bot has joined each channel, sorted by name.
+	Channels []ChannelStatus
+}
+
+// ChannelStatus describes whether the bot has joined a channel's chat.
+type ChannelStatus struct {
+	// Name is the name of the channel.
+	Name string
+	// State is whether the bot has joined the channel.
+	State JoinState
+	// Since is when State last changed.
+	Since time.Time
+	// JoinAttempts is how many times the bot has asked to join the channel since it was last joined.
+	JoinAttempts int
+	// Modes contains the chat modes that are on in the channel, i.e. "slow 30s".
+	Modes []string
+	// BotIsModerator is whether the bot is a moderator (or the broadcaster) in the channel.
+	BotIsModerator bool
+	// BotIsVIP is whether the bot is a VIP in the channel.
+	BotIsVIP bool
+}
+
+// ConnectionStatus returns the bot's connection to Twitch chat.
+func (t *Twitch) ConnectionStatus() ConnectionStatus {
+	t.connStateMu.Lock()
+	status := ConnectionStatus{
+		Connected:  t.Connected(),
+		Since:      t.connStateSince,
+		Reconnects: max(t.connects-1, 0),
+	}
+	t.connStateMu.Unlock()
+
+	for _, c := range t.channels.All() {
+		status.Channels = append(status.Channels, ChannelStatus{
+			Name:           strings.ToLower(c.Name),
+			State:          c.State,
+			Since:          c.StateSince,
+			JoinAttempts:   c.JoinAttempts,
+			Modes:          c.Room.modes(),
+			BotIsModerator: c.BotIsModerator,
+			BotIsVIP:       c.BotIsVIP,
+		})
+	}
+	slices.SortFunc(status.Channels, func(a, b ChannelStatus) int { return strings.Compare(a.Name, b.Name) })
+	return status
+}
+
+// connectIRC connects to Twitch IRC and joins every channel,
+// waiting for the first connection for up to initialConnectTimeout.
+// The connection is supervised until ctx is cancelled or the bot disconnects.
+func (t *Twitch) connectIRC(ctx context.Context) {
+	firstConnect := make(chan struct{})
+	t.irc.OnConnect(func() {
+		t.onConnect()
+		select {
+		case <-firstConnect:
+		default:
+			close(firstConnect)
+		}
+	})
+
+	// The IRC client joins these when it connects, and rejoins them whenever it reconnects.
+	for _, name := range t.channels.Names() {
+		t.logger.Info("Joining channel...", "channel", name)
+		t.irc.Join(strings.ToLower(name))
+	}
+
+	go t.superviseIRC(ctx)
+	go t.watchJoins(ctx)
+
+	timer := time.NewTimer(initialConnectTimeout)
+	defer timer.Stop()
+	select {
+	case <-firstConnect:
+	case <-ctx.Done():
+	case <-timer.C:
+		t.logger.Warn("Not connected to Twitch IRC yet, continuing to connect in the background", "waited", initialConnectTimeout)
+	}
+}
+
+// superviseIRC keeps the bot connected to Twitch IRC until ctx is cancelled or the bot disconnects,
+// reconnecting with backoff if the connection fails.
+// The IRC client reconnects by itself when Twitch asks it to, and rejoins channels when it does,
+// so this only handles the connection failing outright.
+// This function blocks and should be run within a goroutine.
+func (t *Twitch) superviseIRC(ctx context.Context) {
+	backoff := ircMinBackoff
+	for {
+		err := t.irc.Connect()
+		wasConnected := t.connected.Swap(false)
+		if errors.Is(err, twitchirc.ErrClientDisconnected) || ctx.Err() != nil {
+			t.logger.Info("Stopped connecting to Twitch IRC")
+			return
+		}
+		if wasConnected {
+			t.onDisconnect()
+			backoff = ircMinBackoff
+		}
+		t.logger.Warn("Twitch IRC connection lost, reconnecting", "retry_in", backoff, "error", err)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			t.logger.Info("Stopped connecting to Twitch IRC, context cancelled")
+			return
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, ircMaxBackoff)
+	}
+}
+
+// onConnect records that the bot connected to Twitch IRC.
+// The IRC client rejoins every channel when it connects.
+func (t *Twitch) onConnect() {
+	t.connStateMu.Lock()
+	t.connects++
+	t.connStateSince = time.Now()
+	t.connStateMu.Unlock()
+	t.connected.Store(true)
+	t.channels.RejoinAll()
+	t.logger.Info("Connected to Twitch IRC")
+}
+
+// onDisconnect records that the bot lost its connection to Twitch IRC.
+func (t *Twitch) onDisconnect() {
+	t.connStateMu.Lock()
+	t.connStateSince = time.Now()
+	t.connStateMu.Unlock()
+	t.connected.Store(false)
+}
+
+// joinIRC joins a channel's chat and waits for Twitch to confirm it,
+// retrying up to maxJoinAttempts times.
+// It returns ErrBotIsBanned if the bot is banned from the channel,
+// or ErrJoinUnconfirmed if Twitch never confirms it.
+func (t *Twitch) joinIRC(channel string) error {
+	for attempt := 1; attempt <= maxJoinAttempts; attempt++ {
+		confirmed := t.channels.ExpectJoin(channel)
+		t.channels.Update(channel, func(c *twitchChannel) { c.JoinAttempts = attempt })
+		t.rejoinIRC(channel)
+
+		timer := time.NewTimer(joinTimeout)
+		select {
+		case err := <-confirmed:
+			timer.Stop()
+			return err
+		case <-timer.C:
+			t.channels.StopExpecting(channel, confirmed)
+			t.logger.Warn("Join wasn't confirmed in time", "channel", channel, "attempt", attempt, "timeout", joinTimeout)
+		}
+	}
+	return fmt.Errorf("joining %s wasn't confirmed after %d attempts: %w", channel, maxJoinAttempts, ErrJoinUnconfirmed)
+}
+
+// rejoinIRC asks to join a channel's chat, even if the IRC client thinks it already has.
+func (t *Twitch) rejoinIRC(channel string) {
+	if t.irc == nil {
+		t.logger.Warn("Didn't actually join channel - IRC client is nil. This is expected in test, but if you see this in production, something's broken!", "channel", channel)
+		return
+	}
+	channel = strings.ToLower(channel)
+	// The IRC client doesn't send a join for channels it thinks it's in.
+	t.irc.Depart(channel)
+	t.irc.Join(channel)
+}
+
+// watchJoins retries joining channels Twitch hasn't confirmed the bot joined,
+// until ctx is cancelled.
+// This function blocks and should be run within a goroutine.
+func (t *Twitch) watchJoins(ctx context.Context) {
+	ticker := time.NewTicker(joinCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if t.Connected() {
+				t.retryJoins(time.Now())
+			}
+		case <-ctx.Done():
+			t.logger.Info("Stopping watching joins, context cancelled")
+			return
+		}
+	}
+}
+
+// retryJoins retries joining channels that have waited too long for Twitch to confirm the bot joined,
+// marking channels as failed once they run out of attempts.
+func (t *Twitch) retryJoins(now time.Time) {
+	for _, c := range t.channels.All() {
+		waited := now.Sub(c.StateSince)
+		switch {
+		case c.State == JoinStateJoining && waited >= joinRetryAfter && c.JoinAttempts >= maxJoinAttempts:
+			t.logger.Error("Failed to join channel, will try again later", "channel", c.Name, "attempts", c.JoinAttempts, "retry_in", failedJoinRetryAfter)
+			t.channels.SetState(c.Name, JoinStateFailed)
+		case c.State == JoinStateJoining && waited >= joinRetryAfter,
+			c.State == JoinStateFailed && waited >= failedJoinRetryAfter:
+			t.logger.Warn("Join wasn't confirmed, retrying", "channel", c.Name, "state", c.State, "attempts", c.JoinAttempts)
+			t.channels.Update(c.Name, func(c *twitchChannel) {
+				if c.State == JoinStateFailed {
+					c.JoinAttempts = 0
+				}
+				c.State = JoinStateJoining
+				c.StateSince = now
+				c.JoinAttempts++
+			})
+			t.rejoinIRC(c.Name)
+		}
+	}
+}
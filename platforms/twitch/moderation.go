@@ -0,0 +1,222 @@
+package twitch
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/airforce270/airbot/base"
+
+	"github.com/nicklaw5/helix/v2"
+)
+
+// announcementColor is the color of announcements.
+// The primary color is the channel's accent color.
+const announcementColor = "primary"
+
+// Ban permanently bans a user from a channel.
+func (t *Twitch) Ban(username, channel, reason string) error {
+	return t.ban(username, channel, 0, reason)
+}
+
+// Timeout times out a user in a channel.
+func (t *Twitch) Timeout(username, channel string, duration time.Duration) error {
+	return t.TimeoutWithReason(username, channel, duration, "")
+}
+
+// TimeoutWithReason times out a user in a channel, with a reason.
+func (t *Twitch) TimeoutWithReason(username, channel string, duration time.Duration, reason string) error {
+	// Helix timeouts are in whole seconds, and a duration of 0 would be a ban.
+	return t.ban(username, channel, max(int(duration.Seconds()), 1), reason)
+}
+
+// ban bans a user from a channel, for a number of seconds or 0 for a permanent ban.
+func (t *Twitch) ban(username, channel string, seconds int, reason string) error {
+	channelID, userID, err := t.moderationIDs(channel, username)
+	if err != nil {
+		return err
+	}
+	resp, err := t.helix.BanUser(&helix.BanUserParams{
+		BroadcasterID: channelID,
+		ModeratorId:   t.id,
+		Body: helix.BanUserRequestBody{
+			Duration: seconds,
+			Reason:   reason,
+			UserId:   userID,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to ban %s in %s: %w", username, channel, err)
+	}
+	return checkModerationResponse("BanUser", resp.ResponseCommon)
+}
+
+// Unban removes a ban or timeout from a user in a channel.
+func (t *Twitch) Unban(username, channel string) error {
+	channelID, userID, err := t.moderationIDs(channel, username)
+	if err != nil {
+		return err
+	}
+	resp, err := t.helix.UnbanUser(&helix.UnbanUserParams{
+		BroadcasterID: channelID,
+		ModeratorID:   t.id,
+		UserID:        userID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to unban %s in %s: %w", username, channel, err)
+	}
+	return checkModerationResponse("UnbanUser", resp.ResponseCommon)
+}
+
+// DeleteMessage deletes a single message from a channel.
+func (t *Twitch) DeleteMessage(channel, messageID string) error {
+	channelID, err := t.channelID(channel)
+	if err != nil {
+		return err
+	}
+	resp, err := t.helix.DeleteChatMessage(&helix.DeleteChatMessageParams{
+		BroadcasterID: channelID,
+		ModeratorID:   t.id,
+		MessageID:     messageID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete message %s in %s: %w", messageID, channel, err)
+	}
+	return checkModerationResponse("DeleteChatMessage", resp.ResponseCommon)
+}
+
+// ClearChat deletes all messages from a channel.
+func (t *Twitch) ClearChat(channel string) error {
+	channelID, err := t.channelID(channel)
+	if err != nil {
+		return err
+	}
+	resp, err := t.helix.DeleteAllChatMessages(&helix.DeleteAllChatMessagesParams{
+		BroadcasterID: channelID,
+		ModeratorID:   t.id,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to clear chat in %s: %w", channel, err)
+	}
+	return checkModerationResponse("DeleteAllChatMessages", resp.ResponseCommon)
+}
+
+// UpdateChatSettings changes a channel's chat modes.
+func (t *Twitch) UpdateChatSettings(channel string, settings base.ChatSettings) error {
+	channelID, err := t.channelID(channel)
+	if err != nil {
+		return err
+	}
+	params := &helix.UpdateChatSettingsParams{
+		BroadcasterID:  channelID,
+		ModeratorID:    t.id,
+		EmoteMode:      settings.EmoteOnly,
+		FollowerMode:   settings.FollowersOnly,
+		SubscriberMode: settings.SubscribersOnly,
+	}
+	if settings.SlowMode != nil {
+		enabled := *settings.SlowMode > 0
+		params.SlowMode = &enabled
+		if enabled {
+			seconds := int(settings.SlowMode.Seconds())
+			params.SlowModeWaitTime = &seconds
+		}
+	}
+	if settings.FollowersOnly != nil && *settings.FollowersOnly {
+		minutes := int(settings.FollowersOnlyAge.Minutes())
+		params.FollowerModeDuration = &minutes
+	}
+	resp, err := t.helix.UpdateChatSettings(params)
+	if err != nil {
+		return fmt.Errorf("failed to update chat settings in %s: %w", channel, err)
+	}
+	return checkModerationResponse("UpdateChatSettings", resp.ResponseCommon)
+}
+
+// Shoutout gives a shoutout to another channel in a channel.
+func (t *Twitch) Shoutout(channel, target string) error {
+	channelID, targetID, err := t.moderationIDs(channel, target)
+	if err != nil {
+		return err
+	}
+	resp, err := t.helix.SendShoutout(&helix.SendShoutoutParams{
+		FromBroadcasterID: channelID,
+		ToBroadcasterID:   targetID,
+		ModeratorID:       t.id,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to shout out %s in %s: %w", target, channel, err)
+	}
+	return checkModerationResponse("SendShoutout", resp.ResponseCommon)
+}
+
+// Announce sends an announcement to a channel.
+func (t *Twitch) Announce(channel, text string) error {
+	channelID, err := t.channelID(channel)
+	if err != nil {
+		return err
+	}
+	resp, err := t.helix.SendChatAnnouncement(&helix.SendChatAnnouncementParams{
+		BroadcasterID: channelID,
+		ModeratorID:   t.id,
+		Message:       text,
+		Color:         announcementColor,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send announcement in %s: %w", channel, err)
+	}
+	return checkModerationResponse("SendChatAnnouncement", resp.ResponseCommon)
+}
+
+// moderationIDs returns the IDs of a channel and a user, for moderating the user in the channel.
+func (t *Twitch) moderationIDs(channel, username string) (channelID, userID string, err error) {
+	channelID, err = t.channelID(channel)
+	if err != nil {
+		return "", "", err
+	}
+	userID, err = t.userID(username)
+	if err != nil {
+		return "", "", err
+	}
+	return channelID, userID, nil
+}
+
+// channelID returns the ID of a channel,
+// without calling the Twitch API if the bot has joined it.
+func (t *Twitch) channelID(channel string) (string, error) {
+	if c, ok := t.channels.Get(channel); ok && c.ID != "" {
+		return c.ID, nil
+	}
+	return t.userID(channel)
+}
+
+// userID returns the ID of a user.
+func (t *Twitch) userID(username string) (string, error) {
+	resp, err := t.helix.GetUsers(&helix.UsersParams{Logins: []string{strings.TrimPrefix(username, "@")}})
+	if err != nil {
+		return "", fmt.Errorf("failed to get user %s from Helix: %w", username, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("twitch GetUsers call for %q failed: %d %s", username, resp.StatusCode, resp.ErrorMessage)
+	}
+	if len(resp.Data.Users) == 0 {
+		return "", fmt.Errorf("user %s: %w", username, base.ErrNoSuchUser)
+	}
+	return resp.Data.Users[0].ID, nil
+}
+
+// checkModerationResponse returns an error if a Helix moderation call failed.
+func checkModerationResponse(call string, resp helix.ResponseCommon) error {
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusUnauthorized:
+		// Helix refreshes the access token when it's rejected, so it couldn't be refreshed.
+		return fmt.Errorf("twitch %s call failed: %s: %w", call, resp.ErrorMessage, base.ErrUnauthorized)
+	case resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("twitch %s call failed: %s: %w", call, resp.ErrorMessage, base.ErrNotModerator)
+	default:
+		return fmt.Errorf("twitch %s call failed: %d %s", call, resp.StatusCode, resp.ErrorMessage)
+	}
+}
@@ -0,0 +1,120 @@
+package twitch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/airforce270/airbot/base"
+	"github.com/airforce270/airbot/database/databasetest"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTwitch_Moderation(t *testing.T) {
+	t.Parallel()
+	slow := 30 * time.Second
+	tests := []struct {
+		desc   string
+		run    func(tw *Twitch) error
+		status int
+		want   []string
+		// wantErr is the error the call should wrap, if any.
+		wantErr error
+	}{
+		{
+			desc: "ban",
+			run:  func(tw *Twitch) error { return tw.Ban("user3", "user2", "spam") },
+			want: []string{
+				"GET /users?login=user2",
+				"GET /users?login=user3",
+				`POST /moderation/bans?broadcaster_id=id-user2&moderator_id=fake-user-id {"BroadcasterID":"id-user2","ModeratorId":"fake-user-id","data":{"reason":"spam","user_id":"id-user3"}}`,
+			},
+		},
+		{
+			desc: "timeout",
+			run:  func(tw *Twitch) error { return tw.TimeoutWithReason("user3", "user2", 500*time.Millisecond, "") },
+			want: []string{
+				"GET /users?login=user2",
+				"GET /users?login=user3",
+				`POST /moderation/bans?broadcaster_id=id-user2&moderator_id=fake-user-id {"BroadcasterID":"id-user2","ModeratorId":"fake-user-id","data":{"duration":1,"reason":"","user_id":"id-user3"}}`,
+			},
+		},
+		{
+			desc: "slow mode",
+			run:  func(tw *Twitch) error { return tw.UpdateChatSettings("user2", base.ChatSettings{SlowMode: &slow}) },
+			want: []string{
+				"GET /users?login=user2",
+				`PATCH /chat/settings?broadcaster_id=id-user2&moderator_id=fake-user-id {"BroadcasterID":"id-user2","ModeratorID":"fake-user-id","slow_mode":true,"slow_mode_wait_time":30}`,
+			},
+		},
+		{
+			desc:    "not moderator",
+			run:     func(tw *Twitch) error { return tw.DeleteMessage("user2", "abc") },
+			status:  http.StatusForbidden,
+			want:    []string{"GET /users?login=user2", "DELETE /moderation/chat?broadcaster_id=id-user2&message_id=abc&moderator_id=fake-user-id"},
+			wantErr: base.ErrNotModerator,
+		},
+		{
+			desc:    "unauthorized",
+			run:     func(tw *Twitch) error { return tw.DeleteMessage("user2", "abc") },
+			status:  http.StatusUnauthorized,
+			want:    []string{"GET /users?login=user2", "DELETE /moderation/chat?broadcaster_id=id-user2&message_id=abc&moderator_id=fake-user-id"},
+			wantErr: base.ErrUnauthorized,
+		},
+		{
+			desc:    "no such user",
+			run:     func(tw *Twitch) error { return tw.Unban("nobody", "user2") },
+			want:    []string{"GET /users?login=user2", "GET /users?login=nobody"},
+			wantErr: base.ErrNoSuchUser,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			var mu sync.Mutex
+			var got []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				req := strings.TrimSpace(fmt.Sprintf("%s %s %s", r.Method, r.URL.RequestURI(), body))
+				mu.Lock()
+				got = append(got, req)
+				mu.Unlock()
+
+				if r.URL.Path == "/users" {
+					login := r.URL.Query().Get("login")
+					if login == "nobody" {
+						fmt.Fprint(w, `{"data": []}`)
+						return
+					}
+					fmt.Fprintf(w, `{"data": [{"id": "id-%s", "login": "%s"}]}`, login, login)
+					return
+				}
+				if tc.status != 0 {
+					w.WriteHeader(tc.status)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			t.Cleanup(server.Close)
+			tw := NewForTesting(t, server.URL, databasetest.New(t))
+
+			err := tc.run(tw)
+			if tc.wantErr == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("requests diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
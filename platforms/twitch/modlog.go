@@ -0,0 +1,67 @@
+package twitch
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/airforce270/airbot/base"
+	"github.com/airforce270/airbot/database/models"
+
+	twitchirc "github.com/gempir/go-twitch-irc/v4"
+)
+
+// persistChatBan records a user being banned or timed out from a channel.
+// Clearing the whole chat isn't recorded.
+func (t *Twitch) persistChatBan(msg twitchirc.ClearChatMessage) {
+	if msg.TargetUserID == "" {
+		t.logger.Info("Chat cleared", "channel", msg.Channel)
+		return
+	}
+
+	user, err := t.findOrCreateUser(msg.TargetUserID, msg.TargetUsername)
+	if err != nil {
+		t.logger.Error("Failed to find/create banned user", "channel", msg.Channel, "user", msg.TargetUsername, "error", err)
+		return
+	}
+	banTime := msg.Time
+	if banTime.IsZero() {
+		banTime = time.Now()
+	}
+	err = t.db.Create(&models.ChatBan{
+		Platform: t.Name(),
+		Channel:  strings.ToLower(msg.Channel),
+		User:     user,
+		Duration: time.Duration(msg.BanDuration) * time.Second,
+		Time:     banTime,
+	}).Error
+	if err != nil {
+		t.logger.Error("Failed to persist ban in database", "channel", msg.Channel, "user", msg.TargetUsername, "error", err)
+	}
+}
+
+// persistDeletedMessage records a message being deleted by a moderator.
+// Twitch doesn't say who sent the message, other than their name,
+// so it's only recorded if the user is known.
+func (t *Twitch) persistDeletedMessage(msg twitchirc.ClearMessage, deletedAt time.Time) {
+	user, err := t.User(msg.Login)
+	if errors.Is(err, base.ErrUserUnknown) {
+		t.logger.Debug("Not persisting deleted message from unknown user", "channel", msg.Channel, "user", msg.Login)
+		return
+	}
+	if err != nil {
+		t.logger.Error("Failed to find user of deleted message", "channel", msg.Channel, "user", msg.Login, "error", err)
+		return
+	}
+	err = t.db.Create(&models.DeletedMessage{
+		Platform:  t.Name(),
+		Channel:   strings.ToLower(msg.Channel),
+		User:      user,
+		MessageID: msg.TargetMsgID,
+		Text:      msg.Message,
+		Time:      deletedAt,
+	}).Error
+	if err != nil {
+		t.logger.Error("Failed to persist deleted message in database", "channel", msg.Channel, "user", msg.Login, "error", err)
+	}
+}
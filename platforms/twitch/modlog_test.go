@@ -0,0 +1,62 @@
+package twitch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/airforce270/airbot/database/databasetest"
+	"github.com/airforce270/airbot/database/models"
+
+	twitchirc "github.com/gempir/go-twitch-irc/v4"
+)
+
+func TestTwitch_PersistChatBan(t *testing.T) {
+	t.Parallel()
+	db := databasetest.New(t)
+	tw := NewForTesting(t, "", db)
+	banTime := time.Date(2020, 5, 15, 10, 0, 0, 0, time.UTC)
+
+	tw.persistChatBan(twitchirc.ClearChatMessage{Channel: "user1", TargetUserID: "user2", TargetUsername: "user2", BanDuration: 600, Time: banTime})
+	tw.persistChatBan(twitchirc.ClearChatMessage{Channel: "user1", TargetUserID: "123", TargetUsername: "newuser", Time: banTime})
+	// Clearing the whole chat isn't a ban.
+	tw.persistChatBan(twitchirc.ClearChatMessage{Channel: "user1", Time: banTime})
+
+	var bans []models.ChatBan
+	if err := db.Preload("User").Order("id").Find(&bans).Error; err != nil {
+		t.Fatalf("Failed to fetch bans: %v", err)
+	}
+	if len(bans) != 2 {
+		t.Fatalf("got %d bans, want 2", len(bans))
+	}
+	if got := bans[0]; got.User.TwitchName != "user2" || got.Channel != "user1" || got.Duration != 10*time.Minute || !got.Time.Equal(banTime) {
+		t.Errorf("timeout = {user: %s, channel: %s, duration: %s, time: %s}, want {user: user2, channel: user1, duration: 10m0s, time: %s}",
+			got.User.TwitchName, got.Channel, got.Duration, got.Time, banTime)
+	}
+	if got := bans[1]; got.User.TwitchID != "123" || got.User.TwitchName != "newuser" || got.Duration != 0 {
+		t.Errorf("ban = {user ID: %s, user: %s, duration: %s}, want {user ID: 123, user: newuser, duration: 0s}",
+			got.User.TwitchID, got.User.TwitchName, got.Duration)
+	}
+}
+
+func TestTwitch_PersistDeletedMessage(t *testing.T) {
+	t.Parallel()
+	db := databasetest.New(t)
+	tw := NewForTesting(t, "", db)
+	deletedAt := time.Date(2020, 5, 15, 10, 0, 0, 0, time.UTC)
+
+	tw.persistDeletedMessage(twitchirc.ClearMessage{Channel: "user1", Login: "user2", TargetMsgID: "abc", Message: "bad words"}, deletedAt)
+	// Unknown users aren't recorded.
+	tw.persistDeletedMessage(twitchirc.ClearMessage{Channel: "user1", Login: "someone", TargetMsgID: "def", Message: "hi"}, deletedAt)
+
+	var deleted []models.DeletedMessage
+	if err := db.Preload("User").Find(&deleted).Error; err != nil {
+		t.Fatalf("Failed to fetch deleted messages: %v", err)
+	}
+	if len(deleted) != 1 {
+		t.Fatalf("got %d deleted messages, want 1", len(deleted))
+	}
+	if got := deleted[0]; got.User.TwitchName != "user2" || got.MessageID != "abc" || got.Text != "bad words" || !got.Time.Equal(deletedAt) {
+		t.Errorf("deleted message = {user: %s, id: %s, text: %s, time: %s}, want {user: user2, id: abc, text: bad words, time: %s}",
+			got.User.TwitchName, got.MessageID, got.Text, got.Time, deletedAt)
+	}
+}
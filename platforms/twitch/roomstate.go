@@ -0,0 +1,242 @@
+package twitch
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/airforce270/airbot/base"
+
+	twitchirc "github.com/gempir/go-twitch-irc/v4"
+	"github.com/nicklaw5/helix/v2"
+)
+
+// ErrEmoteOnly is returned when sending a message that isn't only emotes
+// to a channel in emote-only mode, where the bot isn't a moderator.
+var ErrEmoteOnly = errors.New("channel is in emote-only mode")
+
+// ROOMSTATE tags.
+// See https://dev.twitch.tv/docs/irc/tags/#roomstate-tags
+const (
+	roomStateEmoteOnly     = "emote-only"
+	roomStateFollowersOnly = "followers-only"
+	roomStateUniqueChat    = "r9k"
+	roomStateSlow          = "slow"
+	roomStateSubsOnly      = "subs-only"
+)
+
+// emoteLookupRetryDelay is how long after looking up the bot's emotes fails
+// it's tried again.
+const emoteLookupRetryDelay = 1 * time.Minute
+
+// maxEmoteSetsPerRequest is the most emote sets that can be looked up at once.
+// See https://dev.twitch.tv/docs/api/reference/#get-emote-sets
+const maxEmoteSetsPerRequest = 25
+
+// roomState contains a channel's chat settings.
+type roomState struct {
+	// EmoteOnly is whether only emotes may be sent.
+	EmoteOnly bool
+	// FollowersOnly is whether only followers may chat.
+	FollowersOnly bool
+	// FollowersOnlyAfter is how long users must have followed for to chat in followers-only mode.
+	FollowersOnlyAfter time.Duration
+	// SubsOnly is whether only subscribers may chat.
+	SubsOnly bool
+	// UniqueChat is whether messages must be unique.
+	UniqueChat bool
+	// Slow is how long users must wait between messages, or 0 if slow mode is off.
+	Slow time.Duration
+}
+
+// apply updates the room state from a ROOMSTATE message's state.
+// Twitch sends every setting when the bot joins a channel,
+// but only the setting that changed afterwards.
+func (s *roomState) apply(state map[string]int) {
+	for tag, value := range state {
+		switch tag {
+		case roomStateEmoteOnly:
+			s.EmoteOnly = value == 1
+		case roomStateFollowersOnly:
+			// -1 means followers-only mode is off, otherwise it's how many minutes users must have followed for.
+			s.FollowersOnly = value >= 0
+			s.FollowersOnlyAfter = time.Duration(max(value, 0)) * time.Minute
+		case roomStateUniqueChat:
+			s.UniqueChat = value == 1
+		case roomStateSlow:
+			s.Slow = time.Duration(value) * time.Second
+		case roomStateSubsOnly:
+			s.SubsOnly = value == 1
+		}
+	}
+}
+
+// modes returns the chat modes that are on, i.e. "slow 30s".
+func (s roomState) modes() []string {
+	var modes []string
+	if s.EmoteOnly {
+		modes = append(modes, "emote-only")
+	}
+	if s.FollowersOnly {
+		if s.FollowersOnlyAfter > 0 {
+			modes = append(modes, "followers-only "+s.FollowersOnlyAfter.String())
+		} else {
+			modes = append(modes, "followers-only")
+		}
+	}
+	if s.SubsOnly {
+		modes = append(modes, "subs-only")
+	}
+	if s.UniqueChat {
+		modes = append(modes, "unique-chat")
+	}
+	if s.Slow > 0 {
+		modes = append(modes, "slow "+s.Slow.String())
+	}
+	return modes
+}
+
+// handleRoomState records a channel's chat settings.
+func (t *Twitch) handleRoomState(msg twitchirc.RoomStateMessage) {
+	t.channels.Update(msg.Channel, func(c *twitchChannel) { c.Room.apply(msg.State) })
+}
+
+// handleUserState records the bot's role in a channel.
+// Twitch sends USERSTATE when the bot joins a channel and whenever it sends a message.
+func (t *Twitch) handleUserState(msg twitchirc.UserStateMessage) {
+	isMod := userHasBadge(msg.User, broadcasterBadge) || userHasBadge(msg.User, moderatorBadge)
+	isVIP := userHasBadge(msg.User, vipBadge)
+	t.channels.Update(msg.Channel, func(c *twitchChannel) {
+		if c.BotIsModerator != isMod || c.BotIsVIP != isVIP {
+			t.logger.Info("Bot's role changed", "channel", c.Name, "moderator", isMod, "vip", isVIP)
+		}
+		c.BotIsModerator = isMod
+		c.BotIsVIP = isVIP
+	})
+	t.emotes.setSets(msg.EmoteSets)
+}
+
+// botEmotes contains the emotes the bot can use.
+type botEmotes struct {
+	mu sync.Mutex
+	// sets contains the IDs of the bot's emote sets, sorted.
+	sets []string
+	// names contains the names of the emotes in the bot's emote sets.
+	// It's nil until they're looked up.
+	names map[string]bool
+	// lookupErr is the error looking up the emotes last failed with,
+	// or nil if it hasn't failed since they last changed.
+	lookupErr error
+	// lookupFailedAt is when looking up the emotes last failed.
+	lookupFailedAt time.Time
+}
+
+// setSets records the bot's emote sets,
+// forgetting the emotes that were looked up if they changed.
+func (e *botEmotes) setSets(sets []string) {
+	sets = slices.Sorted(slices.Values(sets))
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if slices.Equal(e.sets, sets) {
+		return
+	}
+	e.sets = sets
+	e.names = nil
+	e.lookupErr = nil
+}
+
+// onlyEmotes returns whether every word of a message is an emote the bot can use.
+func (t *Twitch) onlyEmotes(text string) (bool, error) {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return false, nil
+	}
+	names, err := t.emoteNames()
+	if err != nil {
+		return false, err
+	}
+	for _, word := range words {
+		if !names[word] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// emoteNames returns the names of the emotes the bot can use,
+// looking them up if they haven't been yet.
+// If looking them up fails, it isn't retried for emoteLookupRetryDelay.
+func (t *Twitch) emoteNames() (map[string]bool, error) {
+	t.emotes.mu.Lock()
+	if t.emotes.names != nil {
+		names := t.emotes.names
+		t.emotes.mu.Unlock()
+		return names, nil
+	}
+	if err := t.emotes.lookupErr; err != nil && time.Since(t.emotes.lookupFailedAt) < emoteLookupRetryDelay {
+		t.emotes.mu.Unlock()
+		return nil, err
+	}
+	sets := t.emotes.sets
+	t.emotes.mu.Unlock()
+
+	// Looking up emotes is slow, so it's done without holding the lock.
+	names, err := t.lookUpEmoteNames(sets)
+
+	t.emotes.mu.Lock()
+	defer t.emotes.mu.Unlock()
+	if !slices.Equal(sets, t.emotes.sets) {
+		// The bot's emote sets changed during the lookup, so the result is out of date.
+		return names, err
+	}
+	if err != nil {
+		t.emotes.lookupErr = err
+		t.emotes.lookupFailedAt = time.Now()
+		return nil, err
+	}
+	t.emotes.names = names
+	t.emotes.lookupErr = nil
+	return names, nil
+}
+
+// lookUpEmoteNames looks up the names of the emotes in emote sets.
+func (t *Twitch) lookUpEmoteNames(sets []string) (map[string]bool, error) {
+	names := map[string]bool{}
+	for batch := range slices.Chunk(sets, maxEmoteSetsPerRequest) {
+		resp, err := t.helix.GetEmoteSets(&helix.GetEmoteSetsParams{EmoteSetIDs: batch})
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up emote sets: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("twitch GetEmoteSets call failed: %d %s", resp.StatusCode, resp.ErrorMessage)
+		}
+		for _, emote := range resp.Data.Emotes {
+			names[emote.Name] = true
+		}
+	}
+	return names, nil
+}
+
+// CanSend returns ErrEmoteOnly if a message can't be sent to a channel in emote-only mode.
+func (t *Twitch) CanSend(msg base.Message) error {
+	channel, ok := t.channels.Get(msg.Channel)
+	if !ok {
+		return nil
+	}
+	// Moderators can send anything in emote-only mode.
+	if !channel.Room.EmoteOnly || channel.BotIsModerator || strings.EqualFold(channel.Name, t.username) {
+		return nil
+	}
+	onlyEmotes, err := t.onlyEmotes(msg.Text)
+	if err != nil {
+		t.logger.Warn("Failed to check if message is only emotes", "channel", msg.Channel, "error", err)
+	}
+	if !onlyEmotes {
+		return fmt.Errorf("can't send message to %s: %w", msg.Channel, ErrEmoteOnly)
+	}
+	return nil
+}
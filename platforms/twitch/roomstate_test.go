@@ -0,0 +1,150 @@
+package twitch
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/airforce270/airbot/base"
+	"github.com/airforce270/airbot/database/databasetest"
+	"github.com/google/go-cmp/cmp"
+
+	twitchirc "github.com/gempir/go-twitch-irc/v4"
+)
+
+func TestRoomState_Apply(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		desc      string
+		start     roomState
+		state     map[string]int
+		want      roomState
+		wantModes []string
+	}{
+		{
+			desc:  "join",
+			state: map[string]int{"emote-only": 0, "followers-only": -1, "r9k": 0, "rituals": 0, "slow": 0, "subs-only": 0},
+			want:  roomState{},
+		},
+		{
+			desc:      "all on",
+			state:     map[string]int{"emote-only": 1, "followers-only": 10, "r9k": 1, "slow": 30, "subs-only": 1},
+			want:      roomState{EmoteOnly: true, FollowersOnly: true, FollowersOnlyAfter: 10 * time.Minute, UniqueChat: true, Slow: 30 * time.Second, SubsOnly: true},
+			wantModes: []string{"emote-only", "followers-only 10m0s", "subs-only", "unique-chat", "slow 30s"},
+		},
+		{
+			desc:      "followers-only for any followers",
+			state:     map[string]int{"followers-only": 0},
+			want:      roomState{FollowersOnly: true},
+			wantModes: []string{"followers-only"},
+		},
+		{
+			desc:      "only changed settings are updated",
+			start:     roomState{EmoteOnly: true, Slow: 30 * time.Second},
+			state:     map[string]int{"slow": 0},
+			want:      roomState{EmoteOnly: true},
+			wantModes: []string{"emote-only"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			got := tc.start
+			got.apply(tc.state)
+
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("apply() diff (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantModes, got.modes()); diff != "" {
+				t.Errorf("modes() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTwitch_HandleUserState(t *testing.T) {
+	t.Parallel()
+	tw := NewForTesting(t, "", databasetest.New(t))
+
+	tw.handleUserState(twitchirc.UserStateMessage{Channel: "user1", User: twitchirc.User{Badges: map[string]int{"moderator": 1}}})
+	tw.handleUserState(twitchirc.UserStateMessage{Channel: "user2", User: twitchirc.User{Badges: map[string]int{"vip": 1}}})
+
+	user1, _ := tw.channels.Get("user1")
+	if !user1.BotIsModerator || user1.BotIsVIP {
+		t.Errorf("user1: BotIsModerator = %t, BotIsVIP = %t, want true, false", user1.BotIsModerator, user1.BotIsVIP)
+	}
+	user2, _ := tw.channels.Get("user2")
+	if user2.BotIsModerator || !user2.BotIsVIP {
+		t.Errorf("user2: BotIsModerator = %t, BotIsVIP = %t, want false, true", user2.BotIsModerator, user2.BotIsVIP)
+	}
+
+	// Unmodded.
+	tw.handleUserState(twitchirc.UserStateMessage{Channel: "user1", User: twitchirc.User{Badges: map[string]int{}}})
+	user1, _ = tw.channels.Get("user1")
+	if user1.BotIsModerator {
+		t.Error("user1: BotIsModerator = true after unmod, want false")
+	}
+}
+
+func TestTwitch_Reply_EmoteOnly(t *testing.T) {
+	t.Parallel()
+	var lookups atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups.Add(1)
+		fmt.Fprint(w, `{"data": [{"id": "1", "name": "Kappa", "emote_set_id": "0"}, {"id": "2", "name": "PogChamp", "emote_set_id": "0"}]}`)
+	}))
+	t.Cleanup(server.Close)
+	tw := NewForTesting(t, server.URL, databasetest.New(t))
+	t.Cleanup(tw.pendingWrites.Wait)
+	tw.emotes.setSets([]string{"0"})
+	tw.handleRoomState(twitchirc.RoomStateMessage{Channel: "user1", State: map[string]int{"emote-only": 1}})
+
+	tests := []struct {
+		desc    string
+		text    string
+		mod     bool
+		wantErr error
+	}{
+		{desc: "emotes", text: "Kappa PogChamp Kappa"},
+		{desc: "not emotes", text: "hello Kappa", wantErr: ErrEmoteOnly},
+		{desc: "moderator", text: "hello Kappa", mod: true},
+	}
+	for _, tc := range tests {
+		tw.channels.Update("user1", func(c *twitchChannel) { c.BotIsModerator = tc.mod })
+
+		err := tw.Send(base.Message{Channel: "user1", Text: tc.text})
+		if !errors.Is(err, tc.wantErr) {
+			t.Errorf("%s: Send() error = %v, want %v", tc.desc, err, tc.wantErr)
+		}
+	}
+	if got := lookups.Load(); got != 1 {
+		t.Errorf("emote sets were looked up %d times, want 1", got)
+	}
+}
+
+func TestTwitch_CanSend_CachesFailedLookups(t *testing.T) {
+	t.Parallel()
+	var lookups atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(server.Close)
+	tw := NewForTesting(t, server.URL, databasetest.New(t))
+	tw.emotes.setSets([]string{"0"})
+	tw.handleRoomState(twitchirc.RoomStateMessage{Channel: "user1", State: map[string]int{"emote-only": 1}})
+
+	for range 2 {
+		if err := tw.CanSend(base.Message{Channel: "user1", Text: "Kappa"}); !errors.Is(err, ErrEmoteOnly) {
+			t.Errorf("CanSend() error = %v, want %v", err, ErrEmoteOnly)
+		}
+	}
+	if got := lookups.Load(); got != 1 {
+		t.Errorf("emote sets were looked up %d times, want 1", got)
+	}
+}
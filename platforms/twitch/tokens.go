@@ -0,0 +1,212 @@
+package twitch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/airforce270/airbot/database/models"
+	"github.com/airforce270/airbot/utils/secrets"
+	"gorm.io/gorm"
+)
+
+// ErrMissingScopes is returned by Connect when the access token
+// is missing scopes required for enabled features.
+var ErrMissingScopes = errors.New("access token is missing required scopes")
+
+// scopeRequirement contains the OAuth scopes a feature needs.
+type scopeRequirement struct {
+	// feature describes the feature.
+	feature string
+	// scopes contains the scopes the feature needs.
+	scopes []string
+	// required is whether the bot can't run without the feature.
+	// Otherwise, a warning is logged and the feature won't work.
+	required bool
+	// eventSubOnly is whether the feature is only used when EventSub is enabled.
+	eventSubOnly bool
+}
+
+// scopeRequirements contains the OAuth scopes needed by each feature.
+// See https://dev.twitch.tv/docs/authentication/scopes/
+var scopeRequirements = []scopeRequirement{
+	{feature: "chat", scopes: []string{"chat:read", "chat:edit"}, required: true},
+	{feature: "whispers", scopes: []string{"whispers:read", "user:manage:whispers"}},
+	{
+		feature: "moderation",
+		scopes: []string{
+			"moderator:manage:banned_users",
+			"moderator:manage:chat_messages",
+			"moderator:manage:chat_settings",
+			"moderator:manage:shoutouts",
+			"moderator:manage:announcements",
+		},
+	},
+	{feature: "chatter lists", scopes: []string{"moderator:read:chatters"}},
+	{feature: "follow events", scopes: []string{"moderator:read:followers"}, eventSubOnly: true},
+}
+
+// missingScopes returns the scopes needed by enabled features that weren't granted,
+// by feature, split by whether the features are required.
+func missingScopes(granted []string, eventSub bool) (required, optional map[string][]string) {
+	required, optional = map[string][]string{}, map[string][]string{}
+	for _, req := range scopeRequirements {
+		if req.eventSubOnly && !eventSub {
+			continue
+		}
+		var missing []string
+		for _, scope := range req.scopes {
+			if !slices.Contains(granted, scope) {
+				missing = append(missing, scope)
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+		if req.required {
+			required[req.feature] = missing
+		} else {
+			optional[req.feature] = missing
+		}
+	}
+	return required, optional
+}
+
+// formatMissingScopes formats scopes missing by feature, i.e. "chat (chat:read, chat:edit)".
+func formatMissingScopes(missing map[string][]string) string {
+	var parts []string
+	for _, req := range scopeRequirements {
+		if scopes, ok := missing[req.feature]; ok {
+			parts = append(parts, fmt.Sprintf("%s (%s)", req.feature, strings.Join(scopes, ", ")))
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// validateScopes checks the access token is valid and has the scopes needed by enabled features.
+// It returns an error wrapping ErrMissingScopes if required scopes are missing,
+// and logs a warning if others are.
+func (t *Twitch) validateScopes() error {
+	accessToken, _ := t.tokens()
+	valid, resp, err := t.helix.ValidateToken(accessToken)
+	if err != nil {
+		return fmt.Errorf("failed to validate access token: %w", err)
+	}
+	if !valid {
+		return fmt.Errorf("access token is invalid: %d %s", resp.StatusCode, resp.ErrorMessage)
+	}
+	if !strings.EqualFold(resp.Data.Login, t.username) {
+		t.logger.Warn("Access token is for a different user than the configured username", "token_user", resp.Data.Login, "username", t.username)
+	}
+
+	required, optional := missingScopes(resp.Data.Scopes, t.eventSubEnabled)
+	if len(optional) > 0 {
+		t.logger.Warn("Access token is missing scopes, some features won't work: "+formatMissingScopes(optional), "scopes", resp.Data.Scopes)
+	}
+	if len(required) > 0 {
+		return fmt.Errorf("%w: %s", ErrMissingScopes, formatMissingScopes(required))
+	}
+	return nil
+}
+
+// tokens returns the current access and refresh tokens.
+func (t *Twitch) tokens() (accessToken, refreshToken string) {
+	t.tokenMu.Lock()
+	defer t.tokenMu.Unlock()
+	return t.accessToken, t.refreshToken
+}
+
+// onTokensRefreshed is called when Helix refreshes the access token.
+func (t *Twitch) onTokensRefreshed(accessToken, refreshToken string) {
+	t.logger.Info("Access token refreshed")
+	t.tokenMu.Lock()
+	t.accessToken = accessToken
+	t.refreshToken = refreshToken
+	t.tokenMu.Unlock()
+
+	if t.irc != nil {
+		t.irc.SetIRCToken("oauth:" + accessToken)
+	}
+	if err := t.persistTokens(accessToken, refreshToken); err != nil {
+		t.logger.Error("Failed to persist refreshed access token", "error", err)
+	}
+}
+
+// loadPersistedTokens replaces the configured tokens with tokens refreshed and persisted by a previous run,
+// unless the configured access token has changed since.
+func (t *Twitch) loadPersistedTokens() error {
+	if t.tokenCipher == nil {
+		t.logger.Warn("Refreshed access tokens won't be persisted, set " + secrets.KeyEnvVar + " to persist them")
+		return nil
+	}
+
+	var persisted models.OAuthToken
+	err := t.db.Where(models.OAuthToken{Platform: t.Name(), Username: strings.ToLower(t.username)}).First(&persisted).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch persisted access token: %w", err)
+	}
+	if persisted.ConfigTokenHash != hashToken(t.configAccessToken) {
+		t.logger.Info("Configured access token changed, using it instead of the persisted one")
+		return nil
+	}
+
+	accessToken, err := t.tokenCipher.Open(persisted.AccessToken)
+	if err != nil {
+		t.logger.Warn("Failed to decrypt persisted access token, using the configured one", "error", err)
+		return nil
+	}
+	refreshToken, err := t.tokenCipher.Open(persisted.RefreshToken)
+	if err != nil {
+		t.logger.Warn("Failed to decrypt persisted refresh token, using the configured one", "error", err)
+		return nil
+	}
+
+	t.logger.Info("Using persisted access token", "refreshed_at", persisted.UpdatedAt)
+	t.tokenMu.Lock()
+	t.accessToken = accessToken
+	t.refreshToken = refreshToken
+	t.tokenMu.Unlock()
+	return nil
+}
+
+// persistTokens encrypts and persists refreshed tokens,
+// so they're used instead of the configured ones after restarting.
+func (t *Twitch) persistTokens(accessToken, refreshToken string) error {
+	if t.tokenCipher == nil {
+		return nil
+	}
+	sealedAccessToken, err := t.tokenCipher.Seal(accessToken)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt access token: %w", err)
+	}
+	sealedRefreshToken, err := t.tokenCipher.Seal(refreshToken)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt refresh token: %w", err)
+	}
+
+	var token models.OAuthToken
+	err = t.db.
+		Where(models.OAuthToken{Platform: t.Name(), Username: strings.ToLower(t.username)}).
+		Assign(map[string]any{
+			"access_token":      sealedAccessToken,
+			"refresh_token":     sealedRefreshToken,
+			"config_token_hash": hashToken(t.configAccessToken),
+		}).
+		FirstOrCreate(&token).Error
+	if err != nil {
+		return fmt.Errorf("failed to save access token: %w", err)
+	}
+	return nil
+}
+
+// hashToken returns a hash of a token, to tell whether it changed without storing it.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
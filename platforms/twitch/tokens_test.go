@@ -0,0 +1,144 @@
+package twitch
+
+import (
+	"testing"
+
+	"github.com/airforce270/airbot/database/databasetest"
+	"github.com/airforce270/airbot/utils/secrets"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMissingScopes(t *testing.T) {
+	t.Parallel()
+	allScopes := []string{
+		"chat:read", "chat:edit", "whispers:read", "user:manage:whispers",
+		"moderator:manage:banned_users", "moderator:manage:chat_messages", "moderator:manage:chat_settings",
+		"moderator:manage:shoutouts", "moderator:manage:announcements", "moderator:read:chatters",
+	}
+	tests := []struct {
+		desc         string
+		granted      []string
+		eventSub     bool
+		wantRequired map[string][]string
+		wantOptional map[string][]string
+	}{
+		{
+			desc:         "all scopes",
+			granted:      allScopes,
+			wantRequired: map[string][]string{},
+			wantOptional: map[string][]string{},
+		},
+		{
+			desc:         "all scopes with eventsub",
+			granted:      allScopes,
+			eventSub:     true,
+			wantRequired: map[string][]string{},
+			wantOptional: map[string][]string{"follow events": {"moderator:read:followers"}},
+		},
+		{
+			desc:         "chat only",
+			granted:      []string{"chat:read", "chat:edit", "moderator:manage:banned_users"},
+			wantRequired: map[string][]string{},
+			wantOptional: map[string][]string{
+				"whispers":      {"whispers:read", "user:manage:whispers"},
+				"moderation":    {"moderator:manage:chat_messages", "moderator:manage:chat_settings", "moderator:manage:shoutouts", "moderator:manage:announcements"},
+				"chatter lists": {"moderator:read:chatters"},
+			},
+		},
+		{
+			desc:         "missing chat",
+			granted:      allScopes[1:],
+			wantRequired: map[string][]string{"chat": {"chat:read"}},
+			wantOptional: map[string][]string{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			gotRequired, gotOptional := missingScopes(tc.granted, tc.eventSub)
+			if diff := cmp.Diff(tc.wantRequired, gotRequired); diff != "" {
+				t.Errorf("missingScopes() required diff (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantOptional, gotOptional); diff != "" {
+				t.Errorf("missingScopes() optional diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFormatMissingScopes(t *testing.T) {
+	t.Parallel()
+	got := formatMissingScopes(map[string][]string{
+		"chatter lists": {"moderator:read:chatters"},
+		"chat":          {"chat:read", "chat:edit"},
+	})
+	want := "chat (chat:read, chat:edit); chatter lists (moderator:read:chatters)"
+	if got != want {
+		t.Errorf("formatMissingScopes() = %q, want %q", got, want)
+	}
+}
+
+func TestTwitch_PersistedTokens(t *testing.T) {
+	t.Parallel()
+	const (
+		key      = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+		otherKey = "ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA="
+	)
+	db := databasetest.New(t)
+	newTwitch := func(configAccessToken, key string) *Twitch {
+		t.Helper()
+		tw := NewForTesting(t, "", db)
+		tw.accessToken, tw.refreshToken, tw.configAccessToken = configAccessToken, "config-refresh", configAccessToken
+		cipher, err := secrets.New(key)
+		if err != nil {
+			t.Fatalf("secrets.New() unexpected error: %v", err)
+		}
+		tw.tokenCipher = cipher
+		return tw
+	}
+
+	tw := newTwitch("config-access", key)
+	tw.onTokensRefreshed("refreshed-access", "refreshed-refresh")
+	tw.onTokensRefreshed("refreshed-access-2", "refreshed-refresh-2")
+
+	tests := []struct {
+		desc              string
+		configAccessToken string
+		key               string
+		wantAccessToken   string
+		wantRefreshToken  string
+	}{
+		{
+			desc:              "persisted",
+			configAccessToken: "config-access",
+			key:               key,
+			wantAccessToken:   "refreshed-access-2",
+			wantRefreshToken:  "refreshed-refresh-2",
+		},
+		{
+			desc:              "config token changed",
+			configAccessToken: "new-config-access",
+			key:               key,
+			wantAccessToken:   "new-config-access",
+			wantRefreshToken:  "config-refresh",
+		},
+		{
+			desc:              "key changed",
+			configAccessToken: "config-access",
+			key:               otherKey,
+			wantAccessToken:   "config-access",
+			wantRefreshToken:  "config-refresh",
+		},
+	}
+	for _, tc := range tests {
+		tw := newTwitch(tc.configAccessToken, tc.key)
+		if err := tw.loadPersistedTokens(); err != nil {
+			t.Fatalf("%s: loadPersistedTokens() unexpected error: %v", tc.desc, err)
+		}
+		gotAccessToken, gotRefreshToken := tw.tokens()
+		if gotAccessToken != tc.wantAccessToken || gotRefreshToken != tc.wantRefreshToken {
+			t.Errorf("%s: tokens() = %q, %q, want %q, %q", tc.desc, gotAccessToken, gotRefreshToken, tc.wantAccessToken, tc.wantRefreshToken)
+		}
+	}
+}
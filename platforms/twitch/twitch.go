@@ -5,13 +5,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"math/rand/v2"
 	"net/http"
 	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -21,8 +22,11 @@ import (
 	"github.com/airforce270/airbot/cache/cachetest"
 	"github.com/airforce270/airbot/database"
 	"github.com/airforce270/airbot/database/models"
+	"github.com/airforce270/airbot/logging"
 	"github.com/airforce270/airbot/permission"
+	"github.com/airforce270/airbot/platforms/ratelimit"
 	"github.com/airforce270/airbot/utils"
+	"github.com/airforce270/airbot/utils/secrets"
 
 	twitchirc "github.com/gempir/go-twitch-irc/v4"
 	"github.com/nicklaw5/helix/v2"
@@ -37,9 +41,15 @@ const (
 	twitchMsgIdBanned  = "msg_banned"
 	defaultBotPrefix   = "$"
 	messageSpaceSuffix = " \U000E0000"
+	// maxMessageLength is the maximum number of characters in a message Twitch will accept.
+	// Messages may grow by a couple of characters to bypass same message detection,
+	// so leave room for that.
+	maxMessageLength = 500 - 2
 	// lastSentTwitchMessageExpiration is the duration the last sent message should remain in the cache.
 	// (Twitch blocks messages that are twice in a row in a 30-second period of time)
 	lastSentTwitchMessageExpiration = 30 * time.Second
+	// eventBufferSize is the number of events that can be published before they're handled.
+	eventBufferSize = 100
 )
 
 var (
@@ -56,19 +66,26 @@ type Twitch struct {
 	// isVerifiedBot is whether the user running as is a verified bot on Twitch.
 	// See https://dev.twitch.tv/docs/irc#verified-bots
 	isVerifiedBot bool
-	// channels is the Twitch channels to join.
-	channels []*twitchChannel
+	// channels contains the Twitch channels to join.
+	channels *channelRegistry
 	// owners contains the usernames of the bot's owners. Usually only one.
 	owners []string
 	// clientID is the OAuth Client ID to use when connecting.
 	clientID string
 	// clientSecret is the OAuth client secret to use when connecting.
 	clientSecret string
+	// tokenMu guards accessToken and refreshToken.
+	tokenMu sync.Mutex
 	// accessToken is the OAuth token to use when connecting.
 	// See https://dev.twitch.tv/docs/irc/authenticate-bot#getting-an-access-token
 	accessToken string
 	// refreshToken is the refresh token to use to refresh the access token.
 	refreshToken string
+	// configAccessToken is the access token from the config.
+	configAccessToken string
+	// tokenCipher encrypts refreshed tokens when persisting them.
+	// If nil, they aren't persisted.
+	tokenCipher *secrets.Cipher
 	// irc is the Twitch IRC client.
 	irc *twitchirc.Client
 	// helix is the Twitch API client.
@@ -77,45 +94,128 @@ type Twitch struct {
 	db *gorm.DB
 	// cdb is a reference to the cache.
 	cdb cache.Cache
+	// logger is the logger for Twitch.
+	logger *slog.Logger
+	// connected is whether the bot is currently connected to Twitch IRC.
+	connected atomic.Bool
+	// emotes contains the emotes the bot can use.
+	emotes botEmotes
+	// connStateMu guards connects and connStateSince.
+	connStateMu sync.Mutex
+	// connects is how many times the bot has connected to Twitch IRC.
+	connects int
+	// connStateSince is when the bot last connected to Twitch IRC, or lost its connection.
+	connStateSince time.Time
+	// pendingWrites tracks messages and users still being written to the database.
+	pendingWrites sync.WaitGroup
+	// eventSubEnabled is whether events should be subscribed to over EventSub.
+	eventSubEnabled bool
+	// eventSubURL is the URL of the EventSub websocket server.
+	eventSubURL string
+	// eventSub is the EventSub client, or nil if EventSub isn't enabled or connected.
+	eventSub *eventSub
+	// events receives events published by EventSub and USERNOTICE messages.
+	events chan base.Event
 }
 
 func (t *Twitch) Name() string { return Name }
 
 func (t *Twitch) Username() string { return t.username }
 
+func (t *Twitch) MaxMessageLength() int { return maxMessageLength }
+
 func (t *Twitch) Send(msg base.Message) error {
 	return t.Reply(msg, "")
 }
 
-func (t *Twitch) Reply(msg base.Message, replyToID string) error {
-	var channel *twitchChannel
-	for _, c := range t.channels {
-		if strings.EqualFold(c.Name, msg.Channel) {
-			channel = c
+// Twitch chat rate limits.
+// See https://dev.twitch.tv/docs/irc#rate-limits
+const (
+	// rateLimitPeriod is the period Twitch's rate limits are counted over.
+	rateLimitPeriod = 30 * time.Second
+	// normalRateLimit is the number of messages that can be sent per period
+	// to channels where the bot isn't the broadcaster, a moderator, or a VIP.
+	normalRateLimit = 20
+	// elevatedRateLimit is the number of messages that can be sent per period
+	// to channels where the bot is the broadcaster, a moderator, or a VIP.
+	elevatedRateLimit = 100
+	// verifiedBotRateLimit is the number of messages a verified bot can send per period
+	// across all channels.
+	verifiedBotRateLimit = 7500
+)
+
+// Twitch whisper rate limits.
+// See https://dev.twitch.tv/docs/api/reference/#send-whisper
+const (
+	// whispersPerSecond is the number of whispers that can be sent per second.
+	whispersPerSecond = 3
+	// whispersPerMinute is the number of whispers that can be sent per minute.
+	whispersPerMinute = 100
+)
+
+// WhisperRateLimits returns the rate limits that apply to sending a whisper.
+func (t *Twitch) WhisperRateLimits() []ratelimit.Rule {
+	return []ratelimit.Rule{
+		{Bucket: "whisper-second", Rate: ratelimit.Rate{Count: whispersPerSecond, Per: time.Second}},
+		{Bucket: "whisper-minute", Rate: ratelimit.Rate{Count: whispersPerMinute, Per: time.Minute}},
+	}
+}
+
+// RateLimits returns the rate limits that apply to sending a message to a channel,
+// based on the bot's role in the channel.
+func (t *Twitch) RateLimits(channel string) []ratelimit.Rule {
+	channel = strings.ToLower(channel)
+	c, _ := t.channels.Get(channel)
+	elevated := strings.EqualFold(channel, t.username) || c.BotIsModerator || c.BotIsVIP
+
+	var rules []ratelimit.Rule
+	if t.isVerifiedBot {
+		rules = append(rules, ratelimit.Rule{Bucket: "global", Rate: ratelimit.Rate{Count: verifiedBotRateLimit, Per: rateLimitPeriod}})
+	} else {
+		// Messages to all channels count towards the elevated limit,
+		// but messages to channels without an elevated role must also fit in the normal limit.
+		rules = append(rules, ratelimit.Rule{Bucket: "global", Rate: ratelimit.Rate{Count: elevatedRateLimit, Per: rateLimitPeriod}})
+		if !elevated {
+			rules = append(rules, ratelimit.Rule{Bucket: "global-normal", Rate: ratelimit.Rate{Count: normalRateLimit, Per: rateLimitPeriod}})
 		}
 	}
-	if channel == nil {
-		return fmt.Errorf("can't send message to unjoined channel %q", msg.Channel)
+
+	if elevated {
+		rules = append(rules, ratelimit.Rule{Bucket: "channel:" + channel, Rate: ratelimit.Rate{Count: elevatedRateLimit, Per: rateLimitPeriod}})
+	} else {
+		rules = append(rules,
+			ratelimit.Rule{Bucket: "channel:" + channel, Rate: ratelimit.Rate{Count: normalRateLimit, Per: rateLimitPeriod}},
+			// Without an elevated role, messages sent less than a second apart
+			// (or the channel's slow mode delay) are held back.
+			ratelimit.Rule{Bucket: "channel-slow:" + channel, Rate: ratelimit.Rate{Count: 1, Per: max(time.Second, c.Room.Slow)}},
+		)
 	}
+	return rules
+}
 
-	// If the bot has "normal permissions" (not verified, mod, or VIP),
-	// sending the message too quickly will get it held back.
-	if !t.isVerifiedBot && !channel.BotIsModerator && !channel.BotIsVIP {
-		time.Sleep(time.Millisecond * 100)
+func (t *Twitch) Reply(msg base.Message, replyToID string) error {
+	channel, ok := t.channels.Get(msg.Channel)
+	if !ok {
+		return fmt.Errorf("can't send message to unjoined channel %q", msg.Channel)
 	}
 
 	// Any newlines in the message causes Twitch to drop the rest of the message.
 	text := strings.ReplaceAll(msg.Text, "\n", " ")
 
+	// The sender checks this before sending, but messages can also be sent directly.
+	if err := t.CanSend(base.Message{Channel: channel.Name, Text: text}); err != nil {
+		return err
+	}
+
 	// Bypass 30-second same message detection.
 	lastSentMsg, err := t.cdb.FetchString(cache.KeyLastSentTwitchMessage)
 	if err != nil {
-		log.Printf("Failed to check if message (%q/%q) is in cache: %v", msg.Channel, text, err)
+		t.logger.Error("Failed to check if message is in cache", "channel", msg.Channel, "text", text, "error", err)
 	} else if lastSentMsg == text {
 		text = bypassSameMessageDetection(text)
 	}
 
-	go t.persistUserAndMessage(t.id, t.username, text, msg.Channel, msg.Time)
+	t.pendingWrites.Go(func() { t.persistUserAndMessage(t.id, t.username, text, msg.Channel, msg.Time) })
 
 	if t.irc != nil {
 		if replyToID != "" {
@@ -124,11 +224,11 @@ func (t *Twitch) Reply(msg base.Message, replyToID string) error {
 			t.irc.Say(msg.Channel, text)
 		}
 	} else {
-		log.Print("Didn't actually send message - IRC client is nil. This is expected in test, but if you see this in production, something's broken!")
+		t.logger.Warn("Didn't actually send message - IRC client is nil. This is expected in test, but if you see this in production, something's broken!")
 	}
 
 	if err := t.cdb.StoreExpiringString(cache.KeyLastSentTwitchMessage, text, lastSentTwitchMessageExpiration); err != nil {
-		log.Printf("[Twitch.persistUserAndMessage]: Failed to persist message in cache, %q: %v", text, err)
+		t.logger.Error("Failed to persist sent message in cache", "text", text, "error", err)
 	}
 	return nil
 }
@@ -136,15 +236,20 @@ func (t *Twitch) Reply(msg base.Message, replyToID string) error {
 func (t *Twitch) Listen() <-chan base.IncomingMessage {
 	c := make(chan base.IncomingMessage)
 	t.irc.OnPrivateMessage(func(msg twitchirc.PrivateMessage) {
-		go t.persistUserAndMessage(msg.User.ID, msg.User.DisplayName, msg.Message, msg.Channel, msg.Time)
+		t.pendingWrites.Go(func() { t.persistUserAndMessage(msg.User.ID, msg.User.DisplayName, msg.Message, msg.Channel, msg.Time) })
+		text, parentID := msg.Message, ""
+		if msg.Reply != nil {
+			text, parentID = replyText(msg.Message, msg.Reply), msg.Reply.ParentMsgID
+		}
 		c <- base.IncomingMessage{
 			Message: base.Message{
-				Text:    msg.Message,
-				Channel: msg.Channel,
-				ID:      msg.ID,
-				UserID:  msg.User.ID,
-				User:    msg.User.Name,
-				Time:    msg.Time,
+				Text:     text,
+				Channel:  msg.Channel,
+				ID:       msg.ID,
+				ParentID: parentID,
+				UserID:   msg.User.ID,
+				User:     msg.User.Name,
+				Time:     msg.Time,
 			},
 			Prefix:          t.prefix(msg.Channel),
 			PermissionLevel: t.level(&msg),
@@ -153,98 +258,164 @@ func (t *Twitch) Listen() <-chan base.IncomingMessage {
 			},
 		}
 	})
+	t.irc.OnWhisperMessage(func(msg twitchirc.WhisperMessage) {
+		t.logger.Debug("WHISPER", "raw", msg.Raw)
+		now := time.Now()
+		t.pendingWrites.Go(func() { t.persistUserAndMessage(msg.User.ID, msg.User.Name, msg.Message, "whisper-"+t.Username(), now) })
+		c <- base.IncomingMessage{
+			Message: base.Message{
+				Text:   msg.Message,
+				ID:     msg.MessageID,
+				UserID: msg.User.ID,
+				User:   msg.User.Name,
+				Time:   now,
+			},
+			Prefix:          defaultBotPrefix,
+			PermissionLevel: t.whisperLevel(msg.User),
+			Whisper:         true,
+			Resources: base.Resources{
+				Platform: t,
+			},
+		}
+	})
 	return c
 }
 
+// replyText returns the text of a reply without the mention of the user replied to
+// that chat clients start replies with, so replies can be commands.
+func replyText(text string, reply *twitchirc.Reply) string {
+	mention, rest, _ := strings.Cut(text, " ")
+	name, ok := strings.CutPrefix(mention, "@")
+	if !ok || !(strings.EqualFold(name, reply.ParentUserLogin) || strings.EqualFold(name, reply.ParentDisplayName)) {
+		return text
+	}
+	return strings.TrimLeft(rest, " ")
+}
+
+// Events returns a channel that will provide events from EventSub.
+func (t *Twitch) Events() <-chan base.Event { return t.events }
+
 func (t *Twitch) Join(channel string, prefix string) error {
 	channelInfo, err := t.Channel(channel)
 	if err != nil {
 		return fmt.Errorf("failed to look up channel: %w", err)
 	}
 
-	startTime := time.Now()
-
+	joined := twitchChannel{
+		ID:     channelInfo.BroadcasterID,
+		Name:   channelInfo.BroadcasterName,
+		Prefix: prefix,
+		State:  JoinStateJoined,
+	}
 	if t.irc != nil {
-		t.irc.Join(channelInfo.BroadcasterName)
+		err = t.joinIRC(joined.Name)
 	} else {
-		log.Printf("Didn't actually join channel %s - IRC client is nil. This is expected in test, but if you see this in production, something's broken!", channelInfo.BroadcasterName)
+		t.logger.Warn("Didn't actually join channel - IRC client is nil. This is expected in test, but if you see this in production, something's broken!", "channel", joined.Name)
 	}
+	if errors.Is(err, ErrBotIsBanned) {
+		return fmt.Errorf("bot is banned from %s/%s: %w", t.Name(), channel, err)
+	}
+	if err != nil {
+		// The channel is kept, so joining it is retried later.
+		joined.State = JoinStateFailed
+	}
+	t.channels.Add(joined)
 
-	// Wait for ban messages to come in.
-	// If we're banned, the IRC server will send a ban message about 150ms after we try to join.
-	time.Sleep(250 * time.Millisecond)
+	if t.eventSub != nil {
+		go func() {
+			if err := t.eventSub.subscribe(&joined); err != nil {
+				t.eventSub.logSubscribeError(joined.Name, err)
+			}
+		}()
+	}
+	return err
+}
 
-	var banCount int64
-	err = t.db.Model(&models.BotBan{}).Where("platform = ? AND channel = ? AND banned_at > ?", t.Name(), strings.ToLower(channel), startTime).Count(&banCount).Error
-	if err != nil {
-		return fmt.Errorf("failed to retrieve %s channels bot is banned in: %w", t.Name(), err)
+// HooksChanged subscribes to the kinds of events a channel now has hooks for over EventSub,
+// and unsubscribes from those it no longer does.
+func (t *Twitch) HooksChanged(channel string) error {
+	if t.eventSub == nil {
+		return nil
 	}
-	if isBanned := banCount > 0; isBanned {
-		return fmt.Errorf("bot is banned from %s/%s: %w", t.Name(), channel, ErrBotIsBanned)
+	joined, ok := t.channels.Get(channel)
+	if !ok {
+		return nil
 	}
+	return t.eventSub.subscribe(&joined)
+}
 
-	t.channels = append(t.channels, &twitchChannel{
-		ID:     channelInfo.BroadcasterID,
-		Name:   channelInfo.BroadcasterName,
-		Prefix: prefix,
-	})
-	return nil
+// hookedEvents returns the kinds of events a channel has hooks for.
+func (t *Twitch) hookedEvents(channel string) (map[base.EventKind]bool, error) {
+	var kinds []string
+	err := t.db.Model(&models.EventHook{}).
+		Where(models.EventHook{Platform: t.Name(), Source: strings.ToLower(channel)}).
+		Distinct().
+		Pluck("event", &kinds).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch hooks for %s: %w", channel, err)
+	}
+	hooked := make(map[base.EventKind]bool, len(kinds))
+	for _, kind := range kinds {
+		hooked[base.EventKind(kind)] = true
+	}
+	return hooked, nil
 }
 
 func (t *Twitch) Leave(channel string) error {
 	if t.irc != nil {
-		t.irc.Depart(channel)
+		t.irc.Depart(strings.ToLower(channel))
 	} else {
-		log.Printf("Didn't actually depart channel %s - IRC client is nil. This is expected in test, but if you see this in production, something's broken!", channel)
+		t.logger.Warn("Didn't actually depart channel - IRC client is nil. This is expected in test, but if you see this in production, something's broken!", "channel", channel)
 	}
 
-	var newChannels []*twitchChannel
-	for _, ch := range t.channels {
-		if strings.EqualFold(ch.Name, channel) {
-			continue
-		}
-		newChannels = append(newChannels, ch)
+	left, ok := t.channels.Remove(channel)
+	if ok && t.eventSub != nil {
+		go func() {
+			if err := t.eventSub.unsubscribe(left.ID); err != nil {
+				t.logger.Warn("Failed to unsubscribe from events", "channel", left.Name, "error", err)
+			}
+		}()
 	}
-	t.channels = newChannels
 
 	return nil
 }
 
 func (t *Twitch) Connect(ctx context.Context) error {
-	log.Printf("[%s] Initializing channel data...", t.Name())
+	t.logger.Info("Initializing channel data...")
 	if err := t.ensureSelfIsJoined(); err != nil {
-		return fmt.Errorf("[%s] failed to join self: %w", t.Name(), err)
+		return fmt.Errorf("failed to join self: %w", err)
 	}
 	if err := t.populateInMemoryJoinedChannelCache(); err != nil {
-		return fmt.Errorf("[%s] failed to populate in-memory joined channel cache: %w", t.Name(), err)
+		return fmt.Errorf("failed to populate in-memory joined channel cache: %w", err)
+	}
+
+	if err := t.loadPersistedTokens(); err != nil {
+		return fmt.Errorf("failed to load persisted tokens: %w", err)
 	}
+	accessToken, refreshToken := t.tokens()
 
-	log.Printf("[%s] Creating IRC client...", t.Name())
-	i := twitchirc.NewClient(t.username, "oauth:"+t.accessToken)
+	t.logger.Info("Creating IRC client...")
+	i := twitchirc.NewClient(t.username, "oauth:"+accessToken)
 	t.irc = i
 
-	log.Printf("[%s] Connecting to Twitch API...", t.Name())
+	t.logger.Info("Connecting to Twitch API...")
 	h, err := helix.NewClient(&helix.Options{
 		ClientID:        t.clientID,
 		ClientSecret:    t.clientSecret,
-		UserAccessToken: t.accessToken,
-		RefreshToken:    t.refreshToken,
+		UserAccessToken: accessToken,
+		RefreshToken:    refreshToken,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to connect to Twitch API: %w", err)
 	}
 	t.helix = h
 
-	t.helix.OnUserAccessTokenRefreshed(func(userAccessToken, refreshToken string) {
-		t.accessToken = userAccessToken
-		t.refreshToken = refreshToken
-		t.irc.SetIRCToken("oauth:" + userAccessToken)
-	})
+	t.helix.OnUserAccessTokenRefreshed(t.onTokensRefreshed)
 
 	// Make sure to do this before connecting to IRC.
 	// This makes an API call to Twitch which automatically refreshes the token if needed.
 	// That way we have a valid token before connecting to IRC.
-	log.Printf("[%s] Refetching bot's ID...", t.Name())
+	t.logger.Info("Refetching bot's ID...")
 	botUser, err := t.FetchUser(t.username)
 	if err != nil {
 		return fmt.Errorf("failed to fetch user %s: %w", t.username, err)
@@ -254,80 +425,74 @@ func (t *Twitch) Connect(ctx context.Context) error {
 	}
 	t.id = botUser.ID
 
-	log.Printf("[%s] Updating cached joined channels...", t.Name())
+	t.logger.Info("Validating access token scopes...")
+	if err := t.validateScopes(); err != nil {
+		return fmt.Errorf("failed to validate access token: %w", err)
+	}
+
+	t.logger.Info("Updating cached joined channels...")
 	if _, err := t.updateCachedJoinedChannels(); err != nil {
 		return fmt.Errorf("failed to update cached joined channels: %w", err)
 	}
 	go t.startWatchingForChannelRenames(ctx)
 
+	if t.eventSubEnabled {
+		t.logger.Info("Connecting to EventSub...")
+		t.eventSub = newEventSub(t.eventSubURL, t.helix, t.id, t.channels.All, t.hookedEvents, t.events, t.logger)
+		go t.eventSub.run(ctx)
+	}
+
 	t.setUpIRCHandlers()
 
-	log.Printf("[%s] Checking if the bot is a verified bot...", t.Name())
+	t.logger.Info("Checking if the bot is a verified bot...")
 	ivrClient := ivr.NewDefaultClient()
 	ivrUsers, err := ivrClient.FetchUsers(t.username)
 	if err != nil {
-		fmt.Printf("Failed to fetch info about %s from IVR, assuming not a verified bot: %v", t.username, err)
+		t.logger.Warn("Failed to fetch bot user info from IVR, assuming not a verified bot", "user", t.username, "error", err)
 		t.isVerifiedBot = false
 	} else if len(ivrUsers) != 1 {
-		fmt.Printf("IVR API returned %d users for %s, assuming not a verified bot: %v", len(ivrUsers), t.username, ivrUsers)
+		t.logger.Warn("IVR API returned an unexpected number of users, assuming not a verified bot", "user", t.username, "count", len(ivrUsers))
 		t.isVerifiedBot = false
 	} else {
 		t.isVerifiedBot = ivrUsers[0].IsVerifiedBot
 	}
 
 	if t.isVerifiedBot {
-		log.Printf("[%s] Bot user %s is a verified bot, using increased rate limit", t.Name(), t.username)
+		t.logger.Info("Bot user is a verified bot, using increased rate limit", "user", t.username)
 		t.irc.SetJoinRateLimiter(twitchirc.CreateVerifiedRateLimiter())
 	}
 
-	log.Printf("[%s] Connecting to Twitch IRC...", t.Name())
-	t.connectIRC()
-
-	go t.listenForModAndVIPChanges(ctx, ivrClient)
+	t.logger.Info("Connecting to Twitch IRC...")
+	t.connectIRC(ctx)
 
 	return nil
 }
 
-func (t *Twitch) connectIRC() {
-	var wg sync.WaitGroup
-	wg.Add(1)
-	t.irc.OnConnect(func() { wg.Done() })
-	go func() {
-		if err := t.irc.Connect(); err != nil && !errors.Is(err, twitchirc.ErrClientDisconnected) {
-			log.Fatalf("failed to connect to twitch IRC: %v", err)
-		}
-	}()
-	wg.Wait()
-	t.irc.OnConnect(nil)
-
-	for _, channel := range t.channels {
-		log.Printf("[%s] Joining channel %s...", t.Name(), channel.Name)
-		t.irc.Join(channel.Name)
-	}
-}
-
 func (t *Twitch) Disconnect() error {
-	for _, channel := range t.channels {
-		log.Printf("[%s] Leaving channel %s...", t.Name(), channel.Name)
-		t.irc.Depart(channel.Name)
+	for _, name := range t.channels.Names() {
+		t.logger.Info("Leaving channel...", "channel", name)
+		t.irc.Depart(strings.ToLower(name))
 	}
-	log.Printf("[%s] Disconnecting from Twitch IRC...", t.Name())
-	return t.irc.Disconnect()
+	t.logger.Info("Disconnecting from Twitch IRC...")
+	t.connected.Store(false)
+	err := t.irc.Disconnect()
+	t.logger.Info("Waiting for pending database writes...")
+	t.pendingWrites.Wait()
+	return err
 }
 
+func (t *Twitch) Connected() bool { return t.connected.Load() }
+
 func (t *Twitch) SetPrefix(channel, prefix string) error {
-	for _, c := range t.channels {
-		if strings.EqualFold(c.Name, channel) {
-			c.Prefix = prefix
-			return nil
-		}
+	if t.channels.Update(channel, func(c *twitchChannel) { c.Prefix = prefix }) {
+		return nil
 	}
 	return fmt.Errorf("channel %s not joined", channel)
 }
 
 func (t *Twitch) User(username string) (models.User, error) {
 	var user models.User
-	err := t.db.Where("LOWER(twitch_name) = ?", strings.ToLower(username)).Limit(1).Find(&user).Error
+	err := t.db.Where("twitch_name_lower = ?", strings.ToLower(username)).Limit(1).Find(&user).Error
 	if err != nil {
 		return models.User{}, fmt.Errorf("failed to retrieve twitch user %s from db: %w", username, err)
 	}
@@ -339,7 +504,7 @@ func (t *Twitch) User(username string) (models.User, error) {
 
 func (t *Twitch) CurrentUsers() ([]string, error) {
 	var allChatters []string
-	for _, c := range t.channels {
+	for _, c := range t.channels.All() {
 		pageToken := "<unset>" + strconv.Itoa(rand.Int())
 		for pageToken != "" {
 			req := &helix.GetChatChattersParams{
@@ -350,7 +515,7 @@ func (t *Twitch) CurrentUsers() ([]string, error) {
 			}
 			resp, err := t.helix.GetChannelChatChatters(req)
 			if err != nil {
-				return nil, fmt.Errorf("[%s] failed to fetch chatters for %s: %w", t.Name(), c.Name, err)
+				return nil, fmt.Errorf("failed to fetch chatters for %s: %w", c.Name, err)
 			}
 			pageToken = resp.Data.Pagination.Cursor
 
@@ -365,13 +530,6 @@ func (t *Twitch) CurrentUsers() ([]string, error) {
 	return allChatters, nil
 }
 
-func (t *Twitch) Timeout(username, channel string, duration time.Duration) error {
-	return t.Send(base.Message{
-		Channel: channel,
-		Text:    fmt.Sprintf("/timeout %s %.f", username, duration.Seconds()),
-	})
-}
-
 func (t *Twitch) FetchUser(channel string) (*helix.User, error) {
 	users, err := t.helix.GetUsers(&helix.UsersParams{Logins: []string{channel}})
 	if err != nil {
@@ -401,11 +559,53 @@ func (t *Twitch) Channel(channel string) (*helix.ChannelInformation, error) {
 		return nil, fmt.Errorf("channel %s not found: %w", channel, ErrChannelNotFound)
 	}
 	if len(resp.Data.Channels) > 1 {
-		log.Printf("more than one channel found for %s, using the first", channel)
+		t.logger.Warn("More than one channel found, using the first", "channel", channel)
 	}
 	return &resp.Data.Channels[0], nil
 }
 
+// Whisper sends a whisper to the user with an ID.
+func (t *Twitch) Whisper(userID, text string) error {
+	resp, err := t.helix.SendUserWhisper(&helix.SendUserWhisperParams{
+		FromUserID: t.id,
+		ToUserID:   userID,
+		Message:    text,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to whisper user %s: %w", userID, err)
+	}
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("failed to whisper user %s: %d %s", userID, resp.StatusCode, resp.ErrorMessage)
+	}
+	return nil
+}
+
+// maxStreamsPerRequest is the maximum number of streams that can be looked up in one Helix request.
+const maxStreamsPerRequest = 100
+
+// LiveStreams returns the streams that are currently live on channels, by lowercased channel name.
+// Channels that aren't live aren't included.
+func (t *Twitch) LiveStreams(channels []string) (map[string]helix.Stream, error) {
+	live := map[string]helix.Stream{}
+	for batch := range slices.Chunk(channels, maxStreamsPerRequest) {
+		resp, err := t.helix.GetStreams(&helix.StreamsParams{
+			UserLogins: batch,
+			First:      maxStreamsPerRequest,
+			Type:       "live",
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get streams from Helix: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("twitch GetStreams call failed: %d %s", resp.StatusCode, resp.ErrorMessage)
+		}
+		for _, stream := range resp.Data.Streams {
+			live[strings.ToLower(stream.UserLogin)] = stream
+		}
+	}
+	return live, nil
+}
+
 func (t *Twitch) startWatchingForChannelRenames(ctx context.Context) {
 	const checkInterval = 2 * time.Minute
 	ticker := time.NewTicker(checkInterval)
@@ -414,15 +614,15 @@ func (t *Twitch) startWatchingForChannelRenames(ctx context.Context) {
 		case <-ticker.C:
 			renamed, err := t.updateCachedJoinedChannels()
 			if err != nil {
-				log.Printf("[%s] Failed to update cached joined channels: %v", t.Name(), err)
+				t.logger.Error("Failed to update cached joined channels", "error", err)
 			}
 			for _, channel := range renamed {
 				if err := t.Join(channel.Channel, channel.Prefix); err != nil {
-					log.Printf("[%s] Failed to rejoin renamed channel %s (%s)", t.Name(), channel.Channel, channel.ChannelID)
+					t.logger.Error("Failed to rejoin renamed channel", "channel", channel.Channel, "channel_id", channel.ChannelID)
 				}
 			}
 		case <-ctx.Done():
-			log.Printf("[%s] Stopping watching for channel renames, context cancelled", t.Name())
+			t.logger.Info("Stopping watching for channel renames, context cancelled")
 			ticker.Stop()
 			return
 		}
@@ -472,11 +672,11 @@ func (t *Twitch) updateCachedJoinedChannels() ([]*models.JoinedChannel, error) {
 
 	for _, joinedChannel := range joinedChannels {
 		if joinedChannel.ChannelID == "" {
-			log.Printf("[%s] Detected joined channel with no ID (%s) - probably the bot. Attempting to fix.\n", t.Name(), joinedChannel.Channel)
+			t.logger.Warn("Detected joined channel with no ID - probably the bot. Attempting to fix.", "channel", joinedChannel.Channel)
 			found := false
 			for id, user := range helixUsersByID {
 				if user.Login == joinedChannel.Channel {
-					log.Printf("[%s] Found ID for %s: %s", t.Name(), joinedChannel.Channel, id)
+					t.logger.Info("Found ID for channel", "channel", joinedChannel.Channel, "channel_id", id)
 					joinedChannel.ChannelID = id
 					renamed = append(renamed, joinedChannel)
 					found = true
@@ -486,16 +686,16 @@ func (t *Twitch) updateCachedJoinedChannels() ([]*models.JoinedChannel, error) {
 			if found {
 				continue
 			}
-			log.Printf("[%s] Couldn't find ID for channel %s", t.Name(), joinedChannel.Channel)
+			t.logger.Warn("Couldn't find ID for channel", "channel", joinedChannel.Channel)
 		}
 
 		helixUser, ok := helixUsersByID[joinedChannel.ChannelID]
 		if !ok {
-			log.Printf("[%s] User %s (%s) not found in Helix lookup", t.Name(), joinedChannel.ChannelID, joinedChannel.Channel)
+			t.logger.Warn("User not found in Helix lookup", "channel", joinedChannel.Channel, "channel_id", joinedChannel.ChannelID)
 			continue
 		}
 		if joinedChannel.Channel != helixUser.Login {
-			log.Printf("[%s] Detected renamed channel (%s): %s->%s", t.Name(), helixUser.ID, joinedChannel.Channel, helixUser.Login)
+			t.logger.Info("Detected renamed channel", "channel_id", helixUser.ID, "old_name", joinedChannel.Channel, "new_name", helixUser.Login)
 			joinedChannel.Channel = helixUser.Login
 			renamed = append(renamed, joinedChannel)
 		}
@@ -503,17 +703,12 @@ func (t *Twitch) updateCachedJoinedChannels() ([]*models.JoinedChannel, error) {
 
 	for _, renamedChannel := range renamed {
 		if err := t.db.Save(renamedChannel).Error; err != nil {
-			log.Printf("[%s] Failed to persist rename of %s to %s: %v", t.Name(), renamedChannel.ChannelID, renamedChannel.Channel, err)
+			t.logger.Error("Failed to persist channel rename", "channel_id", renamedChannel.ChannelID, "new_name", renamedChannel.Channel, "error", err)
 		}
 	}
 
-	for _, channel := range t.channels {
-		for _, renamedChannel := range renamed {
-			if renamedChannel.ChannelID == channel.ID {
-				channel.Name = renamedChannel.Channel
-				break
-			}
-		}
+	for _, renamedChannel := range renamed {
+		t.channels.Rename(renamedChannel.ChannelID, renamedChannel.Channel)
 	}
 
 	return renamed, nil
@@ -533,22 +728,20 @@ func (t *Twitch) populateInMemoryJoinedChannelCache() error {
 			ID:     dbChannel.ChannelID,
 			Name:   dbChannel.Channel,
 			Prefix: dbChannel.Prefix,
+			State:  JoinStateJoining,
 		})
 	}
 
-	t.channels = channels
+	t.channels.Replace(channels)
 
 	return nil
 }
 
 func (t *Twitch) prefix(channel string) string {
-	for _, c := range t.channels {
-		if !strings.EqualFold(c.Name, channel) {
-			continue
-		}
+	if c, ok := t.channels.Get(channel); ok {
 		return c.Prefix
 	}
-	log.Printf("No prefix found for channel %s", channel)
+	t.logger.Warn("No prefix found for channel", "channel", channel)
 	return ""
 }
 
@@ -558,10 +751,7 @@ func (t *Twitch) level(msg *twitchirc.PrivateMessage) permission.Level {
 		return permission.Owner
 	}
 
-	for _, channel := range t.channels {
-		if !strings.EqualFold(channel.Name, msg.Channel) {
-			continue
-		}
+	if _, ok := t.channels.Get(msg.Channel); ok {
 		for badgeType, level := range badgeLevels {
 			if userHasBadge(msg.User, badgeType) {
 				return level
@@ -571,6 +761,15 @@ func (t *Twitch) level(msg *twitchirc.PrivateMessage) permission.Level {
 	return permission.Normal
 }
 
+// whisperLevel returns the permission level of a user whispering the bot.
+// Whispers aren't sent in a channel, so channel roles don't apply.
+func (t *Twitch) whisperLevel(user twitchirc.User) permission.Level {
+	if slices.Contains(t.owners, strings.ToLower(user.Name)) {
+		return permission.Owner
+	}
+	return permission.Normal
+}
+
 func (t *Twitch) ensureSelfIsJoined() error {
 	var botChannel models.JoinedChannel
 	result := t.db.Where(models.JoinedChannel{Platform: t.Name(), Channel: strings.ToLower(t.username)}).
@@ -584,18 +783,21 @@ func (t *Twitch) ensureSelfIsJoined() error {
 
 func (t *Twitch) setUpIRCHandlers() {
 	t.irc.OnClearMessage(func(msg twitchirc.ClearMessage) {
-		log.Printf("[%s] CLEAR: %s", t.Name(), msg.Raw)
+		t.logger.Debug("CLEAR", "raw", msg.Raw)
+		t.pendingWrites.Go(func() { t.persistDeletedMessage(msg, time.Now()) })
 	})
 	t.irc.OnClearChatMessage(func(msg twitchirc.ClearChatMessage) {
-		log.Printf("[%s] CLEARCHAT: %s", t.Name(), msg.Raw)
+		t.logger.Debug("CLEARCHAT", "raw", msg.Raw)
+		t.pendingWrites.Go(func() { t.persistChatBan(msg) })
 	})
 	// OnConnect is set within Twitch.Connect()
-	t.irc.OnGlobalUserStateMessage(func(msg twitchirc.GlobalUserStateMessage) {})
+	t.irc.OnGlobalUserStateMessage(func(msg twitchirc.GlobalUserStateMessage) { t.emotes.setSets(msg.EmoteSets) })
 	t.irc.OnNoticeMessage(func(msg twitchirc.NoticeMessage) {
-		log.Printf("[%s] NOTICE: %s", t.Name(), msg.Raw)
+		t.logger.Info("NOTICE", "raw", msg.Raw)
 
 		// This fires when we the bot tries to join a channel it's banned in.
 		if msg.MsgID == twitchMsgIdBanned {
+			t.channels.FailJoin(msg.Channel, ErrBotIsBanned)
 			t.handleBannedFromChannel(msg.Channel)
 		}
 	})
@@ -603,98 +805,64 @@ func (t *Twitch) setUpIRCHandlers() {
 	// OnPrivateMessage is set within Twitch.Connect()
 	t.irc.OnPongMessage(func(msg twitchirc.PongMessage) {})
 	t.irc.OnReconnectMessage(func(msg twitchirc.ReconnectMessage) {
-		log.Printf("[%s] Reconnect requested, reconnecting...", t.Name())
-		if err := t.Disconnect(); err != nil {
-			log.Printf("[%s] Disconnect failed: %v", t.Name(), err)
-		}
-		t.connectIRC()
+		// The IRC client reconnects and rejoins channels by itself.
+		t.logger.Info("Reconnect requested, reconnecting...")
+		t.onDisconnect()
+	})
+	t.irc.OnRoomStateMessage(t.handleRoomState)
+	t.irc.OnSelfJoinMessage(func(msg twitchirc.UserJoinMessage) {
+		t.logger.Debug("SELFJOIN", "channel", msg.Channel)
+		t.channels.ConfirmJoin(msg.Channel)
 	})
-	t.irc.OnRoomStateMessage(func(msg twitchirc.RoomStateMessage) {})
-	t.irc.OnSelfJoinMessage(func(msg twitchirc.UserJoinMessage) {})
 	t.irc.OnSelfPartMessage(func(msg twitchirc.UserPartMessage) {
-		log.Printf("[%s] SELFPART: %s", t.Name(), msg.Raw)
+		t.logger.Info("SELFPART", "raw", msg.Raw)
+		// Channels that were left on purpose have already been removed.
+		// Any others are rejoined.
+		t.channels.SetState(msg.Channel, JoinStateJoining)
 	})
 	t.irc.OnUnsetMessage(func(msg twitchirc.RawMessage) {
-		log.Printf("[%s] UNSET: %s", t.Name(), msg.Raw)
+		t.logger.Debug("UNSET", "raw", msg.Raw)
 	})
 	t.irc.OnUserJoinMessage(func(msg twitchirc.UserJoinMessage) {
-		log.Printf("[%s] USERJOIN: %s", t.Name(), msg.Raw)
+		t.logger.Debug("USERJOIN", "raw", msg.Raw)
 	})
 	t.irc.OnUserNoticeMessage(func(msg twitchirc.UserNoticeMessage) {
-		log.Printf("[%s] USERNOTICE: %s", t.Name(), msg.Raw)
+		t.logger.Debug("USERNOTICE", "raw", msg.Raw)
+		t.publishUserNotice(msg)
 	})
 	t.irc.OnUserPartMessage(func(msg twitchirc.UserPartMessage) {
-		log.Printf("[%s] USERPART: %s", t.Name(), msg.Raw)
-	})
-	t.irc.OnUserStateMessage(func(msg twitchirc.UserStateMessage) {})
-	t.irc.OnWhisperMessage(func(msg twitchirc.WhisperMessage) {
-		log.Printf("[%s] WHISPER: %s", t.Name(), msg.Raw)
-		t.persistUserAndMessage(msg.User.ID, msg.User.Name, msg.Message, "whisper-"+t.Username(), time.Now())
+		t.logger.Debug("USERPART", "raw", msg.Raw)
 	})
+	t.irc.OnUserStateMessage(t.handleUserState)
+	// OnWhisperMessage is set within Twitch.Listen()
 }
 
 func (t *Twitch) persistUserAndMessage(twitchID, twitchName, message, channel string, sentTime time.Time) {
-	var user models.User
-	result := t.db.Where(models.User{TwitchID: twitchID}).Assign(models.User{TwitchName: twitchName}).FirstOrCreate(&user)
-	if err := result.Error; err != nil {
-		log.Printf("[Twitch.persistUserAndMessage]: Failed to find/create user, twitchName:%q %v", twitchName, err)
+	user, err := t.findOrCreateUser(twitchID, twitchName)
+	if err != nil {
+		t.logger.Error("Failed to find/create user", "user", twitchName, "error", err)
 	}
-	result = t.db.Create(&models.Message{
+	result := t.db.Create(&models.Message{
 		Text:    message,
 		Channel: channel,
 		User:    user,
 		Time:    sentTime,
 	})
 	if err := result.Error; err != nil {
-		log.Printf("[Twitch.persistUserAndMessage]: Failed to persist message in database, %q/%q: %v", channel, message, err)
+		t.logger.Error("Failed to persist message in database", "channel", channel, "text", message, "error", err)
 	}
 }
 
-func (t *Twitch) listenForModAndVIPChanges(ctx context.Context, ivrClient *ivr.Client) {
-	const checkInterval = 30 * time.Second
-	ticker := time.NewTicker(checkInterval)
-	for {
-		select {
-		case <-ticker.C:
-			for _, channel := range t.channels {
-				modsAndVIPs, err := ivrClient.FetchModsAndVIPs(channel.Name)
-				if err != nil {
-					log.Printf("Failed to look up mods and VIPs for %s: %v", channel.Name, err)
-					break
-				}
-				go t.updateModStatusForChannel(channel, modsAndVIPs.Mods)
-				go t.updateVIPStatusForChannel(channel, modsAndVIPs.VIPs)
-			}
-		case <-ctx.Done():
-			log.Printf("[%s] Context cancelled, stopping listening for mod and VIP changes", t.Name())
-			ticker.Stop()
-			return
-		}
-	}
-}
-
-func (t *Twitch) updateModStatusForChannel(channel *twitchChannel, mods []*ivr.ModOrVIPUser) {
-	for _, mod := range mods {
-		if mod.ID == t.id || t.username == channel.Name {
-			channel.BotIsModerator = true
-			return
-		}
-	}
-	channel.BotIsModerator = false
-}
-
-func (t *Twitch) updateVIPStatusForChannel(channel *twitchChannel, vips []*ivr.ModOrVIPUser) {
-	for _, vip := range vips {
-		if vip.ID == t.id {
-			channel.BotIsVIP = true
-			return
-		}
-	}
-	channel.BotIsVIP = false
+// findOrCreateUser returns the user with a Twitch ID, creating them if needed
+// and updating their name if it changed.
+func (t *Twitch) findOrCreateUser(twitchID, twitchName string) (models.User, error) {
+	var user models.User
+	err := t.db.Where(models.User{TwitchID: twitchID}).Assign(models.User{TwitchName: twitchName, TwitchNameLower: strings.ToLower(twitchName)}).FirstOrCreate(&user).Error
+	return user, err
 }
 
 func (t *Twitch) handleBannedFromChannel(channel string) {
-	log.Printf("[%s] Banned from channel %s, leaving it", t.Name(), channel)
+	t.logger.Warn("Banned from channel, leaving it", "channel", channel)
 	go func() {
 		err := t.db.Create(&models.BotBan{
 			Platform: t.Name(),
@@ -702,32 +870,41 @@ func (t *Twitch) handleBannedFromChannel(channel string) {
 			BannedAt: time.Now(),
 		}).Error
 		if err != nil {
-			log.Printf("failed to create bot ban DB entry: %v", err)
+			t.logger.Error("Failed to create bot ban DB entry", "channel", channel, "error", err)
 		}
 	}()
 	go func() {
 		if err := database.LeaveChannel(t.db, t.Name(), channel); err != nil {
-			log.Printf("[%s] Failed to leave channel %s (database): %v", t.Name(), channel, err)
+			t.logger.Error("Failed to leave channel (database)", "channel", channel, "error", err)
 		}
 	}()
 	go func() {
 		if err := t.Leave(channel); err != nil {
-			log.Printf("[%s] Failed to leave channel %s (IRC): %v", t.Name(), channel, err)
+			t.logger.Error("Failed to leave channel (IRC)", "channel", channel, "error", err)
 		}
 	}()
 }
 
 // New creates a new Twitch connection.
-func New(username string, owners []string, clientID, clientSecret, accessToken, refreshToken string, db *gorm.DB, cdb cache.Cache) *Twitch {
+// If eventSub is true, events in joined channels are subscribed to over EventSub.
+// If tokenCipher is nil, refreshed tokens aren't persisted.
+func New(username string, owners []string, clientID, clientSecret, accessToken, refreshToken string, tokenCipher *secrets.Cipher, eventSub bool, db *gorm.DB, cdb cache.Cache, logger *slog.Logger) *Twitch {
 	return &Twitch{
-		username:     username,
-		owners:       lowercaseAll(owners),
-		clientID:     clientID,
-		clientSecret: clientSecret,
-		accessToken:  accessToken,
-		refreshToken: refreshToken,
-		db:           db,
-		cdb:          cdb,
+		username:          username,
+		owners:            lowercaseAll(owners),
+		clientID:          clientID,
+		clientSecret:      clientSecret,
+		accessToken:       accessToken,
+		refreshToken:      refreshToken,
+		configAccessToken: accessToken,
+		tokenCipher:       tokenCipher,
+		db:                db,
+		cdb:               cdb,
+		logger:            logger,
+		eventSubEnabled:   eventSub,
+		channels:          newChannelRegistry(),
+		eventSubURL:       defaultEventSubURL,
+		events:            make(chan base.Event, eventBufferSize),
 	}
 }
 
@@ -745,30 +922,24 @@ func NewForTesting(t *testing.T, url string, db *gorm.DB) *Twitch {
 
 	return &Twitch{
 		username:      "fake-username",
-		id:            "",
+		id:            "fake-user-id",
 		isVerifiedBot: true,
-		channels: []*twitchChannel{
-			{Name: "user1"},
-			{Name: "user2"},
-		},
+		channels: newChannelRegistry(
+			&twitchChannel{Name: "user1", State: JoinStateJoined},
+			&twitchChannel{Name: "user2", State: JoinStateJoined},
+		),
 		owners:      nil,
 		clientID:    "fake-client-id",
 		accessToken: "fake-access-token",
 		irc:         nil,
 		helix:       helixClient,
 		db:          db,
-		cdb:         cachetest.NewSQLite(t, db),
+		cdb:         cachetest.NewDB(t, db),
+		logger:      logging.Discard(),
+		events:      make(chan base.Event, eventBufferSize),
 	}
 }
 
-type twitchChannel struct {
-	ID             string
-	Name           string
-	Prefix         string
-	BotIsModerator bool
-	BotIsVIP       bool
-}
-
 func lowercaseAll(strs []string) []string {
 	lower := make([]string, len(strs))
 	for i, str := range strs {
@@ -7,10 +7,14 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/airforce270/airbot/apiclients/twitchtest"
 	"github.com/airforce270/airbot/database/databasetest"
+	"github.com/airforce270/airbot/platforms/ratelimit"
 	"github.com/google/go-cmp/cmp"
+
+	twitchirc "github.com/gempir/go-twitch-irc/v4"
 )
 
 func TestTwitch_CurrentUsers(t *testing.T) {
@@ -101,6 +105,46 @@ func TestBypassSameMessageDetection(t *testing.T) {
 	}
 }
 
+func TestReplyText(t *testing.T) {
+	t.Parallel()
+	reply := &twitchirc.Reply{ParentMsgID: "abc", ParentUserLogin: "user2", ParentDisplayName: "User2"}
+	tests := []struct {
+		desc  string
+		input string
+		want  string
+	}{
+		{
+			desc:  "mention of login",
+			input: "@user2 $delete",
+			want:  "$delete",
+		},
+		{
+			desc:  "mention of display name",
+			input: "@User2  $delete",
+			want:  "$delete",
+		},
+		{
+			desc:  "mention of another user",
+			input: "@user3 $delete",
+			want:  "@user3 $delete",
+		},
+		{
+			desc:  "no mention",
+			input: "$delete",
+			want:  "$delete",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			if got := replyText(tc.input, reply); got != tc.want {
+				t.Errorf("replyText() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
 func newTestServer() *httptest.Server {
 	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if strings.Contains(r.URL.Path, "/chat/chatters") {
@@ -110,3 +154,91 @@ func newTestServer() *httptest.Server {
 		}
 	}))
 }
+
+func TestTwitch_RateLimits(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		desc          string
+		isVerifiedBot bool
+		channel       twitchChannel
+		want          []ratelimit.Rule
+	}{
+		{
+			desc:    "normal",
+			channel: twitchChannel{Name: "user1"},
+			want: []ratelimit.Rule{
+				{Bucket: "global", Rate: ratelimit.Rate{Count: 100, Per: 30 * time.Second}},
+				{Bucket: "global-normal", Rate: ratelimit.Rate{Count: 20, Per: 30 * time.Second}},
+				{Bucket: "channel:user1", Rate: ratelimit.Rate{Count: 20, Per: 30 * time.Second}},
+				{Bucket: "channel-slow:user1", Rate: ratelimit.Rate{Count: 1, Per: time.Second}},
+			},
+		},
+		{
+			desc:    "slow mode",
+			channel: twitchChannel{Name: "user1", Room: roomState{Slow: 30 * time.Second}},
+			want: []ratelimit.Rule{
+				{Bucket: "global", Rate: ratelimit.Rate{Count: 100, Per: 30 * time.Second}},
+				{Bucket: "global-normal", Rate: ratelimit.Rate{Count: 20, Per: 30 * time.Second}},
+				{Bucket: "channel:user1", Rate: ratelimit.Rate{Count: 20, Per: 30 * time.Second}},
+				{Bucket: "channel-slow:user1", Rate: ratelimit.Rate{Count: 1, Per: 30 * time.Second}},
+			},
+		},
+		{
+			desc:    "moderator in slow mode",
+			channel: twitchChannel{Name: "user1", BotIsModerator: true, Room: roomState{Slow: 30 * time.Second}},
+			want: []ratelimit.Rule{
+				{Bucket: "global", Rate: ratelimit.Rate{Count: 100, Per: 30 * time.Second}},
+				{Bucket: "channel:user1", Rate: ratelimit.Rate{Count: 100, Per: 30 * time.Second}},
+			},
+		},
+		{
+			desc:    "moderator",
+			channel: twitchChannel{Name: "user1", BotIsModerator: true},
+			want: []ratelimit.Rule{
+				{Bucket: "global", Rate: ratelimit.Rate{Count: 100, Per: 30 * time.Second}},
+				{Bucket: "channel:user1", Rate: ratelimit.Rate{Count: 100, Per: 30 * time.Second}},
+			},
+		},
+		{
+			desc:    "vip",
+			channel: twitchChannel{Name: "user1", BotIsVIP: true},
+			want: []ratelimit.Rule{
+				{Bucket: "global", Rate: ratelimit.Rate{Count: 100, Per: 30 * time.Second}},
+				{Bucket: "channel:user1", Rate: ratelimit.Rate{Count: 100, Per: 30 * time.Second}},
+			},
+		},
+		{
+			desc:    "broadcaster",
+			channel: twitchChannel{Name: "fake-username"},
+			want: []ratelimit.Rule{
+				{Bucket: "global", Rate: ratelimit.Rate{Count: 100, Per: 30 * time.Second}},
+				{Bucket: "channel:fake-username", Rate: ratelimit.Rate{Count: 100, Per: 30 * time.Second}},
+			},
+		},
+		{
+			desc:          "verified bot",
+			isVerifiedBot: true,
+			channel:       twitchChannel{Name: "user1"},
+			want: []ratelimit.Rule{
+				{Bucket: "global", Rate: ratelimit.Rate{Count: 7500, Per: 30 * time.Second}},
+				{Bucket: "channel:user1", Rate: ratelimit.Rate{Count: 20, Per: 30 * time.Second}},
+				{Bucket: "channel-slow:user1", Rate: ratelimit.Rate{Count: 1, Per: time.Second}},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			db := databasetest.New(t)
+			tw := NewForTesting(t, "", db)
+			tw.isVerifiedBot = tc.isVerifiedBot
+			tw.channels = newChannelRegistry(&tc.channel)
+
+			got := tw.RateLimits(strings.ToUpper(tc.channel.Name))
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("RateLimits() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
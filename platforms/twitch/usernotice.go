@@ -0,0 +1,106 @@
+package twitch
+
+import (
+	"strconv"
+
+	"github.com/airforce270/airbot/base"
+
+	twitchirc "github.com/gempir/go-twitch-irc/v4"
+	"github.com/nicklaw5/helix/v2"
+)
+
+// USERNOTICE message IDs.
+// See https://dev.twitch.tv/docs/irc/tags#usernotice-tags
+const (
+	userNoticeSub            = "sub"
+	userNoticeResub          = "resub"
+	userNoticeSubGift        = "subgift"
+	userNoticeSubMysteryGift = "submysterygift"
+	userNoticeRaid           = "raid"
+)
+
+// eventSubTypes contains the EventSub subscription type that publishes each kind of event
+// also published from USERNOTICE messages.
+var eventSubTypes = map[base.EventKind]string{
+	base.EventSubscribe:         helix.EventSubTypeChannelSubscription,
+	base.EventResubscribe:       helix.EventSubTypeChannelSubscriptionMessage,
+	base.EventGiftSubscriptions: helix.EventSubTypeChannelSubscriptionGift,
+	base.EventRaid:              helix.EventSubTypeChannelRaid,
+}
+
+// publishUserNotice publishes the events in a USERNOTICE message,
+// except those already published by EventSub in the channel.
+// It's called from the IRC client's goroutine, so rather than blocking it
+// while events aren't being handled, events are dropped.
+func (t *Twitch) publishUserNotice(msg twitchirc.UserNoticeMessage) {
+	for _, event := range parseUserNotice(msg) {
+		if t.eventSub != nil && t.eventSub.subscribed(event.ChannelID, eventSubTypes[event.Kind]) {
+			continue
+		}
+		select {
+		case t.events <- event:
+		default:
+			t.logger.Warn("Dropped event, too many are waiting to be handled", "kind", event.Kind, "channel", event.Channel)
+		}
+	}
+}
+
+// parseUserNotice converts a USERNOTICE message to events.
+// Messages that aren't about subscriptions or raids have none.
+func parseUserNotice(msg twitchirc.UserNoticeMessage) []base.Event {
+	event := base.Event{
+		ID:        msg.ID,
+		Channel:   msg.Channel,
+		ChannelID: msg.RoomID,
+		UserID:    msg.User.ID,
+		User:      msg.User.Name,
+		Time:      msg.Time,
+		Tier:      msg.MsgParams["msg-param-sub-plan"],
+	}
+	switch msg.MsgID {
+	case userNoticeSub:
+		event.Kind = base.EventSubscribe
+		return []base.Event{event}
+	case userNoticeResub:
+		event.Kind = base.EventResubscribe
+		event.Months = atoi(msg.MsgParams["msg-param-cumulative-months"])
+		event.Text = msg.Message
+		return []base.Event{event}
+	case userNoticeSubGift:
+		recipient := event
+		recipient.Kind = base.EventSubscribe
+		recipient.UserID = msg.MsgParams["msg-param-recipient-id"]
+		recipient.User = msg.MsgParams["msg-param-recipient-user-name"]
+		recipient.Gift = true
+		if _, ok := msg.MsgParams["msg-param-community-gift-id"]; ok {
+			// Part of a submysterygift, which is published on its own.
+			return []base.Event{recipient}
+		}
+		event.Kind = base.EventGiftSubscriptions
+		event.Gift = true
+		event.Count = 1
+		return []base.Event{event, recipient}
+	case userNoticeSubMysteryGift:
+		event.Kind = base.EventGiftSubscriptions
+		event.Gift = true
+		event.Count = atoi(msg.MsgParams["msg-param-mass-gift-count"])
+		return []base.Event{event}
+	case userNoticeRaid:
+		event.Kind = base.EventRaid
+		event.User = msg.MsgParams["msg-param-login"]
+		event.Tier = ""
+		event.Viewers = atoi(msg.MsgParams["msg-param-viewerCount"])
+		return []base.Event{event}
+	default:
+		return nil
+	}
+}
+
+// atoi converts a string to an int, returning 0 if it isn't one.
+func atoi(s string) int {
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return 0
+	}
+	return i
+}
@@ -0,0 +1,120 @@
+package twitch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/airforce270/airbot/base"
+	"github.com/airforce270/airbot/logging"
+	"github.com/google/go-cmp/cmp"
+
+	twitchirc "github.com/gempir/go-twitch-irc/v4"
+)
+
+func TestParseUserNotice(t *testing.T) {
+	t.Parallel()
+	sent := time.Date(2020, 5, 15, 10, 7, 0, 0, time.UTC)
+	userNotice := func(msgID string, params map[string]string, text string) twitchirc.UserNoticeMessage {
+		return twitchirc.UserNoticeMessage{
+			User:      twitchirc.User{ID: "3", Name: "user3"},
+			Message:   text,
+			Channel:   "user1",
+			RoomID:    "1",
+			ID:        "message-1",
+			Time:      sent,
+			MsgID:     msgID,
+			MsgParams: params,
+		}
+	}
+	tests := []struct {
+		desc  string
+		input twitchirc.UserNoticeMessage
+		want  []base.Event
+	}{
+		{
+			desc:  "sub",
+			input: userNotice("sub", map[string]string{"msg-param-sub-plan": "1000"}, ""),
+			want: []base.Event{
+				{Kind: base.EventSubscribe, ID: "message-1", Channel: "user1", ChannelID: "1", UserID: "3", User: "user3", Time: sent, Tier: "1000"},
+			},
+		},
+		{
+			desc:  "resub",
+			input: userNotice("resub", map[string]string{"msg-param-sub-plan": "2000", "msg-param-cumulative-months": "12"}, "hello"),
+			want: []base.Event{
+				{Kind: base.EventResubscribe, ID: "message-1", Channel: "user1", ChannelID: "1", UserID: "3", User: "user3", Time: sent, Tier: "2000", Months: 12, Text: "hello"},
+			},
+		},
+		{
+			desc:  "gift",
+			input: userNotice("subgift", map[string]string{"msg-param-sub-plan": "1000", "msg-param-recipient-id": "2", "msg-param-recipient-user-name": "user2"}, ""),
+			want: []base.Event{
+				{Kind: base.EventGiftSubscriptions, ID: "message-1", Channel: "user1", ChannelID: "1", UserID: "3", User: "user3", Time: sent, Tier: "1000", Gift: true, Count: 1},
+				{Kind: base.EventSubscribe, ID: "message-1", Channel: "user1", ChannelID: "1", UserID: "2", User: "user2", Time: sent, Tier: "1000", Gift: true},
+			},
+		},
+		{
+			desc:  "gift from mystery gift",
+			input: userNotice("subgift", map[string]string{"msg-param-sub-plan": "1000", "msg-param-recipient-id": "2", "msg-param-recipient-user-name": "user2", "msg-param-community-gift-id": "123"}, ""),
+			want: []base.Event{
+				{Kind: base.EventSubscribe, ID: "message-1", Channel: "user1", ChannelID: "1", UserID: "2", User: "user2", Time: sent, Tier: "1000", Gift: true},
+			},
+		},
+		{
+			desc:  "mystery gift",
+			input: userNotice("submysterygift", map[string]string{"msg-param-sub-plan": "1000", "msg-param-mass-gift-count": "5"}, ""),
+			want: []base.Event{
+				{Kind: base.EventGiftSubscriptions, ID: "message-1", Channel: "user1", ChannelID: "1", UserID: "3", User: "user3", Time: sent, Tier: "1000", Gift: true, Count: 5},
+			},
+		},
+		{
+			desc:  "raid",
+			input: userNotice("raid", map[string]string{"msg-param-login": "user3", "msg-param-viewerCount": "50"}, ""),
+			want: []base.Event{
+				{Kind: base.EventRaid, ID: "message-1", Channel: "user1", ChannelID: "1", UserID: "3", User: "user3", Time: sent, Viewers: 50},
+			},
+		},
+		{
+			desc:  "other",
+			input: userNotice("announcement", nil, "hello"),
+			want:  nil,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			got := parseUserNotice(tc.input)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("parseUserNotice() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPublishUserNotice(t *testing.T) {
+	t.Parallel()
+	tw := &Twitch{events: make(chan base.Event, 1), logger: logging.Discard()}
+	raid := func(raider string) twitchirc.UserNoticeMessage {
+		return twitchirc.UserNoticeMessage{
+			User:      twitchirc.User{ID: raider, Name: raider},
+			Channel:   "user1",
+			RoomID:    "1",
+			ID:        "raid-" + raider,
+			MsgID:     userNoticeRaid,
+			MsgParams: map[string]string{"msg-param-login": raider, "msg-param-viewerCount": "50"},
+		}
+	}
+
+	// Nothing is handling events, so once the buffer is full, the rest are dropped
+	// instead of blocking.
+	tw.publishUserNotice(raid("user2"))
+	tw.publishUserNotice(raid("user3"))
+
+	if got := len(tw.events); got != 1 {
+		t.Fatalf("published %d events, want 1", got)
+	}
+	if got := <-tw.events; got.User != "user2" {
+		t.Errorf("published event from %s, want user2", got.User)
+	}
+}
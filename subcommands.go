@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/airforce270/airbot/database"
+)
+
+// subcommand is a command-line subcommand, run instead of starting the bot.
+type subcommand struct {
+	// Usage is the usage of the subcommand's arguments.
+	Usage string
+	// Desc is the description of the subcommand.
+	Desc string
+	// Run runs the subcommand with the given arguments.
+	Run func(ctx context.Context, args []string) error
+}
+
+var subcommands = map[string]subcommand{
+	"migrate-to-postgres": {
+		Usage: "<postgres dsn>",
+		Desc:  "Copies all data from the SQLite database into an empty PostgreSQL database. The bot must not be running.",
+		Run:   migrateToPostgres,
+	},
+	"restore": {
+		Usage: "<snapshot>",
+		Desc:  "Validates a database backup and restores it. The bot must not be running.",
+		Run:   restoreSnapshot,
+	},
+}
+
+var errBadUsage = errors.New("bad usage")
+
+// runSubcommand runs the named subcommand.
+func runSubcommand(name string, args []string) error {
+	cmd, ok := subcommands[name]
+	if !ok {
+		return fmt.Errorf("unknown subcommand %q, available subcommands:\n%s", name, subcommandsHelp())
+	}
+	if err := cmd.Run(context.Background(), args); err != nil {
+		if errors.Is(err, errBadUsage) {
+			return fmt.Errorf("usage: airbot %s %s", name, cmd.Usage)
+		}
+		return err
+	}
+	return nil
+}
+
+func subcommandsHelp() string {
+	names := make([]string, 0, len(subcommands))
+	for name := range subcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var help strings.Builder
+	for _, name := range names {
+		cmd := subcommands[name]
+		fmt.Fprintf(&help, "  %s %s: %s\n", name, cmd.Usage, cmd.Desc)
+	}
+	return help.String()
+}
+
+func restoreSnapshot(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errBadUsage
+	}
+	snapshot, dbFile := args[0], dbFilePath()
+
+	slog.Info("Restoring snapshot...", "snapshot", snapshot, "database", dbFile)
+	previous, err := database.Restore(ctx, snapshot, dbFile)
+	if err != nil {
+		return err
+	}
+	if previous != "" {
+		slog.Info("Moved previous database", "path", previous)
+	}
+	slog.Info("Restored snapshot", "snapshot", snapshot)
+	return nil
+}
+
+func migrateToPostgres(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errBadUsage
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	src, err := database.Connect(ctx, log.Default(), database.SQLite{File: dbFilePath()})
+	if err != nil {
+		return fmt.Errorf("failed to connect to SQLite database: %w", err)
+	}
+	if err := database.Migrate(src); err != nil {
+		return fmt.Errorf("failed to migrate SQLite database: %w", err)
+	}
+	dst, err := database.Connect(ctx, log.Default(), database.Postgres{DSN: args[0]})
+	if err != nil {
+		return fmt.Errorf("failed to connect to PostgreSQL database: %w", err)
+	}
+
+	slog.Info("Copying database to PostgreSQL...", "database", dbFilePath())
+	if err := database.Copy(ctx, src, dst, slog.Default()); err != nil {
+		return err
+	}
+	slog.Info(fmt.Sprintf("Copied database to PostgreSQL. Set driver = %q in the [database] config to use it.", database.PostgresDriverName), "database", dbFilePath())
+	return nil
+}
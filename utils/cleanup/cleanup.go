@@ -5,6 +5,7 @@ package cleanup
 import (
 	"errors"
 	"fmt"
+	"slices"
 )
 
 // NewCleaner creates a new Cleaner.
@@ -19,9 +20,11 @@ func (c *Cleaner) Register(f Func) {
 	*c = append(*c, f)
 }
 
+// Cleanup calls the registered functions in the reverse order they were registered,
+// like deferred calls, so things are cleaned up before what they depend on.
 func (c *Cleaner) Cleanup() error {
 	var errs []error
-	for _, f := range *c {
+	for _, f := range slices.Backward(*c) {
 		if err := f.F(); err != nil {
 			errs = append(errs, fmt.Errorf("cleanup function %s failed: %w", f.Name, err))
 		}
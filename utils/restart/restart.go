@@ -3,7 +3,6 @@ package restart
 
 import (
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/airforce270/airbot/base"
@@ -15,24 +14,29 @@ import (
 var C = make(chan bool, 1)
 
 // WriteRequester writes information about where the restart was requested from.
-func WriteRequester(c cache.Cache, platform, channel, id string) {
+func WriteRequester(c cache.Cache, platform, channel, id string) error {
 	const expireIn = 30 * time.Second
 
-	go func() {
+	var eg errgroup.Group
+	eg.Go(func() error {
 		if err := c.StoreExpiringString(cache.KeyRestartRequestedOnPlatform, platform, expireIn); err != nil {
-			log.Printf("Failed to store platform that restart was requested from (%s): %v", platform, err)
+			return fmt.Errorf("failed to store platform that restart was requested from (%s): %w", platform, err)
 		}
-	}()
-	go func() {
+		return nil
+	})
+	eg.Go(func() error {
 		if err := c.StoreExpiringString(cache.KeyRestartRequestedInChannel, channel, expireIn); err != nil {
-			log.Printf("Failed to store channel that restart was requested from (%s): %v", channel, err)
+			return fmt.Errorf("failed to store channel that restart was requested from (%s): %w", channel, err)
 		}
-	}()
-	go func() {
+		return nil
+	})
+	eg.Go(func() error {
 		if err := c.StoreExpiringString(cache.KeyRestartRequestedByMessageID, id, expireIn); err != nil {
-			log.Printf("Failed to store message ID that requested restart (%s): %v", id, err)
+			return fmt.Errorf("failed to store message ID that requested restart (%s): %w", id, err)
 		}
-	}()
+		return nil
+	})
+	return eg.Wait()
 }
 
 // Notify notifies interested parties that the restart has finished.
@@ -0,0 +1,94 @@
+// Package secrets encrypts secrets stored at rest, i.e. OAuth tokens in the database.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// KeyEnvVar is the environment variable containing the key secrets are encrypted with.
+// It must be 32 random bytes, base64-encoded, i.e. from `openssl rand -base64 32`.
+const KeyEnvVar = "AIRBOT_TOKEN_KEY"
+
+// keySize is the size of the key, in bytes (AES-256).
+const keySize = 32
+
+var (
+	// ErrNoKey is returned by NewFromEnv when no key is set.
+	ErrNoKey = errors.New(KeyEnvVar + " is not set")
+	// ErrDecrypt is returned by Open when a secret can't be decrypted,
+	// i.e. it was encrypted with a different key.
+	ErrDecrypt = errors.New("failed to decrypt secret")
+)
+
+// Cipher encrypts and decrypts secrets with AES-GCM.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewFromEnv creates a Cipher with the key in KeyEnvVar.
+// It returns ErrNoKey if it isn't set.
+func NewFromEnv() (*Cipher, error) {
+	key, ok := os.LookupEnv(KeyEnvVar)
+	if !ok || key == "" {
+		return nil, ErrNoKey
+	}
+	c, err := New(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", KeyEnvVar, err)
+	}
+	return c, nil
+}
+
+// New creates a Cipher with a base64-encoded 32-byte key.
+func New(key string) (*Cipher, error) {
+	rawKey, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("key must be base64-encoded: %w", err)
+	}
+	if len(rawKey) != keySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", keySize, len(rawKey))
+	}
+	block, err := aes.NewCipher(rawKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM cipher: %w", err)
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// Seal encrypts a secret, returning it base64-encoded.
+func (c *Cipher) Seal(plaintext string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a secret encrypted by Seal.
+// It returns ErrDecrypt if it was encrypted with a different key or has been modified.
+func (c *Cipher) Open(ciphertext string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("%w: not base64-encoded: %w", ErrDecrypt, err)
+	}
+	nonceSize := c.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", fmt.Errorf("%w: too short", ErrDecrypt)
+	}
+	plaintext, err := c.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrDecrypt, err)
+	}
+	return string(plaintext), nil
+}
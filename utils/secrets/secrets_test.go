@@ -0,0 +1,74 @@
+package secrets
+
+import (
+	"errors"
+	"testing"
+)
+
+const (
+	testKey      = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+	otherTestKey = "ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA="
+)
+
+func TestCipher(t *testing.T) {
+	t.Parallel()
+	c, err := New(testKey)
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	sealed, err := c.Seal("some-token")
+	if err != nil {
+		t.Fatalf("Seal() unexpected error: %v", err)
+	}
+	if sealed == "some-token" {
+		t.Errorf("Seal() = %q, want it encrypted", sealed)
+	}
+	got, err := c.Open(sealed)
+	if err != nil {
+		t.Fatalf("Open() unexpected error: %v", err)
+	}
+	if got != "some-token" {
+		t.Errorf("Open() = %q, want %q", got, "some-token")
+	}
+
+	other, err := New(otherTestKey)
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if _, err := other.Open(sealed); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Open() with another key error = %v, want %v", err, ErrDecrypt)
+	}
+}
+
+func TestNew_InvalidKey(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		desc string
+		key  string
+	}{
+		{desc: "not base64", key: "not base64!"},
+		{desc: "too short", key: "c2hvcnQ="},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			if _, err := New(tc.key); err == nil {
+				t.Errorf("New(%q) = nil error, want error", tc.key)
+			}
+		})
+	}
+}
+
+func TestNewFromEnv(t *testing.T) {
+	t.Setenv(KeyEnvVar, "")
+	if _, err := NewFromEnv(); !errors.Is(err, ErrNoKey) {
+		t.Errorf("NewFromEnv() without key error = %v, want %v", err, ErrNoKey)
+	}
+
+	t.Setenv(KeyEnvVar, testKey)
+	if _, err := NewFromEnv(); err != nil {
+		t.Errorf("NewFromEnv() unexpected error: %v", err)
+	}
+}